@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	cookedembed "github.com/air-gapped/cooked/embed"
+	"github.com/air-gapped/cooked/internal/credentials"
+	"github.com/air-gapped/cooked/internal/fetch"
+	"github.com/air-gapped/cooked/internal/forge"
+	"github.com/air-gapped/cooked/internal/offline"
+)
+
+// exportFlags holds the flags of `cooked export`.
+type exportFlags struct {
+	output            string
+	prefix            string
+	depth             int
+	maxPages          int
+	theme             string
+	fetchTimeout      time.Duration
+	maxFileSize       int64
+	tlsSkipVerify     bool
+	credentialsFile   string
+	forgeHosts        string
+	forgeProfilesFile string
+	forgeAutodetect   bool
+}
+
+// runExport implements `cooked export`: it crawls upstream documentation
+// into an offline static site and returns the process exit code.
+func runExport(args []string, stdout, stderr io.Writer) int {
+	var f exportFlags
+	fs := flag.NewFlagSet("cooked export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&f.output, "output", "cooked-site", "Output directory")
+	fs.StringVar(&f.prefix, "prefix", "", "Only follow links below this URL (default: directory of the first start URL)")
+	fs.IntVar(&f.depth, "depth", 5, "Maximum number of link hops from the start URLs")
+	fs.IntVar(&f.maxPages, "max-pages", 500, "Maximum number of pages to export")
+	fs.StringVar(&f.theme, "theme", "auto", "Default theme: auto, light, or dark")
+	fs.DurationVar(&f.fetchTimeout, "fetch-timeout", 10*time.Second, "Upstream request timeout")
+	fs.Int64Var(&f.maxFileSize, "max-file-size", 5*1024*1024, "Max upstream file size in bytes")
+	fs.BoolVar(&f.tlsSkipVerify, "tls-skip-verify", false, "Disable TLS certificate verification for upstream fetches")
+	fs.StringVar(&f.credentialsFile, "credentials-file", "", "Path to JSON file of per-host upstream credentials")
+	fs.StringVar(&f.forgeHosts, "forge-hosts", "", "Comma-separated host=profile bindings for forge URL translation")
+	fs.StringVar(&f.forgeProfilesFile, "forge-profiles-file", "", "Path to JSON file of operator-defined regex forge profiles")
+	fs.BoolVar(&f.forgeAutodetect, "forge-autodetect", false, "Recognise Gitea, GitLab and Bitbucket page URLs on hosts not bound with --forge-hosts")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: cooked export [flags] URL...\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	report, err := export(ctx, fs.Args(), f)
+	if report != nil {
+		printExportReport(stdout, stderr, report, f.output)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cooked export: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func export(ctx context.Context, starts []string, f exportFlags) (*offline.ExportReport, error) {
+	switch f.theme {
+	case "auto", "light", "dark":
+	default:
+		return nil, fmt.Errorf("invalid --theme %q (must be auto, light, or dark)", f.theme)
+	}
+	if f.depth < 0 || f.maxPages <= 0 || f.maxFileSize <= 0 || f.fetchTimeout <= 0 {
+		return nil, errors.New("--depth must not be negative; --max-pages, --max-file-size and --fetch-timeout must be positive")
+	}
+
+	forges, err := forge.Load(f.forgeHosts, f.forgeProfilesFile, f.forgeAutodetect)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]string, len(starts))
+	for i, start := range starts {
+		resolved[i], _, _ = forges.Resolve(start)
+	}
+
+	prefix, err := exportPrefix(f.prefix, resolved[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// The crawl runs on the operator's behalf against hosts they chose, which
+	// are usually internal: no SSRF protection.
+	fetchOpts := []fetch.Option{fetch.WithSSRFProtection(false)}
+	if f.credentialsFile != "" {
+		creds, err := credentials.Load(f.credentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		fetchOpts = append(fetchOpts, fetch.WithCredentials(creds))
+	}
+	client := fetch.NewClient(f.fetchTimeout, f.maxFileSize, f.tlsSkipVerify, fetchOpts...)
+
+	r := offline.NewRenderer(cookedembed.Assets, version, f.theme)
+	return r.Export(ctx, client, resolved, f.output, offline.ExportOptions{
+		Prefix:   prefix,
+		MaxDepth: f.depth,
+		MaxPages: f.maxPages,
+	})
+}
+
+// exportPrefix normalizes the --prefix flag, defaulting to the directory of
+// the first start URL. It always ends in a slash, so that /docs does not
+// also admit /docs-old.
+func exportPrefix(prefix, firstStart string) (string, error) {
+	if prefix == "" {
+		prefix = firstStart[:strings.LastIndex(firstStart, "/")+1]
+	}
+	u, err := url.Parse(prefix)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid prefix %q: must be an http or https URL", prefix)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	p := u.String()
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p, nil
+}
+
+func printExportReport(stdout, stderr io.Writer, report *offline.ExportReport, output string) {
+	fmt.Fprintf(stdout, "exported %d pages and %d assets to %s", len(report.Pages), report.Assets, output)
+	if report.Index != "" {
+		fmt.Fprintf(stdout, " (index: %s)", report.Index)
+	}
+	fmt.Fprintln(stdout)
+	if report.Unfollowed > 0 {
+		fmt.Fprintf(stdout, "%d links not followed: --depth or --max-pages reached\n", report.Unfollowed)
+	}
+	for _, b := range report.Broken {
+		if b.Page == "" {
+			fmt.Fprintf(stderr, "broken: %s: %s\n", b.Target, b.Reason)
+		} else {
+			fmt.Fprintf(stderr, "broken: %s (linked from %s): %s\n", b.Target, b.Page, b.Reason)
+		}
+	}
+}
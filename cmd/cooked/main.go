@@ -14,7 +14,10 @@ import (
 	cookeddocs "github.com/air-gapped/cooked/docs"
 	cookedembed "github.com/air-gapped/cooked/embed"
 	"github.com/air-gapped/cooked/internal/config"
+	"github.com/air-gapped/cooked/internal/credentials"
+	"github.com/air-gapped/cooked/internal/fetch"
 	"github.com/air-gapped/cooked/internal/server"
+	"github.com/air-gapped/cooked/internal/tracing"
 )
 
 // Set by linker via -ldflags.
@@ -33,6 +36,15 @@ func main() {
 		}
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "render":
+			os.Exit(runRender(os.Args[2:], os.Stdout, os.Stderr))
+		case "export":
+			os.Exit(runExport(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	cfg, err := config.Parse(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "cooked: %v\n", err)
@@ -49,28 +61,49 @@ func main() {
 		slog.Warn("TLS certificate verification disabled for upstream fetches")
 	}
 
+	var fetchOpts []fetch.Option
+	if cfg.CredentialsFile != "" {
+		creds, err := credentials.Load(cfg.CredentialsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cooked: %v\n", err)
+			os.Exit(1)
+		}
+		fetchOpts = append(fetchOpts, fetch.WithCredentials(creds))
+		slog.Info("upstream credentials loaded", "count", creds.Len())
+	}
+
 	slog.Info("config loaded",
 		"listen", cfg.Listen,
 		"cache_ttl", cfg.CacheTTL.String(),
 		"cache_max_size", cfg.CacheMaxSize,
+		"cache_dir", cfg.CacheDir,
+		"cache_stale_while_revalidate", cfg.CacheStaleWhileRevalidate.String(),
+		"cache_stale_if_error", cfg.CacheStaleIfError.String(),
 		"fetch_timeout", cfg.FetchTimeout.String(),
 		"max_file_size", cfg.MaxFileSize,
 		"default_theme", cfg.DefaultTheme,
 		"tls_skip_verify", cfg.TLSSkipVerify,
+		"admin_api", cfg.AdminToken != "",
+		"admin_listen", cfg.AdminListen,
+		"webhook", cfg.WebhookSecret != "",
+		"webhook_prewarm", cfg.WebhookPrewarm,
+		"metrics", cfg.Metrics,
+		"metrics_listen", cfg.MetricsListen,
+		"otlp_endpoint", cfg.OTLPEndpoint,
+		"otlp_protocol", cfg.OTLPProtocol,
+		"trace_sample_ratio", cfg.TraceSampleRatio,
 	)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint, cfg.OTLPProtocol, cfg.TraceSampleRatio, version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cooked: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create server with all dependencies
-	srv := server.New(cfg, version, cookedembed.Assets, cookeddocs.Assets)
+	srv := server.New(cfg, version, cookedembed.Assets, cookeddocs.Assets, fetchOpts...)
 
-	httpServer := &http.Server{
-		Addr:              cfg.Listen,
-		Handler:           srv.Handler(),
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       30 * time.Second,
-		WriteTimeout:      60 * time.Second,
-		IdleTimeout:       120 * time.Second,
-		MaxHeaderBytes:    1 << 20, // 1 MB
-	}
+	httpServer := newHTTPServer(cfg.Listen, srv.Handler())
 
 	// Start server in background
 	go func() {
@@ -81,6 +114,17 @@ func main() {
 		}
 	}()
 
+	// The admin API and metrics get their own listeners when configured, so
+	// they can stay off the public ingress. Metrics share the admin listener
+	// when both use the same address.
+	var internalServers []*http.Server
+	if cfg.AdminListen != "" {
+		internalServers = append(internalServers, startInternal("admin", cfg.AdminListen, srv.AdminHandler()))
+	}
+	if cfg.Metrics && cfg.MetricsListen != "" && cfg.MetricsListen != cfg.AdminListen {
+		internalServers = append(internalServers, startInternal("metrics", cfg.MetricsListen, srv.MetricsHandler()))
+	}
+
 	// Wait for shutdown signal
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
@@ -92,10 +136,44 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	for _, internal := range internalServers {
+		if err := internal.Shutdown(shutdownCtx); err != nil {
+			slog.Error("shutdown error", "listen", internal.Addr, "error", err)
+		}
+	}
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		slog.Error("shutdown error", "error", err)
 		os.Exit(1)
 	}
+	// Flush spans of the requests that just drained.
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("trace export shutdown error", "error", err)
+	}
 
 	slog.Info("shutdown complete")
 }
+
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20, // 1 MB
+	}
+}
+
+// startInternal serves an operator-only handler in the background.
+func startInternal(name, addr string, handler http.Handler) *http.Server {
+	srv := newHTTPServer(addr, handler)
+	go func() {
+		slog.Info(name+" server started", "listen", addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(name+" listen failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+	return srv
+}
@@ -122,3 +122,88 @@ func TestGracefulShutdown(t *testing.T) {
 		t.Error("server still responding after shutdown")
 	}
 }
+
+func TestRender(t *testing.T) {
+	bin := buildBinary(t)
+
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md":     "# Project\n\nSee [the guide](docs/guide.md).\n\n![logo](docs/logo.png)\n",
+		"docs/guide.md": "# Guide\n",
+		"docs/logo.png": "PNG",
+	} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "site")
+
+	cmd := exec.Command(bin, "render", "--output", out, "README.md", "docs/*")
+	cmd.Dir = src
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("render failed: %v\n%s", err, b)
+	}
+
+	readme, err := os.ReadFile(filepath.Join(out, "README.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(readme), `href="docs/guide.html"`) {
+		t.Error("link to guide not rewritten to its .html sibling")
+	}
+	for _, name := range []string{"docs/guide.html", "docs/logo.png"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		}
+	}
+
+	if err := exec.Command(bin, "render", filepath.Join(src, "missing.md")).Run(); err == nil {
+		t.Error("render of a missing file succeeded")
+	}
+}
+
+func TestExport(t *testing.T) {
+	bin := buildBinary(t)
+
+	upstream := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/README.md":
+			w.Write([]byte("# Docs\n\n[Guide](guide.md) [Gone](gone.md)\n"))
+		case "/docs/guide.md":
+			w.Write([]byte("# Guide\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go upstream.Serve(ln)
+	defer upstream.Close()
+
+	out := filepath.Join(t.TempDir(), "site")
+	cmd := exec.Command(bin, "export", "--output", out, "http://"+ln.Addr().String()+"/docs/README.md")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("export failed: %v\n%s", err, stderr.String())
+	}
+
+	if !strings.Contains(string(stdout), "exported 2 pages") {
+		t.Errorf("stdout = %s", stdout)
+	}
+	if !strings.Contains(stderr.String(), "broken: http://"+ln.Addr().String()+"/docs/gone.md") {
+		t.Errorf("broken link not reported: %s", stderr.String())
+	}
+	for _, name := range []string{"index.html", "README.html", "guide.html"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		}
+	}
+}
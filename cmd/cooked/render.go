@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cookedembed "github.com/air-gapped/cooked/embed"
+	"github.com/air-gapped/cooked/internal/offline"
+	"github.com/air-gapped/cooked/internal/render"
+)
+
+// runRender implements `cooked render`: it renders local files to
+// self-contained HTML pages and returns the process exit code.
+func runRender(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cooked render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("output", "cooked-html", "Output directory")
+	root := fs.String("root", "", "Directory whose layout is mirrored in the output (default: common parent of the inputs)")
+	theme := fs.String("theme", "auto", "Default theme: auto, light, or dark")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: cooked render [flags] FILE|GLOB...\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := renderFiles(fs.Args(), *root, *output, *theme, stdout); err != nil {
+		fmt.Fprintf(stderr, "cooked render: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func renderFiles(patterns []string, root, output, theme string, stdout io.Writer) error {
+	switch theme {
+	case "auto", "light", "dark":
+	default:
+		return fmt.Errorf("invalid --theme %q (must be auto, light, or dark)", theme)
+	}
+
+	files, err := expandInputs(patterns)
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		root = commonDir(files)
+	} else if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+
+	r := offline.NewRenderer(cookedembed.Assets, version, theme)
+	written, err := r.RenderFiles(root, files, output)
+	for _, name := range written {
+		fmt.Fprintln(stdout, filepath.Join(output, filepath.FromSlash(name)))
+	}
+	return err
+}
+
+// expandInputs resolves file arguments and glob patterns to absolute paths.
+// Files named explicitly must exist; glob matches are narrowed to renderable
+// files, so `docs/*` skips images and subdirectories.
+func expandInputs(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		isGlob := strings.ContainsAny(pattern, `*?[`)
+		if !isGlob {
+			info, err := os.Stat(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("%s is a directory", pattern)
+			}
+			matches = []string{pattern}
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+
+		for _, m := range matches {
+			if isGlob {
+				info, err := os.Stat(m)
+				if err != nil || !info.Mode().IsRegular() || render.DetectFile(m).ContentType == render.TypeUnsupported {
+					continue
+				}
+			}
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, abs)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no renderable files")
+	}
+	return files, nil
+}
+
+// commonDir returns the deepest directory containing all of files.
+func commonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		for {
+			rel, err := filepath.Rel(dir, f)
+			if err == nil && filepath.IsLocal(rel) {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return dir
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
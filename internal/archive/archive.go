@@ -0,0 +1,344 @@
+// Package archive reads the members of zip and tar archives held in memory,
+// with limits that keep decompression bombs from exhausting memory or CPU.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Format is an archive format.
+type Format int
+
+const (
+	Zip Format = iota + 1
+	Tar
+	TarGzip
+	TarBzip2
+)
+
+const (
+	// MaxEntries caps the number of members indexed.
+	MaxEntries = 100000
+	// MaxRatio caps how far data may expand when decompressed: per member
+	// for zip, over the whole stream for compressed tar, which Open also
+	// holds to the absolute limit it is given.
+	MaxRatio = 100
+	// minExpansion is the decompressed size always allowed for a compressed
+	// tar, as tar's block padding compresses extremely well.
+	minExpansion = 16 << 20
+)
+
+var extensions = []struct {
+	suffix string
+	format Format
+}{
+	{".tar.gz", TarGzip},
+	{".tgz", TarGzip},
+	{".tar.bz2", TarBzip2},
+	{".tbz2", TarBzip2},
+	{".tar", Tar},
+	{".zip", Zip},
+}
+
+// Detect returns the archive format of a file name by its extension.
+func Detect(name string) (Format, bool) {
+	lower := strings.ToLower(name)
+	for _, e := range extensions {
+		if strings.HasSuffix(lower, e.suffix) {
+			return e.format, true
+		}
+	}
+	return 0, false
+}
+
+// Entry describes a member of an archive. Directories that have no entry of
+// their own in the archive are implied by the paths below them.
+type Entry struct {
+	Name    string
+	IsDir   bool
+	Regular bool  // false for symlinks and other special members
+	Size    int64 // uncompressed size as declared by the archive; -1 for directories
+}
+
+type member struct {
+	Entry
+	zipFile   *zip.File
+	tarIndex  int   // position of the member's header in the tar stream
+	tarOffset int64 // offset of the member's content in the tar stream
+	sparse    bool  // content is stored as a GNU sparse map, not at tarOffset
+}
+
+// Archive is an indexed archive. It is safe for concurrent use.
+type Archive struct {
+	format   Format
+	data     []byte             // the zip file, or the decompressed tar stream
+	members  map[string]*member // by slash-separated path, "." for the top
+	children map[string][]string
+}
+
+// Open indexes the archive in data. A compressed tar stream is decompressed
+// once, here, and fails if it expands past maxExpanded bytes or MaxRatio
+// times its size; members are then read from the decompressed stream without
+// inflating it again. Member paths are cleaned; members whose path would
+// leave the archive, such as ../x, are ignored.
+func Open(data []byte, format Format, maxExpanded int64) (*Archive, error) {
+	a := &Archive{
+		format:   format,
+		data:     data,
+		members:  map[string]*member{".": {Entry: Entry{Name: ".", IsDir: true, Size: -1}}},
+		children: make(map[string][]string),
+	}
+	var err error
+	switch format {
+	case Zip:
+		err = a.indexZip()
+	case Tar:
+		err = a.indexTar()
+	default:
+		if a.data, err = decompress(data, format, maxExpanded); err == nil {
+			err = a.indexTar()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, names := range a.children {
+		sort.Strings(names)
+	}
+	return a, nil
+}
+
+// Size returns how many bytes the archive holds in memory: the zip file, or
+// the decompressed tar stream.
+func (a *Archive) Size() int64 {
+	return int64(len(a.data))
+}
+
+func (a *Archive) indexZip() error {
+	zr, err := zip.NewReader(bytes.NewReader(a.data), int64(len(a.data)))
+	if err != nil {
+		return err
+	}
+	if len(zr.File) > MaxEntries {
+		return fmt.Errorf("archive has too many members (limit is %d)", MaxEntries)
+	}
+	for _, f := range zr.File {
+		mode := f.Mode()
+		size := int64(f.UncompressedSize64)
+		if f.UncompressedSize64 > 1<<62 {
+			size = 1 << 62
+		}
+		a.add(f.Name, &member{
+			Entry:   Entry{IsDir: mode.IsDir(), Regular: mode.IsRegular(), Size: size},
+			zipFile: f,
+		})
+	}
+	return nil
+}
+
+func (a *Archive) indexTar() error {
+	r := bytes.NewReader(a.data)
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if i >= MaxEntries {
+			return fmt.Errorf("archive has too many members (limit is %d)", MaxEntries)
+		}
+		switch h.Typeflag {
+		case tar.TypeXGlobalHeader, tar.TypeXHeader, tar.TypeGNULongName, tar.TypeGNULongLink:
+			continue // consumed by the reader
+		}
+		// The reader has consumed exactly the member's header blocks, so
+		// its content starts here.
+		a.add(h.Name, &member{
+			Entry: Entry{
+				IsDir:   h.Typeflag == tar.TypeDir,
+				Regular: h.Typeflag == tar.TypeReg,
+				Size:    h.Size,
+			},
+			tarIndex:  i,
+			tarOffset: r.Size() - int64(r.Len()),
+			sparse:    isSparse(h),
+		})
+	}
+}
+
+// add records a member under its cleaned path, along with the directories
+// implied by it. Later members replace earlier ones with the same path, as
+// when extracting.
+func (a *Archive) add(name string, m *member) {
+	p := path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))[1:]
+	if p == "" || !fs.ValidPath(p) {
+		return
+	}
+	m.Name = path.Base(p)
+	if m.IsDir {
+		m.Size = -1
+	}
+	if prev, ok := a.members[p]; ok && prev.IsDir && m.IsDir {
+		return
+	}
+	a.link(p)
+	a.members[p] = m
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := a.members[dir]; ok {
+			break
+		}
+		a.link(dir)
+		a.members[dir] = &member{Entry: Entry{Name: path.Base(dir), IsDir: true, Size: -1}}
+	}
+}
+
+// link lists p in its parent directory once.
+func (a *Archive) link(p string) {
+	if _, ok := a.members[p]; ok {
+		return
+	}
+	dir := path.Dir(p)
+	a.children[dir] = append(a.children[dir], p)
+}
+
+// Stat describes the member at p, a slash-separated path valid per
+// fs.ValidPath; "." is the top of the archive.
+func (a *Archive) Stat(p string) (Entry, error) {
+	m, ok := a.members[p]
+	if !ok {
+		return Entry{}, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return m.Entry, nil
+}
+
+// ReadDir lists the directory at p, sorted by path.
+func (a *Archive) ReadDir(p string) ([]Entry, error) {
+	m, ok := a.members[p]
+	if !ok || !m.IsDir {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrNotExist}
+	}
+	entries := make([]Entry, 0, len(a.children[p]))
+	for _, c := range a.children[p] {
+		entries = append(entries, a.members[c].Entry)
+	}
+	return entries, nil
+}
+
+// ReadFile decompresses the regular file at p. It fails once more than
+// maxSize bytes come out, whatever size the archive declares.
+func (a *Archive) ReadFile(p string, maxSize int64) ([]byte, error) {
+	m, ok := a.members[p]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: fs.ErrNotExist}
+	}
+	if !m.Regular {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: errors.New("not a regular file")}
+	}
+	if m.Size > maxSize {
+		return nil, fmt.Errorf("file too large: %d bytes exceeds %d", m.Size, maxSize)
+	}
+
+	var r io.Reader
+	if m.zipFile != nil {
+		f := m.zipFile
+		if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > MaxRatio {
+			return nil, fmt.Errorf("member too large for its compressed size (ratio above %d)", MaxRatio)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		r = rc
+	} else if !m.sparse {
+		if m.tarOffset+m.Size > int64(len(a.data)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return bytes.Clone(a.data[m.tarOffset : m.tarOffset+m.Size]), nil
+	} else {
+		// A sparse member's holes are filled in by the tar reader, which
+		// has to be walked to it.
+		tr := tar.NewReader(bytes.NewReader(a.data))
+		for i := 0; i <= m.tarIndex; i++ {
+			if _, err := tr.Next(); err != nil {
+				return nil, err
+			}
+		}
+		r = tr
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("file too large: exceeds %d bytes", maxSize)
+	}
+	return body, nil
+}
+
+// decompress decompresses a tar stream, failing once it produces more than
+// maxExpanded bytes or MaxRatio times the size of data.
+func decompress(data []byte, format Format, maxExpanded int64) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(data)
+	switch format {
+	case TarGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case TarBzip2:
+		r = bzip2.NewReader(r)
+	}
+	limit := min(max(int64(len(data))*MaxRatio, minExpansion), maxExpanded)
+	return io.ReadAll(&expansionLimiter{r: r, remaining: limit, limit: limit})
+}
+
+// isSparse reports whether a tar member is stored as a GNU sparse file,
+// whose content is not laid out contiguously in the stream.
+func isSparse(h *tar.Header) bool {
+	if h.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range h.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// expansionLimiter fails a decompression stream that produces more than
+// limit bytes.
+type expansionLimiter struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *expansionLimiter) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, fmt.Errorf("decompressed archive too large: exceeds %d bytes", l.limit)
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
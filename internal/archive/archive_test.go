@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func zipData(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzData(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	for name, want := range map[string]Format{
+		"app-1.2.tar.gz": TarGzip,
+		"APP.TGZ":        TarGzip,
+		"docs.tar.bz2":   TarBzip2,
+		"docs.tar":       Tar,
+		"bundle.zip":     Zip,
+	} {
+		if got, ok := Detect(name); !ok || got != want {
+			t.Errorf("Detect(%q) = %v, %v; want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"README.md", "app.gz", "zip"} {
+		if _, ok := Detect(name); ok {
+			t.Errorf("Detect(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	files := map[string]string{
+		"./app-1.2/README.md":       "# App\n",
+		"app-1.2/docs/install.md":   "# Install\n",
+		"app-1.2/docs/img/arch.png": "PNG",
+		"../escape.md":              "# Outside\n",
+		"/abs/x.md":                 "# Abs\n",
+	}
+	for name, data := range map[string]struct {
+		data   []byte
+		format Format
+	}{
+		"zip":    {zipData(t, files), Zip},
+		"tar.gz": {tarGzData(t, files), TarGzip},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, err := Open(data.data, data.format, 1<<30)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			top, err := a.ReadDir(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range top {
+				names = append(names, e.Name)
+			}
+			// ../escape.md is cleaned to escape.md within the archive.
+			if strings.Join(names, ",") != "abs,app-1.2,escape.md" {
+				t.Errorf("ReadDir(.) = %v", names)
+			}
+
+			if e, err := a.Stat("app-1.2/docs"); err != nil || !e.IsDir {
+				t.Errorf("implied directory: %+v, %v", e, err)
+			}
+			e, err := a.Stat("app-1.2/docs/install.md")
+			if err != nil || e.IsDir || !e.Regular || e.Size != 10 {
+				t.Errorf("Stat(install.md) = %+v, %v", e, err)
+			}
+			body, err := a.ReadFile("app-1.2/docs/install.md", 1024)
+			if err != nil || string(body) != "# Install\n" {
+				t.Errorf("ReadFile = %q, %v", body, err)
+			}
+			body, err = a.ReadFile("app-1.2/README.md", 1024)
+			if err != nil || string(body) != "# App\n" {
+				t.Errorf("ReadFile(README.md) = %q, %v", body, err)
+			}
+			if _, err := a.ReadFile("app-1.2/docs/install.md", 5); err == nil || !strings.Contains(err.Error(), "too large") {
+				t.Errorf("ReadFile over the limit: error = %v", err)
+			}
+			if _, err := a.Stat("missing.md"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(missing.md) error = %v", err)
+			}
+			if _, err := a.ReadFile("app-1.2/docs", 1024); err == nil {
+				t.Error("ReadFile of a directory: expected error")
+			}
+		})
+	}
+}
+
+func TestReadFile_Bombs(t *testing.T) {
+	zeros := strings.Repeat("\x00", 4<<20)
+
+	// A highly compressed zip member is refused before decompression.
+	a, err := Open(zipData(t, map[string]string{"bomb.txt": zeros}), Zip, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ReadFile("bomb.txt", 8<<20); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("zip bomb: error = %v", err)
+	}
+
+	// A compressed tar stream may not expand past its limit.
+	data := tarGzData(t, map[string]string{"a.bin": zeros, "b.bin": zeros, "c.bin": zeros, "d.bin": zeros, "e.bin": zeros})
+	if _, err := Open(data, TarGzip, 1<<30); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("tar bomb: error = %v", err)
+	}
+
+	// Nor past the absolute cap, whatever the ratio allows.
+	data = tarGzData(t, map[string]string{"a.bin": zeros[:1<<20]})
+	if _, err := Open(data, TarGzip, 1<<30); err != nil {
+		t.Errorf("tar within the cap: error = %v", err)
+	}
+	if _, err := Open(data, TarGzip, 512<<10); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("tar over the cap: error = %v", err)
+	}
+}
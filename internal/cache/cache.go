@@ -2,6 +2,7 @@ package cache
 
 import (
 	"container/list"
+	"strings"
 	"sync"
 	"time"
 )
@@ -17,6 +18,23 @@ const (
 	StatusStale       Status = "stale" // served expired entry because revalidation failed
 )
 
+// variantSep separates an upstream URL from a variant name in a cache key.
+// Upstream URLs reach cooked without a fragment, so it cannot collide.
+const variantSep = "#cooked-"
+
+// VariantKey returns the cache key for an alternative rendering of rawURL,
+// such as the render API's JSON. A variant is fetched, revalidated and purged
+// like the page but never shares its entry.
+func VariantKey(rawURL, variant string) string {
+	return rawURL + variantSep + variant
+}
+
+// KeyURL returns the upstream URL a cache key refers to.
+func KeyURL(key string) string {
+	rawURL, _, _ := strings.Cut(key, variantSep)
+	return rawURL
+}
+
 // Entry holds a cached rendered page.
 type Entry struct {
 	HTML         []byte
@@ -24,10 +42,28 @@ type Entry struct {
 	LastModified string
 	Size         int64
 	ContentType  string
+	TypeSource   string // what the content type was detected from
 	ExpiresAt    time.Time
+	Freshness    Freshness
+}
+
+// Freshness is the caching policy an upstream declared for a page through
+// Cache-Control and Expires. The zero value means nothing was declared and
+// the default TTL applies.
+type Freshness struct {
+	MaxAge         time.Duration // freshness lifetime, valid when HasMaxAge
+	HasMaxAge      bool
+	NoStore        bool // never cache
+	MustRevalidate bool // never serve once expired without revalidating
+}
+
+// Declared reports whether the upstream sent any caching policy.
+func (f Freshness) Declared() bool {
+	return f.HasMaxAge || f.NoStore || f.MustRevalidate
 }
 
-// Cache is a thread-safe, in-memory LRU cache with TTL and byte-counting eviction.
+// Cache is a thread-safe, in-memory LRU cache with TTL and byte-counting
+// eviction, optionally backed by a persistent disk tier.
 type Cache struct {
 	mu      sync.Mutex
 	items   map[string]*list.Element
@@ -36,11 +72,16 @@ type Cache struct {
 	maxSize int64
 	curSize int64
 	now     func() time.Time // injectable for testing
+	disk    *Disk            // nil when memory-only
+	minTTL  time.Duration    // bounds for upstream-declared lifetimes
+	maxTTL  time.Duration    // 0 = unbounded
+	counts  counters
 }
 
 type cacheItem struct {
 	key   string
 	entry Entry
+	hits  int64
 }
 
 // New creates a cache with the given TTL and max size in bytes.
@@ -54,16 +95,57 @@ func New(ttl time.Duration, maxSize int64) *Cache {
 	}
 }
 
+// SetDisk attaches a disk tier. Entries are written through to disk, and
+// memory misses are looked up on disk and promoted into memory.
+func (c *Cache) SetDisk(d *Disk) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disk = d
+}
+
+// SetTTLBounds clamps upstream-declared freshness lifetimes to [min, max].
+// A max of 0 leaves lifetimes unbounded above. Entries without a declared
+// lifetime use the default TTL unchanged.
+func (c *Cache) SetTTLBounds(minTTL, maxTTL time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.minTTL = minTTL
+	c.maxTTL = maxTTL
+}
+
+// entryTTL returns the lifetime for an entry. Must be called with mu held.
+func (c *Cache) entryTTL(f Freshness) time.Duration {
+	if !f.HasMaxAge {
+		return c.ttl
+	}
+	ttl := f.MaxAge
+	if ttl < c.minTTL {
+		ttl = c.minTTL
+	}
+	if c.maxTTL > 0 && ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+	return ttl
+}
+
 // Get retrieves a cached entry. Returns the entry, true if found (may be expired).
 // The status indicates hit, miss, or expired.
 func (c *Cache) Get(key string) (*Entry, Status) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	elem, ok := c.items[key]
 	if !ok {
-		return nil, StatusMiss
+		disk := c.disk
+		c.mu.Unlock()
+		if disk == nil {
+			return nil, StatusMiss
+		}
+		entry, found := disk.Get(key)
+		if !found {
+			return nil, StatusMiss
+		}
+		return c.promote(key, *entry)
 	}
+	defer c.mu.Unlock()
 
 	item := elem.Value.(*cacheItem)
 
@@ -74,16 +156,68 @@ func (c *Cache) Get(key string) (*Entry, Status) {
 
 	// Move to front (most recently used)
 	c.order.MoveToFront(elem)
+	item.hits++
 	return &item.entry, StatusHit
 }
 
+// promote copies an entry read from disk into memory, keeping its expiry.
+func (c *Cache) promote(key string, entry Entry) (*Entry, Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A concurrent Put may have stored a newer entry meanwhile.
+	if elem, ok := c.items[key]; ok {
+		entry = elem.Value.(*cacheItem).entry
+	} else {
+		c.store(key, entry)
+	}
+
+	status := StatusHit
+	if c.now().After(entry.ExpiresAt) {
+		status = StatusExpired
+	}
+	return &entry, status
+}
+
 // Put stores an entry in the cache. Evicts LRU entries if necessary.
+// Entries marked no-store are not cached, and replace nothing: any older
+// entry for the key is removed instead.
 func (c *Cache) Put(key string, entry Entry) {
+	if entry.Freshness.NoStore {
+		c.Delete(key)
+		return
+	}
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	entry.ExpiresAt = c.now().Add(c.entryTTL(entry.Freshness))
+	c.store(key, entry)
+	disk := c.disk
+	c.mu.Unlock()
 
-	entry.ExpiresAt = c.now().Add(c.ttl)
+	if disk != nil {
+		disk.Put(key, entry)
+	}
+}
 
+// Delete removes an entry from memory and disk.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		item := elem.Value.(*cacheItem)
+		c.curSize -= item.entry.Size
+		delete(c.items, key)
+		c.order.Remove(elem)
+	}
+	disk := c.disk
+	c.mu.Unlock()
+
+	if disk != nil {
+		disk.Delete(key)
+	}
+}
+
+// store inserts or replaces an entry in memory. Must be called with mu held.
+func (c *Cache) store(key string, entry Entry) {
 	// Update existing
 	if elem, ok := c.items[key]; ok {
 		old := elem.Value.(*cacheItem)
@@ -106,13 +240,38 @@ func (c *Cache) Put(key string, entry Entry) {
 
 // RefreshTTL resets the TTL for an existing entry (used after 304 revalidation).
 func (c *Cache) RefreshTTL(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.refresh(key, nil)
+}
 
+// UpdateFreshness replaces an entry's caching policy with the one from a 304
+// response and resets its TTL accordingly. A no-store policy removes it.
+func (c *Cache) UpdateFreshness(key string, f Freshness) {
+	if f.NoStore {
+		c.Delete(key)
+		return
+	}
+	c.refresh(key, &f)
+}
+
+func (c *Cache) refresh(key string, f *Freshness) {
+	c.mu.Lock()
+	var refreshed *Entry
 	if elem, ok := c.items[key]; ok {
 		item := elem.Value.(*cacheItem)
-		item.entry.ExpiresAt = c.now().Add(c.ttl)
+		if f != nil {
+			item.entry.Freshness = *f
+		}
+		item.entry.ExpiresAt = c.now().Add(c.entryTTL(item.entry.Freshness))
 		c.order.MoveToFront(elem)
+		e := item.entry
+		refreshed = &e
+	}
+	disk := c.disk
+	c.mu.Unlock()
+
+	// Keep the disk copy's expiry in step so a restart does not revalidate again.
+	if disk != nil && refreshed != nil {
+		disk.Put(key, *refreshed)
 	}
 }
 
@@ -127,6 +286,7 @@ func (c *Cache) evict() {
 		c.curSize -= item.entry.Size
 		delete(c.items, item.key)
 		c.order.Remove(oldest)
+		c.counts.evictions.Add(1)
 	}
 }
 
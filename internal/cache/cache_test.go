@@ -236,3 +236,144 @@ func TestCache_SizeAccounting(t *testing.T) {
 		t.Errorf("Len = %d, want 2", c.Len())
 	}
 }
+
+func TestCache_DiskTierPromotion(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(5*time.Minute, 1024*1024)
+	c.SetDisk(disk)
+	c.Put("key1", Entry{HTML: []byte("<h1>Hello</h1>"), Size: 14, ETag: `"abc"`})
+
+	if disk.Len() != 1 {
+		t.Fatalf("disk Len = %d, want 1 (write-through)", disk.Len())
+	}
+
+	// Simulate a restart: fresh memory tier over the same directory.
+	disk, err = OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := New(5*time.Minute, 1024*1024)
+	restarted.SetDisk(disk)
+
+	entry, status := restarted.Get("key1")
+	if status != StatusHit {
+		t.Fatalf("status = %q, want hit from disk", status)
+	}
+	if string(entry.HTML) != "<h1>Hello</h1>" || entry.ETag != `"abc"` {
+		t.Errorf("entry = %q, ETag %q", entry.HTML, entry.ETag)
+	}
+	if restarted.Len() != 1 {
+		t.Errorf("memory Len = %d, want 1 after promotion", restarted.Len())
+	}
+}
+
+func TestCache_DiskTierKeepsExpiry(t *testing.T) {
+	disk, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(5*time.Minute, 1024*1024)
+	c.SetDisk(disk)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Put("key1", Entry{HTML: []byte("data"), Size: 4, ETag: `"v1"`})
+
+	// Evict from memory only, then look it up after the TTL has passed.
+	fresh := New(5*time.Minute, 1024*1024)
+	fresh.SetDisk(disk)
+	fresh.now = func() time.Time { return now.Add(6 * time.Minute) }
+
+	entry, status := fresh.Get("key1")
+	if status != StatusExpired {
+		t.Errorf("status = %q, want expired", status)
+	}
+	if entry == nil || entry.ETag != `"v1"` {
+		t.Error("expired disk entry should keep its validators for revalidation")
+	}
+}
+
+func TestCache_UpstreamFreshness(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	c.SetTTLBounds(time.Minute, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		freshness Freshness
+		wantTTL   time.Duration
+	}{
+		{"undeclared uses default", Freshness{}, 5 * time.Minute},
+		{"declared", Freshness{MaxAge: 10 * time.Minute, HasMaxAge: true}, 10 * time.Minute},
+		{"clamped to min", Freshness{MaxAge: 5 * time.Second, HasMaxAge: true}, time.Minute},
+		{"zero clamped to min", Freshness{HasMaxAge: true, MustRevalidate: true}, time.Minute},
+		{"clamped to max", Freshness{MaxAge: 48 * time.Hour, HasMaxAge: true}, time.Hour},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.Put(tc.name, Entry{HTML: []byte("x"), Size: 1, Freshness: tc.freshness})
+			entry, _ := c.Get(tc.name)
+			if got := entry.ExpiresAt.Sub(now); got != tc.wantTTL {
+				t.Errorf("TTL = %v, want %v", got, tc.wantTTL)
+			}
+		})
+	}
+}
+
+func TestCache_NoStore(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	c.Put("key1", Entry{HTML: []byte("old"), Size: 3})
+
+	c.Put("key1", Entry{HTML: []byte("new"), Size: 3, Freshness: Freshness{NoStore: true}})
+	if _, status := c.Get("key1"); status != StatusMiss {
+		t.Errorf("status = %q, want miss: no-store must not be cached and drops the old entry", status)
+	}
+	if c.Size() != 0 {
+		t.Errorf("Size = %d, want 0", c.Size())
+	}
+}
+
+func TestCache_UpdateFreshness(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Put("key1", Entry{HTML: []byte("x"), Size: 1})
+
+	c.UpdateFreshness("key1", Freshness{MaxAge: time.Hour, HasMaxAge: true})
+	entry, _ := c.Get("key1")
+	if got := entry.ExpiresAt.Sub(now); got != time.Hour {
+		t.Errorf("TTL = %v, want 1h from the 304's Cache-Control", got)
+	}
+
+	// RefreshTTL keeps the stored policy.
+	c.now = func() time.Time { return now.Add(2 * time.Hour) }
+	c.RefreshTTL("key1")
+	entry, _ = c.Get("key1")
+	if got := entry.ExpiresAt.Sub(now.Add(2 * time.Hour)); got != time.Hour {
+		t.Errorf("TTL after refresh = %v, want 1h", got)
+	}
+
+	c.UpdateFreshness("key1", Freshness{NoStore: true})
+	if _, status := c.Get("key1"); status != StatusMiss {
+		t.Errorf("status = %q, want miss after no-store revalidation", status)
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	page := "https://example.com/docs/README.md?ref=main"
+	key := VariantKey(page, "api")
+	if key == page {
+		t.Fatal("variant key equals the page key")
+	}
+	if got := KeyURL(key); got != page {
+		t.Errorf("KeyURL(%q) = %q, want %q", key, got, page)
+	}
+	if got := KeyURL(page); got != page {
+		t.Errorf("KeyURL(%q) = %q, want it unchanged", page, got)
+	}
+}
@@ -0,0 +1,403 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Disk is a persistent second cache tier. Each entry is one file named by
+// the SHA-256 of its key, so the directory can be an emptyDir or any other
+// scratch volume that outlives the process but not necessarily the pod.
+//
+// Files are written to a temporary name and renamed into place, and carry a
+// length and checksum; truncated or corrupt files are treated as misses and
+// removed. Files cooked did not create are left alone.
+type Disk struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List // front = most recently used
+	curSize int64
+	gen     uint64
+}
+
+type diskItem struct {
+	key  string
+	size int64  // file size on disk
+	gen  uint64 // bumped on every write, guards removal of replaced files
+}
+
+const (
+	diskMagic   = "cooked-cache v1\n"
+	diskFileExt = ".entry"
+	diskTempExt = ".tmp"
+)
+
+// diskMeta is the JSON header line that precedes the page bytes.
+type diskMeta struct {
+	Key            string    `json:"key"`
+	ETag           string    `json:"etag,omitempty"`
+	LastModified   string    `json:"last_modified,omitempty"`
+	ContentType    string    `json:"content_type,omitempty"`
+	TypeSource     string    `json:"type_source,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	MaxAge         *int64    `json:"max_age,omitempty"` // seconds; nil = not declared
+	MustRevalidate bool      `json:"must_revalidate,omitempty"`
+	Length         int64     `json:"length"`
+	CRC32          uint32    `json:"crc32"`
+}
+
+// OpenDisk opens (creating if needed) a disk cache in dir holding at most
+// maxSize bytes. Existing entries are indexed oldest-first by modification
+// time; leftover temporary files and entries with unreadable headers are
+// removed.
+func OpenDisk(dir string, maxSize int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	d := &Disk{
+		dir:     dir,
+		maxSize: maxSize,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cache dir: %w", err)
+	}
+
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var entries []found
+	for _, de := range dirents {
+		name := de.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case strings.HasSuffix(name, diskTempExt):
+			// Interrupted write from a previous process.
+			os.Remove(path)
+			continue
+		case !strings.HasSuffix(name, diskFileExt) || !de.Type().IsRegular():
+			continue
+		}
+
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		meta, headerLen, err := readDiskHeader(path)
+		if err != nil || diskFileName(meta.Key) != name || headerLen+meta.Length != info.Size() {
+			slog.Warn("removing corrupt disk cache entry", "file", name)
+			os.Remove(path)
+			continue
+		}
+		entries = append(entries, found{key: meta.Key, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	for _, e := range entries {
+		d.items[e.key] = d.order.PushFront(&diskItem{key: e.key, size: e.size})
+		d.curSize += e.size
+	}
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+
+	return d, nil
+}
+
+// Get reads an entry from disk. Entries are returned whether or not they
+// have expired; the caller decides based on ExpiresAt.
+func (d *Disk) Get(key string) (*Entry, bool) {
+	d.mu.Lock()
+	elem, ok := d.items[key]
+	if !ok {
+		d.mu.Unlock()
+		return nil, false
+	}
+	d.order.MoveToFront(elem)
+	gen := elem.Value.(*diskItem).gen
+	d.mu.Unlock()
+
+	path := filepath.Join(d.dir, diskFileName(key))
+	entry, err := readDiskEntry(path, key)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("removing corrupt disk cache entry", "file", filepath.Base(path), "error", err)
+		}
+		d.remove(key, gen)
+		return nil, false
+	}
+
+	// Persist recency so LRU order survives restarts.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return entry, true
+}
+
+// Put writes an entry to disk, replacing any previous entry for key. Write
+// failures (e.g. a full volume) are logged and the entry is skipped.
+func (d *Disk) Put(key string, entry Entry) {
+	size, err := d.write(key, entry)
+	if err != nil {
+		slog.Warn("disk cache write failed", "error", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.gen++
+	if elem, ok := d.items[key]; ok {
+		item := elem.Value.(*diskItem)
+		d.curSize += size - item.size
+		item.size = size
+		item.gen = d.gen
+		d.order.MoveToFront(elem)
+	} else {
+		d.items[key] = d.order.PushFront(&diskItem{key: key, size: size, gen: d.gen})
+		d.curSize += size
+	}
+	d.evict()
+}
+
+// Delete removes an entry from disk.
+func (d *Disk) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.items[key]; ok {
+		d.removeElement(elem)
+	}
+}
+
+// Peek returns the metadata of an entry on disk without reading its body.
+func (d *Disk) Peek(key string) (EntryInfo, bool) {
+	d.mu.Lock()
+	_, ok := d.items[key]
+	d.mu.Unlock()
+	if !ok {
+		return EntryInfo{}, false
+	}
+
+	meta, _, err := readDiskHeader(filepath.Join(d.dir, diskFileName(key)))
+	if err != nil || meta.Key != key {
+		return EntryInfo{}, false
+	}
+	return EntryInfo{
+		Key:          key,
+		Size:         meta.Length,
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+		LastModified: meta.LastModified,
+		ExpiresAt:    meta.ExpiresAt,
+		Tier:         "disk",
+	}, true
+}
+
+// Purge removes every entry whose key matches and returns the removed keys.
+func (d *Disk) Purge(match func(key string) bool) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var removed []string
+	for key, elem := range d.items {
+		if match(key) {
+			d.removeElement(elem)
+			removed = append(removed, key)
+		}
+	}
+	return removed
+}
+
+// Len returns the number of entries on disk.
+func (d *Disk) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.items)
+}
+
+// Size returns the total size of the entry files on disk.
+func (d *Disk) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.curSize
+}
+
+// write stores the entry in a temporary file and renames it into place so
+// readers never observe a partial file. It returns the final file size.
+func (d *Disk) write(key string, entry Entry) (int64, error) {
+	meta := diskMeta{
+		Key:            key,
+		ETag:           entry.ETag,
+		LastModified:   entry.LastModified,
+		ContentType:    entry.ContentType,
+		TypeSource:     entry.TypeSource,
+		ExpiresAt:      entry.ExpiresAt,
+		Length:         int64(len(entry.HTML)),
+		CRC32:          crc32.ChecksumIEEE(entry.HTML),
+		MustRevalidate: entry.Freshness.MustRevalidate,
+	}
+	if entry.Freshness.HasMaxAge {
+		secs := int64(entry.Freshness.MaxAge / time.Second)
+		meta.MaxAge = &secs
+	}
+	header, err := json.Marshal(meta)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(d.dir, "entry-*"+diskTempExt)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	// bufio errors are sticky and surface from Flush.
+	w := bufio.NewWriter(tmp)
+	_, _ = w.WriteString(diskMagic)
+	_, _ = w.Write(header)
+	_ = w.WriteByte('\n')
+	_, _ = w.Write(entry.HTML)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(d.dir, diskFileName(key))); err != nil {
+		return 0, err
+	}
+	return int64(len(diskMagic)+len(header)+1) + meta.Length, nil
+}
+
+// remove drops key from the index and deletes its file, unless the entry was
+// rewritten since gen was observed.
+func (d *Disk) remove(key string, gen uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.items[key]; ok && elem.Value.(*diskItem).gen == gen {
+		d.removeElement(elem)
+	}
+}
+
+// evict removes LRU entries until curSize <= maxSize. Must be called with mu held.
+func (d *Disk) evict() {
+	for d.curSize > d.maxSize && d.order.Len() > 0 {
+		d.removeElement(d.order.Back())
+	}
+}
+
+// removeElement deletes an indexed entry and its file. Must be called with mu held.
+func (d *Disk) removeElement(elem *list.Element) {
+	item := elem.Value.(*diskItem)
+	d.curSize -= item.size
+	delete(d.items, item.key)
+	d.order.Remove(elem)
+	os.Remove(filepath.Join(d.dir, diskFileName(item.key)))
+}
+
+// diskFileName maps a cache key to its file name.
+func diskFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskFileExt
+}
+
+// readDiskHeader reads the magic line and metadata of an entry file and
+// returns the metadata and the header length in bytes.
+func readDiskHeader(path string) (diskMeta, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return diskMeta{}, 0, err
+	}
+	defer f.Close()
+	return parseDiskHeader(bufio.NewReader(f))
+}
+
+func parseDiskHeader(r *bufio.Reader) (diskMeta, int64, error) {
+	var meta diskMeta
+	magic, err := r.ReadString('\n')
+	if err != nil || magic != diskMagic {
+		return meta, 0, errors.New("bad magic")
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return meta, 0, errors.New("truncated header")
+	}
+	if err := json.Unmarshal(bytes.TrimSuffix(line, []byte("\n")), &meta); err != nil {
+		return meta, 0, fmt.Errorf("bad header: %w", err)
+	}
+	if meta.Length < 0 {
+		return meta, 0, errors.New("bad length")
+	}
+	return meta, int64(len(magic) + len(line)), nil
+}
+
+// readDiskEntry reads and verifies a full entry file.
+func readDiskEntry(path, key string) (*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	meta, _, err := parseDiskHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Key != key {
+		return nil, errors.New("key mismatch")
+	}
+
+	html := make([]byte, meta.Length)
+	if _, err := io.ReadFull(r, html); err != nil {
+		return nil, errors.New("truncated body")
+	}
+	if extra, _ := r.Peek(1); len(extra) > 0 {
+		return nil, errors.New("trailing data")
+	}
+	if crc32.ChecksumIEEE(html) != meta.CRC32 {
+		return nil, errors.New("checksum mismatch")
+	}
+
+	entry := &Entry{
+		HTML:         html,
+		ETag:         meta.ETag,
+		LastModified: meta.LastModified,
+		Size:         meta.Length,
+		ContentType:  meta.ContentType,
+		TypeSource:   meta.TypeSource,
+		ExpiresAt:    meta.ExpiresAt,
+	}
+	entry.Freshness.MustRevalidate = meta.MustRevalidate
+	if meta.MaxAge != nil {
+		entry.Freshness.HasMaxAge = true
+		entry.Freshness.MaxAge = time.Duration(*meta.MaxAge) * time.Second
+	}
+	return entry, nil
+}
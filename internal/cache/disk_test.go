@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDisk_PutGet(t *testing.T) {
+	d, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	d.Put("https://git.internal/README.md", Entry{
+		HTML:         []byte("<h1>Hello</h1>"),
+		ETag:         `"abc"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+		Size:         14,
+		ContentType:  "markdown",
+		TypeSource:   "path",
+		ExpiresAt:    expires,
+	})
+
+	entry, ok := d.Get("https://git.internal/README.md")
+	if !ok {
+		t.Fatal("entry not found")
+	}
+	if string(entry.HTML) != "<h1>Hello</h1>" {
+		t.Errorf("HTML = %q", entry.HTML)
+	}
+	if entry.ETag != `"abc"` || entry.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("validators = %q, %q", entry.ETag, entry.LastModified)
+	}
+	if entry.ContentType != "markdown" || entry.TypeSource != "path" || entry.Size != 14 {
+		t.Errorf("ContentType = %q, TypeSource = %q, Size = %d", entry.ContentType, entry.TypeSource, entry.Size)
+	}
+	if !entry.ExpiresAt.Equal(expires) {
+		t.Errorf("ExpiresAt = %v, want %v", entry.ExpiresAt, expires)
+	}
+
+	if _, ok := d.Get("https://git.internal/other.md"); ok {
+		t.Error("unexpected hit for unknown key")
+	}
+}
+
+func TestDisk_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("key1", Entry{HTML: []byte("one"), Size: 3})
+	d.Put("key2", Entry{HTML: []byte("two"), Size: 3})
+
+	reopened, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 2 {
+		t.Errorf("Len = %d, want 2", reopened.Len())
+	}
+	if reopened.Size() != d.Size() {
+		t.Errorf("Size = %d, want %d", reopened.Size(), d.Size())
+	}
+	entry, ok := reopened.Get("key2")
+	if !ok || string(entry.HTML) != "two" {
+		t.Errorf("Get(key2) = %v, %v", entry, ok)
+	}
+}
+
+func TestDisk_CorruptFilesRemoved(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("truncated", Entry{HTML: []byte("<p>complete page</p>"), Size: 20})
+	d.Put("flipped", Entry{HTML: []byte("<p>checksummed</p>"), Size: 18})
+
+	// Truncate one file and flip a body byte in the other.
+	truncated := filepath.Join(dir, diskFileName("truncated"))
+	info, _ := os.Stat(truncated)
+	if err := os.Truncate(truncated, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+	flipped := filepath.Join(dir, diskFileName("flipped"))
+	data, _ := os.ReadFile(flipped)
+	data[len(data)-2] ^= 0xff
+	if err := os.WriteFile(flipped, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Checksum damage is caught on read.
+	if _, ok := d.Get("flipped"); ok {
+		t.Error("corrupt entry should be a miss")
+	}
+	if _, err := os.Stat(flipped); !os.IsNotExist(err) {
+		t.Error("corrupt file should be removed")
+	}
+
+	// Truncation is caught when the directory is indexed.
+	reopened, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 0 {
+		t.Errorf("Len = %d, want 0", reopened.Len())
+	}
+	if _, err := os.Stat(truncated); !os.IsNotExist(err) {
+		t.Error("truncated file should be removed")
+	}
+}
+
+func TestDisk_StartupCleanup(t *testing.T) {
+	dir := t.TempDir()
+	leftover := filepath.Join(dir, "entry-123.tmp")
+	garbage := filepath.Join(dir, diskFileName("garbage"))
+	foreign := filepath.Join(dir, "lost+found")
+	os.WriteFile(leftover, []byte("partial"), 0o600)
+	os.WriteFile(garbage, []byte("not a cache entry"), 0o600)
+	os.Mkdir(foreign, 0o700)
+
+	d, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 0 {
+		t.Errorf("Len = %d, want 0", d.Len())
+	}
+	for _, p := range []string{leftover, garbage} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", filepath.Base(p))
+		}
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Error("files cooked did not create must be left alone")
+	}
+}
+
+func TestDisk_LRUEviction(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := make([]byte, 100)
+	d.Put("a", Entry{HTML: page, Size: 100})
+	fileSize := d.Size()
+
+	// Room for exactly two entries.
+	d, err = OpenDisk(dir, 2*fileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("b", Entry{HTML: page, Size: 100})
+	d.Get("a") // a is now most recently used
+	d.Put("c", Entry{HTML: page, Size: 100})
+
+	if _, ok := d.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, diskFileName("b"))); !os.IsNotExist(err) {
+		t.Error("evicted file should be removed")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := d.Get(k); !ok {
+			t.Errorf("%s should still be cached", k)
+		}
+	}
+	if d.Size() > 2*fileSize {
+		t.Errorf("Size = %d exceeds limit %d", d.Size(), 2*fileSize)
+	}
+}
+
+func TestDisk_Delete(t *testing.T) {
+	d, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("key", Entry{HTML: []byte("x"), Size: 1})
+	d.Delete("key")
+	if _, ok := d.Get("key"); ok {
+		t.Error("deleted entry should be a miss")
+	}
+	if d.Size() != 0 {
+		t.Errorf("Size = %d, want 0", d.Size())
+	}
+}
+
+func TestDisk_PersistsFreshness(t *testing.T) {
+	d, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Freshness{MaxAge: 90 * time.Second, HasMaxAge: true, MustRevalidate: true}
+	d.Put("declared", Entry{HTML: []byte("x"), Size: 1, Freshness: want})
+	d.Put("undeclared", Entry{HTML: []byte("y"), Size: 1})
+
+	entry, ok := d.Get("declared")
+	if !ok || entry.Freshness != want {
+		t.Errorf("Freshness = %+v, want %+v", entry.Freshness, want)
+	}
+	entry, ok = d.Get("undeclared")
+	if !ok || entry.Freshness != (Freshness{}) {
+		t.Errorf("Freshness = %+v, want zero value", entry.Freshness)
+	}
+}
+
+func TestDisk_Purge(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("keep", Entry{HTML: []byte("k"), Size: 1})
+	d.Put("drop-1", Entry{HTML: []byte("1"), Size: 1})
+	d.Put("drop-2", Entry{HTML: []byte("2"), Size: 1})
+
+	removed := d.Purge(func(key string) bool { return strings.HasPrefix(key, "drop-") })
+	if len(removed) != 2 {
+		t.Errorf("Purge removed %v, want 2 keys", removed)
+	}
+
+	// Purged files must be gone from disk, not just the index.
+	reopened, err := OpenDisk(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 1 {
+		t.Errorf("entries after reopen = %d, want 1", reopened.Len())
+	}
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// counters tracks how lookups were answered and how many entries were
+// evicted from memory.
+type counters struct {
+	hits, misses, revalidated, expired, stale atomic.Int64
+	evictions                                 atomic.Int64
+}
+
+// Stats is a snapshot of cache size and lookup counters.
+type Stats struct {
+	Entries     int
+	Bytes       int64
+	MaxBytes    int64
+	DiskEntries int
+	DiskBytes   int64
+	DiskEnabled bool
+
+	Hits        int64
+	Misses      int64
+	Revalidated int64
+	Expired     int64
+	Stale       int64
+	Evictions   int64 // memory entries dropped to stay under MaxBytes
+}
+
+// EntryInfo describes a cached entry without its page body.
+type EntryInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified string
+	ExpiresAt    time.Time
+	Hits         int64  // memory hits since the entry was loaded
+	Tier         string // "memory" or "disk"
+}
+
+// Record counts how a lookup was finally answered. The caller records once
+// per lookup, after revalidation has decided the outcome.
+func (c *Cache) Record(s Status) {
+	switch s {
+	case StatusHit:
+		c.counts.hits.Add(1)
+	case StatusMiss:
+		c.counts.misses.Add(1)
+	case StatusRevalidated:
+		c.counts.revalidated.Add(1)
+	case StatusExpired:
+		c.counts.expired.Add(1)
+	case StatusStale:
+		c.counts.stale.Add(1)
+	}
+}
+
+// Stats returns the current size and counters.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	st := Stats{
+		Entries:  len(c.items),
+		Bytes:    c.curSize,
+		MaxBytes: c.maxSize,
+	}
+	disk := c.disk
+	c.mu.Unlock()
+
+	if disk != nil {
+		st.DiskEnabled = true
+		st.DiskEntries = disk.Len()
+		st.DiskBytes = disk.Size()
+	}
+	st.Hits = c.counts.hits.Load()
+	st.Misses = c.counts.misses.Load()
+	st.Revalidated = c.counts.revalidated.Load()
+	st.Expired = c.counts.expired.Load()
+	st.Stale = c.counts.stale.Load()
+	st.Evictions = c.counts.evictions.Load()
+	return st
+}
+
+// Top returns up to n in-memory entries with the most hits, largest first
+// among equals.
+func (c *Cache) Top(n int) []EntryInfo {
+	c.mu.Lock()
+	infos := make([]EntryInfo, 0, len(c.items))
+	for _, elem := range c.items {
+		item := elem.Value.(*cacheItem)
+		infos = append(infos, item.info())
+	}
+	c.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Hits != infos[j].Hits {
+			return infos[i].Hits > infos[j].Hits
+		}
+		if infos[i].Size != infos[j].Size {
+			return infos[i].Size > infos[j].Size
+		}
+		return infos[i].Key < infos[j].Key
+	})
+	if n >= 0 && len(infos) > n {
+		infos = infos[:n]
+	}
+	return infos
+}
+
+// Peek reports whether key is cached in memory or on disk, without
+// promoting it or affecting LRU order.
+func (c *Cache) Peek(key string) (EntryInfo, bool) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		info := elem.Value.(*cacheItem).info()
+		c.mu.Unlock()
+		return info, true
+	}
+	disk := c.disk
+	c.mu.Unlock()
+
+	if disk != nil {
+		return disk.Peek(key)
+	}
+	return EntryInfo{}, false
+}
+
+// Purge removes every entry whose key matches from memory and disk, and
+// returns the number of distinct keys removed.
+func (c *Cache) Purge(match func(key string) bool) int {
+	removed := make(map[string]bool)
+
+	c.mu.Lock()
+	for key, elem := range c.items {
+		if !match(key) {
+			continue
+		}
+		item := elem.Value.(*cacheItem)
+		c.curSize -= item.entry.Size
+		delete(c.items, key)
+		c.order.Remove(elem)
+		removed[key] = true
+	}
+	disk := c.disk
+	c.mu.Unlock()
+
+	if disk != nil {
+		for _, key := range disk.Purge(match) {
+			removed[key] = true
+		}
+	}
+	return len(removed)
+}
+
+func (item *cacheItem) info() EntryInfo {
+	return EntryInfo{
+		Key:          item.key,
+		Size:         item.entry.Size,
+		ContentType:  item.entry.ContentType,
+		ETag:         item.entry.ETag,
+		LastModified: item.entry.LastModified,
+		ExpiresAt:    item.entry.ExpiresAt,
+		Hits:         item.hits,
+		Tier:         "memory",
+	}
+}
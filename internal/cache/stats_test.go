@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_StatsCounters(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	c.Put("a", Entry{HTML: []byte("aaaa"), Size: 4})
+	c.Put("b", Entry{HTML: []byte("bb"), Size: 2})
+
+	for _, s := range []Status{StatusHit, StatusHit, StatusMiss, StatusRevalidated, StatusStale, StatusExpired} {
+		c.Record(s)
+	}
+
+	st := c.Stats()
+	if st.Entries != 2 || st.Bytes != 6 || st.MaxBytes != 1024*1024 {
+		t.Errorf("size = %d entries / %d bytes / %d max", st.Entries, st.Bytes, st.MaxBytes)
+	}
+	if st.Hits != 2 || st.Misses != 1 || st.Revalidated != 1 || st.Stale != 1 || st.Expired != 1 {
+		t.Errorf("counters = %+v", st)
+	}
+	if st.DiskEnabled {
+		t.Error("DiskEnabled should be false without a disk tier")
+	}
+}
+
+func TestCache_StatsEvictions(t *testing.T) {
+	c := New(5*time.Minute, 10)
+	c.Put("a", Entry{HTML: []byte("aaaaaa"), Size: 6})
+	c.Put("b", Entry{HTML: []byte("bbbbbb"), Size: 6})
+	c.Put("c", Entry{HTML: []byte("cccccc"), Size: 6})
+
+	if got := c.Stats().Evictions; got != 2 {
+		t.Errorf("Evictions = %d, want 2", got)
+	}
+}
+
+func TestCache_Top(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	c.Put("small", Entry{HTML: []byte("s"), Size: 1})
+	c.Put("large", Entry{HTML: []byte("llll"), Size: 4})
+	c.Put("popular", Entry{HTML: []byte("p"), Size: 1})
+	c.Get("popular")
+	c.Get("popular")
+
+	top := c.Top(2)
+	if len(top) != 2 {
+		t.Fatalf("len(Top(2)) = %d, want 2", len(top))
+	}
+	if top[0].Key != "popular" || top[0].Hits != 2 {
+		t.Errorf("top[0] = %+v, want popular with 2 hits", top[0])
+	}
+	if top[1].Key != "large" {
+		t.Errorf("top[1] = %q, want large (ties broken by size)", top[1].Key)
+	}
+}
+
+func TestCache_Peek(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	d, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDisk(d)
+
+	c.Put("mem", Entry{HTML: []byte("m"), Size: 1, ETag: `"m"`, ContentType: "markdown"})
+	d.Put("disk", Entry{HTML: []byte("dd"), Size: 2, ETag: `"d"`})
+
+	info, ok := c.Peek("mem")
+	if !ok || info.Tier != "memory" || info.ETag != `"m"` || info.ContentType != "markdown" {
+		t.Errorf("Peek(mem) = %+v, %v", info, ok)
+	}
+	info, ok = c.Peek("disk")
+	if !ok || info.Tier != "disk" || info.Size != 2 || info.ETag != `"d"` {
+		t.Errorf("Peek(disk) = %+v, %v", info, ok)
+	}
+	if c.Len() != 1 {
+		t.Error("Peek should not promote disk entries into memory")
+	}
+	if _, ok := c.Peek("missing"); ok {
+		t.Error("Peek(missing) should report not cached")
+	}
+}
+
+func TestCache_Purge(t *testing.T) {
+	c := New(5*time.Minute, 1024*1024)
+	d, err := OpenDisk(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDisk(d)
+
+	c.Put("https://a.example/one.md", Entry{HTML: []byte("1"), Size: 1})
+	c.Put("https://a.example/two.md", Entry{HTML: []byte("2"), Size: 1})
+	c.Put("https://b.example/three.md", Entry{HTML: []byte("3"), Size: 1})
+	// Only on disk, e.g. evicted from memory or left by a previous process.
+	d.Put("https://a.example/four.md", Entry{HTML: []byte("4"), Size: 1})
+
+	n := c.Purge(func(key string) bool { return strings.HasPrefix(key, "https://a.example/") })
+	if n != 3 {
+		t.Errorf("Purge = %d, want 3", n)
+	}
+	if c.Len() != 1 || c.Size() != 1 {
+		t.Errorf("memory after purge: %d entries, %d bytes", c.Len(), c.Size())
+	}
+	if d.Len() != 1 {
+		t.Errorf("disk after purge: %d entries, want 1", d.Len())
+	}
+	if _, status := c.Get("https://a.example/one.md"); status != StatusMiss {
+		t.Errorf("purged entry status = %q, want miss", status)
+	}
+	if _, status := c.Get("https://b.example/three.md"); status != StatusHit {
+		t.Errorf("unmatched entry status = %q, want hit", status)
+	}
+}
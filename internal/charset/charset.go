@@ -0,0 +1,283 @@
+// Package charset detects the character encoding of fetched documents and
+// transcodes them to UTF-8 for rendering.
+package charset
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Source names what an encoding was detected from.
+type Source string
+
+const (
+	SourceBOM         Source = "bom"
+	SourceContentType Source = "content-type"
+	SourceDeclaration Source = "declaration" // HTML meta or XML declaration
+	SourceStatistics  Source = "statistics"
+)
+
+// UTF8 is the name of the UTF-8 encoding.
+const UTF8 = "utf-8"
+
+// replacement is what invalid byte sequences become.
+var replacement = []byte("\uFFFD")
+
+// declarationLen is how far into a document HTML meta charsets are looked
+// for, as in the HTML prescan.
+const declarationLen = 1024
+
+var (
+	xmlDeclaration  = regexp.MustCompile(`^<\?xml[^>]*?\sencoding\s*=\s*["']([\w.:-]+)["']`)
+	metaDeclaration = regexp.MustCompile(`(?i)<meta\s[^>]*?charset\s*=\s*["']?([\w.:-]+)`)
+)
+
+// Detect determines the encoding of body, in order from a byte order mark,
+// the charset parameter of contentType, and, unless the body is valid UTF-8,
+// an XML declaration or HTML meta tag and then the byte statistics of the
+// body. Latin-1 labels are not trusted for bodies that are valid UTF-8, as
+// servers commonly apply them to every file. It returns the WHATWG name of
+// the encoding and its source; the source is empty for UTF-8 by default.
+func Detect(body []byte, contentType string) (string, Source) {
+	if name, _ := bomEncoding(body); name != "" {
+		return name, SourceBOM
+	}
+	valid := utf8.Valid(body)
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if name := canonical(params["charset"]); name != "" && !(valid && singleByteLatin(name)) {
+			return name, SourceContentType
+		}
+	}
+	if valid {
+		return UTF8, ""
+	}
+	if name := declared(body); name != "" {
+		return name, SourceDeclaration
+	}
+	return guess(body), SourceStatistics
+}
+
+// ToUTF8 transcodes body from the encoding Detect finds to UTF-8, dropping
+// any byte order mark. Invalid byte sequences become U+FFFD.
+func ToUTF8(body []byte, contentType string) []byte {
+	name, _ := Detect(body, contentType)
+	return Decode(body, name)
+}
+
+// Decode transcodes body from the named encoding to UTF-8, dropping any byte
+// order mark. Invalid byte sequences become U+FFFD; unknown names are
+// treated as UTF-8.
+func Decode(body []byte, name string) []byte {
+	if bomName, n := bomEncoding(body); bomName == name {
+		body = body[n:]
+	}
+	enc := lookup(name)
+	if enc == nil {
+		return bytes.ToValidUTF8(body, replacement)
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		// Decoders replace what they cannot map; an error means the input
+		// could not be read at all.
+		return bytes.ToValidUTF8(body, replacement)
+	}
+	return bytes.ToValidUTF8(out, replacement)
+}
+
+// lookup returns the decoder for name, or nil for UTF-8 and unknown names.
+func lookup(name string) encoding.Encoding {
+	switch name {
+	case UTF8, "":
+		return nil
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil
+	}
+	return enc
+}
+
+// canonical returns the WHATWG name for an encoding label, or "" for labels
+// that are unknown or, like "replacement", cannot be decoded.
+func canonical(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return ""
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return ""
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil || name == "replacement" {
+		return ""
+	}
+	return name
+}
+
+// singleByteLatin reports whether name is one of the encodings Latin-1
+// labels map to.
+func singleByteLatin(name string) bool {
+	return name == "windows-1252" || name == "iso-8859-15"
+}
+
+// bomEncoding returns the encoding named by a byte order mark at the start
+// of body and the mark's length.
+func bomEncoding(body []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8, 3
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return "utf-16le", 2
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return "utf-16be", 2
+	}
+	return "", 0
+}
+
+// declared returns the encoding named by an XML declaration or an HTML meta
+// tag near the start of body.
+func declared(body []byte) string {
+	head := body[:min(len(body), declarationLen)]
+	if m := xmlDeclaration.FindSubmatch(head); m != nil {
+		if name := canonical(string(m[1])); name != "" {
+			return name
+		}
+	}
+	if m := metaDeclaration.FindSubmatch(head); m != nil {
+		return canonical(string(m[1]))
+	}
+	return ""
+}
+
+// guess picks the likeliest encoding of a body that is not valid UTF-8:
+// UTF-16 when every other byte is mostly zero, Shift-JIS or EUC-JP when the
+// body is made of their double-byte characters, and Windows-1252, the
+// superset of Latin-1, otherwise.
+func guess(body []byte) string {
+	if name := guessUTF16(body); name != "" {
+		return name
+	}
+	latin := latinScore(body)
+	best, bestScore := "windows-1252", latin
+	if score, ok := shiftJISScore(body); ok && score > bestScore {
+		best, bestScore = "shift_jis", score
+	}
+	if score, ok := eucJPScore(body); ok && score > bestScore {
+		best = "euc-jp"
+	}
+	return best
+}
+
+// guessUTF16 detects UTF-16 without a byte order mark from the zero high
+// bytes of mostly-ASCII text.
+func guessUTF16(body []byte) string {
+	n := len(body) &^ 1
+	if n < 4 {
+		return ""
+	}
+	var even, odd int
+	for i := 0; i < n; i += 2 {
+		if body[i] == 0 {
+			even++
+		}
+		if body[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case odd*10 >= pairs*3 && even*20 < pairs:
+		return "utf-16le"
+	case even*10 >= pairs*3 && odd*20 < pairs:
+		return "utf-16be"
+	}
+	return ""
+}
+
+// latinScore counts bytes above ASCII with ASCII letters on both sides, as
+// with accented letters inside words.
+func latinScore(body []byte) int {
+	score := 0
+	for i := 1; i+1 < len(body); i++ {
+		if body[i] >= 0x80 && isASCIILetter(body[i-1]) && isASCIILetter(body[i+1]) {
+			score++
+		}
+	}
+	return score
+}
+
+// shiftJISScore reports whether body is well-formed Shift-JIS and counts its
+// double-byte characters with both bytes above ASCII, as kana and most
+// kanji are.
+func shiftJISScore(body []byte) (int, bool) {
+	score := 0
+	for i := 0; i < len(body); i++ {
+		b := body[i]
+		switch {
+		case b < 0x80, 0xA1 <= b && b <= 0xDF: // ASCII, half-width katakana
+		case 0x81 <= b && b <= 0x9F, 0xE0 <= b && b <= 0xFC:
+			if i+1 >= len(body) {
+				return score, false
+			}
+			t := body[i+1]
+			if t < 0x40 || t == 0x7F || t > 0xFC {
+				return score, false
+			}
+			if t >= 0x80 {
+				score++
+			}
+			i++
+		default:
+			return score, false
+		}
+	}
+	return score, true
+}
+
+// eucJPScore reports whether body is well-formed EUC-JP and counts its
+// multi-byte characters.
+func eucJPScore(body []byte) (int, bool) {
+	score := 0
+	for i := 0; i < len(body); i++ {
+		b := body[i]
+		switch {
+		case b < 0x80:
+		case b == 0x8E: // half-width katakana
+			if i+1 >= len(body) || body[i+1] < 0xA1 || body[i+1] > 0xDF {
+				return score, false
+			}
+			i++
+		case b == 0x8F: // JIS X 0212
+			if i+2 >= len(body) || !isEUCByte(body[i+1]) || !isEUCByte(body[i+2]) {
+				return score, false
+			}
+			score++
+			i += 2
+		case isEUCByte(b):
+			if i+1 >= len(body) || !isEUCByte(body[i+1]) {
+				return score, false
+			}
+			score++
+			i++
+		default:
+			return score, false
+		}
+	}
+	return score, true
+}
+
+func isEUCByte(b byte) bool { return 0xA1 <= b && b <= 0xFE }
+
+func isASCIILetter(b byte) bool { return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' }
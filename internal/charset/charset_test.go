@@ -0,0 +1,88 @@
+package charset
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestDetect(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("# 見出し\n\nこんにちは、世界。日本語の文書です。\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eucjp, err := japanese.EUCJP.NewEncoder().Bytes([]byte("# 見出し\n\nこんにちは、世界。日本語の文書です。\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16le, err := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("# Title\n\nHello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+		wantSource  Source
+	}{
+		{"ascii", []byte("# Hello\n"), "", UTF8, ""},
+		{"utf-8", []byte("# Café\n"), "text/plain", UTF8, ""},
+		{"utf-8 bom", []byte("\xEF\xBB\xBF# Hi\n"), "text/plain; charset=iso-8859-1", UTF8, SourceBOM},
+		{"utf-16le bom", []byte("\xFF\xFE#\x00"), "", "utf-16le", SourceBOM},
+		{"utf-16be bom", []byte("\xFE\xFF\x00#"), "", "utf-16be", SourceBOM},
+		{"content-type", []byte("caf\xE9"), "text/markdown; charset=ISO-8859-1", "windows-1252", SourceContentType},
+		{"content-type shift_jis", sjis, "text/plain; charset=Shift_JIS", "shift_jis", SourceContentType},
+		{"latin-1 label on utf-8", []byte("# Café\n"), "text/plain; charset=iso-8859-1", UTF8, ""},
+		{"unknown label", []byte("caf\xE9 cr\xE8me br\xFBl\xE9e"), "text/plain; charset=x-bogus", "windows-1252", SourceStatistics},
+		{"xml declaration", []byte("<?xml version=\"1.0\" encoding=\"Shift_JIS\"?>\n<a>\x82\xA0</a>"), "", "shift_jis", SourceDeclaration},
+		{"html meta", []byte("<html><head><meta charset=\"windows-1252\"></head>\x93hi\x94"), "", "windows-1252", SourceDeclaration},
+		{"html http-equiv", []byte("<meta http-equiv=\"Content-Type\" content=\"text/html; charset=euc-jp\">\xA4\xA2"), "", "euc-jp", SourceDeclaration},
+		{"latin-1 text", []byte("Fran\xE7ais: le caf\xE9 est tr\xE8s bon, \xFCber alles.\n"), "", "windows-1252", SourceStatistics},
+		{"windows-1252 quotes", []byte("He said \x93hello\x94 \x96 twice.\n"), "", "windows-1252", SourceStatistics},
+		{"shift_jis text", sjis, "", "shift_jis", SourceStatistics},
+		{"euc-jp text", eucjp, "", "euc-jp", SourceStatistics},
+		{"utf-16le without bom", append(utf16le, 0xFF, 0xD8), "", "utf-16le", SourceStatistics},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := Detect(tt.body, tt.contentType)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("Detect() = %q from %q, want %q from %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("こんにちは、世界。日本語です。"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{"utf-8 unchanged", []byte("# Café ☕\n"), "", "# Café ☕\n"},
+		{"utf-8 bom dropped", []byte("\xEF\xBB\xBF# Hi"), "", "# Hi"},
+		{"latin-1", []byte("caf\xE9 cr\xE8me"), "text/plain; charset=iso-8859-1", "café crème"},
+		{"windows-1252", []byte("\x93quoted\x94 \x80"), "text/plain; charset=windows-1252", "“quoted” €"},
+		{"shift_jis", sjis, "", "こんにちは、世界。日本語です。"},
+		{"utf-16le", []byte("\xFF\xFE#\x00 \x00\xE9\x00\n\x00"), "", "# é\n"},
+		{"utf-16be", []byte("\xFE\xFF\x00#\x00 \x00\xE9"), "", "# é"},
+		{"invalid utf-8 declared", []byte("ok \xFF\xFE\xFD ok"), "text/plain; charset=utf-8", "ok � ok"},
+		{"invalid shift_jis", []byte("\x82\xA0\x82"), "text/plain; charset=shift_jis", "あ�"},
+		{"odd utf-16", []byte("\xFF\xFEA\x00B"), "", "A�"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ToUTF8(tt.body, tt.contentType)); got != tt.want {
+				t.Errorf("ToUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,24 +4,55 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/air-gapped/cooked/internal/forge"
 )
 
 // Config holds all runtime configuration for cooked.
 type Config struct {
-	Listen           string
-	CacheTTL         time.Duration
-	CacheMaxSize     int64
-	FetchTimeout     time.Duration
-	MaxFileSize      int64
-	AllowedUpstreams string
-	BaseURL          string
-	DefaultTheme     string
-	TLSSkipVerify    bool
-	FrameAncestors   string
-	TrustedProxies   string
+	Listen                    string
+	CacheTTL                  time.Duration
+	CacheMaxSize              int64
+	CacheMinTTL               time.Duration
+	CacheMaxTTL               time.Duration
+	CacheDir                  string
+	CacheDiskMaxSize          int64
+	CacheStaleWhileRevalidate time.Duration
+	CacheStaleIfError         time.Duration
+	CacheRefreshWorkers       int
+	FetchTimeout              time.Duration
+	MaxFileSize               int64
+	MaxArchiveSize            int64
+	ArchiveCacheSize          int64
+	AllowedUpstreams          string
+	BaseURL                   string
+	DefaultTheme              string
+	TLSSkipVerify             bool
+	FrameAncestors            string
+	TrustedProxies            string
+	CredentialsFile           string
+	ForgeHosts                string
+	ForgeProfilesFile         string
+	ForgeAutodetect           bool
+	AdminListen               string
+	AdminTokenFile            string
+	AdminToken                string // read from AdminTokenFile
+	WebhookSecretFile         string
+	WebhookSecret             string // read from WebhookSecretFile
+	WebhookPrewarm            bool
+	Metrics                   bool
+	MetricsListen             string
+	OTLPEndpoint              string
+	OTLPProtocol              string
+	TraceSampleRatio          float64
+	LocalRoots                map[string]string // name → directory, served at /_cooked/local/{name}/
+	GitRepos                  map[string]string // name → repository directory, served at /_cooked/git/{name}/
 }
 
 // Parse reads configuration from CLI flags with environment variable fallback.
@@ -33,8 +64,17 @@ func Parse(args []string) (*Config, error) {
 	fs.StringVar(&cfg.Listen, "listen", envOr("COOKED_LISTEN", "127.0.0.1:8080"), "Listen address")
 	fs.DurationVar(&cfg.CacheTTL, "cache-ttl", envDurationOr("COOKED_CACHE_TTL", 5*time.Minute), "Cache TTL duration")
 	cacheMaxSize := fs.String("cache-max-size", envOr("COOKED_CACHE_MAX_SIZE", "100MB"), "Max cache size (e.g. 100MB)")
+	fs.DurationVar(&cfg.CacheMinTTL, "cache-min-ttl", envDurationOr("COOKED_CACHE_MIN_TTL", 0), "Lower bound for upstream-declared cache lifetimes (Cache-Control, Expires)")
+	fs.DurationVar(&cfg.CacheMaxTTL, "cache-max-ttl", envDurationOr("COOKED_CACHE_MAX_TTL", 24*time.Hour), "Upper bound for upstream-declared cache lifetimes (0 means no limit)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", envOr("COOKED_CACHE_DIR", ""), "Directory for the persistent disk cache tier (disabled if empty)")
+	cacheDiskMaxSize := fs.String("cache-disk-max-size", envOr("COOKED_CACHE_DISK_MAX_SIZE", "1GB"), "Max disk cache size (e.g. 1GB)")
+	fs.DurationVar(&cfg.CacheStaleWhileRevalidate, "cache-stale-while-revalidate", envDurationOr("COOKED_CACHE_STALE_WHILE_REVALIDATE", 0), "Serve expired pages this long past expiry while refreshing in the background (0 disables)")
+	fs.DurationVar(&cfg.CacheStaleIfError, "cache-stale-if-error", envDurationOr("COOKED_CACHE_STALE_IF_ERROR", 24*time.Hour), "Serve expired pages this long past expiry when the upstream fails (0 means no limit)")
+	fs.IntVar(&cfg.CacheRefreshWorkers, "cache-refresh-workers", envIntOr("COOKED_CACHE_REFRESH_WORKERS", 4), "Max concurrent background cache refreshes")
 	fs.DurationVar(&cfg.FetchTimeout, "fetch-timeout", envDurationOr("COOKED_FETCH_TIMEOUT", 30*time.Second), "Upstream fetch timeout")
 	maxFileSize := fs.String("max-file-size", envOr("COOKED_MAX_FILE_SIZE", "5MB"), "Max file size to render (e.g. 5MB)")
+	maxArchiveSize := fs.String("max-archive-size", envOr("COOKED_MAX_ARCHIVE_SIZE", "50MB"), "Max size of a tar or zip archive fetched to browse its members (e.g. 50MB)")
+	archiveCacheSize := fs.String("archive-cache-size", envOr("COOKED_ARCHIVE_CACHE_SIZE", "200MB"), "Memory held by fetched archives, decompressed, across all archives (e.g. 200MB)")
 	fs.StringVar(&cfg.AllowedUpstreams, "allowed-upstreams", envOr("COOKED_ALLOWED_UPSTREAMS", ""), "Comma-separated allowed upstreams: hostnames, *.wildcard, or CIDR ranges (e.g. \"cgit.internal,*.corp,10.0.0.0/8\")")
 	fs.StringVar(&cfg.BaseURL, "base-url", envOr("COOKED_BASE_URL", ""), "Public base URL of cooked (auto-detect from Host header if empty)")
 	fs.StringVar(&cfg.DefaultTheme, "default-theme", envOr("COOKED_DEFAULT_THEME", "auto"), "Default theme: auto, light, or dark")
@@ -42,6 +82,33 @@ func Parse(args []string) (*Config, error) {
 	fs.StringVar(&cfg.FrameAncestors, "frame-ancestors", envOr("COOKED_FRAME_ANCESTORS", "none"), "CSP frame-ancestors: none, self, or space-separated origins (e.g. \"https://gitea.internal\")")
 	fs.StringVar(&cfg.TrustedProxies, "trusted-proxies", envOr("COOKED_TRUSTED_PROXIES", ""), "Comma-separated trusted proxy IPs or CIDRs for X-Forwarded-For (e.g. \"127.0.0.1,10.0.0.0/8\")")
 
+	fs.StringVar(&cfg.CredentialsFile, "credentials-file", envOr("COOKED_CREDENTIALS_FILE", ""), "Path to JSON file of per-upstream credentials (host or host/path prefix → header, bearer, or basic auth)")
+
+	fs.StringVar(&cfg.ForgeHosts, "forge-hosts", envOr("COOKED_FORGE_HOSTS", ""), "Comma-separated host=profile bindings for forge URL translation (e.g. \"cgit.internal=cgit,git.corp=gitea\")")
+	fs.StringVar(&cfg.ForgeProfilesFile, "forge-profiles-file", envOr("COOKED_FORGE_PROFILES_FILE", ""), "Path to JSON file of operator-defined regex forge profiles")
+	fs.BoolVar(&cfg.ForgeAutodetect, "forge-autodetect", envBoolOr("COOKED_FORGE_AUTODETECT", false), "Recognise Gitea, GitLab and Bitbucket page URLs on hosts not bound with --forge-hosts")
+
+	fs.StringVar(&cfg.AdminListen, "admin-listen", envOr("COOKED_ADMIN_LISTEN", ""), "Separate listen address for the admin API (served on the main listener if empty)")
+	fs.StringVar(&cfg.AdminTokenFile, "admin-token-file", envOr("COOKED_ADMIN_TOKEN_FILE", ""), "Path to file holding the admin API bearer token (admin API disabled if empty)")
+
+	fs.BoolVar(&cfg.Metrics, "metrics", envBoolOr("COOKED_METRICS", false), "Serve Prometheus metrics at /metrics")
+	fs.StringVar(&cfg.MetricsListen, "metrics-listen", envOr("COOKED_METRICS_LISTEN", ""), "Separate listen address for /metrics (served on the main listener if empty)")
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", envOr("COOKED_OTLP_ENDPOINT", ""), "OTLP collector URL for trace export (e.g. \"http://localhost:4318\"; tracing disabled if empty)")
+	fs.StringVar(&cfg.OTLPProtocol, "otlp-protocol", envOr("COOKED_OTLP_PROTOCOL", "http"), "OTLP transport: http or grpc")
+	fs.Float64Var(&cfg.TraceSampleRatio, "trace-sample-ratio", envFloatOr("COOKED_TRACE_SAMPLE_RATIO", 1), "Fraction of new traces to sample, 0 to 1 (incoming sampled traces are always followed)")
+	fs.StringVar(&cfg.WebhookSecretFile, "webhook-secret-file", envOr("COOKED_WEBHOOK_SECRET_FILE", ""), "Path to file holding the push webhook secret (webhook disabled if empty)")
+	fs.BoolVar(&cfg.WebhookPrewarm, "webhook-prewarm", envBoolOr("COOKED_WEBHOOK_PREWARM", false), "Re-render pages purged by a push webhook in the background")
+	var localRoots []string
+	fs.Func("local-root", "Local directory served at /_cooked/local/{name}/, as name=/path (repeatable; COOKED_LOCAL_ROOTS takes a comma-separated list)", func(v string) error {
+		localRoots = append(localRoots, v)
+		return nil
+	})
+	var gitRepos []string
+	fs.Func("git-repo", "Local git repository served at /_cooked/git/{name}/{ref}/, as name=/path/to/repo.git (repeatable; COOKED_GIT_REPOS takes a comma-separated list)", func(v string) error {
+		gitRepos = append(gitRepos, v)
+		return nil
+	})
+
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
@@ -52,11 +119,40 @@ func Parse(args []string) (*Config, error) {
 		return nil, fmt.Errorf("parse cache-max-size: %w", err)
 	}
 
+	cfg.CacheDiskMaxSize, err = parseByteSize(*cacheDiskMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("parse cache-disk-max-size: %w", err)
+	}
+
 	cfg.MaxFileSize, err = parseByteSize(*maxFileSize)
 	if err != nil {
 		return nil, fmt.Errorf("parse max-file-size: %w", err)
 	}
 
+	cfg.MaxArchiveSize, err = parseByteSize(*maxArchiveSize)
+	if err != nil {
+		return nil, fmt.Errorf("parse max-archive-size: %w", err)
+	}
+
+	cfg.ArchiveCacheSize, err = parseByteSize(*archiveCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("parse archive-cache-size: %w", err)
+	}
+
+	if cfg.CacheMinTTL < 0 || cfg.CacheMaxTTL < 0 {
+		return nil, fmt.Errorf("invalid cache TTL bounds: durations must not be negative")
+	}
+	if cfg.CacheMaxTTL > 0 && cfg.CacheMinTTL > cfg.CacheMaxTTL {
+		return nil, fmt.Errorf("invalid cache TTL bounds: cache-min-ttl %s exceeds cache-max-ttl %s", cfg.CacheMinTTL, cfg.CacheMaxTTL)
+	}
+
+	if cfg.CacheStaleWhileRevalidate < 0 || cfg.CacheStaleIfError < 0 {
+		return nil, fmt.Errorf("invalid cache stale windows: durations must not be negative")
+	}
+	if cfg.CacheRefreshWorkers < 1 {
+		return nil, fmt.Errorf("invalid cache-refresh-workers %d: must be at least 1", cfg.CacheRefreshWorkers)
+	}
+
 	if err := validateAllowedUpstreams(cfg.AllowedUpstreams); err != nil {
 		return nil, fmt.Errorf("invalid allowed-upstreams: %w", err)
 	}
@@ -71,9 +167,114 @@ func Parse(args []string) (*Config, error) {
 		return nil, err
 	}
 
+	if _, err := forge.Load(cfg.ForgeHosts, cfg.ForgeProfilesFile, cfg.ForgeAutodetect); err != nil {
+		return nil, fmt.Errorf("invalid forge configuration: %w", err)
+	}
+
+	if cfg.AdminTokenFile != "" {
+		cfg.AdminToken, err = readToken(cfg.AdminTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin-token-file: %w", err)
+		}
+	} else if cfg.AdminListen != "" {
+		return nil, fmt.Errorf("admin-listen requires admin-token-file")
+	}
+
+	for name, addr := range map[string]string{"admin-listen": cfg.AdminListen, "metrics-listen": cfg.MetricsListen} {
+		if addr != "" && addr == cfg.Listen {
+			return nil, fmt.Errorf("invalid %s %q: must differ from listen", name, addr)
+		}
+	}
+
+	if cfg.WebhookSecretFile != "" {
+		cfg.WebhookSecret, err = readToken(cfg.WebhookSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook-secret-file: %w", err)
+		}
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		u, err := url.Parse(cfg.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid otlp-endpoint %q: must be an http or https URL", cfg.OTLPEndpoint)
+		}
+	}
+	switch cfg.OTLPProtocol {
+	case "http", "grpc":
+	default:
+		return nil, fmt.Errorf("invalid otlp-protocol %q: must be http or grpc", cfg.OTLPProtocol)
+	}
+	if cfg.TraceSampleRatio < 0 || cfg.TraceSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid trace-sample-ratio %g: must be between 0 and 1", cfg.TraceSampleRatio)
+	}
+
+	if localRoots == nil && os.Getenv("COOKED_LOCAL_ROOTS") != "" {
+		localRoots = strings.Split(os.Getenv("COOKED_LOCAL_ROOTS"), ",")
+	}
+	cfg.LocalRoots, err = parseNamedDirs(localRoots)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local-root: %w", err)
+	}
+
+	if gitRepos == nil && os.Getenv("COOKED_GIT_REPOS") != "" {
+		gitRepos = strings.Split(os.Getenv("COOKED_GIT_REPOS"), ",")
+	}
+	cfg.GitRepos, err = parseNamedDirs(gitRepos)
+	if err != nil {
+		return nil, fmt.Errorf("invalid git-repo: %w", err)
+	}
+
 	return cfg, nil
 }
 
+// dirNameRe matches local root and git repository names, which appear as a
+// URL path segment.
+var dirNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// parseNamedDirs parses name=/path bindings, checking that every path is an
+// existing directory.
+func parseNamedDirs(bindings []string) (map[string]string, error) {
+	if len(bindings) == 0 {
+		return nil, nil
+	}
+	roots := make(map[string]string, len(bindings))
+	for _, b := range bindings {
+		name, dir, ok := strings.Cut(strings.TrimSpace(b), "=")
+		if !ok || dir == "" {
+			return nil, fmt.Errorf("%q: want name=/path", b)
+		}
+		if !dirNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%q: name must be letters, digits, '.', '_' or '-'", b)
+		}
+		if _, dup := roots[name]; dup {
+			return nil, fmt.Errorf("duplicate name %q", name)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+		roots[name] = dir
+	}
+	return roots, nil
+}
+
+// readToken reads a secret from a file, ignoring surrounding whitespace such
+// as the trailing newline most editors and secret mounts add.
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
 // validateAllowedUpstreams checks that all entries in the comma-separated
 // allowlist are well-formed at startup time.
 func validateAllowedUpstreams(raw string) error {
@@ -133,6 +334,26 @@ func envDurationOr(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+func envIntOr(key string, fallback int) int {
+	if v, ok := os.LookupEnv(key); ok {
+		n, err := strconv.Atoi(v)
+		if err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func envFloatOr(key string, fallback float64) float64 {
+	if v, ok := os.LookupEnv(key); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
 func envBoolOr(key string, fallback bool) bool {
 	if v, ok := os.LookupEnv(key); ok {
 		return v == "1" || v == "true" || v == "yes"
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -27,6 +29,12 @@ func TestParse_Defaults(t *testing.T) {
 	if cfg.MaxFileSize != 5*1024*1024 {
 		t.Errorf("MaxFileSize = %d, want %d", cfg.MaxFileSize, 5*1024*1024)
 	}
+	if cfg.MaxArchiveSize != 50*1024*1024 {
+		t.Errorf("MaxArchiveSize = %d, want %d", cfg.MaxArchiveSize, 50*1024*1024)
+	}
+	if cfg.ArchiveCacheSize != 200*1024*1024 {
+		t.Errorf("ArchiveCacheSize = %d, want %d", cfg.ArchiveCacheSize, 200*1024*1024)
+	}
 	if cfg.AllowedUpstreams != "" {
 		t.Errorf("AllowedUpstreams = %q, want empty", cfg.AllowedUpstreams)
 	}
@@ -122,6 +130,286 @@ func TestParse_FlagOverridesEnv(t *testing.T) {
 	}
 }
 
+func TestParse_CredentialsFile(t *testing.T) {
+	t.Setenv("COOKED_CREDENTIALS_FILE", "/etc/cooked/credentials.json")
+
+	cfg, err := Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CredentialsFile != "/etc/cooked/credentials.json" {
+		t.Errorf("CredentialsFile = %q, want /etc/cooked/credentials.json", cfg.CredentialsFile)
+	}
+}
+
+func TestParse_AdminToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Parse([]string{"--admin-token-file", tokenFile, "--admin-listen", "127.0.0.1:9090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AdminToken != "s3cret" {
+		t.Errorf("AdminToken = %q, want s3cret (trailing newline trimmed)", cfg.AdminToken)
+	}
+	if cfg.AdminListen != "127.0.0.1:9090" {
+		t.Errorf("AdminListen = %q, want 127.0.0.1:9090", cfg.AdminListen)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"--admin-token-file", empty},
+		{"--admin-token-file", filepath.Join(t.TempDir(), "missing")},
+		{"--admin-listen", "127.0.0.1:9090"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Errorf("Parse(%q): expected error, got nil", args)
+		}
+	}
+}
+
+func TestParse_WebhookSecret(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("hook-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COOKED_WEBHOOK_PREWARM", "true")
+
+	cfg, err := Parse([]string{"--webhook-secret-file", secretFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WebhookSecret != "hook-secret" || !cfg.WebhookPrewarm {
+		t.Errorf("WebhookSecret = %q, WebhookPrewarm = %v", cfg.WebhookSecret, cfg.WebhookPrewarm)
+	}
+
+	if _, err := Parse([]string{"--webhook-secret-file", filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for missing webhook-secret-file, got nil")
+	}
+}
+
+func TestParse_Metrics(t *testing.T) {
+	cfg, err := Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Metrics || cfg.MetricsListen != "" {
+		t.Errorf("defaults: Metrics = %v, MetricsListen = %q", cfg.Metrics, cfg.MetricsListen)
+	}
+
+	t.Setenv("COOKED_METRICS_LISTEN", "0.0.0.0:9100")
+	cfg, err = Parse([]string{"--metrics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Metrics || cfg.MetricsListen != "0.0.0.0:9100" {
+		t.Errorf("Metrics = %v, MetricsListen = %q", cfg.Metrics, cfg.MetricsListen)
+	}
+
+	if _, err := Parse([]string{"--listen", ":8080", "--metrics-listen", ":8080"}); err == nil {
+		t.Error("expected error when metrics-listen equals listen, got nil")
+	}
+}
+
+func TestParse_Tracing(t *testing.T) {
+	cfg, err := Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OTLPEndpoint != "" || cfg.OTLPProtocol != "http" || cfg.TraceSampleRatio != 1 {
+		t.Errorf("defaults: endpoint = %q, protocol = %q, ratio = %g", cfg.OTLPEndpoint, cfg.OTLPProtocol, cfg.TraceSampleRatio)
+	}
+
+	t.Setenv("COOKED_TRACE_SAMPLE_RATIO", "0.25")
+	cfg, err = Parse([]string{"--otlp-endpoint", "http://localhost:4317", "--otlp-protocol", "grpc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OTLPEndpoint != "http://localhost:4317" || cfg.OTLPProtocol != "grpc" || cfg.TraceSampleRatio != 0.25 {
+		t.Errorf("endpoint = %q, protocol = %q, ratio = %g", cfg.OTLPEndpoint, cfg.OTLPProtocol, cfg.TraceSampleRatio)
+	}
+
+	for _, args := range [][]string{
+		{"--otlp-endpoint", "localhost:4318"},
+		{"--otlp-protocol", "thrift"},
+		{"--trace-sample-ratio", "1.5"},
+		{"--trace-sample-ratio", "-0.1"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Errorf("Parse(%q): expected error, got nil", args)
+		}
+	}
+}
+
+func TestParse_LocalRoots(t *testing.T) {
+	docs, nfs := t.TempDir(), t.TempDir()
+	file := filepath.Join(docs, "README.md")
+	if err := os.WriteFile(file, []byte("# Docs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Parse([]string{"--local-root", "docs=" + docs, "--local-root", "nfs=" + nfs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.LocalRoots) != 2 || cfg.LocalRoots["docs"] != docs || cfg.LocalRoots["nfs"] != nfs {
+		t.Errorf("LocalRoots = %v", cfg.LocalRoots)
+	}
+
+	t.Setenv("COOKED_LOCAL_ROOTS", "a="+docs+",b="+nfs)
+	cfg, err = Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.LocalRoots) != 2 || cfg.LocalRoots["b"] != nfs {
+		t.Errorf("LocalRoots from env = %v", cfg.LocalRoots)
+	}
+
+	for _, arg := range []string{
+		docs,
+		"docs=",
+		"bad/name=" + docs,
+		"missing=" + filepath.Join(docs, "missing"),
+		"file=" + file,
+	} {
+		if _, err := Parse([]string{"--local-root", arg}); err == nil {
+			t.Errorf("--local-root %q: expected error, got nil", arg)
+		}
+	}
+	if _, err := Parse([]string{"--local-root", "d=" + docs, "--local-root", "d=" + nfs}); err == nil {
+		t.Error("duplicate local root name: expected error, got nil")
+	}
+}
+
+func TestParse_GitRepos(t *testing.T) {
+	repo := t.TempDir()
+	cfg, err := Parse([]string{"--git-repo", "docs=" + repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.GitRepos) != 1 || cfg.GitRepos["docs"] != repo {
+		t.Errorf("GitRepos = %v", cfg.GitRepos)
+	}
+
+	t.Setenv("COOKED_GIT_REPOS", "a="+repo+",b="+repo)
+	cfg, err = Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.GitRepos) != 2 {
+		t.Errorf("GitRepos from env = %v", cfg.GitRepos)
+	}
+
+	if _, err := Parse([]string{"--git-repo", "docs=" + filepath.Join(repo, "missing")}); err == nil {
+		t.Error("missing repository: expected error, got nil")
+	}
+}
+
+func TestParse_CacheDir(t *testing.T) {
+	cfg, err := Parse([]string{"--cache-dir", "/var/cache/cooked", "--cache-disk-max-size", "2GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheDir != "/var/cache/cooked" {
+		t.Errorf("CacheDir = %q, want /var/cache/cooked", cfg.CacheDir)
+	}
+	if cfg.CacheDiskMaxSize != 2*1024*1024*1024 {
+		t.Errorf("CacheDiskMaxSize = %d, want %d", cfg.CacheDiskMaxSize, 2*1024*1024*1024)
+	}
+
+	if _, err := Parse([]string{"--cache-disk-max-size", "lots"}); err == nil {
+		t.Error("expected error for invalid cache-disk-max-size, got nil")
+	}
+}
+
+func TestParse_CacheTTLBounds(t *testing.T) {
+	cfg, err := Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheMinTTL != 0 || cfg.CacheMaxTTL != 24*time.Hour {
+		t.Errorf("defaults: min = %v, max = %v, want 0 and 24h", cfg.CacheMinTTL, cfg.CacheMaxTTL)
+	}
+
+	cfg, err = Parse([]string{"--cache-min-ttl", "30s", "--cache-max-ttl", "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheMinTTL != 30*time.Second || cfg.CacheMaxTTL != time.Hour {
+		t.Errorf("min = %v, max = %v, want 30s and 1h", cfg.CacheMinTTL, cfg.CacheMaxTTL)
+	}
+
+	if _, err := Parse([]string{"--cache-min-ttl", "2h", "--cache-max-ttl", "1h"}); err == nil {
+		t.Error("expected error when cache-min-ttl exceeds cache-max-ttl")
+	}
+}
+
+func TestParse_CacheStaleWindows(t *testing.T) {
+	cfg, err := Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheStaleWhileRevalidate != 0 {
+		t.Errorf("CacheStaleWhileRevalidate default = %v, want 0 (disabled)", cfg.CacheStaleWhileRevalidate)
+	}
+	if cfg.CacheStaleIfError != 24*time.Hour {
+		t.Errorf("CacheStaleIfError default = %v, want 24h", cfg.CacheStaleIfError)
+	}
+	if cfg.CacheRefreshWorkers != 4 {
+		t.Errorf("CacheRefreshWorkers default = %d, want 4", cfg.CacheRefreshWorkers)
+	}
+
+	t.Setenv("COOKED_CACHE_STALE_WHILE_REVALIDATE", "10m")
+	t.Setenv("COOKED_CACHE_REFRESH_WORKERS", "8")
+	cfg, err = Parse([]string{"--cache-stale-if-error", "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheStaleWhileRevalidate != 10*time.Minute || cfg.CacheStaleIfError != time.Hour || cfg.CacheRefreshWorkers != 8 {
+		t.Errorf("got swr=%v sie=%v workers=%d", cfg.CacheStaleWhileRevalidate, cfg.CacheStaleIfError, cfg.CacheRefreshWorkers)
+	}
+
+	if _, err := Parse([]string{"--cache-refresh-workers", "0"}); err == nil {
+		t.Error("expected error for zero refresh workers")
+	}
+	if _, err := Parse([]string{"--cache-stale-if-error", "-1m"}); err == nil {
+		t.Error("expected error for negative stale-if-error")
+	}
+}
+
+func TestParse_ForgeHosts(t *testing.T) {
+	cfg, err := Parse([]string{"--forge-hosts", "cgit.internal=cgit,git.corp=gitea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ForgeHosts != "cgit.internal=cgit,git.corp=gitea" {
+		t.Errorf("ForgeHosts = %q", cfg.ForgeHosts)
+	}
+	if cfg.ForgeAutodetect {
+		t.Error("ForgeAutodetect = true, want false by default")
+	}
+	cfg, err = Parse([]string{"--forge-autodetect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ForgeAutodetect {
+		t.Error("ForgeAutodetect = false, want true")
+	}
+
+	if _, err := Parse([]string{"--forge-hosts", "cgit.internal=unknown"}); err == nil {
+		t.Error("expected error for unknown forge profile, got nil")
+	}
+	if _, err := Parse([]string{"--forge-profiles-file", "/nonexistent/forges.json"}); err == nil {
+		t.Error("expected error for missing forge profiles file, got nil")
+	}
+}
+
 func TestParse_InvalidTheme(t *testing.T) {
 	_, err := Parse([]string{"--default-theme", "neon"})
 	if err == nil {
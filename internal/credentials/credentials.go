@@ -0,0 +1,255 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Credential types supported in the credentials file.
+const (
+	TypeHeader = "header" // static header, e.g. PRIVATE-TOKEN: xyz
+	TypeBearer = "bearer" // Authorization: Bearer <token>
+	TypeBasic  = "basic"  // Authorization: Basic base64(user:password)
+)
+
+// fileFormat is the on-disk JSON layout of the credentials file.
+//
+//	{
+//	  "credentials": [
+//	    {"match": "gitea.internal", "type": "bearer", "secret_file": "/etc/cooked/gitea-token"},
+//	    {"match": "gitlab.internal/group/", "type": "header", "header": "PRIVATE-TOKEN", "secret_file": "/etc/cooked/gitlab-token"},
+//	    {"match": "nexus.internal:8443", "type": "basic", "username": "reader", "secret_file": "/etc/cooked/nexus-password"}
+//	  ]
+//	}
+type fileFormat struct {
+	Credentials []fileEntry `json:"credentials"`
+}
+
+type fileEntry struct {
+	Match        string `json:"match"`
+	Type         string `json:"type"`
+	Header       string `json:"header,omitempty"`
+	Username     string `json:"username,omitempty"`
+	UsernameFile string `json:"username_file,omitempty"`
+	SecretFile   string `json:"secret_file"`
+}
+
+// Credential is a single resolved credential bound to a host and optional
+// path prefix. The secret is held in memory only and never logged.
+type Credential struct {
+	host       string // lowercased; includes port only if the match entry did
+	pathPrefix string // "" or a prefix beginning with "/"
+	header     string // canonical header name to set
+	value      string // header value (secret)
+}
+
+// Header returns the name of the header this credential sets.
+func (c *Credential) Header() string {
+	return c.header
+}
+
+// Apply sets the credential header on the request.
+func (c *Credential) Apply(req *http.Request) {
+	req.Header.Set(c.header, c.value)
+}
+
+// String describes the credential without revealing the secret.
+func (c *Credential) String() string {
+	return fmt.Sprintf("%s%s (%s)", c.host, c.pathPrefix, c.header)
+}
+
+// Store holds all configured credentials. A nil Store matches nothing.
+type Store struct {
+	entries []*Credential // sorted most-specific first
+}
+
+// Load reads a credentials file and all referenced secret files.
+func Load(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read credentials file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse builds a Store from the JSON credentials file contents. Secret files
+// referenced by entries are read immediately so misconfiguration fails at startup.
+func Parse(data []byte) (*Store, error) {
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse credentials file: %w", err)
+	}
+
+	s := &Store{}
+	for i, e := range f.Credentials {
+		c, err := resolve(e)
+		if err != nil {
+			return nil, fmt.Errorf("credentials entry %d (%q): %w", i, e.Match, err)
+		}
+		s.entries = append(s.entries, c)
+	}
+
+	// Longest path prefix wins; hosts with explicit ports before bare hosts.
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		a, b := s.entries[i], s.entries[j]
+		if len(a.pathPrefix) != len(b.pathPrefix) {
+			return len(a.pathPrefix) > len(b.pathPrefix)
+		}
+		return strings.Contains(a.host, ":") && !strings.Contains(b.host, ":")
+	})
+
+	return s, nil
+}
+
+func resolve(e fileEntry) (*Credential, error) {
+	match := strings.TrimSpace(e.Match)
+	if match == "" {
+		return nil, fmt.Errorf("empty match")
+	}
+	if strings.Contains(match, "://") {
+		return nil, fmt.Errorf("match must be host or host/path, without scheme")
+	}
+
+	c := &Credential{host: strings.ToLower(match)}
+	if i := strings.Index(match, "/"); i >= 0 {
+		c.host = strings.ToLower(match[:i])
+		c.pathPrefix = match[i:]
+	}
+	if c.host == "" {
+		return nil, fmt.Errorf("empty host in match")
+	}
+
+	if e.SecretFile == "" {
+		return nil, fmt.Errorf("secret_file is required")
+	}
+	secret, err := readSecret(e.SecretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Type {
+	case TypeBearer:
+		c.header = "Authorization"
+		c.value = "Bearer " + secret
+	case TypeBasic:
+		username := e.Username
+		if e.UsernameFile != "" {
+			username, err = readSecret(e.UsernameFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if username == "" {
+			return nil, fmt.Errorf("basic credentials require username or username_file")
+		}
+		c.header = "Authorization"
+		c.value = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+secret))
+	case TypeHeader:
+		if e.Header == "" {
+			return nil, fmt.Errorf("header credentials require a header name")
+		}
+		c.header = http.CanonicalHeaderKey(e.Header)
+		c.value = secret
+	default:
+		return nil, fmt.Errorf("unknown type %q: must be header, bearer, or basic", e.Type)
+	}
+
+	return c, nil
+}
+
+// readSecret reads a secret file, trimming the trailing newline that
+// Kubernetes Secrets and editors commonly leave behind.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %q is empty", path)
+	}
+	return secret, nil
+}
+
+// Match returns the most specific credential for the URL, or nil.
+func (s *Store) Match(u *url.URL) *Credential {
+	if s == nil || u == nil {
+		return nil
+	}
+
+	host := strings.ToLower(u.Host)
+	hostname := strings.ToLower(u.Hostname())
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+
+	for _, c := range s.entries {
+		if c.host != host && c.host != hostname {
+			continue
+		}
+		if c.pathPrefix != "" && !underPrefix(p, c.pathPrefix) {
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+// underPrefix reports whether path p is prefix or lies below it, matching
+// whole path segments: /group covers /group/repo but not /group-other.
+func underPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// Headers returns the set of header names any credential may set. Used to
+// strip credentials from requests that must not carry them.
+func (s *Store) Headers() []string {
+	if s == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for _, c := range s.entries {
+		if !seen[c.header] {
+			seen[c.header] = true
+			out = append(out, c.header)
+		}
+	}
+	return out
+}
+
+// Len returns the number of configured credentials.
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// SameOrigin reports whether two URLs share scheme, host and port.
+// Credentials are only re-applied on redirects that stay on the same origin,
+// so a redirect from https to http never carries them in the clear.
+func SameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(hostPort(a), hostPort(b))
+}
+
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
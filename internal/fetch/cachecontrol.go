@@ -0,0 +1,90 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+)
+
+// parseFreshness derives the caching policy from upstream response headers.
+// cooked is a shared cache, so s-maxage takes precedence over max-age, which
+// takes precedence over Expires. no-cache is treated as a zero lifetime that
+// must be revalidated before every use, and s-maxage implies
+// proxy-revalidate.
+func parseFreshness(h http.Header, now time.Time) cache.Freshness {
+	var f cache.Freshness
+	var noCache bool
+	var maxAge, sMaxAge *time.Duration
+
+	for _, line := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "no-store":
+				f.NoStore = true
+			case "no-cache":
+				noCache = true
+			case "must-revalidate", "proxy-revalidate":
+				f.MustRevalidate = true
+			case "max-age":
+				d := parseDeltaSeconds(value)
+				maxAge = &d
+			case "s-maxage":
+				d := parseDeltaSeconds(value)
+				sMaxAge = &d
+			}
+		}
+	}
+
+	switch {
+	case f.NoStore:
+	case noCache:
+		f.HasMaxAge, f.MustRevalidate = true, true
+	case sMaxAge != nil:
+		f.MaxAge, f.HasMaxAge, f.MustRevalidate = *sMaxAge, true, true
+	case maxAge != nil:
+		f.MaxAge, f.HasMaxAge = *maxAge, true
+	default:
+		if expires := h.Get("Expires"); expires != "" {
+			f.MaxAge, f.HasMaxAge = expiresLifetime(expires, h.Get("Date"), now), true
+		}
+	}
+	return f
+}
+
+// parseDeltaSeconds parses a Cache-Control delta-seconds value. Invalid
+// values are treated as zero (already stale), as RFC 9111 recommends.
+func parseDeltaSeconds(v string) time.Duration {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	const maxSeconds = int64(1<<63-1) / int64(time.Second)
+	if n > maxSeconds {
+		n = maxSeconds
+	}
+	return time.Duration(n) * time.Second
+}
+
+// expiresLifetime returns the lifetime implied by an Expires header, measured
+// against the response Date when present. Unparseable dates mean already
+// expired.
+func expiresLifetime(expires, date string, now time.Time) time.Duration {
+	exp, err := http.ParseTime(expires)
+	if err != nil {
+		return 0
+	}
+	base := now
+	if d, err := http.ParseTime(date); err == nil {
+		base = d
+	}
+	if lifetime := exp.Sub(base); lifetime > 0 {
+		return lifetime
+	}
+	return 0
+}
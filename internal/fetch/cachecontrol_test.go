@@ -0,0 +1,59 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+)
+
+func TestParseFreshness(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   cache.Freshness
+	}{
+		{"none", http.Header{}, cache.Freshness{}},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=600"}},
+			cache.Freshness{MaxAge: 10 * time.Minute, HasMaxAge: true}},
+		{"s-maxage wins and implies revalidation", http.Header{"Cache-Control": {"max-age=60, s-maxage=3600"}},
+			cache.Freshness{MaxAge: time.Hour, HasMaxAge: true, MustRevalidate: true}},
+		{"no-store", http.Header{"Cache-Control": {"no-store, max-age=600"}},
+			cache.Freshness{NoStore: true}},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}},
+			cache.Freshness{HasMaxAge: true, MustRevalidate: true}},
+		{"must-revalidate", http.Header{"Cache-Control": {"max-age=30, must-revalidate"}},
+			cache.Freshness{MaxAge: 30 * time.Second, HasMaxAge: true, MustRevalidate: true}},
+		{"case and quotes", http.Header{"Cache-Control": {`Max-Age="120"`}},
+			cache.Freshness{MaxAge: 2 * time.Minute, HasMaxAge: true}},
+		{"multiple header lines", http.Header{"Cache-Control": {"public", "max-age=5"}},
+			cache.Freshness{MaxAge: 5 * time.Second, HasMaxAge: true}},
+		{"invalid max-age is stale", http.Header{"Cache-Control": {"max-age=soon"}},
+			cache.Freshness{HasMaxAge: true}},
+		{"expires relative to date", http.Header{
+			"Date":    {"Sun, 01 Mar 2026 11:00:00 GMT"},
+			"Expires": {"Sun, 01 Mar 2026 11:30:00 GMT"},
+		}, cache.Freshness{MaxAge: 30 * time.Minute, HasMaxAge: true}},
+		{"expires without date uses now", http.Header{"Expires": {"Sun, 01 Mar 2026 13:00:00 GMT"}},
+			cache.Freshness{MaxAge: time.Hour, HasMaxAge: true}},
+		{"expires in the past", http.Header{"Expires": {"Thu, 01 Jan 1970 00:00:00 GMT"}},
+			cache.Freshness{HasMaxAge: true}},
+		{"invalid expires", http.Header{"Expires": {"0"}},
+			cache.Freshness{HasMaxAge: true}},
+		{"max-age beats expires", http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Expires":       {"Sun, 01 Mar 2026 13:00:00 GMT"},
+		}, cache.Freshness{MaxAge: time.Minute, HasMaxAge: true}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseFreshness(tc.header, now); got != tc.want {
+				t.Errorf("parseFreshness = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,28 @@
 package fetch
 
 import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/tracing"
 )
 
-// CachedClient wraps a fetch Client with an in-memory cache.
+// CachedClient wraps a fetch Client with an in-memory cache. Concurrent
+// upstream fetches for the same URL are coalesced into one.
 type CachedClient struct {
 	client *Client
 	cache  *cache.Cache
+
+	fetches flightGroup[*Result]
+	shared  flightGroup[any]
+
+	staleWhileRevalidate time.Duration // 0 = always revalidate synchronously
+	staleIfError         time.Duration // 0 = serve stale on error regardless of age
+	refresher            *refresher
+	now                  func() time.Time // injectable for testing
 }
 
 // CachedResult extends Result with cache status.
@@ -15,21 +30,90 @@ type CachedResult struct {
 	*Result
 	CacheStatus cache.Status
 	RenderMs    int64 // set by caller after rendering
+	Collapsed   int   // concurrent requests that shared this upstream fetch
+
+	// Refresh is set when an expired entry was served inside the
+	// stale-while-revalidate window; the caller should schedule a background
+	// refresh with Refresh.
+	Refresh bool
+}
+
+// CachedOption configures a CachedClient.
+type CachedOption func(*CachedClient)
+
+// WithStaleWhileRevalidate serves entries that expired less than window ago
+// immediately, leaving revalidation to a background refresh. At most workers
+// refreshes run at once.
+func WithStaleWhileRevalidate(window time.Duration, workers int) CachedOption {
+	return func(cc *CachedClient) {
+		cc.staleWhileRevalidate = window
+		cc.refresher = newRefresher(workers)
+	}
+}
+
+// WithStaleIfError limits how long after expiry an entry may still be served
+// when revalidation fails. Zero means no limit.
+func WithStaleIfError(maxAge time.Duration) CachedOption {
+	return func(cc *CachedClient) {
+		cc.staleIfError = maxAge
+	}
 }
 
 // NewCachedClient creates a fetch client with caching.
-func NewCachedClient(client *Client, c *cache.Cache) *CachedClient {
-	return &CachedClient{client: client, cache: c}
+func NewCachedClient(client *Client, c *cache.Cache, opts ...CachedOption) *CachedClient {
+	cc := &CachedClient{client: client, cache: c, now: time.Now}
+	for _, opt := range opts {
+		opt(cc)
+	}
+	if cc.refresher == nil {
+		cc.refresher = newRefresher(1)
+	}
+	return cc
 }
 
 // Fetch retrieves content from upstream, using the cache when possible.
 // On cache hit with ETag/LastModified, performs conditional GET.
 // On 304, serves from cache and resets TTL.
 // On miss/expired, fetches fresh content.
+// Inside the stale-while-revalidate window an expired entry is returned as
+// stale with Refresh set instead of being revalidated.
 // The caller is responsible for rendering and storing the result in the cache.
-func (cc *CachedClient) Fetch(rawURL string) (*CachedResult, *cache.Entry, error) {
+// key is the upstream URL, or a cache.VariantKey of it.
+func (cc *CachedClient) Fetch(ctx context.Context, key string) (*CachedResult, *cache.Entry, error) {
+	result, entry, err := cc.fetch(ctx, key, true)
+	if err == nil {
+		cc.cache.Record(result.CacheStatus)
+	}
+	return result, entry, err
+}
+
+// Revalidate is Fetch without the stale-while-revalidate window: expired
+// entries are always revalidated upstream. Background refreshes use it.
+func (cc *CachedClient) Revalidate(ctx context.Context, key string) (*CachedResult, *cache.Entry, error) {
+	return cc.fetch(ctx, key, false)
+}
+
+func (cc *CachedClient) fetch(ctx context.Context, key string, allowStale bool) (*CachedResult, *cache.Entry, error) {
+	ctx, span := tracing.Start(ctx, "CachedClient.Fetch", tracing.URL(key))
+	defer span.End()
+
+	result, entry, err := cc.lookup(ctx, key, allowStale)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, nil, err
+	}
+	span.SetAttributes(
+		attribute.String("cooked.cache", string(result.CacheStatus)),
+		attribute.Int("cooked.collapsed", result.Collapsed),
+	)
+	return result, entry, nil
+}
+
+// lookup serves key from the cache, revalidating or fetching upstream as
+// the entry's state requires.
+func (cc *CachedClient) lookup(ctx context.Context, key string, allowStale bool) (*CachedResult, *cache.Entry, error) {
 	// Check cache
-	entry, status := cc.cache.Get(rawURL)
+	entry, status := cc.cache.Get(key)
 
 	switch status {
 	case cache.StatusHit:
@@ -39,21 +123,44 @@ func (cc *CachedClient) Fetch(rawURL string) (*CachedResult, *cache.Entry, error
 		}, entry, nil
 
 	case cache.StatusExpired:
+		staleFor := cc.now().Sub(entry.ExpiresAt)
+
+		// Serve immediately and let the caller refresh in the background.
+		// must-revalidate and no-cache entries are never served stale.
+		mustRevalidate := entry.Freshness.MustRevalidate
+		if allowStale && !mustRevalidate && staleFor <= cc.staleWhileRevalidate {
+			return &CachedResult{
+				Result:      &Result{StatusCode: 200, FetchMs: 0},
+				CacheStatus: cache.StatusStale,
+				Refresh:     true,
+			}, entry, nil
+		}
+
 		// Attempt revalidation with conditional GET
-		result, err := cc.client.Fetch(rawURL, entry.ETag, entry.LastModified)
+		result, collapsed, err := cc.fetchCoalesced(ctx, key, entry.ETag, entry.LastModified)
 		if err != nil {
+			if mustRevalidate || (cc.staleIfError > 0 && staleFor > cc.staleIfError) {
+				return nil, nil, err
+			}
 			// On error, serve stale cache
 			return &CachedResult{
 				Result:      &Result{StatusCode: 200, FetchMs: 0},
 				CacheStatus: cache.StatusStale,
+				Collapsed:   collapsed,
 			}, entry, nil
 		}
 
 		if result.StatusCode == 304 {
-			cc.cache.RefreshTTL(rawURL)
+			// A 304 carrying caching headers replaces the stored policy.
+			if result.Freshness.Declared() {
+				cc.cache.UpdateFreshness(key, result.Freshness)
+			} else {
+				cc.cache.RefreshTTL(key)
+			}
 			return &CachedResult{
 				Result:      result,
 				CacheStatus: cache.StatusRevalidated,
+				Collapsed:   collapsed,
 			}, entry, nil
 		}
 
@@ -61,10 +168,11 @@ func (cc *CachedClient) Fetch(rawURL string) (*CachedResult, *cache.Entry, error
 		return &CachedResult{
 			Result:      result,
 			CacheStatus: cache.StatusExpired,
+			Collapsed:   collapsed,
 		}, nil, nil
 
 	default: // miss
-		result, err := cc.client.Fetch(rawURL, "", "")
+		result, collapsed, err := cc.fetchCoalesced(ctx, key, "", "")
 		if err != nil {
 			return nil, nil, err
 		}
@@ -72,10 +180,52 @@ func (cc *CachedClient) Fetch(rawURL string) (*CachedResult, *cache.Entry, error
 		return &CachedResult{
 			Result:      result,
 			CacheStatus: cache.StatusMiss,
+			Collapsed:   collapsed,
 		}, nil, nil
 	}
 }
 
+// fetchCoalesced performs one upstream fetch for all concurrent callers of
+// the same cache key. Conditional and unconditional fetches are kept apart so a
+// caller without a cached entry never receives a bare 304. The shared Result
+// must be treated as read-only. The fetch runs with the first caller's
+// context.
+func (cc *CachedClient) fetchCoalesced(ctx context.Context, key, etag, lastModified string) (*Result, int, error) {
+	flight := key
+	if etag != "" || lastModified != "" {
+		flight = "revalidate\x00" + key
+	}
+	return cc.fetches.do(flight, func() (*Result, error) {
+		return cc.client.Fetch(ctx, cache.KeyURL(key), etag, lastModified)
+	})
+}
+
+// Coalesce runs fn once for all concurrent callers sharing key, typically the
+// upstream URL, so the work after a fetch (rendering, storing) is also done
+// only once. It returns fn's result and the number of callers collapsed into
+// the call. The result is shared between callers and must not be mutated.
+func (cc *CachedClient) Coalesce(key string, fn func() (any, error)) (any, int, error) {
+	return cc.shared.do(key, fn)
+}
+
+// Waiting returns how many callers are waiting on the in-flight Coalesce call
+// for key, or -1 when none is in flight.
+func (cc *CachedClient) Waiting(key string) int {
+	return cc.shared.waiting(key)
+}
+
+// Refresh runs fn in the background to refresh key, unless a refresh for key
+// is already running or all refresh workers are busy. It reports whether fn
+// was started.
+func (cc *CachedClient) Refresh(key string, fn func()) bool {
+	return cc.refresher.schedule(key, fn)
+}
+
+// WaitRefreshes blocks until all background refreshes have finished.
+func (cc *CachedClient) WaitRefreshes() {
+	cc.refresher.wait()
+}
+
 // Store caches a rendered page entry.
 func (cc *CachedClient) Store(key string, entry cache.Entry) {
 	cc.cache.Put(key, entry)
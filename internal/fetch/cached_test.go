@@ -3,6 +3,8 @@ package fetch
 import (
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,7 +20,7 @@ func TestCachedClient_Miss(t *testing.T) {
 	c := newTestClient(10*time.Second, 5*1024*1024)
 	cc := NewCachedClient(c, cache.New(5*time.Minute, 100*1024*1024))
 
-	result, entry, err := cc.Fetch(upstream.URL + "/README.md")
+	result, entry, err := cc.Fetch(t.Context(), upstream.URL+"/README.md")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -49,7 +51,7 @@ func TestCachedClient_Hit(t *testing.T) {
 	url := upstream.URL + "/README.md"
 
 	// First fetch — miss
-	result1, _, err := cc.Fetch(url)
+	result1, _, err := cc.Fetch(t.Context(), url)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -65,7 +67,7 @@ func TestCachedClient_Hit(t *testing.T) {
 	})
 
 	// Second fetch — hit
-	result2, entry, err := cc.Fetch(url)
+	result2, entry, err := cc.Fetch(t.Context(), url)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -117,7 +119,7 @@ func TestCachedClient_Revalidation304(t *testing.T) {
 	time.Sleep(5 * time.Millisecond)
 
 	// Fetch — should revalidate
-	result, entry, err := cc.Fetch(url)
+	result, entry, err := cc.Fetch(t.Context(), url)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -157,7 +159,7 @@ func TestCachedClient_RevalidationError_ServesStale(t *testing.T) {
 	upstream.Close()
 
 	// Fetch — should serve stale content
-	result, entry, err := cc.Fetch(url)
+	result, entry, err := cc.Fetch(t.Context(), url)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -174,3 +176,258 @@ func TestCachedClient_RevalidationError_ServesStale(t *testing.T) {
 		t.Errorf("FetchMs = %d, want 0 for stale serve", result.FetchMs)
 	}
 }
+
+func TestCachedClient_ConcurrentMissesCoalesced(t *testing.T) {
+	var fetchCount atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount.Add(1)
+		<-release
+		w.Write([]byte("# Incident runbook"))
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	cc := NewCachedClient(c, cache.New(5*time.Minute, 100*1024*1024))
+	url := upstream.URL + "/runbook.md"
+
+	const n = 8
+	results := make([]*CachedResult, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, _, err := cc.Fetch(t.Context(), url)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = r
+		}(i)
+	}
+
+	waitJoined(t, &cc.fetches, url, n-1)
+	close(release)
+	wg.Wait()
+
+	if got := fetchCount.Load(); got != 1 {
+		t.Errorf("upstream fetched %d times, want 1", got)
+	}
+	for i, r := range results {
+		if r == nil {
+			continue
+		}
+		if r.CacheStatus != cache.StatusMiss {
+			t.Errorf("result %d CacheStatus = %q, want miss", i, r.CacheStatus)
+		}
+		if r.Collapsed != n-1 {
+			t.Errorf("result %d Collapsed = %d, want %d", i, r.Collapsed, n-1)
+		}
+		if string(r.Body) != "# Incident runbook" {
+			t.Errorf("result %d Body = %q", i, r.Body)
+		}
+	}
+}
+
+func TestCachedClient_ConcurrentRevalidationsCoalesced(t *testing.T) {
+	var fetchCount atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount.Add(1)
+		<-release
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(time.Millisecond, 100*1024*1024)
+	cc := NewCachedClient(c, memCache)
+	url := upstream.URL + "/runbook.md"
+
+	cc.Store(url, cache.Entry{HTML: []byte("<h1>cached</h1>"), Size: 15, ETag: `"v1"`})
+	time.Sleep(5 * time.Millisecond) // let the entry expire
+
+	const n = 5
+	statuses := make([]cache.Status, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, _, err := cc.Fetch(t.Context(), url)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			statuses[i] = r.CacheStatus
+		}(i)
+	}
+
+	waitJoined(t, &cc.fetches, "revalidate\x00"+url, n-1)
+	close(release)
+	wg.Wait()
+
+	if got := fetchCount.Load(); got != 1 {
+		t.Errorf("upstream revalidated %d times, want 1", got)
+	}
+	for i, st := range statuses {
+		if st != cache.StatusRevalidated {
+			t.Errorf("result %d CacheStatus = %q, want revalidated", i, st)
+		}
+	}
+}
+
+func TestCachedClient_StaleWhileRevalidate(t *testing.T) {
+	var fetchCount atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount.Add(1)
+		w.Write([]byte("fresh"))
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(time.Millisecond, 100*1024*1024)
+	cc := NewCachedClient(c, memCache, WithStaleWhileRevalidate(time.Hour, 2))
+
+	url := upstream.URL + "/file.md"
+	cc.Store(url, cache.Entry{HTML: []byte("<p>old</p>"), Size: 10, ETag: `"v1"`})
+
+	// Ten minutes later the entry has expired but is inside the window.
+	time.Sleep(5 * time.Millisecond)
+	now := time.Now()
+	cc.now = func() time.Time { return now.Add(10 * time.Minute) }
+
+	result, entry, err := cc.Fetch(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CacheStatus != cache.StatusStale || !result.Refresh {
+		t.Errorf("CacheStatus = %q, Refresh = %v, want stale with refresh", result.CacheStatus, result.Refresh)
+	}
+	if entry == nil || string(entry.HTML) != "<p>old</p>" {
+		t.Error("stale entry should be returned")
+	}
+	if got := fetchCount.Load(); got != 0 {
+		t.Errorf("upstream fetched %d times, want 0 (revalidation is left to the caller)", got)
+	}
+
+	// Revalidate ignores the window.
+	result, _, err = cc.Revalidate(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CacheStatus != cache.StatusExpired || result.Refresh {
+		t.Errorf("Revalidate CacheStatus = %q, Refresh = %v, want expired without refresh", result.CacheStatus, result.Refresh)
+	}
+	if got := fetchCount.Load(); got != 1 {
+		t.Errorf("upstream fetched %d times, want 1", got)
+	}
+}
+
+func TestCachedClient_StaleWhileRevalidate_OutsideWindow(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fresh"))
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(time.Millisecond, 100*1024*1024)
+	cc := NewCachedClient(c, memCache, WithStaleWhileRevalidate(time.Minute, 2))
+
+	url := upstream.URL + "/file.md"
+	cc.Store(url, cache.Entry{HTML: []byte("<p>old</p>"), Size: 10})
+	time.Sleep(5 * time.Millisecond)
+	now := time.Now()
+	cc.now = func() time.Time { return now.Add(time.Hour) }
+
+	result, _, err := cc.Fetch(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CacheStatus != cache.StatusExpired || result.Refresh {
+		t.Errorf("CacheStatus = %q, Refresh = %v, want synchronous revalidation", result.CacheStatus, result.Refresh)
+	}
+	if string(result.Body) != "fresh" {
+		t.Errorf("Body = %q, want fresh", result.Body)
+	}
+}
+
+func TestCachedClient_StaleIfError_MaxAge(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := upstream.URL + "/file.md"
+	upstream.Close() // every revalidation fails
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(time.Millisecond, 100*1024*1024)
+	cc := NewCachedClient(c, memCache, WithStaleIfError(time.Hour))
+	cc.Store(url, cache.Entry{HTML: []byte("<p>old</p>"), Size: 10})
+	time.Sleep(5 * time.Millisecond)
+	now := time.Now()
+
+	// Within the limit: stale entry served.
+	cc.now = func() time.Time { return now.Add(30 * time.Minute) }
+	result, entry, err := cc.Fetch(t.Context(), url)
+	if err != nil {
+		t.Fatalf("within stale-if-error: %v", err)
+	}
+	if result.CacheStatus != cache.StatusStale || entry == nil {
+		t.Errorf("CacheStatus = %q, want stale entry", result.CacheStatus)
+	}
+
+	// Past the limit: the fetch error is returned.
+	cc.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, _, err := cc.Fetch(t.Context(), url); err == nil {
+		t.Error("expected fetch error past the stale-if-error limit")
+	}
+}
+
+func TestCachedClient_MustRevalidate_NeverStale(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := upstream.URL + "/file.md"
+	upstream.Close() // every revalidation fails
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(5*time.Minute, 100*1024*1024)
+	cc := NewCachedClient(c, memCache, WithStaleWhileRevalidate(time.Hour, 1))
+	cc.Store(url, cache.Entry{
+		HTML:      []byte("<p>old</p>"),
+		Size:      10,
+		Freshness: cache.Freshness{HasMaxAge: true, MustRevalidate: true}, // no-cache
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	if _, _, err := cc.Fetch(t.Context(), url); err == nil {
+		t.Error("must-revalidate entry should not be served stale when revalidation fails")
+	}
+}
+
+func TestCachedClient_Revalidation304_UpdatesFreshness(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	memCache := cache.New(time.Millisecond, 100*1024*1024)
+	cc := NewCachedClient(c, memCache)
+
+	url := upstream.URL + "/file.md"
+	cc.Store(url, cache.Entry{HTML: []byte("<p>cached</p>"), Size: 13, ETag: `"v1"`})
+	time.Sleep(5 * time.Millisecond)
+
+	result, _, err := cc.Fetch(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CacheStatus != cache.StatusRevalidated {
+		t.Fatalf("CacheStatus = %q, want revalidated", result.CacheStatus)
+	}
+
+	// The 304's max-age replaces the 1ms default TTL.
+	time.Sleep(5 * time.Millisecond)
+	if _, status := memCache.Get(url); status != cache.StatusHit {
+		t.Errorf("status = %q, want hit under the 304's max-age", status)
+	}
+}
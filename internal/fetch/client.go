@@ -7,33 +7,50 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/credentials"
 	"github.com/air-gapped/cooked/internal/ssrf"
+	"github.com/air-gapped/cooked/internal/tracing"
 )
 
 // Result holds the outcome of an upstream fetch.
 type Result struct {
-	Body         []byte
-	StatusCode   int
-	ContentType  string
-	ETag         string
-	LastModified string
-	ContentLen   int64
-	FetchMs      int64
+	Body        []byte
+	StatusCode  int
+	ContentType string
+	// ContentDisposition may name the file when the URL does not.
+	ContentDisposition string
+	ETag               string
+	LastModified       string
+	ContentLen         int64
+	FetchMs            int64
+	Freshness          cache.Freshness // caching policy from Cache-Control/Expires
 }
 
 // RedirectValidator is called on each redirect hop. It receives the target URL
 // and should return a non-nil error to block the redirect.
 type RedirectValidator func(target *url.URL) error
 
+// Observer is called after every upstream fetch with the requested URL, the
+// response status (0 when the fetch failed), the elapsed time, and the error.
+type Observer func(rawURL string, status int, elapsed time.Duration, err error)
+
 // Option configures the fetch Client.
 type Option func(*options)
 
 type options struct {
 	redirectValidator RedirectValidator
 	ssrfProtection    bool
+	credentials       *credentials.Store
+	observer          Observer
 }
 
 // WithRedirectValidator sets a callback to validate each redirect hop.
@@ -47,10 +64,24 @@ func WithSSRFProtection(enabled bool) Option {
 	return func(o *options) { o.ssrfProtection = enabled }
 }
 
+// WithCredentials sets server-side credentials applied to matching upstream
+// requests. Credentials are never sent to a redirect target on another host.
+func WithCredentials(s *credentials.Store) Option {
+	return func(o *options) { o.credentials = s }
+}
+
+// WithObserver registers a callback invoked after every fetch, e.g. to
+// record metrics.
+func WithObserver(o Observer) Option {
+	return func(opts *options) { opts.observer = o }
+}
+
 // Client fetches content from upstream URLs.
 type Client struct {
 	httpClient  *http.Client
 	maxFileSize int64
+	credentials *credentials.Store
+	observer    Observer
 }
 
 // maxRedirects is the maximum number of HTTP redirects to follow.
@@ -93,9 +124,14 @@ func NewClient(timeout time.Duration, maxFileSize int64, tlsSkipVerify bool, opt
 		}
 	}
 
+	// Every hop, redirects included, gets its own span with DNS, connect
+	// and TLS timings.
 	client := &http.Client{
-		Timeout:   timeout,
-		Transport: transport,
+		Timeout: timeout,
+		Transport: otelhttp.NewTransport(transport,
+			otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
+				return otelhttptrace.NewClientTrace(ctx)
+			})),
 	}
 
 	// F-01: CheckRedirect to validate each redirect hop.
@@ -112,26 +148,73 @@ func NewClient(timeout time.Duration, maxFileSize int64, tlsSkipVerify bool, opt
 				return fmt.Errorf("redirect blocked: %w", err)
 			}
 		}
+		applyRedirectCredentials(cfg.credentials, req, via[0])
 		return nil
 	}
 
 	return &Client{
 		httpClient:  client,
 		maxFileSize: maxFileSize,
+		credentials: cfg.credentials,
+		observer:    cfg.observer,
+	}
+}
+
+// applyRedirectCredentials strips any credential headers copied from the
+// previous hop and re-applies a matching credential only when the redirect
+// stays on the same scheme, host and port.
+func applyRedirectCredentials(store *credentials.Store, req, first *http.Request) {
+	if store == nil {
+		return
+	}
+	for _, h := range store.Headers() {
+		req.Header.Del(h)
+	}
+	if !credentials.SameOrigin(req.URL, first.URL) {
+		return
+	}
+	if cred := store.Match(req.URL); cred != nil {
+		cred.Apply(req)
 	}
 }
 
 // Fetch retrieves content from the given URL. It does not forward any credentials
-// from the original browser request. If ifNoneMatch or ifModifiedSince are set,
-// a conditional GET is performed.
-func (c *Client) Fetch(rawURL, ifNoneMatch, ifModifiedSince string) (*Result, error) {
+// from the original browser request; only operator-configured server-side
+// credentials (see WithCredentials) are applied. If ifNoneMatch or
+// ifModifiedSince are set, a conditional GET is performed.
+func (c *Client) Fetch(ctx context.Context, rawURL, ifNoneMatch, ifModifiedSince string) (*Result, error) {
+	ctx, span := tracing.Start(ctx, "Client.Fetch", tracing.URL(rawURL),
+		attribute.Bool("cooked.conditional", ifNoneMatch != "" || ifModifiedSince != ""))
+	defer span.End()
+
 	start := time.Now()
+	result, err := c.fetch(ctx, rawURL, ifNoneMatch, ifModifiedSince, start)
+	if err != nil {
+		tracing.Fail(span, err)
+	} else {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode),
+			attribute.Int64("cooked.body_bytes", result.ContentLen))
+	}
+	if c.observer != nil {
+		status := 0
+		if result != nil {
+			status = result.StatusCode
+		}
+		c.observer(rawURL, status, time.Since(start), err)
+	}
+	return result, err
+}
 
-	req, err := http.NewRequest("GET", rawURL, nil)
+func (c *Client) fetch(ctx context.Context, rawURL, ifNoneMatch, ifModifiedSince string, start time.Time) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
+	if cred := c.credentials.Match(req.URL); cred != nil {
+		cred.Apply(req)
+	}
+
 	// Conditional GET headers
 	if ifNoneMatch != "" {
 		req.Header.Set("If-None-Match", ifNoneMatch)
@@ -155,6 +238,7 @@ func (c *Client) Fetch(rawURL, ifNoneMatch, ifModifiedSince string) (*Result, er
 			ETag:         resp.Header.Get("ETag"),
 			LastModified: resp.Header.Get("Last-Modified"),
 			FetchMs:      fetchMs,
+			Freshness:    parseFreshness(resp.Header, time.Now()),
 		}, nil
 	}
 
@@ -175,12 +259,14 @@ func (c *Client) Fetch(rawURL, ifNoneMatch, ifModifiedSince string) (*Result, er
 	}
 
 	return &Result{
-		Body:         body,
-		StatusCode:   resp.StatusCode,
-		ContentType:  resp.Header.Get("Content-Type"),
-		ETag:         resp.Header.Get("ETag"),
-		LastModified: resp.Header.Get("Last-Modified"),
-		ContentLen:   int64(len(body)),
-		FetchMs:      fetchMs,
+		Body:               body,
+		StatusCode:         resp.StatusCode,
+		ContentType:        resp.Header.Get("Content-Type"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		ETag:               resp.Header.Get("ETag"),
+		LastModified:       resp.Header.Get("Last-Modified"),
+		ContentLen:         int64(len(body)),
+		FetchMs:            fetchMs,
+		Freshness:          parseFreshness(resp.Header, time.Now()),
 	}, nil
 }
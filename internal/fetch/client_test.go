@@ -5,9 +5,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/air-gapped/cooked/internal/credentials"
 )
 
 // newTestClient creates a fetch client with SSRF protection disabled,
@@ -26,7 +30,7 @@ func TestFetch_Success(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 5*1024*1024)
-	result, err := c.Fetch(upstream.URL+"/README.md", "", "")
+	result, err := c.Fetch(t.Context(), upstream.URL+"/README.md", "", "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,6 +52,60 @@ func TestFetch_Success(t *testing.T) {
 	}
 }
 
+func TestFetch_Freshness(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=120, must-revalidate")
+		w.Write([]byte("# Hello"))
+	}))
+	defer upstream.Close()
+
+	c := newTestClient(10*time.Second, 5*1024*1024)
+	result, err := c.Fetch(t.Context(), upstream.URL+"/README.md", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := result.Freshness
+	if !f.HasMaxAge || f.MaxAge != 2*time.Minute || !f.MustRevalidate {
+		t.Errorf("Freshness = %+v, want max-age 2m with must-revalidate", f)
+	}
+}
+
+func TestFetch_Observer(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.md" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer upstream.Close()
+
+	type event struct {
+		url    string
+		status int
+		err    bool
+	}
+	var events []event
+	c := NewClient(10*time.Second, 50, false, WithSSRFProtection(false),
+		WithObserver(func(rawURL string, status int, elapsed time.Duration, err error) {
+			if elapsed <= 0 {
+				t.Errorf("elapsed = %v, want > 0", elapsed)
+			}
+			events = append(events, event{rawURL, status, err != nil})
+		}))
+
+	c.Fetch(t.Context(), upstream.URL+"/missing.md", "", "")
+	c.Fetch(t.Context(), upstream.URL+"/large.md", "", "")
+
+	want := []event{
+		{upstream.URL + "/missing.md", 404, false},
+		{upstream.URL + "/large.md", 0, true},
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
 func TestFetch_ConditionalGet304(t *testing.T) {
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Header.Get("If-None-Match") == `"abc123"` {
@@ -59,7 +117,7 @@ func TestFetch_ConditionalGet304(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 5*1024*1024)
-	result, err := c.Fetch(upstream.URL+"/file.md", `"abc123"`, "")
+	result, err := c.Fetch(t.Context(), upstream.URL+"/file.md", `"abc123"`, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -80,7 +138,7 @@ func TestFetch_FileTooLarge_ContentLength(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 1024) // 1KB limit
-	_, err := c.Fetch(upstream.URL+"/big.md", "", "")
+	_, err := c.Fetch(t.Context(), upstream.URL+"/big.md", "", "")
 	if err == nil {
 		t.Error("expected error for large file, got nil")
 	}
@@ -100,7 +158,7 @@ func TestFetch_FileTooLarge_StreamingBody(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 1024) // 1KB limit
-	_, err := c.Fetch(upstream.URL+"/big.md", "", "")
+	_, err := c.Fetch(t.Context(), upstream.URL+"/big.md", "", "")
 	if err == nil {
 		t.Error("expected error for large streamed file, got nil")
 	}
@@ -117,7 +175,7 @@ func TestFetch_NoCredentialForwarding(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 5*1024*1024)
-	_, err := c.Fetch(upstream.URL+"/file.md", "", "")
+	_, err := c.Fetch(t.Context(), upstream.URL+"/file.md", "", "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -138,7 +196,7 @@ func TestFetch_Timeout(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(100*time.Millisecond, 5*1024*1024)
-	_, err := c.Fetch(upstream.URL+"/slow.md", "", "")
+	_, err := c.Fetch(t.Context(), upstream.URL+"/slow.md", "", "")
 	if err == nil {
 		t.Error("expected timeout error, got nil")
 	}
@@ -152,7 +210,7 @@ func TestFetch_UpstreamNon200(t *testing.T) {
 	defer upstream.Close()
 
 	c := newTestClient(10*time.Second, 5*1024*1024)
-	result, err := c.Fetch(upstream.URL+"/missing.md", "", "")
+	result, err := c.Fetch(t.Context(), upstream.URL+"/missing.md", "", "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -171,7 +229,7 @@ func TestFetch_SSRFDialBlock(t *testing.T) {
 	defer upstream.Close()
 
 	c := NewClient(5*time.Second, 5*1024*1024, false) // SSRF enabled by default
-	_, err := c.Fetch(upstream.URL+"/secret", "", "")
+	_, err := c.Fetch(t.Context(), upstream.URL+"/secret", "", "")
 	if err == nil {
 		t.Fatal("expected SSRF dial block error, got nil")
 	}
@@ -191,7 +249,7 @@ func TestFetch_RedirectChainExceedsMax(t *testing.T) {
 
 	// Use SSRF disabled so we can reach the loopback test server.
 	c := NewClient(5*time.Second, 5*1024*1024, false, WithSSRFProtection(false))
-	_, err := c.Fetch(srv.URL+"/loop", "", "")
+	_, err := c.Fetch(t.Context(), srv.URL+"/loop", "", "")
 	if err == nil {
 		t.Fatal("expected too many redirects error, got nil")
 	}
@@ -226,7 +284,7 @@ func TestFetch_RedirectValidatorBlocks(t *testing.T) {
 		WithSSRFProtection(false),
 		WithRedirectValidator(validator),
 	)
-	_, err := c.Fetch(srvA.URL+"/start", "", "")
+	_, err := c.Fetch(t.Context(), srvA.URL+"/start", "", "")
 	if err == nil {
 		t.Fatal("expected redirect validator error, got nil")
 	}
@@ -234,3 +292,75 @@ func TestFetch_RedirectValidatorBlocks(t *testing.T) {
 		t.Errorf("unexpected error: %v, want 'redirect blocked'", err)
 	}
 }
+
+// newCredentialStore writes a token file and returns a store that applies
+// it as the given header to all requests for host.
+func newCredentialStore(t *testing.T, host, header, token string) *credentials.Store {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := credentials.Parse([]byte(fmt.Sprintf(
+		`{"credentials": [{"match": %q, "type": "header", "header": %q, "secret_file": %q}]}`,
+		host, header, tokenFile)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestFetch_CredentialsApplied(t *testing.T) {
+	var gotToken string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("Private-Token")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	host := upstream.Listener.Addr().String()
+	c := NewClient(5*time.Second, 5*1024*1024, false,
+		WithSSRFProtection(false),
+		WithCredentials(newCredentialStore(t, host, "PRIVATE-TOKEN", "tok123")),
+	)
+	if _, err := c.Fetch(t.Context(), upstream.URL+"/file.md", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if gotToken != "tok123" {
+		t.Errorf("Private-Token = %q, want tok123", gotToken)
+	}
+}
+
+func TestFetch_CredentialsNotSentToOtherHostOnRedirect(t *testing.T) {
+	var gotOnB string
+	srvB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOnB = r.Header.Get("Private-Token")
+		w.Write([]byte("ok"))
+	}))
+	defer srvB.Close()
+
+	var gotOnA string
+	srvA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOnA = r.Header.Get("Private-Token")
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/moved", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, srvB.URL+"/elsewhere", http.StatusFound)
+	}))
+	defer srvA.Close()
+
+	c := NewClient(5*time.Second, 5*1024*1024, false,
+		WithSSRFProtection(false),
+		WithCredentials(newCredentialStore(t, srvA.Listener.Addr().String(), "PRIVATE-TOKEN", "tok123")),
+	)
+	if _, err := c.Fetch(t.Context(), srvA.URL+"/start", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if gotOnA != "tok123" {
+		t.Errorf("same-host redirect Private-Token = %q, want tok123", gotOnA)
+	}
+	if gotOnB != "" {
+		t.Errorf("credential leaked to redirect target on another host: %q", gotOnB)
+	}
+}
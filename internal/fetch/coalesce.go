@@ -0,0 +1,68 @@
+package fetch
+
+import (
+	"fmt"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls that share a key: the first caller
+// runs the function and later callers wait for and share its result.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+	dups int // callers that joined after the first; read after done is closed
+}
+
+// do runs fn once per key among concurrent callers. It returns fn's result
+// and the number of callers collapsed into the call (0 when nobody joined).
+// Every caller sees the same count.
+func (g *flightGroup[T]) do(key string, fn func() (T, error)) (val T, dups int, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.val, c.dups, c.err
+	}
+	c := &flightCall[T]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Waiters must be released even if fn panics; they get an error and the
+	// panic continues in the leader.
+	completed := false
+	defer func() {
+		if !completed {
+			c.err = fmt.Errorf("coalesced call for %q panicked", key)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		dups = c.dups
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+	completed = true
+	return c.val, 0, c.err
+}
+
+// waiting returns the number of callers waiting on the in-flight call for
+// key, or -1 when no call is in flight.
+func (g *flightGroup[T]) waiting(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c.dups
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+package fetch
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup_Coalesces(t *testing.T) {
+	var g flightGroup[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	const n = 10
+	var wg sync.WaitGroup
+	vals := make([]string, n)
+	dups := make([]int, n)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		vals[0], dups[0], _ = g.do("k", func() (string, error) {
+			calls.Add(1)
+			close(started)
+			<-release
+			return "v", nil
+		})
+	}()
+	<-started
+
+	for i := 1; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vals[i], dups[i], _ = g.do("k", func() (string, error) {
+				calls.Add(1)
+				return "other", nil
+			})
+		}(i)
+	}
+
+	waitJoined(t, &g, "k", n-1)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("fn called %d times, want 1", got)
+	}
+	for i := range n {
+		if vals[i] != "v" {
+			t.Errorf("caller %d got %q, want shared value", i, vals[i])
+		}
+		if dups[i] != n-1 {
+			t.Errorf("caller %d collapsed = %d, want %d", i, dups[i], n-1)
+		}
+	}
+}
+
+func TestFlightGroup_SequentialCallsRunAgain(t *testing.T) {
+	var g flightGroup[int]
+	calls := 0
+	for range 3 {
+		v, dups, err := g.do("k", func() (int, error) {
+			calls++
+			return calls, nil
+		})
+		if err != nil || dups != 0 || v != calls {
+			t.Fatalf("do = (%d, %d, %v), want (%d, 0, nil)", v, dups, err, calls)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestFlightGroup_Error(t *testing.T) {
+	var g flightGroup[int]
+	want := errors.New("boom")
+	_, _, err := g.do("k", func() (int, error) { return 0, want })
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+}
+
+func TestFlightGroup_PanicReleasesWaiters(t *testing.T) {
+	var g flightGroup[int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leaderDone := make(chan any)
+	go func() {
+		defer func() { leaderDone <- recover() }()
+		g.do("k", func() (int, error) {
+			close(started)
+			<-release
+			panic("render exploded")
+		})
+	}()
+	<-started
+
+	followerErr := make(chan error)
+	go func() {
+		_, _, err := g.do("k", func() (int, error) { return 1, nil })
+		followerErr <- err
+	}()
+
+	waitJoined(t, &g, "k", 1)
+	close(release)
+
+	if r := <-leaderDone; r == nil {
+		t.Error("panic should propagate to the leader")
+	}
+	if err := <-followerErr; err == nil {
+		t.Error("follower should get an error when the leader panics")
+	}
+
+	// The key is usable again afterwards.
+	v, _, err := g.do("k", func() (int, error) { return 2, nil })
+	if err != nil || v != 2 {
+		t.Errorf("do after panic = (%d, %v), want (2, nil)", v, err)
+	}
+}
+
+// waitJoined blocks until n followers are waiting on the in-flight call for key.
+func waitJoined[T any](t *testing.T, g *flightGroup[T], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		joined := g.waiting(key)
+		if joined == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d followers joined %q, want %d", joined, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,65 @@
+package fetch
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// refresher runs background cache refreshes with bounded concurrency and at
+// most one refresh per key at a time.
+type refresher struct {
+	sem chan struct{}
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func newRefresher(workers int) *refresher {
+	if workers < 1 {
+		workers = 1
+	}
+	return &refresher{
+		sem:     make(chan struct{}, workers),
+		running: make(map[string]bool),
+	}
+}
+
+// schedule starts fn in the background unless a refresh for key is already
+// running or every worker is busy. Skipped refreshes are not queued: the next
+// request that is served the stale entry schedules it again.
+func (r *refresher) schedule(key string, fn func()) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running[key] {
+		return false
+	}
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		return false
+	}
+	r.running[key] = true
+	r.wg.Add(1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("background refresh panicked", "panic", p)
+			}
+			r.mu.Lock()
+			delete(r.running, key)
+			r.mu.Unlock()
+			<-r.sem
+			r.wg.Done()
+		}()
+		fn()
+	}()
+	return true
+}
+
+// wait blocks until all running refreshes have finished.
+func (r *refresher) wait() {
+	r.wg.Wait()
+}
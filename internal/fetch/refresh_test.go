@@ -0,0 +1,62 @@
+package fetch
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRefresher_DedupPerKey(t *testing.T) {
+	r := newRefresher(4)
+	release := make(chan struct{})
+	var runs atomic.Int32
+
+	if !r.schedule("a", func() { runs.Add(1); <-release }) {
+		t.Fatal("first refresh should start")
+	}
+	if r.schedule("a", func() { runs.Add(1) }) {
+		t.Error("second refresh for the same key should be skipped while the first runs")
+	}
+	if !r.schedule("b", func() { runs.Add(1) }) {
+		t.Error("refresh for another key should start")
+	}
+
+	close(release)
+	r.wait()
+	if got := runs.Load(); got != 2 {
+		t.Errorf("runs = %d, want 2", got)
+	}
+
+	// Once finished, the key can be refreshed again.
+	if !r.schedule("a", func() {}) {
+		t.Error("refresh should start after the previous one finished")
+	}
+	r.wait()
+}
+
+func TestRefresher_BoundedConcurrency(t *testing.T) {
+	r := newRefresher(2)
+	release := make(chan struct{})
+
+	for _, key := range []string{"a", "b"} {
+		if !r.schedule(key, func() { <-release }) {
+			t.Fatalf("refresh %s should start", key)
+		}
+	}
+	if r.schedule("c", func() {}) {
+		t.Error("refresh should be skipped when all workers are busy")
+	}
+
+	close(release)
+	r.wait()
+}
+
+func TestRefresher_RecoversPanic(t *testing.T) {
+	r := newRefresher(1)
+	r.schedule("a", func() { panic("render exploded") })
+	r.wait()
+
+	if !r.schedule("a", func() {}) {
+		t.Error("worker slot should be released after a panic")
+	}
+	r.wait()
+}
@@ -0,0 +1,226 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/listing"
+)
+
+// Directory is a forge directory page whose contents can be listed through
+// the forge's API instead of scraping its HTML.
+type Directory struct {
+	Profile string // "gitea" or "gitlab"
+	APIURL  string // listing endpoint to fetch
+
+	repoBase string // scheme://host/owner/repo
+	refKind  string // gitea only: branch, tag, or commit
+	ref      string
+	path     string // directory path within the repository, no slashes at the ends
+}
+
+const (
+	// gitlabPageSize is how many entries a GitLab tree API page holds.
+	gitlabPageSize = 100
+
+	// MaxPages caps how many API pages are fetched for one directory
+	// listing.
+	MaxPages = 10
+)
+
+var (
+	// /owner/repo/src/branch/main/docs/ (also /raw/…) — trailing slash required,
+	// since without it the path may name a file.
+	giteaDirRe = regexp.MustCompile(`^(/[^/]+/[^/]+)/(?:src|raw)/(branch|tag|commit)/([^/]+)((?:/[^/]+)*)/$`)
+
+	// /group/sub/repo/-/tree/main/docs — /-/tree/ is always a directory.
+	gitlabDirRe = regexp.MustCompile(`^(/.+?)/-/tree/([^/]+)((?:/[^/]+)*)/?$`)
+)
+
+// Directory recognises a Gitea/Forgejo or GitLab directory page URL and
+// returns how to list it via the forge API. Hosts bound to another profile,
+// and unbound hosts when autodetection is off, never match, so they fall
+// back to HTML autoindex parsing.
+func (r *Resolver) Directory(rawURL string) (*Directory, bool) {
+	if r == nil {
+		return nil, false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+
+	var profiles []string
+	for _, p := range r.autodetect {
+		profiles = append(profiles, p.Name)
+	}
+	if p, ok := r.hosts[strings.ToLower(u.Hostname())]; ok {
+		profiles = []string{p.Name}
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	for _, name := range profiles {
+		switch name {
+		case "gitea":
+			m := giteaDirRe.FindStringSubmatch(u.Path)
+			if m == nil {
+				continue
+			}
+			d := &Directory{
+				Profile:  "gitea",
+				repoBase: origin + m[1],
+				refKind:  m[2],
+				ref:      m[3],
+				path:     strings.Trim(m[4], "/"),
+			}
+			owner, repo, _ := strings.Cut(strings.TrimPrefix(m[1], "/"), "/")
+			api := origin + "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/contents"
+			if d.path != "" {
+				api += "/" + EscapePath(d.path)
+			}
+			d.APIURL = api + "?ref=" + url.QueryEscape(d.ref)
+			return d, true
+
+		case "gitlab":
+			m := gitlabDirRe.FindStringSubmatch(u.Path)
+			if m == nil {
+				continue
+			}
+			d := &Directory{
+				Profile:  "gitlab",
+				repoBase: origin + m[1],
+				ref:      m[2],
+				path:     strings.Trim(m[3], "/"),
+			}
+			q := url.Values{}
+			q.Set("ref", d.ref)
+			q.Set("per_page", strconv.Itoa(gitlabPageSize))
+			if d.path != "" {
+				q.Set("path", d.path)
+			}
+			project := url.PathEscape(strings.TrimPrefix(m[1], "/"))
+			d.APIURL = origin + "/api/v4/projects/" + project + "/repository/tree?" + q.Encode()
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// IsRoot reports whether the directory is the repository root at its ref.
+func (d *Directory) IsRoot() bool {
+	return d.path == ""
+}
+
+// NextPage returns the API URL of the listing page after page, counted from
+// 1, given that page's response body. It reports false when there is no
+// further page: the Gitea contents API returns a directory whole, and the
+// GitLab tree API is done once a page comes back short.
+func (d *Directory) NextPage(page int, body []byte) (string, bool) {
+	if d.Profile != "gitlab" {
+		return "", false
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil || len(items) < gitlabPageSize {
+		return "", false
+	}
+	u, err := url.Parse(d.APIURL)
+	if err != nil {
+		return "", false
+	}
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page+1))
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+// giteaContent is one element of the Gitea/Forgejo contents API response.
+type giteaContent struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // file, dir, symlink, submodule
+	Size int64  `json:"size"`
+}
+
+// gitlabTreeItem is one element of the GitLab repository tree API response,
+// which carries no file sizes.
+type gitlabTreeItem struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // blob, tree, commit
+}
+
+// Entries parses the API response body into listing entries. Page links use
+// the forge's human URLs (which cooked translates when followed) and content
+// URLs point at the raw file endpoints on the same host.
+func (d *Directory) Entries(body []byte) ([]listing.Entry, error) {
+	var entries []listing.Entry
+
+	switch d.Profile {
+	case "gitea":
+		var items []giteaContent
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("parse gitea contents: %w", err)
+		}
+		refPath := d.refKind + "/" + url.PathEscape(d.ref)
+		for _, it := range items {
+			p := EscapePath(it.Path)
+			switch it.Type {
+			case "dir":
+				entries = append(entries, listing.Entry{
+					Name: it.Name, IsDir: true, Size: -1,
+					URL: d.repoBase + "/src/" + refPath + "/" + p + "/",
+				})
+			case "file", "symlink":
+				entries = append(entries, listing.Entry{
+					Name: it.Name, Size: it.Size,
+					URL:    d.repoBase + "/src/" + refPath + "/" + p,
+					RawURL: d.repoBase + "/raw/" + refPath + "/" + p,
+				})
+			}
+		}
+
+	case "gitlab":
+		var items []gitlabTreeItem
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("parse gitlab tree: %w", err)
+		}
+		ref := url.PathEscape(d.ref)
+		for _, it := range items {
+			p := EscapePath(it.Path)
+			switch it.Type {
+			case "tree":
+				entries = append(entries, listing.Entry{
+					Name: it.Name, IsDir: true, Size: -1,
+					URL: d.repoBase + "/-/tree/" + ref + "/" + p + "/",
+				})
+			case "blob":
+				// Sizes would take a request per file, so they are left
+				// unknown.
+				entries = append(entries, listing.Entry{
+					Name: it.Name, Size: -1,
+					URL:    d.repoBase + "/-/blob/" + ref + "/" + p,
+					RawURL: d.repoBase + "/-/raw/" + ref + "/" + p,
+				})
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("no directory API for profile %q", d.Profile)
+	}
+
+	return entries, nil
+}
+
+// EscapePath path-escapes each segment of a slash-separated path, keeping the
+// slashes, for building forge page and API URLs from repository paths.
+func EscapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+	return strings.Join(parts, "/")
+}
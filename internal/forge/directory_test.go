@@ -0,0 +1,150 @@
+package forge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectory_Gitea(t *testing.T) {
+	r, err := New("", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, ok := r.Directory("https://gitea.internal/ops/runbooks/src/branch/main/docs/")
+	if !ok {
+		t.Fatal("gitea directory not recognised")
+	}
+	if d.Profile != "gitea" {
+		t.Errorf("Profile = %q, want gitea", d.Profile)
+	}
+	if want := "https://gitea.internal/api/v1/repos/ops/runbooks/contents/docs?ref=main"; d.APIURL != want {
+		t.Errorf("APIURL = %q, want %q", d.APIURL, want)
+	}
+	if d.IsRoot() {
+		t.Error("docs/ should not be the repository root")
+	}
+
+	body := `[
+		{"name":"guides","path":"docs/guides","type":"dir","size":0},
+		{"name":"README.md","path":"docs/README.md","type":"file","size":120}
+	]`
+	entries, err := d.Entries([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if got, want := entries[0].URL, "https://gitea.internal/ops/runbooks/src/branch/main/docs/guides/"; got != want {
+		t.Errorf("dir URL = %q, want %q", got, want)
+	}
+	if got, want := entries[1].ContentURL(), "https://gitea.internal/ops/runbooks/raw/branch/main/docs/README.md"; got != want {
+		t.Errorf("file content URL = %q, want %q", got, want)
+	}
+	if entries[1].Size != 120 {
+		t.Errorf("file size = %d, want 120", entries[1].Size)
+	}
+}
+
+func TestDirectory_GitLab(t *testing.T) {
+	r, err := New("", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, ok := r.Directory("https://gitlab.internal/platform/infra/-/tree/main")
+	if !ok {
+		t.Fatal("gitlab directory not recognised")
+	}
+	if want := "https://gitlab.internal/api/v4/projects/platform%2Finfra/repository/tree?per_page=100&ref=main"; d.APIURL != want {
+		t.Errorf("APIURL = %q, want %q", d.APIURL, want)
+	}
+	if !d.IsRoot() {
+		t.Error("tree at ref should be the repository root")
+	}
+
+	body := `[
+		{"name":"docs","path":"docs","type":"tree"},
+		{"name":"README.md","path":"README.md","type":"blob"},
+		{"name":"vendor","path":"vendor","type":"commit"}
+	]`
+	entries, err := d.Entries([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (submodules skipped)", len(entries))
+	}
+	if got, want := entries[1].URL, "https://gitlab.internal/platform/infra/-/blob/main/README.md"; got != want {
+		t.Errorf("file URL = %q, want %q", got, want)
+	}
+	if got, want := entries[1].ContentURL(), "https://gitlab.internal/platform/infra/-/raw/main/README.md"; got != want {
+		t.Errorf("file content URL = %q, want %q", got, want)
+	}
+
+	if next, ok := d.NextPage(1, []byte(body)); ok {
+		t.Errorf("short page: NextPage = %q, want none", next)
+	}
+	full := "[" + strings.Repeat(`{"name":"a.md","path":"a.md","type":"blob"},`, 99) + `{"name":"b.md","path":"b.md","type":"blob"}]`
+	next, ok := d.NextPage(2, []byte(full))
+	if want := "https://gitlab.internal/api/v4/projects/platform%2Finfra/repository/tree?page=3&per_page=100&ref=main"; !ok || next != want {
+		t.Errorf("full page: NextPage = %q, %v; want %q", next, ok, want)
+	}
+}
+
+func TestDirectory_NoMatch(t *testing.T) {
+	r, err := New("files.internal=cgit", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{
+		"https://gitea.internal/ops/runbooks/src/branch/main/README.md", // file, no trailing slash
+		"https://files.internal/ops/runbooks/src/branch/main/docs/",     // bound to another profile
+		"https://files.internal/pub/docs/",
+	} {
+		if d, ok := r.Directory(input); ok {
+			t.Errorf("Directory(%q) matched %+v, want no match", input, d)
+		}
+	}
+
+	r, err = New("gitea.internal=gitea", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Directory("https://gitea.internal/ops/runbooks/src/branch/main/docs/"); !ok {
+		t.Error("autodetection off: bound host did not match")
+	}
+	if d, ok := r.Directory("https://gitlab.internal/platform/infra/-/tree/main/docs"); ok {
+		t.Errorf("autodetection off: unbound host matched %+v", d)
+	}
+
+	var nilResolver *Resolver
+	if _, ok := nilResolver.Directory("https://gitea.internal/o/r/src/branch/main/"); ok {
+		t.Error("nil resolver should not match")
+	}
+}
+
+func TestDirectory_BadBody(t *testing.T) {
+	r, _ := New("", nil, true)
+	d, _ := r.Directory("https://gitea.internal/ops/runbooks/src/branch/main/")
+	if _, err := d.Entries([]byte("not json")); err == nil {
+		t.Error("expected error for invalid API response")
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := map[string]string{
+		"docs/guide.md":   "docs/guide.md",
+		"release/1.0":     "release/1.0",
+		"my docs/a#b?.md": "my%20docs/a%23b%3F.md",
+		"100%/ünïcode.md": "100%25/%C3%BCn%C3%AFcode.md",
+		"":                "",
+	}
+	for in, want := range tests {
+		if got := EscapePath(in); got != want {
+			t.Errorf("EscapePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
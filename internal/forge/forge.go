@@ -0,0 +1,248 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Profile rewrites a forge's human-facing "blob" page URL into the URL of the
+// raw file. Patterns are matched against the escaped URL path; the query
+// string is carried over unchanged (cgit ?h= and Bitbucket ?at= refs).
+type Profile struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Replace string // regexp.Expand template, e.g. "$1/raw/$2"
+
+	// Autodetect allows the profile to be tried for hosts that have no
+	// explicit profile binding. Only layouts unlikely to collide with plain
+	// file servers enable this.
+	Autodetect bool
+}
+
+// builtins are the forge layouts cooked understands out of the box, in the
+// order they are tried during autodetection.
+var builtins = []*Profile{
+	{
+		// /group/sub/repo/-/blob/main/README.md → /group/sub/repo/-/raw/main/README.md
+		Name:       "gitlab",
+		Pattern:    regexp.MustCompile(`^(/.+?)/-/blob/(.+)$`),
+		Replace:    "$1/-/raw/$2",
+		Autodetect: true,
+	},
+	{
+		// /projects/P/repos/r/browse/docs/x.md?at=refs/heads/main → .../raw/docs/x.md?at=...
+		Name:       "bitbucket",
+		Pattern:    regexp.MustCompile(`^(/projects/[^/]+/repos/[^/]+)/browse/(.+)$`),
+		Replace:    "$1/raw/$2",
+		Autodetect: true,
+	},
+	{
+		// /owner/repo/src/branch/main/README.md → /owner/repo/raw/branch/main/README.md
+		Name:       "gitea",
+		Pattern:    regexp.MustCompile(`^(/[^/]+/[^/]+)/src/(branch|tag|commit)/(.+)$`),
+		Replace:    "$1/raw/$2/$3",
+		Autodetect: true,
+	},
+	{
+		// /owner/repo/blob/main/README.md → /owner/repo/raw/main/README.md
+		// Any two directories above a "blob" one match, so the host must be
+		// bound.
+		Name:    "github",
+		Pattern: regexp.MustCompile(`^(/[^/]+/[^/]+)/blob/(.+)$`),
+		Replace: "$1/raw/$2",
+	},
+	{
+		// /owner/repo/src/main/README.md → /owner/repo/raw/main/README.md
+		Name:    "gogs",
+		Pattern: regexp.MustCompile(`^(/[^/]+/[^/]+)/src/(.+)$`),
+		Replace: "$1/raw/$2",
+	},
+	{
+		// /repo.git/tree/README.md?h=main → /repo.git/plain/README.md?h=main
+		Name:    "cgit",
+		Pattern: regexp.MustCompile(`^(/.+?)/tree/(.+)$`),
+		Replace: "$1/plain/$2",
+	},
+}
+
+// aliases maps alternative profile names onto built-in profiles.
+var aliases = map[string]string{
+	"forgejo":           "gitea",
+	"github-enterprise": "github",
+	"ghe":               "github",
+	"bitbucket-server":  "bitbucket",
+}
+
+// Resolver translates forge page URLs into raw-file URLs.
+// A nil Resolver translates nothing.
+type Resolver struct {
+	hosts      map[string]*Profile // lowercased hostname → bound profile
+	custom     []*Profile          // operator-defined, tried for unbound hosts
+	autodetect []*Profile
+}
+
+// profileFile is the JSON layout of the operator profiles file.
+//
+//	{
+//	  "profiles": [
+//	    {"name": "wiki", "pattern": "^(/.+)/view/(.+)$", "replace": "$1/raw/$2"}
+//	  ]
+//	}
+type profileFile struct {
+	Profiles []struct {
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+		Replace string `json:"replace"`
+	} `json:"profiles"`
+}
+
+// Load builds a Resolver from a comma-separated host=profile binding list
+// (e.g. "cgit.internal=cgit,git.corp=gitea") and an optional JSON file of
+// operator-defined regex profiles. Unless autodetect is set, built-in
+// profiles only apply to the hosts bound to them.
+func Load(hostBindings, profilesFile string, autodetect bool) (*Resolver, error) {
+	var custom []*Profile
+	if profilesFile != "" {
+		data, err := os.ReadFile(profilesFile)
+		if err != nil {
+			return nil, fmt.Errorf("read forge profiles file: %w", err)
+		}
+		custom, err = ParseProfiles(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return New(hostBindings, custom, autodetect)
+}
+
+// ParseProfiles parses operator-defined regex profiles from JSON.
+func ParseProfiles(data []byte) ([]*Profile, error) {
+	var f profileFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse forge profiles file: %w", err)
+	}
+
+	var out []*Profile
+	for i, p := range f.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("forge profile %d: empty name", i)
+		}
+		if builtin(p.Name) != nil {
+			return nil, fmt.Errorf("forge profile %q: name collides with built-in profile", p.Name)
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("forge profile %q: invalid pattern: %w", p.Name, err)
+		}
+		if p.Replace == "" {
+			return nil, fmt.Errorf("forge profile %q: empty replace", p.Name)
+		}
+		out = append(out, &Profile{Name: p.Name, Pattern: re, Replace: p.Replace})
+	}
+	return out, nil
+}
+
+// New builds a Resolver from host bindings and custom profiles, trying the
+// autodetectable built-ins on unbound hosts if autodetect is set.
+func New(hostBindings string, custom []*Profile, autodetect bool) (*Resolver, error) {
+	r := &Resolver{
+		hosts:  make(map[string]*Profile),
+		custom: custom,
+	}
+	for _, p := range builtins {
+		if autodetect && p.Autodetect {
+			r.autodetect = append(r.autodetect, p)
+		}
+	}
+
+	byName := make(map[string]*Profile, len(custom))
+	for _, p := range custom {
+		byName[strings.ToLower(p.Name)] = p
+	}
+
+	for _, entry := range strings.Split(hostBindings, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, name, ok := strings.Cut(entry, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || host == "" || name == "" {
+			return nil, fmt.Errorf("invalid forge binding %q: want host=profile", entry)
+		}
+		p := byName[name]
+		if p == nil {
+			p = builtin(name)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("invalid forge binding %q: unknown profile %q", entry, name)
+		}
+		r.hosts[host] = p
+	}
+
+	return r, nil
+}
+
+func builtin(name string) *Profile {
+	name = strings.ToLower(name)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	for _, p := range builtins {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Resolve rewrites a forge page URL to its raw-file URL. It returns the
+// rewritten URL, the name of the profile that matched, and whether a rewrite
+// happened. Hosts bound to a profile only use that profile; other hosts try
+// custom profiles, then the autodetectable built-ins.
+func (r *Resolver) Resolve(rawURL string) (string, string, bool) {
+	if r == nil {
+		return rawURL, "", false
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL, "", false
+	}
+
+	// Directory listings have no raw-file equivalent.
+	if strings.HasSuffix(u.Path, "/") {
+		return rawURL, "", false
+	}
+
+	candidates := r.autodetect
+	if p, ok := r.hosts[strings.ToLower(u.Hostname())]; ok {
+		candidates = []*Profile{p}
+	} else if len(r.custom) > 0 {
+		candidates = append(append([]*Profile{}, r.custom...), r.autodetect...)
+	}
+
+	escaped := u.EscapedPath()
+	for _, p := range candidates {
+		m := p.Pattern.FindStringSubmatchIndex(escaped)
+		if m == nil {
+			continue
+		}
+		newPath := string(p.Pattern.ExpandString(nil, p.Replace, escaped, m))
+		decoded, err := url.PathUnescape(newPath)
+		if err != nil {
+			continue
+		}
+		out := *u
+		out.Path = decoded
+		out.RawPath = newPath
+		return out.String(), p.Name, true
+	}
+
+	return rawURL, "", false
+}
@@ -0,0 +1,190 @@
+package forge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve_Autodetect(t *testing.T) {
+	r, err := New("", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		wantProfile string
+	}{
+		{"gitea branch", "https://gitea.internal/ops/runbooks/src/branch/main/README.md",
+			"https://gitea.internal/ops/runbooks/raw/branch/main/README.md", "gitea"},
+		{"gitea tag", "https://gitea.internal/ops/runbooks/src/tag/v1.0/docs/install.md",
+			"https://gitea.internal/ops/runbooks/raw/tag/v1.0/docs/install.md", "gitea"},
+		{"gitea commit", "https://gitea.internal/ops/runbooks/src/commit/abc123/README.md",
+			"https://gitea.internal/ops/runbooks/raw/commit/abc123/README.md", "gitea"},
+		{"gitlab nested group", "https://gitlab.internal/platform/infra/tools/-/blob/main/docs/README.md",
+			"https://gitlab.internal/platform/infra/tools/-/raw/main/docs/README.md", "gitlab"},
+		{"bitbucket server with ref", "https://bitbucket.internal/projects/OPS/repos/docs/browse/guide/setup.md?at=refs%2Fheads%2Fmain",
+			"https://bitbucket.internal/projects/OPS/repos/docs/raw/guide/setup.md?at=refs%2Fheads%2Fmain", "bitbucket"},
+		{"escaped path preserved", "https://gitea.internal/ops/runbooks/src/branch/main/my%20doc.md",
+			"https://gitea.internal/ops/runbooks/raw/branch/main/my%20doc.md", "gitea"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, profile, ok := r.Resolve(tc.input)
+			if !ok {
+				t.Fatalf("Resolve(%q) did not match", tc.input)
+			}
+			if got != tc.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+			if profile != tc.wantProfile {
+				t.Errorf("profile = %q, want %q", profile, tc.wantProfile)
+			}
+		})
+	}
+}
+
+func TestResolve_NoMatch(t *testing.T) {
+	r, err := New("", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{
+		"https://gitea.internal/ops/runbooks/raw/branch/main/README.md",
+		"https://files.internal/project/repo/src/main.go",      // gogs layout needs a binding
+		"https://cgit.internal/repo.git/tree/README.md?h=main", // cgit layout needs a binding
+		"https://files.internal/assets/img/blob/logo.png",      // github layout needs a binding
+		"https://gitea.internal/ops/runbooks/src/branch/main/docs/",
+		"https://example.com/README.md",
+		"not a url",
+	} {
+		if got, _, ok := r.Resolve(input); ok {
+			t.Errorf("Resolve(%q) = %q, want no match", input, got)
+		}
+	}
+
+	// With autodetection off, unbound hosts are left alone.
+	r, err = New("", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, ok := r.Resolve("https://gitea.internal/ops/runbooks/src/branch/main/README.md"); ok {
+		t.Errorf("autodetection off: Resolve = %q, want no match", got)
+	}
+
+	var nilResolver *Resolver
+	if _, _, ok := nilResolver.Resolve("https://gitea.internal/o/r/src/branch/main/README.md"); ok {
+		t.Error("nil resolver should not match")
+	}
+}
+
+func TestResolve_HostBindings(t *testing.T) {
+	r, err := New("cgit.internal=cgit, git.corp=Gogs, code.internal=forgejo, ghe.internal=ghe", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://cgit.internal/infra.git/tree/docs/README.md?h=main",
+			"https://cgit.internal/infra.git/plain/docs/README.md?h=main"},
+		{"https://git.corp/team/repo/src/master/README.md",
+			"https://git.corp/team/repo/raw/master/README.md"},
+		{"https://code.internal/team/repo/src/branch/main/README.md",
+			"https://code.internal/team/repo/raw/branch/main/README.md"},
+		{"https://ghe.internal/org/repo/blob/main/README.md",
+			"https://ghe.internal/org/repo/raw/main/README.md"},
+	}
+	for _, tc := range tests {
+		got, _, ok := r.Resolve(tc.input)
+		if !ok || got != tc.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", tc.input, got, ok, tc.want)
+		}
+	}
+
+	// A bound host only uses its own profile.
+	if got, _, ok := r.Resolve("https://cgit.internal/o/r/-/blob/main/README.md"); ok {
+		t.Errorf("bound host matched another profile: %q", got)
+	}
+}
+
+func TestResolve_CustomProfile(t *testing.T) {
+	custom, err := ParseProfiles([]byte(`{"profiles": [
+		{"name": "Wiki", "pattern": "^(/.+)/view/(.+)$", "replace": "$1/raw/$2"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New("wiki.internal=wiki", custom, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, profile, ok := r.Resolve("https://wiki.internal/space/view/page.md")
+	if !ok || got != "https://wiki.internal/space/raw/page.md" || profile != "Wiki" {
+		t.Errorf("Resolve = %q, %q, %v", got, profile, ok)
+	}
+
+	// Unbound hosts try custom profiles too.
+	got, _, ok = r.Resolve("https://other.internal/space/view/page.md")
+	if !ok || got != "https://other.internal/space/raw/page.md" {
+		t.Errorf("Resolve on unbound host = %q, %v", got, ok)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forges.json")
+	if err := os.WriteFile(path, []byte(`{"profiles": [{"name": "docs", "pattern": "^/view(/.+)$", "replace": "/raw$1"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load("docs.internal=docs", path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, ok := r.Resolve("https://docs.internal/view/a/b.md"); !ok || got != "https://docs.internal/raw/a/b.md" {
+		t.Errorf("Resolve = %q, %v", got, ok)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings string
+		profiles string
+	}{
+		{"binding without profile", "cgit.internal", ""},
+		{"binding with empty host", "=cgit", ""},
+		{"unknown profile", "cgit.internal=sourcehut", ""},
+		{"invalid json", "", `{`},
+		{"invalid regex", "", `{"profiles": [{"name": "x", "pattern": "(", "replace": "$1"}]}`},
+		{"empty name", "", `{"profiles": [{"name": "", "pattern": "^/x$", "replace": "/y"}]}`},
+		{"empty replace", "", `{"profiles": [{"name": "x", "pattern": "^/x$", "replace": ""}]}`},
+		{"builtin collision", "", `{"profiles": [{"name": "gitea", "pattern": "^/x$", "replace": "/y"}]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := ""
+			if tc.profiles != "" {
+				path = filepath.Join(t.TempDir(), "forges.json")
+				if err := os.WriteFile(path, []byte(tc.profiles), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := Load(tc.bindings, path, true); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+
+	if _, err := Load("", "/nonexistent/forges.json", true); err == nil {
+		t.Error("expected error for missing profiles file")
+	}
+}
@@ -0,0 +1,251 @@
+// Package gitrepo reads files from local git repositories at a branch, tag or
+// commit, without a git binary or a working tree.
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// minAbbrev is the shortest abbreviated commit SHA that is resolved, as in
+// git's default core.abbrev.
+const minAbbrev = 7
+
+// Repo is a git repository opened for reading. It is safe for concurrent use.
+type Repo struct {
+	// go-git's object storage is not safe for concurrent use.
+	mu   sync.Mutex
+	repo *git.Repository
+}
+
+// Open opens the repository at dir, which may be bare or have a working tree.
+func Open(dir string) (*Repo, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{repo: repo}, nil
+}
+
+// Commit is a ref resolved to a commit.
+type Commit struct {
+	Hash string
+	// Immutable reports that the ref was a full commit SHA, so the commit's
+	// content can never change under it. An abbreviated SHA is not: it may
+	// become ambiguous, and fail to resolve, as the repository grows.
+	Immutable bool
+
+	repo *Repo
+	tree *object.Tree
+}
+
+// Entry describes a file or directory in a commit.
+type Entry struct {
+	Name  string
+	IsDir bool
+	// Regular is false for symlinks and submodules.
+	Regular bool
+	Size    int64  // -1 for directories and submodules
+	Hash    string // blob or tree SHA
+}
+
+// Resolve resolves ref to a commit. Branches win over tags, and both win over
+// abbreviated commit SHAs of at least seven digits, as with git rev-parse; a
+// full 40-digit SHA is always a commit. Annotated tags are peeled. Unknown
+// refs fail with an error matching fs.ErrNotExist.
+func (r *Repo) Resolve(ref string) (*Commit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notFound := &fs.PathError{Op: "resolve", Path: ref, Err: fs.ErrNotExist}
+	if ref == "" {
+		return nil, notFound
+	}
+
+	if isHex(ref) && len(ref) == 40 {
+		return r.commit(plumbing.NewHash(ref), true)
+	}
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+	} {
+		reference, err := r.repo.Reference(name, true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r.commit(reference.Hash(), false)
+	}
+	if isHex(ref) && len(ref) >= minAbbrev {
+		hash, err := r.repo.ResolveRevision(plumbing.Revision(ref))
+		if err == nil && strings.HasPrefix(hash.String(), strings.ToLower(ref)) {
+			return r.commit(*hash, false)
+		}
+	}
+	return nil, notFound
+}
+
+// ResolvePath splits refPath, a ref followed by a path, at the shortest
+// prefix that resolves, so that refs containing slashes such as
+// release/1.0 are found. It returns the commit, the ref and the remaining
+// path.
+func (r *Repo) ResolvePath(refPath string) (*Commit, string, string, error) {
+	segments := strings.Split(refPath, "/")
+	for i := 1; i <= len(segments); i++ {
+		ref := strings.Join(segments[:i], "/")
+		c, err := r.Resolve(ref)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", "", err
+		}
+		return c, ref, strings.Join(segments[i:], "/"), nil
+	}
+	return nil, "", "", &fs.PathError{Op: "resolve", Path: segments[0], Err: fs.ErrNotExist}
+}
+
+// commit looks up the commit at hash, peeling annotated tags. The caller
+// holds r.mu.
+func (r *Repo) commit(hash plumbing.Hash, immutable bool) (*Commit, error) {
+	c, err := r.lookupCommit(hash)
+	if err != nil {
+		// The pack index is loaded once; packs written by a fetch or gc
+		// since then are only seen after reindexing.
+		if s, ok := r.repo.Storer.(interface{ Reindex() }); ok {
+			s.Reindex()
+			c, err = r.lookupCommit(hash)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &Commit{Hash: c.Hash.String(), Immutable: immutable, repo: r, tree: tree}, nil
+}
+
+func (r *Repo) lookupCommit(hash plumbing.Hash) (*object.Commit, error) {
+	c, err := r.repo.CommitObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		tag, tagErr := r.repo.TagObject(hash)
+		if tagErr != nil {
+			return nil, &fs.PathError{Op: "resolve", Path: hash.String(), Err: fs.ErrNotExist}
+		}
+		c, err = tag.Commit()
+	}
+	return c, err
+}
+
+// Stat describes the file or directory at p, a slash-separated path that is
+// valid per fs.ValidPath; "." is the commit's root tree.
+func (c *Commit) Stat(p string) (Entry, error) {
+	c.repo.mu.Lock()
+	defer c.repo.mu.Unlock()
+
+	if p == "." {
+		return Entry{Name: ".", IsDir: true, Size: -1, Hash: c.tree.Hash.String()}, nil
+	}
+	te, err := c.tree.FindEntry(p)
+	if err != nil {
+		return Entry{}, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return c.entry(*te)
+}
+
+// ReadFile returns the contents of the file at p. Files larger than maxSize
+// fail without being read.
+func (c *Commit) ReadFile(p string, maxSize int64) ([]byte, error) {
+	c.repo.mu.Lock()
+	defer c.repo.mu.Unlock()
+
+	f, err := c.tree.File(p)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.Mode == filemode.Dir || f.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: errors.New("is a directory")}
+	}
+	if f.Size > maxSize {
+		return nil, fmt.Errorf("file too large: %d bytes exceeds %d", f.Size, maxSize)
+	}
+	rd, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return io.ReadAll(rd)
+}
+
+// ReadDir lists the directory at p in tree order.
+func (c *Commit) ReadDir(p string) ([]Entry, error) {
+	c.repo.mu.Lock()
+	defer c.repo.mu.Unlock()
+
+	tree := c.tree
+	if p != "." {
+		var err error
+		tree, err = c.tree.Tree(p)
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrNotExist}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries := make([]Entry, 0, len(tree.Entries))
+	for _, te := range tree.Entries {
+		e, err := c.entry(te)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Join(p, te.Name), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// entry describes a tree entry, looking up the size of blobs. The caller
+// holds c.repo.mu.
+func (c *Commit) entry(te object.TreeEntry) (Entry, error) {
+	e := Entry{Name: te.Name, Size: -1, Hash: te.Hash.String()}
+	switch te.Mode {
+	case filemode.Dir:
+		e.IsDir = true
+	case filemode.Submodule:
+		// The commit lives in another repository.
+	default:
+		blob, err := c.repo.repo.BlobObject(te.Hash)
+		if err != nil {
+			return Entry{}, err
+		}
+		e.Size = blob.Size
+		e.Regular = te.Mode != filemode.Symlink
+	}
+	return e, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,196 @@
+package gitrepo
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// commitFiles writes files to the worktree and commits them, returning the
+// commit SHA.
+func commitFiles(t *testing.T, repo *git.Repository, files map[string]string) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		f, err := wt.Filesystem.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// newBareRepo creates a bare repository on disk, committing through an
+// in-memory worktree. It returns the directory and the repository.
+func newBareRepo(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+	dir := t.TempDir()
+	storage := filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault())
+	repo, err := git.Init(storage, memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, repo
+}
+
+func TestResolve(t *testing.T) {
+	dir, repo := newBareRepo(t)
+	first := commitFiles(t, repo, map[string]string{"README.md": "# v1\n"})
+	if _, err := repo.CreateTag("v1.0", first, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)},
+		Message: "v1.0",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("light", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/release/1.0", first)); err != nil {
+		t.Fatal(err)
+	}
+	second := commitFiles(t, repo, map[string]string{"README.md": "# v2\n"})
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref       string
+		want      plumbing.Hash
+		immutable bool
+	}{
+		{"master", second, false},
+		{"v1.0", first, false},
+		{"light", first, false},
+		{"release/1.0", first, false},
+		{first.String(), first, true},
+		{first.String()[:7], first, false},
+		{strings.ToUpper(second.String()[:10]), second, false},
+	}
+	for _, tt := range tests {
+		c, err := r.Resolve(tt.ref)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.ref, err)
+			continue
+		}
+		if c.Hash != tt.want.String() || c.Immutable != tt.immutable {
+			t.Errorf("Resolve(%q) = %s immutable=%v, want %s immutable=%v", tt.ref, c.Hash, c.Immutable, tt.want, tt.immutable)
+		}
+	}
+
+	for _, ref := range []string{"", "missing", first.String()[:6], strings.Repeat("0", 40), "master~1"} {
+		if _, err := r.Resolve(ref); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Resolve(%q) error = %v, want fs.ErrNotExist", ref, err)
+		}
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	dir, repo := newBareRepo(t)
+	hash := commitFiles(t, repo, map[string]string{"docs/guide.md": "# Guide\n"})
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/release/1.0", hash)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct{ in, ref, path string }{
+		{"master/docs/guide.md", "master", "docs/guide.md"},
+		{"release/1.0/docs/guide.md", "release/1.0", "docs/guide.md"},
+		{"master", "master", ""},
+		{"master/", "master", ""},
+	} {
+		c, ref, p, err := r.ResolvePath(tt.in)
+		if err != nil || ref != tt.ref || p != tt.path || c.Hash != hash.String() {
+			t.Errorf("ResolvePath(%q) = %q, %q, %v; want %q, %q", tt.in, ref, p, err, tt.ref, tt.path)
+		}
+	}
+	if _, _, _, err := r.ResolvePath("nope/docs/guide.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("unknown ref: error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestCommitFiles(t *testing.T) {
+	dir, repo := newBareRepo(t)
+	commitFiles(t, repo, map[string]string{
+		"README.md":     "# Hello\n",
+		"docs/guide.md": "# Guide\n",
+		"big.txt":       strings.Repeat("x", 100),
+	})
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Resolve("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := c.Stat(".")
+	if err != nil || !root.IsDir || root.Hash == "" {
+		t.Errorf("Stat(.) = %+v, %v", root, err)
+	}
+	readme, err := c.Stat("README.md")
+	if err != nil || readme.IsDir || !readme.Regular || readme.Size != 8 || len(readme.Hash) != 40 {
+		t.Errorf("Stat(README.md) = %+v, %v", readme, err)
+	}
+	if e, err := c.Stat("docs"); err != nil || !e.IsDir {
+		t.Errorf("Stat(docs) = %+v, %v", e, err)
+	}
+	if _, err := c.Stat("missing.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(missing.md) error = %v, want fs.ErrNotExist", err)
+	}
+
+	body, err := c.ReadFile("docs/guide.md", 1024)
+	if err != nil || string(body) != "# Guide\n" {
+		t.Errorf("ReadFile(docs/guide.md) = %q, %v", body, err)
+	}
+	if _, err := c.ReadFile("big.txt", 10); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("ReadFile over the limit: error = %v", err)
+	}
+	if _, err := c.ReadFile("missing.md", 1024); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile(missing.md) error = %v, want fs.ErrNotExist", err)
+	}
+
+	entries, err := c.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") != "README.md,big.txt,docs" {
+		t.Errorf("ReadDir(.) = %v", names)
+	}
+	if _, err := c.ReadDir("nope"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir(nope) error = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,245 @@
+package listing
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/render"
+)
+
+// Entry is a single file or directory in a directory listing.
+type Entry struct {
+	Name   string
+	IsDir  bool
+	Size   int64  // -1 when unknown
+	URL    string // upstream page URL used for links; directories end in "/"
+	RawURL string // upstream URL of the file contents; empty means URL
+}
+
+// ContentURL returns the URL to fetch the entry's raw contents from.
+func (e Entry) ContentURL() string {
+	if e.RawURL != "" {
+		return e.RawURL
+	}
+	return e.URL
+}
+
+// readmeNames lists README filenames in order of preference.
+var readmeNames = []string{
+	"readme.md",
+	"readme.markdown",
+	"readme.mdx",
+	"readme.adoc",
+	"readme.asciidoc",
+	"readme.org",
+	"readme.rst",
+	"readme.txt",
+	"readme",
+}
+
+// Sort orders entries directories first, then case-insensitively by name.
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+}
+
+// SelectReadme returns the preferred README file in the listing, or nil.
+func SelectReadme(entries []Entry) *Entry {
+	for _, want := range readmeNames {
+		for i := range entries {
+			if !entries[i].IsDir && strings.ToLower(entries[i].Name) == want {
+				return &entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// anchorRe matches <a href="...">text</a> in autoindex HTML.
+var anchorRe = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+
+// tagRe strips HTML tags from the text following an anchor.
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// sizeRe matches autoindex size columns: "1234", "1.2K", "3M".
+var sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)([KMGT]?)$`)
+
+// ParseAutoindex extracts directory entries from an HTML autoindex page
+// (nginx, Apache, cgit tree, and similar). Only links to direct children of
+// dirURL on the same host are kept; sort links, parent links and navigation
+// are ignored. Sizes are read from the text following each link when present.
+func ParseAutoindex(body []byte, dirURL string) ([]Entry, error) {
+	base, err := url.Parse(dirURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse directory url: %w", err)
+	}
+	dirPath := base.Path
+	if !strings.HasSuffix(dirPath, "/") {
+		dirPath += "/"
+	}
+
+	var entries []Entry
+	seen := make(map[string]bool)
+	matches := anchorRe.FindAllSubmatchIndex(body, -1)
+	for i, m := range matches {
+		href := html.UnescapeString(string(body[m[2]:m[3]]))
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		target := base.ResolveReference(ref)
+		if target.Scheme != base.Scheme || target.Host != base.Host {
+			continue
+		}
+
+		rest, ok := strings.CutPrefix(target.Path, dirPath)
+		if !ok {
+			continue
+		}
+		isDir := strings.HasSuffix(rest, "/")
+		name := strings.TrimSuffix(rest, "/")
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+
+		target.Fragment = ""
+		key := target.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		// Text between this anchor and the next one holds the size column.
+		end := len(body)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		tail := body[m[1]:end]
+		if nl := bytes.IndexByte(tail, '\n'); nl >= 0 {
+			tail = tail[:nl]
+		}
+
+		entries = append(entries, Entry{
+			Name:  name,
+			IsDir: isDir,
+			Size:  parseSizeColumn(string(tail), isDir),
+			URL:   key,
+		})
+	}
+	return entries, nil
+}
+
+// parseSizeColumn returns the last size-like field in an autoindex row.
+func parseSizeColumn(tail string, isDir bool) int64 {
+	if isDir {
+		return -1
+	}
+	fields := strings.Fields(html.UnescapeString(tagRe.ReplaceAllString(tail, " ")))
+	for i := len(fields) - 1; i >= 0; i-- {
+		m := sizeRe.FindStringSubmatch(fields[i])
+		if m == nil {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		switch m[2] {
+		case "K":
+			n *= 1024
+		case "M":
+			n *= 1024 * 1024
+		case "G":
+			n *= 1024 * 1024 * 1024
+		case "T":
+			n *= 1024 * 1024 * 1024 * 1024
+		}
+		return int64(n)
+	}
+	return -1
+}
+
+// Render produces the file browser HTML for a listing. linkFor maps each
+// entry to the href used in the listing; parentURL, when non-empty, adds a
+// ".." row linking to it.
+func Render(entries []Entry, parentURL string, linkFor func(Entry) string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<div class="cooked-dir-listing" data-entry-count="%d">`, len(entries))
+	buf.WriteString("\n<table>\n<thead><tr><th>Name</th><th class=\"cooked-dir-size\">Size</th></tr></thead>\n<tbody>\n")
+
+	if parentURL != "" {
+		fmt.Fprintf(&buf, `<tr class="cooked-dir-entry" data-type="parent"><td><span class="cooked-dir-icon">%s</span><a href="%s">..</a></td><td class="cooked-dir-size"></td></tr>`,
+			iconDir, html.EscapeString(parentURL))
+		buf.WriteByte('\n')
+	}
+
+	for _, e := range entries {
+		kind := "file"
+		name := e.Name
+		size := formatSize(e.Size)
+		if e.IsDir {
+			kind = "dir"
+			name += "/"
+			size = ""
+		}
+		fmt.Fprintf(&buf, `<tr class="cooked-dir-entry" data-type="%s"><td><span class="cooked-dir-icon">%s</span><a href="%s">%s</a></td><td class="cooked-dir-size">%s</td></tr>`,
+			kind, iconFor(e), html.EscapeString(linkFor(e)), html.EscapeString(name), size)
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteString("</tbody>\n</table>\n</div>")
+	return buf.Bytes()
+}
+
+// Icons are HTML entities so the listing markup stays ASCII.
+const (
+	iconDir    = "&#x1F4C1;" // 📁
+	iconMarkup = "&#x1F4DD;" // 📝
+	iconText   = "&#x1F4C3;" // 📃
+	iconImage  = "&#x1F5BC;" // 🖼
+	iconOther  = "&#x1F4E6;" // 📦
+)
+
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+}
+
+func iconFor(e Entry) string {
+	if e.IsDir {
+		return iconDir
+	}
+	switch render.DetectFile(e.Name).ContentType {
+	case render.TypeMarkdown, render.TypeMDX, render.TypeAsciiDoc, render.TypeOrg, render.TypeReST, render.TypeNotebook, render.TypeOpenAPI:
+		return iconMarkup
+	case render.TypeCode, render.TypeTable, render.TypePlaintext:
+		return iconText
+	}
+	if imageExts[strings.ToLower(path.Ext(e.Name))] {
+		return iconImage
+	}
+	return iconOther
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n < 0:
+		return ""
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,148 @@
+package listing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAutoindex_Nginx(t *testing.T) {
+	body := `<html><head><title>Index of /docs/</title></head><body>
+<h1>Index of /docs/</h1><hr><pre><a href="../">../</a>
+<a href="guides/">guides/</a>                                            01-Jan-2026 10:00       -
+<a href="README.md">README.md</a>                                          01-Jan-2026 10:00     1234
+<a href="my%20notes.txt">my notes.txt</a>                                       01-Jan-2026 10:00     2048
+</pre><hr></body></html>`
+
+	entries, err := ParseAutoindex([]byte(body), "http://files.internal/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+
+	want := []Entry{
+		{Name: "guides", IsDir: true, Size: -1, URL: "http://files.internal/docs/guides/"},
+		{Name: "README.md", Size: 1234, URL: "http://files.internal/docs/README.md"},
+		{Name: "my notes.txt", Size: 2048, URL: "http://files.internal/docs/my%20notes.txt"},
+	}
+	for i, w := range want {
+		if entries[i] != w {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], w)
+		}
+	}
+}
+
+func TestParseAutoindex_Apache(t *testing.T) {
+	body := `<table>
+<tr><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+<tr><td><a href="/pub/">Parent Directory</a></td><td>-</td></tr>
+<tr><td><a href="install.adoc">install.adoc</a></td><td align="right">2016-01-01 10:00</td><td align="right">3.5K</td></tr>
+<tr><td><a href="images/">images/</a></td><td align="right">2016-01-01 10:00</td><td align="right"> - </td></tr>
+</table>`
+
+	entries, err := ParseAutoindex([]byte(body), "http://files.internal/pub/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	if entries[0].Name != "install.adoc" || entries[0].Size != 3584 {
+		t.Errorf("entry 0 = %+v, want install.adoc of 3584 bytes", entries[0])
+	}
+	if entries[1].Name != "images" || !entries[1].IsDir {
+		t.Errorf("entry 1 = %+v, want images directory", entries[1])
+	}
+}
+
+func TestParseAutoindex_IgnoresForeignLinks(t *testing.T) {
+	body := `<a href="https://other.internal/docs/a.md">a.md</a>
+<a href="/elsewhere/b.md">b.md</a>
+<a href="sub/deep/c.md">c.md</a>
+<a href="d.md#top">d.md</a>
+<a href="d.md">d.md</a>`
+
+	entries, err := ParseAutoindex([]byte(body), "http://files.internal/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "d.md" {
+		t.Errorf("entries = %+v, want only d.md", entries)
+	}
+}
+
+func TestSort(t *testing.T) {
+	entries := []Entry{
+		{Name: "zeta.md"},
+		{Name: "Beta", IsDir: true},
+		{Name: "alpha.md"},
+		{Name: "alpha", IsDir: true},
+	}
+	Sort(entries)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name)
+	}
+	if want := "alpha,Beta,alpha.md,zeta.md"; strings.Join(got, ",") != want {
+		t.Errorf("order = %v, want %s", got, want)
+	}
+}
+
+func TestSelectReadme(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    string
+	}{
+		{"prefers markdown", []Entry{{Name: "README.txt"}, {Name: "README.md"}}, "README.md"},
+		{"case insensitive", []Entry{{Name: "Readme.adoc"}}, "Readme.adoc"},
+		{"extensionless", []Entry{{Name: "main.go"}, {Name: "README"}}, "README"},
+		{"ignores directories", []Entry{{Name: "readme.md", IsDir: true}}, ""},
+		{"none", []Entry{{Name: "main.go"}}, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SelectReadme(tc.entries)
+			if tc.want == "" {
+				if got != nil {
+					t.Errorf("SelectReadme = %q, want nil", got.Name)
+				}
+				return
+			}
+			if got == nil || got.Name != tc.want {
+				t.Errorf("SelectReadme = %v, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	entries := []Entry{
+		{Name: "docs", IsDir: true, Size: -1, URL: "http://h/docs/"},
+		{Name: "a<b>.md", Size: 2048, URL: "http://h/a.md"},
+	}
+	out := string(Render(entries, "/up/", func(e Entry) string { return "/" + e.URL }))
+
+	for _, want := range []string{
+		`data-entry-count="2"`,
+		`<a href="/up/">..</a>`,
+		`data-type="dir"`,
+		`<a href="/http://h/docs/">docs/</a>`,
+		`a&lt;b&gt;.md`,
+		`2.0 KB`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestRender_NoParent(t *testing.T) {
+	out := string(Render(nil, "", func(e Entry) string { return e.URL }))
+	if strings.Contains(out, `data-type="parent"`) {
+		t.Error("parent row rendered without a parent URL")
+	}
+}
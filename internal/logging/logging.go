@@ -46,6 +46,8 @@ type RequestFields struct {
 	ContentType string
 	Bytes       int64
 	ClientIP    string
+	Collapsed   int    // requests coalesced into this one's upstream fetch and render
+	TraceID     string // OpenTelemetry trace ID, when the request is traced
 }
 
 // LogRequest logs a completed request with structured fields.
@@ -72,6 +74,12 @@ func LogRequest(logger *slog.Logger, f RequestFields) {
 	if f.ClientIP != "" {
 		attrs = append(attrs, "client_ip", f.ClientIP)
 	}
+	if f.Collapsed > 0 {
+		attrs = append(attrs, "collapsed", f.Collapsed)
+	}
+	if f.TraceID != "" {
+		attrs = append(attrs, "trace_id", f.TraceID)
+	}
 	logger.Log(context.Background(), level, "request", attrs...)
 }
 
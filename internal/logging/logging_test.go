@@ -82,6 +82,48 @@ func TestLogRequest_Fields(t *testing.T) {
 	}
 }
 
+func TestLogRequest_Collapsed(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	LogRequest(logger, RequestFields{Status: 200})
+	if bytes.Contains(buf.Bytes(), []byte("collapsed")) {
+		t.Error("collapsed should be omitted when no requests were coalesced")
+	}
+
+	buf.Reset()
+	LogRequest(logger, RequestFields{Status: 200, Collapsed: 7})
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if got, _ := entry["collapsed"].(float64); got != 7 {
+		t.Errorf("collapsed = %v, want 7", entry["collapsed"])
+	}
+}
+
+func TestLogRequest_TraceID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	LogRequest(logger, RequestFields{Status: 200})
+	if bytes.Contains(buf.Bytes(), []byte("trace_id")) {
+		t.Error("trace_id should be omitted for untraced requests")
+	}
+
+	buf.Reset()
+	LogRequest(logger, RequestFields{Status: 200, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"})
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if entry["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace_id = %v", entry["trace_id"])
+	}
+}
+
 func TestLogRequest_Levels(t *testing.T) {
 	tests := []struct {
 		status    int
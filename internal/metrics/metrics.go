@@ -0,0 +1,256 @@
+// Package metrics exposes cooked's Prometheus metrics.
+//
+// Labels never carry upstream URLs: requests are labelled by route pattern
+// and detected content type, and upstream hosts are capped at a fixed number
+// of distinct values.
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/air-gapped/cooked/internal/cache"
+)
+
+// maxHosts bounds the number of distinct upstream host label values; later
+// hosts are reported as "other".
+const maxHosts = 100
+
+// OtherHost is the host label for upstreams beyond the first maxHosts.
+const OtherHost = "other"
+
+// Metrics holds cooked's collectors and the registry that serves them.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	inFlight         prometheus.Gauge
+	upstreamDuration *prometheus.HistogramVec
+	upstreamErrors   *prometheus.CounterVec
+	renderDuration   *prometheus.HistogramVec
+	rawBytes         prometheus.Counter
+
+	hostsMu sync.Mutex
+	hosts   map[string]bool
+}
+
+// New creates the metrics and registers them, together with cache
+// statistics read from c on every scrape and the Go runtime collectors.
+func New(c *cache.Cache, version string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		hosts:    make(map[string]bool),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cooked_http_requests_total",
+			Help: "HTTP requests served, by route pattern, detected content type, and status code.",
+		}, []string{"route", "content_type", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cooked_http_request_duration_seconds",
+			Help:    "HTTP request latency, by route pattern and detected content type.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "content_type"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "cooked_http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cooked_upstream_fetch_duration_seconds",
+			Help:    "Upstream fetch latency, by upstream host.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cooked_upstream_fetch_errors_total",
+			Help: "Failed upstream fetches, by upstream host and reason.",
+		}, []string{"host", "reason"}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cooked_render_duration_seconds",
+			Help:    "Document render time including sanitizing and URL rewriting, by renderer.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"renderer"}),
+		rawBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "cooked_raw_proxy_bytes_total",
+			Help: "Bytes served by the raw proxy endpoint.",
+		}),
+	}
+
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "cooked_build_info",
+		Help:        "Build information; always 1.",
+		ConstLabels: prometheus.Labels{"version": version},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		m.requests, m.requestDuration, m.inFlight,
+		m.upstreamDuration, m.upstreamErrors,
+		m.renderDuration, m.rawBytes,
+		buildInfo,
+		newCacheCollector(c),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RequestStarted marks a request in flight; call the returned function when
+// it completes.
+func (m *Metrics) RequestStarted() func() {
+	m.inFlight.Inc()
+	return m.inFlight.Dec
+}
+
+// ObserveRequest records a completed HTTP request. route is the matched mux
+// pattern and contentType the X-Cooked-Content-Type value.
+func (m *Metrics) ObserveRequest(route, contentType string, status int, elapsed time.Duration) {
+	route = orNone(route)
+	contentType = orNone(contentType)
+	m.requests.WithLabelValues(route, contentType, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(route, contentType).Observe(elapsed.Seconds())
+}
+
+// ObserveFetch records an upstream fetch. It matches fetch.Observer.
+func (m *Metrics) ObserveFetch(rawURL string, status int, elapsed time.Duration, err error) {
+	host := m.hostLabel(rawURL)
+	m.upstreamDuration.WithLabelValues(host).Observe(elapsed.Seconds())
+	if reason := fetchErrorReason(status, err); reason != "" {
+		m.upstreamErrors.WithLabelValues(host, reason).Inc()
+	}
+}
+
+// ObserveRender records the time one renderer took.
+func (m *Metrics) ObserveRender(renderer string, elapsed time.Duration) {
+	m.renderDuration.WithLabelValues(orNone(renderer)).Observe(elapsed.Seconds())
+}
+
+// AddRawBytes counts bytes served by the raw proxy.
+func (m *Metrics) AddRawBytes(n int) {
+	m.rawBytes.Add(float64(n))
+}
+
+// hostLabel returns the lowercased host of rawURL, or OtherHost once
+// maxHosts distinct hosts have been seen.
+func (m *Metrics) hostLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return OtherHost
+	}
+	host := strings.ToLower(u.Host)
+
+	m.hostsMu.Lock()
+	defer m.hostsMu.Unlock()
+	if m.hosts[host] {
+		return host
+	}
+	if len(m.hosts) >= maxHosts {
+		return OtherHost
+	}
+	m.hosts[host] = true
+	return host
+}
+
+// fetchErrorReason classifies a failed fetch; "" means it succeeded.
+// 304 Not Modified and other 2xx/3xx responses are successes.
+func fetchErrorReason(status int, err error) string {
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "too large"):
+			return "too_large"
+		case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline"):
+			return "timeout"
+		case strings.Contains(msg, "ssrf"):
+			return "blocked"
+		case strings.Contains(msg, "redirect"):
+			return "redirect"
+		default:
+			return "connection"
+		}
+	}
+	switch {
+	case status >= 500:
+		return "status_5xx"
+	case status >= 400:
+		return "status_4xx"
+	}
+	return ""
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// cacheCollector reports cache.Cache statistics at scrape time.
+type cacheCollector struct {
+	cache *cache.Cache
+
+	lookups   *prometheus.Desc
+	evictions *prometheus.Desc
+	entries   *prometheus.Desc
+	bytes     *prometheus.Desc
+	maxBytes  *prometheus.Desc
+}
+
+func newCacheCollector(c *cache.Cache) *cacheCollector {
+	return &cacheCollector{
+		cache: c,
+		lookups: prometheus.NewDesc("cooked_cache_lookups_total",
+			"Cache lookups for rendered pages, by result (hit, miss, revalidated, expired, stale).",
+			[]string{"result"}, nil),
+		evictions: prometheus.NewDesc("cooked_cache_evictions_total",
+			"Entries evicted from the memory cache to stay within its size limit.", nil, nil),
+		entries: prometheus.NewDesc("cooked_cache_entries",
+			"Entries in the cache, by tier (memory, disk).", []string{"tier"}, nil),
+		bytes: prometheus.NewDesc("cooked_cache_bytes",
+			"Bytes held by the cache, by tier (memory, disk).", []string{"tier"}, nil),
+		maxBytes: prometheus.NewDesc("cooked_cache_max_bytes",
+			"Configured memory cache size limit.", nil, nil),
+	}
+}
+
+func (cc *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cc.lookups
+	ch <- cc.evictions
+	ch <- cc.entries
+	ch <- cc.bytes
+	ch <- cc.maxBytes
+}
+
+func (cc *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	st := cc.cache.Stats()
+
+	for result, n := range map[string]int64{
+		string(cache.StatusHit):         st.Hits,
+		string(cache.StatusMiss):        st.Misses,
+		string(cache.StatusRevalidated): st.Revalidated,
+		string(cache.StatusExpired):     st.Expired,
+		string(cache.StatusStale):       st.Stale,
+	} {
+		ch <- prometheus.MustNewConstMetric(cc.lookups, prometheus.CounterValue, float64(n), result)
+	}
+	ch <- prometheus.MustNewConstMetric(cc.evictions, prometheus.CounterValue, float64(st.Evictions))
+	ch <- prometheus.MustNewConstMetric(cc.entries, prometheus.GaugeValue, float64(st.Entries), "memory")
+	ch <- prometheus.MustNewConstMetric(cc.bytes, prometheus.GaugeValue, float64(st.Bytes), "memory")
+	if st.DiskEnabled {
+		ch <- prometheus.MustNewConstMetric(cc.entries, prometheus.GaugeValue, float64(st.DiskEntries), "disk")
+		ch <- prometheus.MustNewConstMetric(cc.bytes, prometheus.GaugeValue, float64(st.DiskBytes), "disk")
+	}
+	ch <- prometheus.MustNewConstMetric(cc.maxBytes, prometheus.GaugeValue, float64(st.MaxBytes))
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics_Exposition(t *testing.T) {
+	c := cache.New(5*time.Minute, 1024)
+	c.Put("https://git.internal/README.md", cache.Entry{HTML: []byte("page"), Size: 4})
+	c.Record(cache.StatusHit)
+	c.Record(cache.StatusMiss)
+
+	m := New(c, "v1.2.3")
+	done := m.RequestStarted()
+	m.ObserveRequest("GET /{upstream...}", "markdown", 200, 20*time.Millisecond)
+	m.ObserveRequest("", "", 404, time.Millisecond)
+	m.ObserveFetch("https://git.internal/org/repo/raw/branch/main/README.md", 200, 10*time.Millisecond, nil)
+	m.ObserveFetch("https://git.internal/org/repo/raw/branch/main/missing.md", 404, time.Millisecond, nil)
+	m.ObserveRender("markdown", 5*time.Millisecond)
+	m.AddRawBytes(1500)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`cooked_http_requests_total{content_type="markdown",route="GET /{upstream...}",status="200"} 1`,
+		`cooked_http_requests_total{content_type="none",route="none",status="404"} 1`,
+		`cooked_http_requests_in_flight 1`,
+		`cooked_upstream_fetch_duration_seconds_count{host="git.internal"} 2`,
+		`cooked_upstream_fetch_errors_total{host="git.internal",reason="status_4xx"} 1`,
+		`cooked_render_duration_seconds_count{renderer="markdown"} 1`,
+		`cooked_raw_proxy_bytes_total 1500`,
+		`cooked_cache_lookups_total{result="hit"} 1`,
+		`cooked_cache_lookups_total{result="miss"} 1`,
+		`cooked_cache_entries{tier="memory"} 1`,
+		`cooked_cache_bytes{tier="memory"} 4`,
+		`cooked_cache_max_bytes 1024`,
+		`cooked_build_info{version="v1.2.3"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition missing %q", want)
+		}
+	}
+	if strings.Contains(out, "README.md") {
+		t.Error("exposition must not contain upstream URLs")
+	}
+
+	done()
+	if out := scrape(t, m); !strings.Contains(out, "cooked_http_requests_in_flight 0") {
+		t.Error("in-flight gauge should drop back to 0")
+	}
+}
+
+func TestMetrics_HostLabelBounded(t *testing.T) {
+	m := New(cache.New(time.Minute, 1024), "test")
+	for i := range maxHosts {
+		if got := m.hostLabel(fmt.Sprintf("https://host%d.internal/a.md", i)); got == OtherHost {
+			t.Fatalf("host %d labelled %q before the limit", i, got)
+		}
+	}
+	if got := m.hostLabel("https://one-too-many.internal/a.md"); got != OtherHost {
+		t.Errorf("host beyond limit = %q, want %q", got, OtherHost)
+	}
+	if got := m.hostLabel("https://HOST0.internal/b.md"); got != "host0.internal" {
+		t.Errorf("known host = %q, want host0.internal", got)
+	}
+}
+
+func TestFetchErrorReason(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   string
+	}{
+		{200, nil, ""},
+		{304, nil, ""},
+		{404, nil, "status_4xx"},
+		{502, nil, "status_5xx"},
+		{0, errors.New("upstream fetch: context deadline exceeded (Client.Timeout exceeded)"), "timeout"},
+		{0, errors.New("file too large: exceeds 5 bytes limit"), "too_large"},
+		{0, errors.New("ssrf dial: blocked IP 10.0.0.1"), "blocked"},
+		{0, errors.New("upstream fetch: redirect blocked: not allowed"), "redirect"},
+		{0, errors.New("upstream fetch: connection refused"), "connection"},
+	}
+	for _, tt := range tests {
+		if got := fetchErrorReason(tt.status, tt.err); got != tt.want {
+			t.Errorf("fetchErrorReason(%d, %v) = %q, want %q", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
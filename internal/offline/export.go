@@ -0,0 +1,325 @@
+package offline
+
+import (
+	"context"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/fetch"
+	"github.com/air-gapped/cooked/internal/render"
+	"github.com/air-gapped/cooked/internal/rewrite"
+	"github.com/air-gapped/cooked/internal/template"
+)
+
+// externalDir holds assets fetched from outside the export prefix.
+const externalDir = "_external"
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// Prefix limits the crawl: only renderable links below this URL are
+	// followed, and their layout below it is mirrored in the output.
+	Prefix string
+	// MaxDepth is the number of link hops followed from the start pages;
+	// 0 exports only the start pages.
+	MaxDepth int
+	// MaxPages caps the number of pages fetched.
+	MaxPages int
+}
+
+// ExportedPage is a page written by Export.
+type ExportedPage struct {
+	URL   string
+	Path  string // slash-separated, relative to the output directory
+	Title string
+}
+
+// BrokenLink is a link or image that could not be exported.
+type BrokenLink struct {
+	Page   string // upstream URL of the page containing the reference
+	Target string // upstream URL of the reference
+	Reason string
+}
+
+// ExportReport summarizes an export.
+type ExportReport struct {
+	Pages  []ExportedPage
+	Index  string // path of the generated index page
+	Assets int
+	Broken []BrokenLink
+	// Unfollowed counts in-prefix links left pointing upstream because the
+	// depth or page limit was reached.
+	Unfollowed int
+}
+
+type crawlItem struct {
+	url      string
+	depth    int
+	referrer string
+}
+
+// exporter is the state of one Export run.
+type exporter struct {
+	r      *Renderer
+	client *fetch.Client
+	opts   ExportOptions
+	outDir string
+	report ExportReport
+
+	queued []crawlItem
+	paths  map[string]string // page URL -> output path, for every queued page
+	assets map[string]string // asset URL -> output path, or "" when it failed
+}
+
+// Export crawls the upstream documents at starts, following relative links to
+// renderable files below opts.Prefix, and writes them to outDir as a static
+// site with relative links between the pages, local copies of the images
+// they embed, and a generated index. Links that were not exported point at
+// their upstream URL. Start URLs must lie below the prefix.
+func (r *Renderer) Export(ctx context.Context, client *fetch.Client, starts []string, outDir string, opts ExportOptions) (*ExportReport, error) {
+	e := &exporter{
+		r:      r,
+		client: client,
+		opts:   opts,
+		outDir: outDir,
+		paths:  make(map[string]string),
+		assets: make(map[string]string),
+	}
+	for _, start := range starts {
+		u, err := url.Parse(start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start URL %q: %w", start, err)
+		}
+		if _, ok := e.enqueue(u, 0, ""); !ok {
+			return nil, fmt.Errorf("start URL %s is not below %s", start, opts.Prefix)
+		}
+	}
+
+	for i := 0; i < len(e.queued); i++ {
+		if err := ctx.Err(); err != nil {
+			return &e.report, err
+		}
+		if err := e.exportPage(ctx, e.queued[i]); err != nil {
+			return &e.report, err
+		}
+	}
+
+	if err := e.writeIndex(); err != nil {
+		return &e.report, err
+	}
+	return &e.report, nil
+}
+
+// enqueue schedules the page at u and returns its output path. It reports
+// false when u is not below the prefix, when a link leads to a file that is
+// not rendered, or when the depth or page limit keeps it from being exported.
+func (e *exporter) enqueue(u *url.URL, depth int, referrer string) (string, bool) {
+	key := pageKey(u)
+	if p, ok := e.paths[key]; ok {
+		return p, true
+	}
+	rel, ok := e.prefixPath(u)
+	if !ok {
+		return "", false
+	}
+	// Links are followed to markup documents, as the server routes them
+	// through cooked; start pages may be of any type detected once fetched.
+	if referrer != "" && !render.IsRenderableLink(rel) {
+		return "", false
+	}
+	if depth > e.opts.MaxDepth || (e.opts.MaxPages > 0 && len(e.queued) >= e.opts.MaxPages) {
+		e.report.Unfollowed++
+		return "", false
+	}
+	p := HTMLName(rel)
+	e.paths[key] = p
+	e.queued = append(e.queued, crawlItem{url: key, depth: depth, referrer: referrer})
+	return p, true
+}
+
+func (e *exporter) exportPage(ctx context.Context, item crawlItem) error {
+	result, err := e.client.Fetch(ctx, item.url, "", "")
+	if err != nil {
+		e.broken(item.referrer, item.url, err.Error())
+		return nil
+	}
+	if result.StatusCode != 200 {
+		e.broken(item.referrer, item.url, fmt.Sprintf("upstream returned %d", result.StatusCode))
+		return nil
+	}
+
+	u, _ := url.Parse(item.url)
+	body := charset.ToUTF8(result.Body, result.ContentType)
+	fileInfo, _ := render.DetectDocument(render.Document{
+		Path:               u.Path,
+		ContentType:        result.ContentType,
+		ContentDisposition: result.ContentDisposition,
+		Body:               body,
+	})
+	if fileInfo.ContentType == render.TypeUnsupported {
+		e.broken(item.referrer, item.url, "file type is not supported for rendering")
+		return nil
+	}
+	htmlContent, meta, err := e.r.Render(body, fileInfo)
+	if err != nil {
+		e.broken(item.referrer, item.url, "render failed: "+err.Error())
+		return nil
+	}
+
+	pagePath := e.paths[item.url]
+	htmlContent = rewrite.Relative(htmlContent, func(ref string, isSrc bool) (string, bool) {
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return "", false
+		}
+		target := u.ResolveReference(refURL)
+
+		var local string
+		var ok bool
+		if isSrc {
+			local, ok = e.asset(ctx, target, item.url)
+		} else {
+			local, ok = e.enqueue(target, item.depth+1, item.url)
+		}
+		if !ok {
+			// Not exported: point at the upstream rather than leave a
+			// relative link that resolves to nothing.
+			return target.String(), true
+		}
+		return relativeLink(pagePath, local), true
+	})
+
+	page := e.r.Page(template.PageData{
+		UpstreamURL:    item.url,
+		ContentType:    fileInfo.ContentType,
+		UpstreamStatus: result.StatusCode,
+		FileSize:       result.ContentLen,
+		LastModified:   result.LastModified,
+		Content:        htmltemplate.HTML(htmlContent),
+	}, meta)
+	if err := writeFile(filepath.Join(e.outDir, filepath.FromSlash(pagePath)), page); err != nil {
+		return err
+	}
+
+	title := path.Base(u.Path)
+	if meta != nil && meta.Title != "" {
+		title = meta.Title
+	}
+	e.report.Pages = append(e.report.Pages, ExportedPage{URL: item.url, Path: pagePath, Title: title})
+	return nil
+}
+
+// asset fetches an embedded file once and returns its output path. Assets
+// below the prefix mirror its layout; others are stored by host and path
+// under _external.
+func (e *exporter) asset(ctx context.Context, u *url.URL, referrer string) (string, bool) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	key := pageKey(u)
+	if p, ok := e.assets[key]; ok {
+		return p, p != ""
+	}
+
+	local, ok := e.prefixPath(u)
+	if !ok {
+		local = path.Join(externalDir, strings.ReplaceAll(u.Host, ":", "_"), path.Clean("/"+u.Path))
+		ok = fs.ValidPath(local)
+	}
+	e.assets[key] = ""
+	if !ok {
+		return "", false
+	}
+
+	result, err := e.client.Fetch(ctx, key, "", "")
+	if err != nil {
+		e.broken(referrer, key, err.Error())
+		return "", false
+	}
+	if result.StatusCode != 200 {
+		e.broken(referrer, key, fmt.Sprintf("upstream returned %d", result.StatusCode))
+		return "", false
+	}
+	if err := writeFile(filepath.Join(e.outDir, filepath.FromSlash(local)), result.Body); err != nil {
+		e.broken(referrer, key, err.Error())
+		return "", false
+	}
+	e.assets[key] = local
+	e.report.Assets++
+	return local, true
+}
+
+// prefixPath returns u's path relative to the export prefix.
+func (e *exporter) prefixPath(u *url.URL) (string, bool) {
+	rest, ok := strings.CutPrefix(pageKey(u), e.opts.Prefix)
+	if !ok {
+		return "", false
+	}
+	rel, err := url.PathUnescape(rest)
+	if err != nil || rel == "" || !fs.ValidPath(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+func (e *exporter) broken(page, target, reason string) {
+	e.report.Broken = append(e.report.Broken, BrokenLink{Page: page, Target: target, Reason: reason})
+}
+
+// writeIndex writes the generated table of contents. It is index.html
+// unless an exported page already took that name.
+func (e *exporter) writeIndex() error {
+	name := "index.html"
+	for _, p := range e.report.Pages {
+		if p.Path == name {
+			name = "_index.html"
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("<h1>Index</h1>\n<ul>\n")
+	for _, p := range e.report.Pages {
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a> — <code>%s</code></li>\n",
+			html.EscapeString(relativeLink(name, p.Path)), html.EscapeString(p.Title), html.EscapeString(p.Path))
+	}
+	b.WriteString("</ul>\n")
+
+	page := e.r.Page(template.PageData{
+		UpstreamURL: e.opts.Prefix,
+		Title:       "Index",
+		ContentType: render.TypeMarkdown,
+		Content:     htmltemplate.HTML(b.String()),
+	}, nil)
+	if err := writeFile(filepath.Join(e.outDir, name), page); err != nil {
+		return err
+	}
+	e.report.Index = name
+	return nil
+}
+
+// pageKey returns u without query or fragment, which identifies a page or
+// asset in the export.
+func pageKey(u *url.URL) string {
+	k := *u
+	k.RawQuery = ""
+	k.Fragment = ""
+	k.RawFragment = ""
+	return k.String()
+}
+
+// relativeLink returns the URL reference from the page at from to the file
+// at to, both slash-separated paths relative to the output directory.
+func relativeLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+}
@@ -0,0 +1,156 @@
+package offline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/fetch"
+)
+
+func newExportUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	files := map[string]string{
+		"/repo/docs/README.md":          "# Docs\n\n[Guide](guide/intro.md) [Missing](gone.md) [Outside](../CHANGELOG.md) [Code](main.go)\n\n![logo](img/logo.png) ![shared](../../shared/badge.svg)\n",
+		"/repo/docs/guide/intro.md":     "# Intro\n\n[Home](../README.md#top) [Deep](deep/more.md)\n",
+		"/repo/docs/guide/deep/more.md": "# More\n",
+		"/repo/docs/img/logo.png":       "PNG",
+		"/shared/badge.svg":             "<svg/>",
+		"/repo/CHANGELOG.md":            "# Changes\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func exportClient() *fetch.Client {
+	return fetch.NewClient(5*time.Second, 1024*1024, false, fetch.WithSSRFProtection(false))
+}
+
+func TestExport(t *testing.T) {
+	upstream := newExportUpstream(t)
+	out := t.TempDir()
+
+	r := NewRenderer(testAssets(), "v1", "auto")
+	report, err := r.Export(t.Context(), exportClient(), []string{upstream.URL + "/repo/docs/README.md"}, out, ExportOptions{
+		Prefix:   upstream.URL + "/repo/docs/",
+		MaxDepth: 5,
+		MaxPages: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, p := range report.Pages {
+		paths = append(paths, p.Path)
+	}
+	if strings.Join(paths, ",") != "README.html,guide/intro.html,guide/deep/more.html" {
+		t.Errorf("pages = %v", paths)
+	}
+	if report.Pages[1].Title != "Intro" || report.Assets != 2 || report.Index != "index.html" {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Broken) != 1 || report.Broken[0].Target != upstream.URL+"/repo/docs/gone.md" ||
+		report.Broken[0].Page != upstream.URL+"/repo/docs/README.md" {
+		t.Errorf("broken = %+v", report.Broken)
+	}
+
+	readme := readFile(t, filepath.Join(out, "README.html"))
+	for _, want := range []string{
+		`href="guide/intro.html"`,
+		`href="` + upstream.URL + `/repo/CHANGELOG.md"`,
+		`href="` + upstream.URL + `/repo/docs/main.go"`,
+		`src="img/logo.png"`,
+		`src="_external/` + strings.ReplaceAll(strings.TrimPrefix(upstream.URL, "http://"), ":", "_") + `/shared/badge.svg"`,
+	} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.html missing %s", want)
+		}
+	}
+	intro := readFile(t, filepath.Join(out, "guide", "intro.html"))
+	if !strings.Contains(intro, `href="../README.html#top"`) || !strings.Contains(intro, `href="deep/more.html"`) {
+		t.Error("intro.html links not rewritten")
+	}
+	if got := readFile(t, filepath.Join(out, "img", "logo.png")); got != "PNG" {
+		t.Errorf("logo = %q", got)
+	}
+
+	index := readFile(t, filepath.Join(out, "index.html"))
+	if !strings.Contains(index, `<a href="guide/deep/more.html">More</a>`) {
+		t.Error("index does not link to every page")
+	}
+}
+
+func TestExport_Limits(t *testing.T) {
+	upstream := newExportUpstream(t)
+	start := []string{upstream.URL + "/repo/docs/README.md"}
+	r := NewRenderer(testAssets(), "v1", "auto")
+
+	report, err := r.Export(t.Context(), exportClient(), start, t.TempDir(), ExportOptions{
+		Prefix: upstream.URL + "/repo/docs/", MaxDepth: 1, MaxPages: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pages) != 2 || report.Unfollowed != 1 {
+		t.Errorf("depth 1: %d pages, %d unfollowed", len(report.Pages), report.Unfollowed)
+	}
+
+	out := t.TempDir()
+	report, err = r.Export(t.Context(), exportClient(), start, out, ExportOptions{
+		Prefix: upstream.URL + "/repo/docs/", MaxDepth: 5, MaxPages: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pages) != 1 {
+		t.Errorf("max pages 1: %d pages", len(report.Pages))
+	}
+	if readme := readFile(t, filepath.Join(out, "README.html")); !strings.Contains(readme, `href="`+upstream.URL+`/repo/docs/guide/intro.md"`) {
+		t.Error("unfollowed link should point upstream")
+	}
+}
+
+func TestExport_IndexCollision(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Home\n"))
+	}))
+	defer upstream.Close()
+	out := t.TempDir()
+
+	r := NewRenderer(testAssets(), "v1", "auto")
+	report, err := r.Export(t.Context(), exportClient(), []string{upstream.URL + "/index.md"}, out, ExportOptions{
+		Prefix: upstream.URL + "/", MaxPages: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Index != "_index.html" {
+		t.Errorf("index = %q, want _index.html", report.Index)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.html")); err != nil {
+		t.Error("exported index.md missing")
+	}
+}
+
+func TestExport_StartOutsidePrefix(t *testing.T) {
+	r := NewRenderer(testAssets(), "v1", "auto")
+	_, err := r.Export(t.Context(), exportClient(), []string{"https://example.com/other/README.md"}, t.TempDir(), ExportOptions{
+		Prefix: "https://example.com/docs/", MaxPages: 10,
+	})
+	if err == nil {
+		t.Error("expected error for a start URL outside the prefix")
+	}
+}
@@ -0,0 +1,234 @@
+// Package offline renders documents to self-contained cooked pages without a
+// server, for the render and export subcommands.
+package offline
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/render"
+	"github.com/air-gapped/cooked/internal/rewrite"
+	"github.com/air-gapped/cooked/internal/sanitize"
+	"github.com/air-gapped/cooked/internal/template"
+)
+
+// Renderer renders documents to standalone pages, with styles and mermaid.js
+// inlined so they open without network access.
+type Renderer struct {
+	docRender *render.DocumentRenderer
+	tmpl      *template.Renderer
+
+	lightCSS  string
+	darkCSS   string
+	mermaidJS string
+	version   string
+	theme     string
+}
+
+// NewRenderer creates a renderer using the stylesheets and mermaid.js in
+// assets. theme is the default theme: "auto", "light" or "dark".
+func NewRenderer(assets fs.FS, version, theme string) *Renderer {
+	return &Renderer{
+		docRender: render.NewDocumentRenderer(),
+		tmpl:      template.NewRenderer(),
+		lightCSS:  readAssetString(assets, "github-markdown-light.css"),
+		darkCSS:   readAssetString(assets, "github-markdown-dark.css"),
+		mermaidJS: readAssetString(assets, "mermaid.min.js"),
+		version:   version,
+		theme:     theme,
+	}
+}
+
+// Render runs the renderer for the content type and sanitizes markup
+// formats. Relative URLs are left as written.
+func (r *Renderer) Render(body []byte, fileInfo render.FileInfo) ([]byte, *render.MarkdownMeta, error) {
+	htmlContent, meta, err := r.docRender.Render(body, fileInfo, "", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if render.IsMarkup(fileInfo.ContentType) {
+		htmlContent = sanitize.HTML(htmlContent)
+	}
+	return htmlContent, meta, nil
+}
+
+// Page wraps rendered content in the full cooked page. meta may be nil.
+func (r *Renderer) Page(data template.PageData, meta *render.MarkdownMeta) []byte {
+	data.Version = r.version
+	data.DefaultTheme = r.theme
+	data.MermaidScript = r.mermaidJS
+	if meta != nil {
+		data.Title = meta.Title
+		data.HasMermaid = meta.HasMermaid
+		data.HeadingCount = meta.HeadingCount
+		data.CodeBlockCount = meta.CodeBlockCount
+		data.Headings = meta.Headings
+	}
+	return r.tmpl.RenderPage(data, r.lightCSS, r.darkCSS)
+}
+
+// HTMLName returns the output path of a rendered document. Markup formats
+// replace their extension (guide.md becomes guide.html); other files keep it
+// so that sources differing only in extension do not collide (main.go
+// becomes main.go.html).
+func HTMLName(p string) string {
+	if render.IsRenderableLink(p) {
+		return strings.TrimSuffix(p, path.Ext(p)) + ".html"
+	}
+	return p + ".html"
+}
+
+// RenderFiles renders files, which must lie below root, into outDir,
+// mirroring their layout below root. Relative links between the rendered
+// files are rewritten to their HTML output, and images and other embedded
+// files they reference below root are copied alongside. It returns the
+// slash-separated paths of the pages written, relative to outDir.
+func (r *Renderer) RenderFiles(root string, files []string, outDir string) ([]string, error) {
+	rendered := make(map[string]bool, len(files))
+	var rels []string
+	for _, f := range files {
+		rel, err := filepath.Rel(root, f)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("%s is outside %s", f, root)
+		}
+		rel = filepath.ToSlash(rel)
+		if !rendered[rel] {
+			rendered[rel] = true
+			rels = append(rels, rel)
+		}
+	}
+
+	copied := make(map[string]bool)
+	var written []string
+	for _, rel := range rels {
+		src := filepath.Join(root, filepath.FromSlash(rel))
+		stat, err := os.Stat(src)
+		if err != nil {
+			return written, err
+		}
+		body, err := os.ReadFile(src)
+		if err != nil {
+			return written, err
+		}
+
+		body = charset.ToUTF8(body, "")
+		fileInfo, _ := render.DetectDocument(render.Document{Path: rel, Body: body})
+		if fileInfo.ContentType == render.TypeUnsupported {
+			return written, fmt.Errorf("%s: file type is not supported for rendering", src)
+		}
+		htmlContent, meta, err := r.Render(body, fileInfo)
+		if err != nil {
+			return written, fmt.Errorf("render %s: %w", src, err)
+		}
+
+		var embedded []string
+		htmlContent = rewrite.Relative(htmlContent, func(ref string, isSrc bool) (string, bool) {
+			target, ok := localTarget(path.Dir(rel), ref)
+			if !ok {
+				return "", false
+			}
+			if rendered[target] {
+				return HTMLName(ref), true
+			}
+			if isSrc {
+				embedded = append(embedded, target)
+			}
+			return "", false
+		})
+
+		page := r.Page(template.PageData{
+			UpstreamURL:  path.Base(rel),
+			ContentType:  fileInfo.ContentType,
+			FileSize:     stat.Size(),
+			LastModified: stat.ModTime().UTC().Format(http.TimeFormat),
+			Content:      htmltemplate.HTML(htmlContent),
+		}, meta)
+
+		out := HTMLName(rel)
+		if err := writeFile(filepath.Join(outDir, filepath.FromSlash(out)), page); err != nil {
+			return written, err
+		}
+		written = append(written, out)
+
+		for _, target := range embedded {
+			if copied[target] {
+				continue
+			}
+			copied[target] = true
+			if err := copyRegular(filepath.Join(root, filepath.FromSlash(target)),
+				filepath.Join(outDir, filepath.FromSlash(target))); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// localTarget resolves a relative reference from a document in dir to a
+// slash-separated path below the root. It reports false for references with
+// a scheme, root-relative paths, and paths escaping the root.
+func localTarget(dir, ref string) (string, bool) {
+	if strings.Contains(ref, ":") || strings.HasPrefix(ref, "/") {
+		return "", false
+	}
+	unescaped, err := url.PathUnescape(ref)
+	if err != nil {
+		return "", false
+	}
+	target := path.Join(dir, unescaped)
+	if !fs.ValidPath(target) || target == "." {
+		return "", false
+	}
+	return target, true
+}
+
+// copyRegular copies src to dst when src is a regular file. Missing files,
+// directories and symlinks are skipped, leaving the reference dangling.
+func copyRegular(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+func readAssetString(fsys fs.FS, name string) string {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,163 @@
+package offline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testAssets() fstest.MapFS {
+	return fstest.MapFS{
+		"github-markdown-light.css": {Data: []byte(".light{}")},
+		"github-markdown-dark.css":  {Data: []byte(".dark{}")},
+		"mermaid.min.js":            {Data: []byte("var mermaid={initialize:function(){}};")},
+	}
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHTMLName(t *testing.T) {
+	tests := map[string]string{
+		"README.md":       "README.html",
+		"docs/guide.adoc": "docs/guide.html",
+		"notes.org":       "notes.html",
+		"main.go":         "main.go.html",
+		"LICENSE":         "LICENSE.html",
+		"../up/other.md":  "../up/other.html",
+	}
+	for in, want := range tests {
+		if got := HTMLName(in); got != want {
+			t.Errorf("HTMLName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRenderFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"README.md": "# Project\n\n[Guide](docs/guide.md#setup) [Code](main.go) [Missing](docs/missing.md)\n\n" +
+			"![logo](img/logo.png) ![gone](img/gone.png)\n\n```mermaid\ngraph TD; A-->B\n```\n\n<script>alert(1)</script>\n",
+		"docs/guide.md": "# Guide\n\n[Back](../README.md)\n",
+		"main.go":       "package main\n",
+		"img/logo.png":  "PNG",
+		"img/other.png": "PNG",
+	})
+	out := t.TempDir()
+
+	r := NewRenderer(testAssets(), "v1", "dark")
+	written, err := r.RenderFiles(root, []string{
+		filepath.Join(root, "README.md"),
+		filepath.Join(root, "docs", "guide.md"),
+		filepath.Join(root, "main.go"),
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(written, ",") != "README.html,docs/guide.html,main.go.html" {
+		t.Errorf("written = %v", written)
+	}
+
+	readme := readFile(t, filepath.Join(out, "README.html"))
+	for _, want := range []string{
+		`href="docs/guide.html#setup"`,
+		`href="main.go.html"`,
+		`href="docs/missing.md"`,
+		`src="img/logo.png"`,
+		".light{}",
+		"var mermaid=",
+		`data-theme="dark"`,
+		"<title>Project — cooked</title>",
+	} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.html missing %s", want)
+		}
+	}
+	if strings.Contains(readme, "<script>alert") {
+		t.Error("README.html contains unsanitized script")
+	}
+	if strings.Contains(readme, `src="/_cooked/`) || strings.Contains(readme, `href="/`) {
+		t.Error("README.html references server paths")
+	}
+
+	if guide := readFile(t, filepath.Join(out, "docs", "guide.html")); !strings.Contains(guide, `href="../README.html"`) {
+		t.Error("guide.html link back to README not rewritten")
+	}
+	if got := readFile(t, filepath.Join(out, "img", "logo.png")); got != "PNG" {
+		t.Errorf("copied image = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(out, "img", "other.png")); !os.IsNotExist(err) {
+		t.Error("unreferenced image was copied")
+	}
+}
+
+func TestRenderFiles_Extensionless(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"bin/deploy": "#!/bin/sh\necho deploying\n"})
+	out := t.TempDir()
+
+	r := NewRenderer(testAssets(), "v1", "auto")
+	written, err := r.RenderFiles(root, []string{filepath.Join(root, "bin", "deploy")}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 {
+		t.Fatalf("written = %v", written)
+	}
+	if got := readFile(t, filepath.Join(out, written[0])); !strings.Contains(got, `data-language="bash"`) {
+		t.Errorf("script not highlighted as shell:\n%s", got)
+	}
+}
+
+func TestRenderFiles_Errors(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"logo.png": "PNG"})
+	r := NewRenderer(testAssets(), "v1", "auto")
+
+	if _, err := r.RenderFiles(root, []string{filepath.Join(root, "logo.png")}, t.TempDir()); err == nil {
+		t.Error("unsupported file type: expected error")
+	}
+	if _, err := r.RenderFiles(root, []string{filepath.Join(t.TempDir(), "README.md")}, t.TempDir()); err == nil {
+		t.Error("file outside root: expected error")
+	}
+}
+
+func TestRenderFiles_ImageOutsideRoot(t *testing.T) {
+	parent := t.TempDir()
+	writeTree(t, parent, map[string]string{
+		"secret.png":     "SECRET",
+		"docs/README.md": "![x](../secret.png)\n",
+	})
+	root := filepath.Join(parent, "docs")
+	out := t.TempDir()
+
+	r := NewRenderer(testAssets(), "v1", "auto")
+	if _, err := r.RenderFiles(root, []string{filepath.Join(root, "README.md")}, out); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 1 {
+		t.Errorf("output has %d entries, want only README.html", len(entries))
+	}
+}
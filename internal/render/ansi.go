@@ -0,0 +1,151 @@
+package render
+
+import (
+	gohtml "html"
+	"strconv"
+	"strings"
+)
+
+// ansiColors names the eight basic ANSI colors in code order.
+var ansiColors = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ansiStyle is the SGR state of a terminal: colors 0-15, or -1 for the
+// default.
+type ansiStyle struct {
+	fg, bg                  int
+	bold, italic, underline bool
+}
+
+// ansiToHTML escapes terminal output for HTML and turns its ANSI color and
+// style sequences into spans with the ansi-* classes Jupyter uses. Other
+// escape sequences are dropped.
+func ansiToHTML(s string) string {
+	var b strings.Builder
+	st := ansiStyle{fg: -1, bg: -1}
+	for i := 0; i < len(s); {
+		if s[i] != '\x1b' {
+			j := strings.IndexByte(s[i:], '\x1b')
+			if j < 0 {
+				j = len(s) - i
+			}
+			if class := st.classes(); class != "" {
+				b.WriteString(`<span class="` + class + `">` + gohtml.EscapeString(s[i:i+j]) + "</span>")
+			} else {
+				b.WriteString(gohtml.EscapeString(s[i : i+j]))
+			}
+			i += j
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s[i:], "\x1b["):
+			// CSI: parameters up to a final byte in 0x40-0x7E.
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			if j < len(s) && s[j] == 'm' {
+				st.apply(s[i+2 : j])
+			}
+			i = j + 1
+		case strings.HasPrefix(s[i:], "\x1b]"):
+			// OSC: up to BEL or ST.
+			rest := s[i+2:]
+			end := len(rest)
+			if k := strings.IndexByte(rest, '\x07'); k >= 0 {
+				end = k + 1
+			}
+			if k := strings.Index(rest, "\x1b\\"); k >= 0 && k+2 < end {
+				end = k + 2
+			}
+			i += 2 + end
+		default:
+			i += 2
+		}
+	}
+	return b.String()
+}
+
+// apply updates the style from the parameters of an SGR sequence.
+func (st *ansiStyle) apply(params string) {
+	codes := strings.Split(params, ";")
+	for k := 0; k < len(codes); k++ {
+		n, err := strconv.Atoi(codes[k])
+		if err != nil {
+			n = 0 // an empty parameter is a reset
+		}
+		switch {
+		case n == 0:
+			*st = ansiStyle{fg: -1, bg: -1}
+		case n == 1:
+			st.bold = true
+		case n == 3:
+			st.italic = true
+		case n == 4:
+			st.underline = true
+		case n == 22:
+			st.bold = false
+		case n == 23:
+			st.italic = false
+		case n == 24:
+			st.underline = false
+		case n >= 30 && n <= 37:
+			st.fg = n - 30
+		case n == 39:
+			st.fg = -1
+		case n >= 40 && n <= 47:
+			st.bg = n - 40
+		case n == 49:
+			st.bg = -1
+		case n >= 90 && n <= 97:
+			st.fg = n - 90 + 8
+		case n >= 100 && n <= 107:
+			st.bg = n - 100 + 8
+		case n == 38 || n == 48:
+			// Extended colors: 5;n picks from the 256-color palette, of which
+			// the first 16 are kept; 2;r;g;b is true color and is ignored.
+			color := -1
+			if k+2 < len(codes) && codes[k+1] == "5" {
+				if c, err := strconv.Atoi(codes[k+2]); err == nil && c < 16 {
+					color = c
+				}
+				k += 2
+			} else if k+1 < len(codes) && codes[k+1] == "2" {
+				k += 4
+			}
+			if n == 38 {
+				st.fg = color
+			} else {
+				st.bg = color
+			}
+		}
+	}
+}
+
+// classes returns the CSS classes for the style.
+func (st ansiStyle) classes() string {
+	var classes []string
+	if st.fg >= 0 {
+		classes = append(classes, ansiColorClass(st.fg, "fg"))
+	}
+	if st.bg >= 0 {
+		classes = append(classes, ansiColorClass(st.bg, "bg"))
+	}
+	if st.bold {
+		classes = append(classes, "ansi-bold")
+	}
+	if st.italic {
+		classes = append(classes, "ansi-italic")
+	}
+	if st.underline {
+		classes = append(classes, "ansi-underline")
+	}
+	return strings.Join(classes, " ")
+}
+
+func ansiColorClass(color int, layer string) string {
+	if color >= 8 {
+		return "ansi-" + ansiColors[color-8] + "-intense-" + layer
+	}
+	return "ansi-" + ansiColors[color] + "-" + layer
+}
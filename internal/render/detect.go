@@ -13,15 +13,20 @@ const (
 	TypeMDX         ContentType = "mdx"
 	TypeAsciiDoc    ContentType = "asciidoc"
 	TypeOrg         ContentType = "org"
+	TypeReST        ContentType = "rst"
+	TypeNotebook    ContentType = "notebook"
+	TypeOpenAPI     ContentType = "openapi"
+	TypeTable       ContentType = "table"
 	TypeCode        ContentType = "code"
 	TypePlaintext   ContentType = "plaintext"
+	TypeDirectory   ContentType = "directory"
 	TypeUnsupported ContentType = "unsupported"
 )
 
 // FileInfo holds detected information about a file.
 type FileInfo struct {
 	ContentType ContentType
-	Language    string // e.g. "python", "go", "yaml" — empty for markdown/plaintext/unsupported
+	Language    string // e.g. "python", "go", "yaml", "csv" for tables, or the source format of OpenAPI — empty for markdown/plaintext/unsupported
 	Label       string // human-readable label e.g. "Markdown", "Python", "YAML"
 }
 
@@ -40,6 +45,20 @@ var asciidocExts = map[string]bool{
 	".asc":      true,
 }
 
+// rstExts maps reStructuredText file extensions.
+var rstExts = map[string]bool{
+	".rst":  true,
+	".rest": true,
+}
+
+// tableExts maps delimiter-separated data file extensions to (format, label).
+var tableExts = map[string][2]string{
+	".csv": {"csv", "CSV"},
+	".tsv": {"tsv", "TSV"},
+	".tab": {"tsv", "TSV"},
+	".psv": {"psv", "PSV"},
+}
+
 // codeExts maps file extensions to (language, label).
 var codeExts = map[string][2]string{
 	".py":         {"python", "Python"},
@@ -64,7 +83,6 @@ var codeExts = map[string][2]string{
 	".json":       {"json", "JSON"},
 	".toml":       {"toml", "TOML"},
 	".xml":        {"xml", "XML"},
-	".csv":        {"csv", "CSV"},
 	".sql":        {"sql", "SQL"},
 	".graphql":    {"graphql", "GraphQL"},
 	".tf":         {"hcl", "Terraform"},
@@ -129,6 +147,26 @@ func DetectFile(urlPath string) FileInfo {
 		return FileInfo{ContentType: TypeOrg, Label: "Org"}
 	}
 
+	// Check reStructuredText extensions
+	if rstExts[ext] {
+		return FileInfo{ContentType: TypeReST, Label: "reStructuredText"}
+	}
+
+	// Check Jupyter notebook extension
+	if ext == ".ipynb" {
+		return FileInfo{ContentType: TypeNotebook, Label: "Jupyter Notebook"}
+	}
+
+	// Check OpenAPI description names
+	if format, ok := openAPIFormat(lower); ok {
+		return FileInfo{ContentType: TypeOpenAPI, Language: format, Label: "OpenAPI"}
+	}
+
+	// Check table extensions
+	if info, ok := tableExts[ext]; ok {
+		return FileInfo{ContentType: TypeTable, Language: info[0], Label: info[1]}
+	}
+
 	// Check code extensions
 	if info, ok := codeExts[ext]; ok {
 		return FileInfo{ContentType: TypeCode, Language: info[0], Label: info[1]}
@@ -142,9 +180,87 @@ func DetectFile(urlPath string) FileInfo {
 	return FileInfo{ContentType: TypeUnsupported, Label: "Unknown"}
 }
 
+// mediaTypes maps the media types of documents submitted without a filename,
+// or served upstream without a recognizable one, to their content type.
+var mediaTypes = map[string]FileInfo{
+	"text/markdown":                    {ContentType: TypeMarkdown, Label: "Markdown"},
+	"text/x-markdown":                  {ContentType: TypeMarkdown, Label: "Markdown"},
+	"text/mdx":                         {ContentType: TypeMDX, Label: "MDX"},
+	"text/asciidoc":                    {ContentType: TypeAsciiDoc, Label: "AsciiDoc"},
+	"text/x-asciidoc":                  {ContentType: TypeAsciiDoc, Label: "AsciiDoc"},
+	"text/org":                         {ContentType: TypeOrg, Label: "Org"},
+	"text/x-org":                       {ContentType: TypeOrg, Label: "Org"},
+	"text/x-rst":                       {ContentType: TypeReST, Label: "reStructuredText"},
+	"text/prs.fallenstein.rst":         {ContentType: TypeReST, Label: "reStructuredText"},
+	"application/x-ipynb+json":         {ContentType: TypeNotebook, Label: "Jupyter Notebook"},
+	"application/vnd.oai.openapi":      {ContentType: TypeOpenAPI, Language: "yaml", Label: "OpenAPI"},
+	"application/vnd.oai.openapi+json": {ContentType: TypeOpenAPI, Language: "json", Label: "OpenAPI"},
+	"text/plain":                       {ContentType: TypePlaintext, Label: "Plain Text"},
+
+	"text/csv":                  {ContentType: TypeTable, Language: "csv", Label: "CSV"},
+	"text/tab-separated-values": {ContentType: TypeTable, Language: "tsv", Label: "TSV"},
+
+	"application/json":     {ContentType: TypeCode, Language: "json", Label: "JSON"},
+	"text/json":            {ContentType: TypeCode, Language: "json", Label: "JSON"},
+	"application/yaml":     {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"application/x-yaml":   {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"text/yaml":            {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"text/x-yaml":          {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"application/toml":     {ContentType: TypeCode, Language: "toml", Label: "TOML"},
+	"application/xml":      {ContentType: TypeCode, Language: "xml", Label: "XML"},
+	"text/xml":             {ContentType: TypeCode, Language: "xml", Label: "XML"},
+	"application/x-sh":     {ContentType: TypeCode, Language: "bash", Label: "Shell"},
+	"text/x-shellscript":   {ContentType: TypeCode, Language: "bash", Label: "Shell"},
+	"text/x-python":        {ContentType: TypeCode, Language: "python", Label: "Python"},
+	"text/x-diff":          {ContentType: TypeCode, Language: "diff", Label: "Diff"},
+	"text/x-patch":         {ContentType: TypeCode, Language: "diff", Label: "Patch"},
+	"application/x-python": {ContentType: TypeCode, Language: "python", Label: "Python"},
+}
+
+// DetectMediaType determines the content type from a media type such as
+// "text/markdown; charset=utf-8". Parameters are ignored.
+func DetectMediaType(mediaType string) FileInfo {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	if info, ok := mediaTypes[strings.ToLower(strings.TrimSpace(mediaType))]; ok {
+		return info
+	}
+	return FileInfo{ContentType: TypeUnsupported, Label: "Unknown"}
+}
+
 // IsRenderableLink returns true if the URL path points to a file that cooked
 // can render (used for relative URL rewriting decisions).
 func IsRenderableLink(urlPath string) bool {
 	ext := strings.ToLower(path.Ext(urlPath))
-	return markdownExts[ext] || ext == ".mdx" || asciidocExts[ext] || ext == ".org"
+	if _, ok := openAPIFormat(strings.ToLower(path.Base(urlPath))); ok {
+		return true
+	}
+	return markdownExts[ext] || ext == ".mdx" || asciidocExts[ext] || ext == ".org" || rstExts[ext] || ext == ".ipynb"
+}
+
+// openAPIFormat recognizes the conventional names of OpenAPI and Swagger
+// descriptions — openapi.yaml, swagger.json, petstore.openapi.yml,
+// openapi-v2.json — and returns their source format.
+func openAPIFormat(lower string) (string, bool) {
+	ext := path.Ext(lower)
+	format := ""
+	switch ext {
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".json":
+		format = "json"
+	default:
+		return "", false
+	}
+	stem := strings.TrimSuffix(lower, ext)
+	for _, name := range []string{"openapi", "swagger"} {
+		if stem == name {
+			return format, true
+		}
+		for _, sep := range []string{".", "-", "_"} {
+			if strings.HasPrefix(stem, name+sep) || strings.HasSuffix(stem, sep+name) {
+				return format, true
+			}
+		}
+	}
+	return "", false
 }
@@ -65,7 +65,6 @@ func TestDetectFile_Code(t *testing.T) {
 		{"/data.json", "json", "JSON"},
 		{"/config.toml", "toml", "TOML"},
 		{"/data.xml", "xml", "XML"},
-		{"/data.csv", "csv", "CSV"},
 		{"/query.sql", "sql", "SQL"},
 		{"/schema.graphql", "graphql", "GraphQL"},
 		{"/main.tf", "hcl", "Terraform"},
@@ -91,6 +90,35 @@ func TestDetectFile_Code(t *testing.T) {
 	}
 }
 
+func TestDetectFile_Table(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantFormat string
+		wantLabel  string
+	}{
+		{"/data.csv", "csv", "CSV"},
+		{"/export/Report.CSV", "csv", "CSV"},
+		{"/metrics.tsv", "tsv", "TSV"},
+		{"/metrics.tab", "tsv", "TSV"},
+		{"/dump.psv", "psv", "PSV"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			info := DetectFile(tc.path)
+			if info.ContentType != TypeTable {
+				t.Errorf("DetectFile(%q).ContentType = %q, want %q", tc.path, info.ContentType, TypeTable)
+			}
+			if info.Language != tc.wantFormat {
+				t.Errorf("DetectFile(%q).Language = %q, want %q", tc.path, info.Language, tc.wantFormat)
+			}
+			if info.Label != tc.wantLabel {
+				t.Errorf("DetectFile(%q).Label = %q, want %q", tc.path, info.Label, tc.wantLabel)
+			}
+		})
+	}
+}
+
 func TestDetectFile_SpecialNames(t *testing.T) {
 	tests := []struct {
 		path      string
@@ -190,6 +218,78 @@ func TestDetectFile_Org(t *testing.T) {
 	}
 }
 
+func TestDetectFile_ReST(t *testing.T) {
+	tests := []struct {
+		path string
+		want ContentType
+	}{
+		{"/README.rst", TypeReST},
+		{"/docs/index.rest", TypeReST},
+		{"/CHANGES.RST", TypeReST},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			info := DetectFile(tc.path)
+			if info.ContentType != tc.want {
+				t.Errorf("DetectFile(%q).ContentType = %q, want %q", tc.path, info.ContentType, tc.want)
+			}
+			if info.Label != "reStructuredText" {
+				t.Errorf("DetectFile(%q).Label = %q, want reStructuredText", tc.path, info.Label)
+			}
+		})
+	}
+}
+
+func TestDetectFile_Notebook(t *testing.T) {
+	tests := []struct {
+		path string
+		want ContentType
+	}{
+		{"/analysis.ipynb", TypeNotebook},
+		{"/notebooks/Demo.IPYNB", TypeNotebook},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			info := DetectFile(tc.path)
+			if info.ContentType != tc.want {
+				t.Errorf("DetectFile(%q).ContentType = %q, want %q", tc.path, info.ContentType, tc.want)
+			}
+			if info.Label != "Jupyter Notebook" {
+				t.Errorf("DetectFile(%q).Label = %q, want Jupyter Notebook", tc.path, info.Label)
+			}
+		})
+	}
+}
+
+func TestDetectFile_OpenAPI(t *testing.T) {
+	tests := []struct {
+		path     string
+		want     ContentType
+		wantLang string
+	}{
+		{"/openapi.yaml", TypeOpenAPI, "yaml"},
+		{"/api/OpenAPI.yml", TypeOpenAPI, "yaml"},
+		{"/swagger.json", TypeOpenAPI, "json"},
+		{"/petstore.openapi.yaml", TypeOpenAPI, "yaml"},
+		{"/openapi-v2.json", TypeOpenAPI, "json"},
+		{"/billing_swagger.yaml", TypeOpenAPI, "yaml"},
+		{"/openapi.toml", TypeCode, "toml"},
+		{"/openapiv3.yaml", TypeCode, "yaml"},
+		{"/not-openapi-spec.yaml", TypeCode, "yaml"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			info := DetectFile(tc.path)
+			if info.ContentType != tc.want || info.Language != tc.wantLang {
+				t.Errorf("DetectFile(%q) = %q/%q, want %q/%q", tc.path, info.ContentType, info.Language, tc.want, tc.wantLang)
+			}
+		})
+	}
+}
+
 func TestDetectFile_Unsupported(t *testing.T) {
 	tests := []string{
 		"/image.png",
@@ -221,6 +321,27 @@ func TestDetectFile_CaseInsensitive(t *testing.T) {
 	}
 }
 
+func TestDetectMediaType(t *testing.T) {
+	tests := map[string]ContentType{
+		"text/markdown":                  TypeMarkdown,
+		"text/x-markdown; charset=utf-8": TypeMarkdown,
+		"Text/AsciiDoc":                  TypeAsciiDoc,
+		"text/org":                       TypeOrg,
+		"text/x-rst":                     TypeReST,
+		"application/x-ipynb+json":       TypeNotebook,
+		"application/vnd.oai.openapi":    TypeOpenAPI,
+		"text/csv; header=present":       TypeTable,
+		"text/plain":                     TypePlaintext,
+		"application/octet-stream":       TypeUnsupported,
+		"":                               TypeUnsupported,
+	}
+	for mt, want := range tests {
+		if got := DetectMediaType(mt).ContentType; got != want {
+			t.Errorf("DetectMediaType(%q) = %q, want %q", mt, got, want)
+		}
+	}
+}
+
 func TestIsRenderableLink(t *testing.T) {
 	tests := []struct {
 		path string
@@ -233,6 +354,10 @@ func TestIsRenderableLink(t *testing.T) {
 		{"guide.asciidoc", true},
 		{"notes.asc", true},
 		{"readme.org", true},
+		{"docs/index.rst", true},
+		{"analysis.ipynb", true},
+		{"api/openapi.yaml", true},
+		{"config.yaml", false},
 		{"image.png", false},
 		{"script.py", false},
 		{"readme.txt", false},
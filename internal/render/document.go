@@ -0,0 +1,86 @@
+package render
+
+import "fmt"
+
+// DocumentRenderer renders a document with the renderer for its content
+// type. It is shared by the server and the offline renderers, so both render
+// every format the same way.
+type DocumentRenderer struct {
+	markdown *MarkdownRenderer
+	code     *CodeRenderer
+	asciidoc *AsciiDocRenderer
+	org      *OrgRenderer
+	rst      *RSTRenderer
+	notebook *NotebookRenderer
+	table    *TableRenderer
+	tree     *TreeRenderer
+	openapi  *OpenAPIRenderer
+}
+
+// NewDocumentRenderer creates a renderer for every supported content type.
+func NewDocumentRenderer() *DocumentRenderer {
+	return &DocumentRenderer{
+		markdown: NewMarkdownRenderer(),
+		code:     NewCodeRenderer(),
+		asciidoc: NewAsciiDocRenderer(),
+		org:      NewOrgRenderer(),
+		rst:      NewRSTRenderer(),
+		notebook: NewNotebookRenderer(),
+		table:    NewTableRenderer(),
+		tree:     NewTreeRenderer(),
+		openapi:  NewOpenAPIRenderer(),
+	}
+}
+
+// Render converts body to HTML according to fileInfo. baseURL and load let
+// an OpenAPI description resolve the files it references; load may be nil.
+// The output of markup formats (see IsMarkup) is not yet sanitized.
+func (r *DocumentRenderer) Render(body []byte, fileInfo FileInfo, baseURL string, load RefLoader) ([]byte, *MarkdownMeta, error) {
+	var htmlContent []byte
+	var meta *MarkdownMeta
+	var err error
+
+	switch fileInfo.ContentType {
+	case TypeMarkdown:
+		htmlContent, meta, err = r.markdown.Render(body)
+	case TypeMDX:
+		htmlContent, meta, err = r.markdown.Render(PreprocessMDX(body))
+	case TypeAsciiDoc:
+		htmlContent, meta, err = r.asciidoc.Render(body)
+	case TypeOrg:
+		htmlContent, meta, err = r.org.Render(body)
+	case TypeReST:
+		htmlContent, meta, err = r.rst.Render(body)
+	case TypeNotebook:
+		htmlContent, meta, err = r.notebook.Render(body)
+	case TypeTable:
+		htmlContent, err = r.table.Render(body, fileInfo.Language)
+	case TypeCode:
+		if HasTreeView(fileInfo.Language) {
+			htmlContent, err = r.tree.Render(body, fileInfo.Language)
+		} else {
+			htmlContent, err = r.code.Render(body, fileInfo.Language)
+		}
+	case TypeOpenAPI:
+		htmlContent, meta, err = r.openapi.Render(body, fileInfo.Language, baseURL, load)
+	case TypePlaintext:
+		htmlContent = RenderPlaintext(body)
+	default:
+		err = fmt.Errorf("unsupported content type %q", fileInfo.ContentType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return htmlContent, meta, nil
+}
+
+// IsMarkup reports whether documents of content type ct may carry upstream
+// HTML and relative links, so their rendered output must be sanitized and
+// may have its URLs rewritten.
+func IsMarkup(ct ContentType) bool {
+	switch ct {
+	case TypeMarkdown, TypeMDX, TypeAsciiDoc, TypeOrg, TypeReST, TypeNotebook, TypeOpenAPI:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocumentRenderer_Dispatch(t *testing.T) {
+	r := NewDocumentRenderer()
+
+	tests := []struct {
+		name     string
+		body     string
+		fileInfo FileInfo
+		want     string
+		wantMeta bool
+	}{
+		{"markdown", "# Title\n", FileInfo{ContentType: TypeMarkdown}, "<h1", true},
+		{"mdx", "import X from './x'\n\n# Title\n", FileInfo{ContentType: TypeMDX}, "<h1", true},
+		{"code", "x = 1\n", FileInfo{ContentType: TypeCode, Language: "python"}, `data-language="python"`, false},
+		{"tree", `{"a": 1}`, FileInfo{ContentType: TypeCode, Language: "json"}, "cooked-tree", false},
+		{"table", "a,b\n1,2\n", FileInfo{ContentType: TypeTable, Language: "csv"}, "<table", false},
+		{"plaintext", "hello\n", FileInfo{ContentType: TypePlaintext}, "hello", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			html, meta, err := r.Render([]byte(tc.body), tc.fileInfo, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(html), tc.want) {
+				t.Errorf("output missing %q:\n%s", tc.want, html)
+			}
+			if (meta != nil) != tc.wantMeta {
+				t.Errorf("meta = %v, want present: %v", meta, tc.wantMeta)
+			}
+		})
+	}
+
+	if _, _, err := r.Render([]byte("x"), FileInfo{ContentType: TypeUnsupported}, "", nil); err == nil {
+		t.Error("unsupported content type: expected error")
+	}
+}
+
+func TestIsMarkup(t *testing.T) {
+	for _, ct := range []ContentType{TypeMarkdown, TypeMDX, TypeAsciiDoc, TypeOrg, TypeReST, TypeNotebook, TypeOpenAPI} {
+		if !IsMarkup(ct) {
+			t.Errorf("IsMarkup(%q) = false, want true", ct)
+		}
+	}
+	for _, ct := range []ContentType{TypeCode, TypeTable, TypePlaintext, TypeDirectory} {
+		if IsMarkup(ct) {
+			t.Errorf("IsMarkup(%q) = true, want false", ct)
+		}
+	}
+}
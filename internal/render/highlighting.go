@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	gohtml "html"
+	"io"
 
 	"github.com/alecthomas/chroma/v2"
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -61,6 +62,15 @@ func (r *chromaRenderer) renderFencedCodeBlock(
 		code.Write(line.Value(source))
 	}
 
+	if err := writeCodeBlock(w, r.formatter, code.String(), lang); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkContinue, nil
+}
+
+// writeCodeBlock highlights code with chroma and writes it in the cooked code
+// block wrapper. Unknown and empty languages are written unhighlighted.
+func writeCodeBlock(w io.Writer, formatter *chromahtml.Formatter, code, lang string) error {
 	// Run chroma: lexer → tokenise → format.
 	var lexer chroma.Lexer
 	if lang != "" {
@@ -71,27 +81,25 @@ func (r *chromaRenderer) renderFencedCodeBlock(
 	}
 	lexer = chroma.Coalesce(lexer)
 
-	iterator, err := lexer.Tokenise(nil, code.String())
+	iterator, err := lexer.Tokenise(nil, code)
 	if err != nil {
-		return ast.WalkStop, fmt.Errorf("chroma tokenise: %w", err)
+		return fmt.Errorf("chroma tokenise: %w", err)
 	}
 
 	var highlighted bytes.Buffer
-	if err := r.formatter.Format(&highlighted, styles.Fallback, iterator); err != nil {
-		return ast.WalkStop, fmt.Errorf("chroma format: %w", err)
+	if err := formatter.Format(&highlighted, styles.Fallback, iterator); err != nil {
+		return fmt.Errorf("chroma format: %w", err)
 	}
 
 	// Write the cooked wrapper directly — no post-processing needed.
 	fmt.Fprintf(w, `<div class="cooked-code-block" data-language="%s">`, gohtml.EscapeString(lang))
-	_, _ = w.WriteString("\n<div class=\"cooked-code-header\">\n")
+	io.WriteString(w, "\n<div class=\"cooked-code-header\">\n")
 	if lang != "" {
-		fmt.Fprintf(w, `<span class="cooked-code-language">%s</span>`, gohtml.EscapeString(lang))
-		_ = w.WriteByte('\n')
+		fmt.Fprintf(w, `<span class="cooked-code-language">%s</span>`+"\n", gohtml.EscapeString(lang))
 	}
-	_, _ = w.WriteString("<button class=\"cooked-copy-btn\" data-state=\"idle\">Copy</button>\n")
-	_, _ = w.WriteString("</div>\n")
-	_, _ = w.Write(highlighted.Bytes())
-	_, _ = w.WriteString("\n</div>")
-
-	return ast.WalkContinue, nil
+	io.WriteString(w, "<button class=\"cooked-copy-btn\" data-state=\"idle\">Copy</button>\n")
+	io.WriteString(w, "</div>\n")
+	w.Write(highlighted.Bytes())
+	io.WriteString(w, "\n</div>")
+	return nil
 }
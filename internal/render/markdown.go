@@ -61,12 +61,18 @@ func NewMarkdownRenderer() *MarkdownRenderer {
 
 // Render converts markdown source to HTML and extracts metadata.
 func (r *MarkdownRenderer) Render(source []byte) ([]byte, *MarkdownMeta, error) {
+	return r.render(source, parser.NewContext())
+}
+
+// render is Render with a parser context, which documents made of several
+// markdown parts share so that their heading IDs stay unique.
+func (r *MarkdownRenderer) render(source []byte, pc parser.Context) ([]byte, *MarkdownMeta, error) {
 	// Strip YAML frontmatter before rendering
 	content, title := stripFrontmatter(source)
 
 	var buf bytes.Buffer
 	reader := text.NewReader(content)
-	doc := r.md.Parser().Parse(reader)
+	doc := r.md.Parser().Parse(reader, parser.WithContext(pc))
 
 	meta := &MarkdownMeta{Title: title}
 	extractMeta(doc, content, meta)
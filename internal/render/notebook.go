@@ -0,0 +1,393 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gohtml "html"
+	"io"
+	"regexp"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Limits on embedded outputs, so that notebooks near the file size limit stay
+// quick to render and to scroll through.
+const (
+	notebookMaxText  = 64 << 10  // stream, plain text and JSON outputs
+	notebookMaxHTML  = 256 << 10 // HTML outputs; larger ones fall back to text
+	notebookMaxImage = 2 << 20   // base64 image data
+)
+
+// NotebookRenderer renders Jupyter notebooks (nbformat 4) to HTML: markdown
+// cells through the markdown pipeline, code cells highlighted in the kernel
+// language, and their stored outputs.
+type NotebookRenderer struct {
+	md        *MarkdownRenderer
+	formatter *chromahtml.Formatter
+}
+
+// NewNotebookRenderer creates a new notebook renderer.
+func NewNotebookRenderer() *NotebookRenderer {
+	return &NotebookRenderer{
+		md:        NewMarkdownRenderer(),
+		formatter: chromahtml.New(chromahtml.WithClasses(true)),
+	}
+}
+
+type notebook struct {
+	NBFormat int `json:"nbformat"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+			Name     string `json:"name"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		Title string `json:"title"`
+	} `json:"metadata"`
+	Cells []notebookCell `json:"cells"`
+}
+
+type notebookCell struct {
+	CellType       string                             `json:"cell_type"`
+	Source         notebookText                       `json:"source"`
+	ExecutionCount *int                               `json:"execution_count"`
+	Outputs        []notebookOutput                   `json:"outputs"`
+	Attachments    map[string]map[string]notebookText `json:"attachments"`
+}
+
+type notebookOutput struct {
+	OutputType     string                     `json:"output_type"`
+	Name           string                     `json:"name"`
+	Text           notebookText               `json:"text"`
+	Data           map[string]json.RawMessage `json:"data"`
+	ExecutionCount *int                       `json:"execution_count"`
+	EName          string                     `json:"ename"`
+	EValue         string                     `json:"evalue"`
+	Traceback      []string                   `json:"traceback"`
+}
+
+// notebookText is multiline notebook text, stored either as one string or as
+// a list of lines that are joined as-is.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = notebookText(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return errors.New("notebook text must be a string or a list of strings")
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+// Render converts notebook JSON to HTML and extracts metadata.
+func (r *NotebookRenderer) Render(source []byte) ([]byte, *MarkdownMeta, error) {
+	var nb notebook
+	if err := json.Unmarshal(source, &nb); err != nil {
+		return nil, nil, fmt.Errorf("parse notebook: %w", err)
+	}
+	if nb.NBFormat < 4 {
+		return nil, nil, fmt.Errorf("unsupported nbformat %d", nb.NBFormat)
+	}
+
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.KernelSpec.Language
+	}
+	if lang == "" {
+		lang = "python"
+	}
+
+	meta := &MarkdownMeta{}
+	ids := parser.NewContext().IDs() // shared so heading IDs are unique across cells
+	var buf bytes.Buffer
+	buf.WriteString(`<div class="notebook">` + "\n")
+	for _, cell := range nb.Cells {
+		var err error
+		switch cell.CellType {
+		case "markdown":
+			err = r.writeMarkdownCell(&buf, cell, ids, meta)
+		case "code":
+			err = r.writeCodeCell(&buf, cell, lang, meta)
+		default: // raw
+			fmt.Fprintf(&buf, "<div class=\"notebook-cell notebook-raw\">\n<pre>%s</pre>\n</div>\n", gohtml.EscapeString(strings.TrimSuffix(string(cell.Source), "\n")))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	buf.WriteString("</div>\n")
+
+	if meta.Title == "" {
+		meta.Title = nb.Metadata.Title
+	}
+	return buf.Bytes(), meta, nil
+}
+
+func (r *NotebookRenderer) writeMarkdownCell(w *bytes.Buffer, cell notebookCell, ids parser.IDs, meta *MarkdownMeta) error {
+	src := string(cell.Source)
+	for name, bundle := range cell.Attachments {
+		for mime, data := range bundle {
+			if strings.HasPrefix(mime, "image/") {
+				uri := "data:" + mime + ";base64," + stripSpace(string(data))
+				src = strings.ReplaceAll(src, "attachment:"+name, uri)
+				break
+			}
+		}
+	}
+
+	html, m, err := r.md.render([]byte(src), parser.NewContext(parser.WithIDs(ids)))
+	if err != nil {
+		return err
+	}
+	if meta.Title == "" {
+		meta.Title = m.Title
+	}
+	meta.HeadingCount += m.HeadingCount
+	meta.Headings = append(meta.Headings, m.Headings...)
+	meta.CodeBlockCount += m.CodeBlockCount
+	meta.Languages = append(meta.Languages, m.Languages...)
+	meta.HasMermaid = meta.HasMermaid || m.HasMermaid
+
+	w.WriteString("<div class=\"notebook-cell notebook-markdown\">\n")
+	w.Write(html)
+	w.WriteString("</div>\n")
+	return nil
+}
+
+// cellMagicLangs maps IPython cell magics to the language of the cell body.
+var cellMagicLangs = map[string]string{
+	"bash":       "bash",
+	"sh":         "bash",
+	"html":       "html",
+	"javascript": "javascript",
+	"js":         "javascript",
+	"latex":      "latex",
+	"markdown":   "markdown",
+	"perl":       "perl",
+	"ruby":       "ruby",
+	"sql":        "sql",
+	"svg":        "xml",
+}
+
+var cellMagicRe = regexp.MustCompile(`\A%%(\w+)`)
+
+func (r *NotebookRenderer) writeCodeCell(w *bytes.Buffer, cell notebookCell, lang string, meta *MarkdownMeta) error {
+	code := strings.TrimSuffix(string(cell.Source), "\n")
+	if m := cellMagicRe.FindStringSubmatch(code); m != nil {
+		if l, ok := cellMagicLangs[m[1]]; ok {
+			lang = l
+		}
+	}
+	meta.CodeBlockCount++
+	meta.Languages = append(meta.Languages, lang)
+
+	w.WriteString("<div class=\"notebook-cell notebook-code\">\n")
+	w.WriteString("<div class=\"notebook-input\">\n")
+	fmt.Fprintf(w, "<div class=\"notebook-prompt\">In [%s]:</div>\n", executionCount(cell.ExecutionCount))
+	if err := writeCodeBlock(w, r.formatter, code, lang); err != nil {
+		return err
+	}
+	w.WriteString("\n</div>\n")
+
+	outputs := mergeStreams(cell.Outputs)
+	for _, out := range outputs {
+		if err := r.writeOutput(w, out); err != nil {
+			return err
+		}
+	}
+	w.WriteString("</div>\n")
+	return nil
+}
+
+func executionCount(n *int) string {
+	if n == nil {
+		return " "
+	}
+	return fmt.Sprint(*n)
+}
+
+// mergeStreams joins consecutive stream outputs to the same stream, which
+// kernels emit in arbitrary chunks.
+func mergeStreams(outputs []notebookOutput) []notebookOutput {
+	var merged []notebookOutput
+	for _, out := range outputs {
+		if n := len(merged); n > 0 && out.OutputType == "stream" &&
+			merged[n-1].OutputType == "stream" && merged[n-1].Name == out.Name {
+			merged[n-1].Text += out.Text
+			continue
+		}
+		merged = append(merged, out)
+	}
+	return merged
+}
+
+func (r *NotebookRenderer) writeOutput(w *bytes.Buffer, out notebookOutput) error {
+	prompt := ""
+	if out.OutputType == "execute_result" {
+		prompt = fmt.Sprintf("Out[%s]:", executionCount(out.ExecutionCount))
+	}
+	w.WriteString("<div class=\"notebook-output\">\n")
+	fmt.Fprintf(w, "<div class=\"notebook-prompt\">%s</div>\n", prompt)
+
+	switch out.OutputType {
+	case "stream":
+		class := "notebook-stream"
+		if out.Name == "stderr" {
+			class += " notebook-stderr"
+		}
+		writeNotebookText(w, class, resolveCarriageReturns(string(out.Text)))
+	case "error":
+		tb := strings.Join(out.Traceback, "\n")
+		if tb == "" {
+			tb = out.EName + ": " + out.EValue
+		}
+		writeNotebookText(w, "notebook-error", tb)
+	default: // execute_result, display_data
+		if err := r.writeMimeBundle(w, out.Data); err != nil {
+			return err
+		}
+	}
+	w.WriteString("</div>\n")
+	return nil
+}
+
+// writeMimeBundle renders the richest representation in a display bundle
+// that can be shown safely.
+func (r *NotebookRenderer) writeMimeBundle(w *bytes.Buffer, data map[string]json.RawMessage) error {
+	text := func(mime string) (string, bool) {
+		raw, ok := data[mime]
+		if !ok {
+			return "", false
+		}
+		var t notebookText
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return "", false
+		}
+		return string(t), true
+	}
+
+	if s, ok := text("text/html"); ok && len(s) <= notebookMaxHTML {
+		w.WriteString("<div class=\"notebook-html\">\n" + s + "\n</div>\n")
+		return nil
+	}
+	if s, ok := text("image/svg+xml"); ok {
+		writeNotebookImage(w, "image/svg+xml", base64.StdEncoding.EncodeToString([]byte(s)))
+		return nil
+	}
+	for _, mime := range []string{"image/png", "image/jpeg", "image/gif", "image/webp"} {
+		if s, ok := text(mime); ok {
+			writeNotebookImage(w, mime, stripSpace(s))
+			return nil
+		}
+	}
+	if s, ok := text("text/markdown"); ok {
+		html, _, err := r.md.Render([]byte(s))
+		if err != nil {
+			return err
+		}
+		w.WriteString("<div class=\"notebook-markdown-output\">\n")
+		w.Write(html)
+		w.WriteString("</div>\n")
+		return nil
+	}
+	if s, ok := text("text/latex"); ok {
+		writeNotebookText(w, "notebook-latex", s)
+		return nil
+	}
+	if raw, ok := data["application/json"]; ok {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, raw, "", "  "); err == nil {
+			code, notice := truncateOutput(pretty.String(), notebookMaxText)
+			if err := writeCodeBlock(w, r.formatter, code, "json"); err != nil {
+				return err
+			}
+			w.WriteString("\n" + notice)
+			return nil
+		}
+	}
+	if s, ok := text("text/plain"); ok {
+		writeNotebookText(w, "notebook-text", s)
+	}
+	return nil
+}
+
+// writeNotebookText writes terminal output, truncated and with its ANSI
+// colors turned into classes.
+func writeNotebookText(w io.Writer, class, s string) {
+	s, notice := truncateOutput(s, notebookMaxText)
+	fmt.Fprintf(w, "<pre class=\"%s\">%s</pre>\n%s", class, ansiToHTML(strings.TrimSuffix(s, "\n")), notice)
+}
+
+func writeNotebookImage(w io.Writer, mime, data string) {
+	if len(data) > notebookMaxImage {
+		fmt.Fprintf(w, "<p class=\"notebook-truncated\">Image omitted (%s, %s)</p>\n", mime, formatBytes(len(data)*3/4))
+		return
+	}
+	fmt.Fprintf(w, "<img src=\"data:%s;base64,%s\" alt=\"output\">\n", mime, data)
+}
+
+// truncateOutput cuts s to at most limit bytes at a line break and returns
+// a notice to show after it, or "" if s fits.
+func truncateOutput(s string, limit int) (string, string) {
+	if len(s) <= limit {
+		return s, ""
+	}
+	cut := cutAtLine(s, limit)
+	notice := fmt.Sprintf("<p class=\"notebook-truncated\">Output truncated: showing %s of %s</p>\n",
+		formatBytes(len(cut)), formatBytes(len(s)))
+	return cut, notice
+}
+
+// cutAtLine returns the longest prefix of s of at most limit bytes that ends
+// at a line break, or the first limit bytes if there is none.
+func cutAtLine(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := s[:limit]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut
+}
+
+// resolveCarriageReturns keeps what a terminal would show for progress
+// output that redraws a line with "\r".
+func resolveCarriageReturns(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		lines[i] = line[strings.LastIndexByte(line, '\r')+1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
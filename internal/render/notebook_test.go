@@ -0,0 +1,139 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotebookRenderer_Cells(t *testing.T) {
+	r := NewNotebookRenderer()
+	input := `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Intro\n", "\n", "## Setup\n"]},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "outputs": [], "source": "x <- 1"},
+  {"cell_type": "markdown", "metadata": {}, "source": "## Setup"},
+  {"cell_type": "code", "execution_count": null, "metadata": {}, "outputs": [], "source": "%%bash\nls"}
+ ],
+ "metadata": {"kernelspec": {"language": "R", "name": "ir"}, "language_info": {"name": "R"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+	html, meta, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+
+	want := []Heading{
+		{Level: 1, Text: "Intro", ID: "intro"},
+		{Level: 2, Text: "Setup", ID: "setup"},
+		{Level: 2, Text: "Setup", ID: "setup-1"},
+	}
+	if len(meta.Headings) != len(want) {
+		t.Fatalf("got %d headings, want %d: %+v", len(meta.Headings), len(want), meta.Headings)
+	}
+	for i, h := range want {
+		if meta.Headings[i] != h {
+			t.Errorf("heading %d = %+v, want %+v", i, meta.Headings[i], h)
+		}
+	}
+	if meta.Title != "Intro" {
+		t.Errorf("Title = %q, want Intro", meta.Title)
+	}
+	if strings.Join(meta.Languages, ",") != "R,bash" {
+		t.Errorf("Languages = %v, want [R bash]", meta.Languages)
+	}
+	for _, want := range []string{
+		`<div class="notebook-prompt">In [3]:</div>`,
+		`<div class="notebook-prompt">In [ ]:</div>`,
+		`data-language="R"`,
+		`data-language="bash"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+}
+
+func TestNotebookRenderer_Streams(t *testing.T) {
+	r := NewNotebookRenderer()
+	input := `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "code", "execution_count": 1, "source": "", "outputs": [
+	  {"output_type": "stream", "name": "stdout", "text": "a\n"},
+	  {"output_type": "stream", "name": "stdout", "text": ["b\n", "10%\r50%\r100%\n"]},
+	  {"output_type": "stream", "name": "stderr", "text": "<warn>\n"}
+	]}]}`
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<pre class="notebook-stream">a
+b
+100%</pre>`,
+		`<pre class="notebook-stream notebook-stderr">&lt;warn&gt;</pre>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+}
+
+func TestNotebookRenderer_Truncation(t *testing.T) {
+	r := NewNotebookRenderer()
+	line := strings.Repeat("x", 99) + `\n`
+	big := strings.Repeat(line, 1000) // 100,000 bytes
+	image := strings.Repeat("A", notebookMaxImage+4)
+	input := `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "code", "execution_count": 1, "source": "", "outputs": [
+	  {"output_type": "stream", "name": "stdout", "text": "` + big + `"},
+	  {"output_type": "display_data", "data": {"image/png": "` + image + `", "text/plain": "<Figure>"}}
+	]}]}`
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	if len(got) > notebookMaxText+4096 {
+		t.Errorf("output is %d bytes, want it truncated", len(got))
+	}
+	for _, want := range []string{
+		`<p class="notebook-truncated">Output truncated: showing 64.0 KB of 97.7 KB</p>`,
+		`<p class="notebook-truncated">Image omitted (image/png, 1.5 MB)</p>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output", want)
+		}
+	}
+}
+
+func TestNotebookRenderer_Invalid(t *testing.T) {
+	r := NewNotebookRenderer()
+	for name, input := range map[string]string{
+		"not json":   `{"cells": [`,
+		"nbformat 3": `{"nbformat": 3, "worksheets": []}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := r.Render([]byte(input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestANSIToHTML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain <text>", "plain &lt;text&gt;"},
+		{"\x1b[31mred\x1b[0m off", `<span class="ansi-red-fg">red</span> off`},
+		{"\x1b[1;92;44mhi\x1b[39mx\x1b[m", `<span class="ansi-green-intense-fg ansi-blue-bg ansi-bold">hi</span><span class="ansi-blue-bg ansi-bold">x</span>`},
+		{"\x1b[38;5;9ma\x1b[38;5;200mb\x1b[38;2;1;2;3mc", `<span class="ansi-red-intense-fg">a</span>bc`},
+		{"\x1b]8;;https://x\x07link\x1b]8;;\x1b\\ \x1b[2Kdone", "link done"},
+	}
+	for _, tc := range tests {
+		if got := ansiToHTML(tc.input); got != tc.want {
+			t.Errorf("ansiToHTML(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,1513 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gohtml "html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// RefLoader fetches a document referenced from an OpenAPI description, given
+// its absolute URL.
+type RefLoader func(url string) ([]byte, error)
+
+// Limits that keep rendering bounded for large and self-referencing
+// descriptions.
+const (
+	openAPIMaxRefDocs = 32        // external documents loaded per description
+	openAPIMaxRefHops = 32        // $refs followed to reach a target
+	openAPIMaxDepth   = 8         // schemas nested inside one another
+	openAPIMaxExample = 64 << 10  // bytes of a JSON example
+	openAPIMaxEnum    = 20        // enum values listed
+	openAPIMaxSource  = 8 << 20   // bytes of a description
+	openAPIMaxWork    = 200000    // schemas visited while writing the page
+	openAPISniffLen   = 256 << 10 // bytes searched for top-level YAML keys
+)
+
+// openAPIMethods are the operations of a path item, in the order they are
+// usually documented.
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+var (
+	openAPIYAMLVersion = regexp.MustCompile(`(?m)^(openapi|swagger)[ \t]*:[ \t]*["']?(3\.\d[\w.-]*|2\.0)["']?[ \t]*(#.*)?\r?$`)
+	openAPIYAMLInfo    = regexp.MustCompile(`(?m)^info[ \t]*:`)
+)
+
+// isOpenAPI reports whether a JSON or YAML document declares an OpenAPI 3.x
+// or Swagger 2.0 description in its top-level keys.
+func isOpenAPI(body []byte, language string) bool {
+	body = bytes.TrimPrefix(body, []byte("\ufeff"))
+	switch language {
+	case "json":
+		var top struct {
+			OpenAPI string          `json:"openapi"`
+			Swagger string          `json:"swagger"`
+			Info    json.RawMessage `json:"info"`
+		}
+		if json.Unmarshal(body, &top) != nil || top.Info == nil {
+			return false
+		}
+		return strings.HasPrefix(top.OpenAPI, "3.") || top.Swagger == "2.0"
+	case "yaml":
+		head := body[:min(len(body), openAPISniffLen)]
+		return openAPIYAMLVersion.Match(head) && openAPIYAMLInfo.Match(head)
+	}
+	return false
+}
+
+// OpenAPIRenderer renders OpenAPI 3.x and Swagger 2.0 descriptions as an API
+// reference: operations grouped by tag, with their parameters, request and
+// response schemas, and examples.
+type OpenAPIRenderer struct {
+	md        *MarkdownRenderer
+	tree      *TreeRenderer
+	formatter *chromahtml.Formatter
+}
+
+// NewOpenAPIRenderer creates a new OpenAPI renderer.
+func NewOpenAPIRenderer() *OpenAPIRenderer {
+	return &OpenAPIRenderer{
+		md:        NewMarkdownRenderer(),
+		tree:      NewTreeRenderer(),
+		formatter: chromahtml.New(chromahtml.WithClasses(true)),
+	}
+}
+
+// Render renders a description written in language ("json" or "yaml";
+// anything else is detected from the content). $refs within the document
+// are resolved; refs to other files are resolved against baseURL, the
+// description's own URL, and fetched with load. With no baseURL or load,
+// such refs are shown unresolved. A document that does not parse or is not
+// a description is shown as source, with its tree view.
+func (r *OpenAPIRenderer) Render(source []byte, language, baseURL string, load RefLoader) ([]byte, *MarkdownMeta, error) {
+	if language != "json" && language != "yaml" {
+		language = specLanguage(source)
+	}
+	var root *treeNode
+	if len(source) <= openAPIMaxSource {
+		root, _ = parseSpec(source, language)
+	}
+	if specVersion(root) == "" {
+		html, err := r.tree.Render(source, language)
+		if err != nil {
+			return nil, nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteString("<p class=\"cooked-openapi-notice\">Not an OpenAPI 3.x or Swagger 2.0 description, so it is shown as source.</p>\n")
+		buf.Write(html)
+		return buf.Bytes(), nil, nil
+	}
+
+	w := &specWriter{
+		r:       r,
+		meta:    &MarkdownMeta{},
+		ids:     parser.NewContext().IDs(),
+		main:    &specDoc{url: baseURL, root: root},
+		docs:    map[string]*specDoc{},
+		load:    load,
+		swagger: root.get("swagger") != nil,
+		anchors: map[*treeNode]specAnchor{},
+	}
+	if u, err := url.Parse(baseURL); err == nil && baseURL != "" {
+		u.Fragment = ""
+		w.main.url = u.String()
+		w.docs[w.main.url] = w.main
+	}
+	if err := w.write(); err != nil {
+		return nil, nil, err
+	}
+	return w.buf.Bytes(), w.meta, nil
+}
+
+// specLanguage guesses whether an untyped description is JSON or YAML.
+func specLanguage(source []byte) string {
+	if t := bytes.TrimLeft(source, " \t\r\n\ufeff"); len(t) > 0 && t[0] == '{' {
+		return "json"
+	}
+	return "yaml"
+}
+
+// parseSpec parses a JSON document, or the first document of a YAML stream.
+func parseSpec(source []byte, language string) (*treeNode, error) {
+	source = bytes.TrimPrefix(source, []byte("\ufeff"))
+	if language == "json" {
+		return parseJSONTree(source)
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(source)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return specTree(&doc, map[*yaml.Node]*treeNode{}), nil
+}
+
+// specVersion returns the OpenAPI or Swagger version a document declares,
+// or "" if it is not a description cooked can render.
+func specVersion(root *treeNode) string {
+	if root.get("info") == nil {
+		return ""
+	}
+	if v := root.get("openapi"); v != nil && strings.HasPrefix(v.value, "3.") {
+		return "OpenAPI " + v.value
+	}
+	if v := root.get("swagger"); v != nil && v.value == "2.0" {
+		return "Swagger 2.0"
+	}
+	return ""
+}
+
+// specTree converts a YAML node like yamlTree, but follows aliases and merge
+// keys as a description's readers do. Aliased nodes are shared rather than
+// copied, so aliases cannot multiply the size of the tree.
+func specTree(y *yaml.Node, memo map[*yaml.Node]*treeNode) *treeNode {
+	if n, ok := memo[y]; ok {
+		return n
+	}
+	switch y.Kind {
+	case yaml.DocumentNode:
+		if len(y.Content) == 0 {
+			return &treeNode{kind: "null", value: "null"}
+		}
+		return specTree(y.Content[0], memo)
+	case yaml.AliasNode:
+		return specTree(y.Alias, memo)
+	case yaml.MappingNode:
+		n := &treeNode{kind: "object"}
+		memo[y] = n
+		var merges []*treeNode
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			if y.Content[i].ShortTag() == "!!merge" {
+				merges = append(merges, specTree(y.Content[i+1], memo))
+				continue
+			}
+			n.keys = append(n.keys, y.Content[i].Value)
+			n.items = append(n.items, specTree(y.Content[i+1], memo))
+		}
+		// Merged keys never override the mapping's own.
+		for _, m := range merges {
+			sources := []*treeNode{m}
+			if m.kind == "array" {
+				sources = m.items
+			}
+			for _, src := range sources {
+				if src.kind != "object" || src == n {
+					continue
+				}
+				for j, k := range src.keys {
+					if n.get(k) == nil {
+						n.keys = append(n.keys, k)
+						n.items = append(n.items, src.items[j])
+					}
+				}
+			}
+		}
+		return n
+	case yaml.SequenceNode:
+		n := &treeNode{kind: "array"}
+		memo[y] = n
+		for _, c := range y.Content {
+			n.items = append(n.items, specTree(c, memo))
+		}
+		return n
+	}
+	return yamlTree(y)
+}
+
+// specDoc is a description, or a file it references.
+type specDoc struct {
+	url  string // absolute URL without fragment; "" when unknown
+	root *treeNode
+}
+
+// specAnchor is a component schema rendered in its own section, which refs
+// to it link to.
+type specAnchor struct {
+	name string
+	id   string
+}
+
+// specProp is an object property, with the document its schema is in.
+type specProp struct {
+	name   string
+	doc    *specDoc
+	schema *treeNode
+}
+
+// specOp is an operation and where it is found.
+type specOp struct {
+	method string
+	path   string
+	doc    *specDoc
+	op     *treeNode
+	params *treeNode // the path item's parameters
+}
+
+// specWriter renders one description.
+type specWriter struct {
+	r       *OpenAPIRenderer
+	buf     bytes.Buffer
+	meta    *MarkdownMeta
+	ids     parser.IDs
+	main    *specDoc
+	docs    map[string]*specDoc // loaded documents by URL; nil if loading failed
+	load    RefLoader
+	swagger bool
+	anchors map[*treeNode]specAnchor
+	work    int // schemas visited, see spent
+}
+
+// spent counts a schema visited and reports whether the page has used up its
+// budget. Nodes shared through YAML aliases can be reached along many paths,
+// so the work is bounded by count rather than by the size of the document.
+func (w *specWriter) spent() bool {
+	w.work++
+	return w.work > openAPIMaxWork
+}
+
+func (w *specWriter) write() error {
+	root := w.main.root
+	w.buf.WriteString("<div class=\"cooked-openapi\">\n")
+	w.writeInfo()
+
+	// Component schemas get sections of their own, so that refs to them
+	// are links rather than copies. Their IDs are taken first so that every
+	// link can be written before its target.
+	schemas := root.get("components").get("schemas")
+	if w.swagger {
+		schemas = root.get("definitions")
+	}
+	if schemas != nil {
+		for i, name := range schemas.keys {
+			id := string(w.ids.Generate([]byte("schema-"+name), ast.KindHeading))
+			w.anchors[schemas.items[i]] = specAnchor{name: name, id: id}
+		}
+	}
+
+	if err := w.writeOperations(); err != nil {
+		return err
+	}
+	w.writeSecuritySchemes()
+	if schemas != nil && len(schemas.keys) > 0 {
+		w.heading(2, "Schemas", "", true)
+		for i, name := range schemas.keys {
+			s := schemas.items[i]
+			a := w.anchors[s]
+			w.buf.WriteString("<div class=\"cooked-openapi-schema-def\">\n")
+			fmt.Fprintf(&w.buf, "<h3 id=\"%s\">%s</h3>\n", gohtml.EscapeString(a.id), gohtml.EscapeString(name))
+			w.meta.HeadingCount++
+			d, t, ref := w.deref(w.main, s)
+			if t == nil {
+				fmt.Fprintf(&w.buf, "<p>%s</p>\n</div>\n", unresolved(ref))
+				continue
+			}
+			fmt.Fprintf(&w.buf, "<div class=\"cooked-openapi-schema\">\n<p class=\"cooked-openapi-type\">%s</p>\n", w.typeLabel(d, t, 0))
+			w.markdown(t.get("description"))
+			w.writeConstraints(d, t)
+			w.writeSchemaBody(d, t, 0, map[*treeNode]bool{})
+			w.buf.WriteString("</div>\n</div>\n")
+		}
+	}
+	w.buf.WriteString("</div>\n")
+	return nil
+}
+
+// heading writes a heading, adding it to the TOC when toc is set. The ID is
+// generated from idText, or from text when idText is empty.
+func (w *specWriter) heading(level int, text, idText string, toc bool) string {
+	return w.headingHTML(level, text, gohtml.EscapeString(text), idText, toc)
+}
+
+func (w *specWriter) headingHTML(level int, text, inner, idText string, toc bool) string {
+	if idText == "" {
+		idText = text
+	}
+	id := string(w.ids.Generate([]byte(idText), ast.KindHeading))
+	fmt.Fprintf(&w.buf, "<h%d id=\"%s\">%s</h%d>\n", level, gohtml.EscapeString(id), inner, level)
+	w.meta.HeadingCount++
+	if toc {
+		w.meta.Headings = append(w.meta.Headings, Heading{Level: level, Text: text, ID: id})
+	}
+	return id
+}
+
+// markdown writes a CommonMark description.
+func (w *specWriter) markdown(n *treeNode) {
+	if n == nil || n.kind == "object" || n.kind == "array" || strings.TrimSpace(n.value) == "" {
+		return
+	}
+	html, _, err := w.r.md.render([]byte(n.value), parser.NewContext(parser.WithIDs(w.ids)))
+	if err != nil {
+		fmt.Fprintf(&w.buf, "<p>%s</p>\n", gohtml.EscapeString(n.value))
+		return
+	}
+	w.buf.WriteString("<div class=\"cooked-openapi-description\">\n")
+	w.buf.Write(html)
+	w.buf.WriteString("</div>\n")
+}
+
+func (w *specWriter) writeInfo() {
+	root := w.main.root
+	info := root.get("info")
+	title := scalar(info.get("title"))
+	if title == "" {
+		title = "API reference"
+	}
+	w.meta.Title = title
+	w.heading(1, title, "", true)
+
+	w.buf.WriteString("<p class=\"cooked-openapi-badges\">")
+	if v := scalar(info.get("version")); v != "" {
+		fmt.Fprintf(&w.buf, "<span class=\"cooked-openapi-badge\">Version %s</span> ", gohtml.EscapeString(v))
+	}
+	fmt.Fprintf(&w.buf, "<span class=\"cooked-openapi-badge\">%s</span></p>\n", gohtml.EscapeString(specVersion(root)))
+	w.markdown(info.get("description"))
+
+	var rows [][2]string
+	if servers := w.servers(root); len(servers) > 0 {
+		rows = append(rows, [2]string{"Servers", strings.Join(servers, "<br>")})
+	}
+	if t := scalar(info.get("termsOfService")); t != "" {
+		rows = append(rows, [2]string{"Terms of service", link(t, t)})
+	}
+	if c := info.get("contact"); c != nil {
+		var parts []string
+		if name := scalar(c.get("name")); name != "" {
+			parts = append(parts, gohtml.EscapeString(name))
+		}
+		if email := scalar(c.get("email")); email != "" {
+			parts = append(parts, link("mailto:"+email, email))
+		}
+		if u := scalar(c.get("url")); u != "" {
+			parts = append(parts, link(u, u))
+		}
+		if len(parts) > 0 {
+			rows = append(rows, [2]string{"Contact", strings.Join(parts, " · ")})
+		}
+	}
+	if l := info.get("license"); l != nil {
+		name := gohtml.EscapeString(scalar(l.get("name")))
+		if u := scalar(l.get("url")); u != "" {
+			name = link(u, scalar(l.get("name")))
+		}
+		rows = append(rows, [2]string{"License", name})
+	}
+	if d := root.get("externalDocs"); d != nil {
+		rows = append(rows, [2]string{"Documentation", externalDocs(d)})
+	}
+	if len(rows) > 0 {
+		w.buf.WriteString("<dl class=\"cooked-openapi-info\">\n")
+		for _, row := range rows {
+			fmt.Fprintf(&w.buf, "<dt>%s</dt><dd>%s</dd>\n", row[0], row[1])
+		}
+		w.buf.WriteString("</dl>\n")
+	}
+}
+
+// servers lists the base URLs of the API as HTML.
+func (w *specWriter) servers(root *treeNode) []string {
+	var out []string
+	if w.swagger {
+		host := scalar(root.get("host"))
+		if host == "" {
+			return nil
+		}
+		schemes := []string{"https"}
+		if s := root.get("schemes"); s != nil && len(s.items) > 0 {
+			schemes = nil
+			for _, item := range s.items {
+				schemes = append(schemes, item.value)
+			}
+		}
+		for _, scheme := range schemes {
+			out = append(out, "<code>"+gohtml.EscapeString(scheme+"://"+host+scalar(root.get("basePath")))+"</code>")
+		}
+		return out
+	}
+	for _, s := range root.get("servers").arrayItems() {
+		u := scalar(s.get("url"))
+		if u == "" {
+			continue
+		}
+		entry := "<code>" + gohtml.EscapeString(u) + "</code>"
+		if d := scalar(s.get("description")); d != "" {
+			entry += " — " + gohtml.EscapeString(d)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// writeOperations writes the operations of every path, grouped by their
+// first tag. Tags declared at the top level come first, in their order;
+// untagged operations are grouped under "default".
+func (w *specWriter) writeOperations() error {
+	root := w.main.root
+	var order []string
+	groups := map[string][]specOp{}
+	descriptions := map[string]*treeNode{}
+	for _, t := range root.get("tags").arrayItems() {
+		name := scalar(t.get("name"))
+		if name == "" || descriptions[name] != nil {
+			continue
+		}
+		order = append(order, name)
+		descriptions[name] = t
+		groups[name] = nil
+	}
+
+	paths := root.get("paths")
+	if paths != nil {
+		for i, p := range paths.keys {
+			doc, item, _ := w.deref(w.main, paths.items[i])
+			if item == nil {
+				continue
+			}
+			for j, method := range item.keys {
+				if !openAPIMethods[method] || item.items[j].kind != "object" {
+					continue
+				}
+				op := item.items[j]
+				tag := "default"
+				if tags := op.get("tags").arrayItems(); len(tags) > 0 && scalar(tags[0]) != "" {
+					tag = scalar(tags[0])
+				}
+				if _, ok := groups[tag]; !ok {
+					order = append(order, tag)
+				}
+				groups[tag] = append(groups[tag], specOp{method: method, path: p, doc: doc, op: op, params: item.get("parameters")})
+			}
+		}
+	}
+
+	for _, tag := range order {
+		ops := groups[tag]
+		if len(ops) == 0 {
+			continue
+		}
+		w.buf.WriteString("<div class=\"cooked-openapi-tag\">\n")
+		w.heading(2, tag, "", true)
+		if t := descriptions[tag]; t != nil {
+			w.markdown(t.get("description"))
+			if d := t.get("externalDocs"); d != nil {
+				fmt.Fprintf(&w.buf, "<p>%s</p>\n", externalDocs(d))
+			}
+		}
+		for _, op := range ops {
+			if err := w.writeOperation(op); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteString("</div>\n")
+	}
+	return nil
+}
+
+func (w *specWriter) writeOperation(o specOp) error {
+	op := o.op
+	method := strings.ToUpper(o.method)
+	deprecated := scalar(op.get("deprecated")) == "true"
+
+	class := "cooked-openapi-op"
+	if deprecated {
+		class += " cooked-openapi-deprecated"
+	}
+	fmt.Fprintf(&w.buf, "<div class=\"%s\">\n", class)
+	inner := fmt.Sprintf("<span class=\"cooked-openapi-method cooked-openapi-method-%s\">%s</span> <code>%s</code>",
+		o.method, method, gohtml.EscapeString(o.path))
+	idText := scalar(op.get("operationId"))
+	if idText == "" {
+		idText = method + " " + o.path
+	}
+	w.headingHTML(3, method+" "+o.path, inner, idText, true)
+
+	if deprecated {
+		w.buf.WriteString("<p><span class=\"cooked-openapi-flag\">Deprecated</span></p>\n")
+	}
+	if s := scalar(op.get("summary")); s != "" {
+		fmt.Fprintf(&w.buf, "<p class=\"cooked-openapi-summary\"><strong>%s</strong></p>\n", gohtml.EscapeString(s))
+	}
+	w.markdown(op.get("description"))
+
+	var facts [][2]string
+	if id := scalar(op.get("operationId")); id != "" {
+		facts = append(facts, [2]string{"Operation ID", "<code>" + gohtml.EscapeString(id) + "</code>"})
+	}
+	if tags := op.get("tags").arrayItems(); len(tags) > 1 {
+		var names []string
+		for _, t := range tags {
+			names = append(names, gohtml.EscapeString(scalar(t)))
+		}
+		facts = append(facts, [2]string{"Tags", strings.Join(names, ", ")})
+	}
+	security := op.get("security")
+	if security == nil {
+		security = w.main.root.get("security")
+	}
+	if security != nil {
+		facts = append(facts, [2]string{"Security", securityRequirements(security)})
+	}
+	if d := op.get("externalDocs"); d != nil {
+		facts = append(facts, [2]string{"Documentation", externalDocs(d)})
+	}
+	if len(facts) > 0 {
+		w.buf.WriteString("<dl class=\"cooked-openapi-facts\">\n")
+		for _, f := range facts {
+			fmt.Fprintf(&w.buf, "<dt>%s</dt><dd>%s</dd>\n", f[0], f[1])
+		}
+		w.buf.WriteString("</dl>\n")
+	}
+
+	params, body := w.parameters(o)
+	if len(params) > 0 {
+		w.buf.WriteString("<h4>Parameters</h4>\n")
+		w.writeParameters(params)
+	}
+	if w.swagger {
+		if body.schema != nil {
+			w.buf.WriteString("<h4>Request body</h4>\n")
+			d, p, _ := w.deref(body.doc, body.schema)
+			if scalar(p.get("required")) == "true" {
+				w.buf.WriteString("<p><span class=\"cooked-openapi-flag\">Required</span></p>\n")
+			}
+			w.markdown(p.get("description"))
+			for _, mt := range w.mediaTypes(op, "consumes") {
+				w.writeMedia(d, mt, p.get("schema"), nil, nil)
+			}
+		}
+	} else if rb := op.get("requestBody"); rb != nil {
+		w.buf.WriteString("<h4>Request body</h4>\n")
+		d, rb, ref := w.deref(o.doc, rb)
+		if rb == nil {
+			fmt.Fprintf(&w.buf, "<p>%s</p>\n", unresolved(ref))
+		} else {
+			if scalar(rb.get("required")) == "true" {
+				w.buf.WriteString("<p><span class=\"cooked-openapi-flag\">Required</span></p>\n")
+			}
+			w.markdown(rb.get("description"))
+			w.writeContent(d, rb.get("content"))
+		}
+	}
+
+	if responses := op.get("responses"); responses != nil && len(responses.keys) > 0 {
+		w.buf.WriteString("<h4>Responses</h4>\n")
+		for i, code := range responses.keys {
+			w.writeResponse(o, code, responses.items[i])
+		}
+	}
+	w.buf.WriteString("</div>\n")
+	return nil
+}
+
+// parameters merges the path item's parameters with the operation's, which
+// override them by name and location. A Swagger 2.0 body parameter is
+// returned apart, as it describes the request body.
+func (w *specWriter) parameters(o specOp) ([]specProp, specProp) {
+	var params []specProp
+	var body specProp
+	index := map[string]int{}
+	for _, list := range []*treeNode{o.params, o.op.get("parameters")} {
+		for _, item := range list.arrayItems() {
+			d, p, ref := w.deref(o.doc, item)
+			if p == nil {
+				params = append(params, specProp{name: ref, doc: d})
+				continue
+			}
+			in := scalar(p.get("in"))
+			if in == "body" {
+				body = specProp{name: scalar(p.get("name")), doc: o.doc, schema: item}
+				continue
+			}
+			key := in + "\x00" + scalar(p.get("name"))
+			if i, ok := index[key]; ok {
+				params[i] = specProp{name: key, doc: d, schema: p}
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, specProp{name: key, doc: d, schema: p})
+		}
+	}
+	return params, body
+}
+
+func (w *specWriter) writeParameters(params []specProp) {
+	w.buf.WriteString("<table class=\"cooked-openapi-params\">\n<thead>\n<tr><th>Name</th><th>In</th><th>Type</th><th>Description</th></tr>\n</thead>\n<tbody>\n")
+	for _, prop := range params {
+		p := prop.schema
+		if p == nil {
+			fmt.Fprintf(&w.buf, "<tr><td colspan=\"4\">%s</td></tr>\n", unresolved(prop.name))
+			continue
+		}
+		w.buf.WriteString("<tr><td><code>" + gohtml.EscapeString(scalar(p.get("name"))) + "</code>")
+		if scalar(p.get("required")) == "true" {
+			w.buf.WriteString(" <span class=\"cooked-openapi-required\" title=\"Required\">required</span>")
+		}
+		if scalar(p.get("deprecated")) == "true" {
+			w.buf.WriteString(" <span class=\"cooked-openapi-flag\">Deprecated</span>")
+		}
+		fmt.Fprintf(&w.buf, "</td><td>%s</td><td>", gohtml.EscapeString(scalar(p.get("in"))))
+
+		// OpenAPI 3 describes the value with a schema (or a single media
+		// type); Swagger 2.0 with the parameter's own type fields.
+		schema := p
+		if !w.swagger {
+			schema = p.get("schema")
+			if c := p.get("content"); schema == nil && c != nil && len(c.items) > 0 {
+				schema = c.items[0].get("schema")
+			}
+		}
+		w.buf.WriteString(w.schemaType(prop.doc, schema, 0))
+		w.buf.WriteString("</td><td>\n")
+		w.markdown(p.get("description"))
+		w.writeConstraints(prop.doc, schema)
+		if ex := p.get("example"); ex != nil {
+			fmt.Fprintf(&w.buf, "<p>Example: <code>%s</code></p>\n", gohtml.EscapeString(exampleText(ex)))
+		}
+		for i, name := range p.get("examples").objectKeys() {
+			_, e, _ := w.deref(prop.doc, p.get("examples").items[i])
+			if v := e.get("value"); v != nil {
+				fmt.Fprintf(&w.buf, "<p>Example (%s): <code>%s</code></p>\n", gohtml.EscapeString(name), gohtml.EscapeString(exampleText(v)))
+			}
+		}
+		w.buf.WriteString("</td></tr>\n")
+	}
+	w.buf.WriteString("</tbody>\n</table>\n")
+}
+
+func (w *specWriter) writeResponse(o specOp, code string, resp *treeNode) {
+	class := "cooked-openapi-status"
+	if code != "" && code[0] >= '1' && code[0] <= '5' {
+		class += " cooked-openapi-status-" + code[:1] + "xx"
+	}
+	w.buf.WriteString("<div class=\"cooked-openapi-response\">\n")
+	fmt.Fprintf(&w.buf, "<p><span class=\"%s\">%s</span></p>\n", class, gohtml.EscapeString(code))
+	d, resp, ref := w.deref(o.doc, resp)
+	if resp == nil {
+		fmt.Fprintf(&w.buf, "<p>%s</p>\n</div>\n", unresolved(ref))
+		return
+	}
+	w.markdown(resp.get("description"))
+
+	if headers := resp.get("headers"); headers != nil && len(headers.keys) > 0 {
+		w.buf.WriteString("<table class=\"cooked-openapi-params\">\n<thead>\n<tr><th>Header</th><th>Type</th><th>Description</th></tr>\n</thead>\n<tbody>\n")
+		for i, name := range headers.keys {
+			hd, h, _ := w.deref(d, headers.items[i])
+			schema := h
+			if !w.swagger {
+				schema = h.get("schema")
+			}
+			fmt.Fprintf(&w.buf, "<tr><td><code>%s</code></td><td>%s</td><td>\n", gohtml.EscapeString(name), w.schemaType(hd, schema, 0))
+			w.markdown(h.get("description"))
+			w.buf.WriteString("</td></tr>\n")
+		}
+		w.buf.WriteString("</tbody>\n</table>\n")
+	}
+
+	if w.swagger {
+		schema := resp.get("schema")
+		examples := resp.get("examples")
+		if schema == nil && examples == nil {
+			w.buf.WriteString("</div>\n")
+			return
+		}
+		for _, mt := range w.mediaTypes(o.op, "produces") {
+			w.writeMedia(d, mt, schema, examples.get(mt), nil)
+		}
+	} else {
+		w.writeContent(d, resp.get("content"))
+	}
+	w.buf.WriteString("</div>\n")
+}
+
+// mediaTypes returns a Swagger 2.0 operation's consumes or produces list,
+// falling back to the description's.
+func (w *specWriter) mediaTypes(op *treeNode, key string) []string {
+	list := op.get(key)
+	if list == nil {
+		list = w.main.root.get(key)
+	}
+	var out []string
+	for _, item := range list.arrayItems() {
+		if mt := scalar(item); mt != "" {
+			out = append(out, mt)
+		}
+	}
+	if len(out) == 0 {
+		out = []string{"application/json"}
+	}
+	return out
+}
+
+// writeContent writes an OpenAPI 3 content map.
+func (w *specWriter) writeContent(doc *specDoc, content *treeNode) {
+	for i, mt := range content.objectKeys() {
+		media := content.items[i]
+		w.writeMedia(doc, mt, media.get("schema"), media.get("example"), media.get("examples"))
+	}
+}
+
+// writeMedia writes one media type of a body: its schema and examples. When
+// the description gives no example, one is generated from the schema for
+// JSON media types.
+func (w *specWriter) writeMedia(doc *specDoc, mediaType string, schema, example, examples *treeNode) {
+	w.buf.WriteString("<div class=\"cooked-openapi-media\">\n")
+	fmt.Fprintf(&w.buf, "<p class=\"cooked-openapi-media-type\"><code>%s</code></p>\n", gohtml.EscapeString(mediaType))
+	if schema != nil {
+		w.writeSchema(doc, schema, true)
+	}
+
+	shown := false
+	if example != nil {
+		w.writeExample("Example", mediaType, example, true)
+		shown = true
+	}
+	for i, name := range examples.objectKeys() {
+		_, e, ref := w.deref(doc, examples.items[i])
+		title := "Example: " + name
+		if s := scalar(e.get("summary")); s != "" {
+			title += " — " + s
+		}
+		switch {
+		case e == nil:
+			fmt.Fprintf(&w.buf, "<p>%s: %s</p>\n", gohtml.EscapeString(title), unresolved(ref))
+		case e.get("value") != nil:
+			w.writeExample(title, mediaType, e.get("value"), true)
+		case scalar(e.get("externalValue")) != "":
+			fmt.Fprintf(&w.buf, "<p>%s: %s</p>\n", gohtml.EscapeString(title), link(scalar(e.get("externalValue")), scalar(e.get("externalValue"))))
+		}
+		shown = true
+	}
+	if !shown && schema != nil && isJSONMedia(mediaType) {
+		if v := w.exampleFor(doc, schema, 0, map[*treeNode]bool{}); v != nil {
+			w.writeExample("Example (generated from the schema)", mediaType, v, false)
+		}
+	}
+	w.buf.WriteString("</div>\n")
+}
+
+func isJSONMedia(mediaType string) bool {
+	mt, _, _ := strings.Cut(mediaType, ";")
+	mt = strings.TrimSpace(strings.ToLower(mt))
+	return mt == "*/*" || strings.HasSuffix(mt, "/json") || strings.HasSuffix(mt, "+json")
+}
+
+func (w *specWriter) writeExample(title, mediaType string, v *treeNode, open bool) {
+	code, lang := exampleText(v), "json"
+	if v.kind != "object" && v.kind != "array" {
+		switch {
+		case strings.Contains(mediaType, "xml"):
+			lang = "xml"
+		case strings.Contains(mediaType, "yaml"):
+			lang = "yaml"
+		case v.kind == "string":
+			lang = ""
+		}
+	}
+	if open {
+		w.buf.WriteString("<details class=\"cooked-openapi-example\" open>\n")
+	} else {
+		w.buf.WriteString("<details class=\"cooked-openapi-example\">\n")
+	}
+	fmt.Fprintf(&w.buf, "<summary>%s</summary>\n", gohtml.EscapeString(title))
+	if err := writeCodeBlock(&w.buf, w.r.formatter, code, lang); err != nil {
+		fmt.Fprintf(&w.buf, "<pre><code>%s</code></pre>", gohtml.EscapeString(code))
+	}
+	w.buf.WriteString("\n</details>\n")
+}
+
+// writeSchema writes a schema's type and what it is made of. A ref to a
+// component schema is a link to its section; with expand set, as for the
+// top-level schema of a body, its properties are listed here too.
+func (w *specWriter) writeSchema(doc *specDoc, s *treeNode, expand bool) {
+	w.buf.WriteString("<div class=\"cooked-openapi-schema\">\n")
+	fmt.Fprintf(&w.buf, "<p class=\"cooked-openapi-type\">%s</p>\n", w.schemaType(doc, s, 0))
+	d, t, _ := w.deref(doc, s)
+	if t != nil {
+		if _, named := w.anchors[t]; !named || expand {
+			if t != s {
+				w.markdown(t.get("description"))
+			}
+			w.writeConstraints(d, t)
+			w.writeSchemaBody(d, t, 0, map[*treeNode]bool{})
+		}
+	}
+	w.buf.WriteString("</div>\n")
+}
+
+// writeSchemaBody lists the properties of an object schema, the item schema
+// of an array, or the alternatives of a oneOf or anyOf. Schemas nested in
+// it are expanded too, except refs to component schemas.
+func (w *specWriter) writeSchemaBody(doc *specDoc, s *treeNode, depth int, stack map[*treeNode]bool) {
+	if s == nil || depth > openAPIMaxDepth || stack[s] || w.spent() {
+		return
+	}
+	stack[s] = true
+	defer delete(stack, s)
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		alts := s.get(key).arrayItems()
+		if len(alts) == 0 || !w.expandable(doc, alts) {
+			continue
+		}
+		label := "One of"
+		if key == "anyOf" {
+			label = "Any of"
+		}
+		fmt.Fprintf(&w.buf, "<p>%s:</p>\n<ul class=\"cooked-openapi-alternatives\">\n", label)
+		for _, alt := range alts {
+			fmt.Fprintf(&w.buf, "<li><span class=\"cooked-openapi-type\">%s</span>\n", w.schemaType(doc, alt, 0))
+			w.writeNested(doc, alt, depth, stack)
+			w.buf.WriteString("</li>\n")
+		}
+		w.buf.WriteString("</ul>\n")
+	}
+
+	if primaryType(s) == "array" {
+		if items := s.get("items"); items != nil {
+			w.writeNested(doc, items, depth, stack)
+		}
+		return
+	}
+
+	props, required := w.collectProps(doc, s, map[*treeNode]bool{})
+	if len(props) > 0 {
+		w.buf.WriteString("<ul class=\"cooked-openapi-props\">\n")
+		for _, p := range props {
+			pd, ps, _ := w.deref(p.doc, p.schema)
+			fmt.Fprintf(&w.buf, "<li><code class=\"cooked-openapi-prop\">%s</code> <span class=\"cooked-openapi-type\">%s</span>",
+				gohtml.EscapeString(p.name), w.schemaType(p.doc, p.schema, 0))
+			if required[p.name] {
+				w.buf.WriteString(" <span class=\"cooked-openapi-required\">required</span>")
+			}
+			for _, flag := range []string{"readOnly", "writeOnly", "deprecated"} {
+				if scalar(ps.get(flag)) == "true" {
+					fmt.Fprintf(&w.buf, " <span class=\"cooked-openapi-flag\">%s</span>", flag)
+				}
+			}
+			w.buf.WriteString("\n")
+			// A description next to a $ref describes the property; the
+			// target's own describes the schema.
+			desc := p.schema.get("description")
+			if desc == nil {
+				desc = ps.get("description")
+			}
+			w.markdown(desc)
+			w.writeConstraints(pd, ps)
+			w.writeNested(p.doc, p.schema, depth, stack)
+			w.buf.WriteString("</li>\n")
+		}
+		w.buf.WriteString("</ul>\n")
+	}
+
+	if ap := s.get("additionalProperties"); ap.objectKeys() != nil {
+		fmt.Fprintf(&w.buf, "<p>Additional properties: <span class=\"cooked-openapi-type\">%s</span></p>\n", w.schemaType(doc, ap, 0))
+		w.writeNested(doc, ap, depth, stack)
+	}
+}
+
+// expandable reports whether any of schemas has more to show than its type:
+// alternatives that all refer to component schemas are fully described by
+// the links in the type.
+func (w *specWriter) expandable(doc *specDoc, schemas []*treeNode) bool {
+	for _, s := range schemas {
+		_, t, _ := w.deref(doc, s)
+		if _, named := w.anchors[t]; t != nil && !named {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNested expands a schema nested in another, unless it is a ref to a
+// component schema, which has a section of its own.
+func (w *specWriter) writeNested(doc *specDoc, s *treeNode, depth int, stack map[*treeNode]bool) {
+	d, t, _ := w.deref(doc, s)
+	if t == nil {
+		return
+	}
+	if _, named := w.anchors[t]; named {
+		return
+	}
+	if primaryType(t) == "array" {
+		w.writeNested(d, t.get("items"), depth+1, stack)
+		return
+	}
+	if t.get("properties") == nil && t.get("allOf") == nil && t.get("oneOf") == nil &&
+		t.get("anyOf") == nil && t.get("additionalProperties").objectKeys() == nil {
+		return
+	}
+	start := w.buf.Len()
+	w.buf.WriteString("<div class=\"cooked-openapi-nested\">\n")
+	body := w.buf.Len()
+	w.writeSchemaBody(d, t, depth+1, stack)
+	if w.buf.Len() == body {
+		w.buf.Truncate(start)
+		return
+	}
+	w.buf.WriteString("</div>\n")
+}
+
+// collectProps returns the properties of an object schema, including those
+// of the schemas it combines with allOf, and which of them are required.
+func (w *specWriter) collectProps(doc *specDoc, s *treeNode, seen map[*treeNode]bool) ([]specProp, map[string]bool) {
+	var props []specProp
+	required := map[string]bool{}
+	var walk func(doc *specDoc, s *treeNode, depth int)
+	walk = func(doc *specDoc, s *treeNode, depth int) {
+		doc, s, _ = w.deref(doc, s)
+		if s == nil || seen[s] || depth > openAPIMaxDepth || w.spent() {
+			return
+		}
+		seen[s] = true
+		for _, part := range s.get("allOf").arrayItems() {
+			walk(doc, part, depth+1)
+		}
+		if ps := s.get("properties"); ps != nil {
+			for i, name := range ps.keys {
+				replaced := false
+				for j := range props {
+					if props[j].name == name {
+						props[j] = specProp{name: name, doc: doc, schema: ps.items[i]}
+						replaced = true
+					}
+				}
+				if !replaced {
+					props = append(props, specProp{name: name, doc: doc, schema: ps.items[i]})
+				}
+			}
+		}
+		for _, r := range s.get("required").arrayItems() {
+			required[scalar(r)] = true
+		}
+	}
+	walk(doc, s, 0)
+	return props, required
+}
+
+// writeConstraints lists a schema's allowed values and limits.
+func (w *specWriter) writeConstraints(doc *specDoc, s *treeNode) {
+	if s == nil {
+		return
+	}
+	var parts []string
+	if enum := s.get("enum").arrayItems(); len(enum) > 0 {
+		var vals []string
+		for _, v := range enum[:min(len(enum), openAPIMaxEnum)] {
+			vals = append(vals, "<code>"+gohtml.EscapeString(exampleText(v))+"</code>")
+		}
+		if len(enum) > openAPIMaxEnum {
+			vals = append(vals, fmt.Sprintf("and %d more", len(enum)-openAPIMaxEnum))
+		}
+		parts = append(parts, "Allowed values: "+strings.Join(vals, ", "))
+	}
+	if d := s.get("default"); d != nil {
+		parts = append(parts, "Default: <code>"+gohtml.EscapeString(exampleText(d))+"</code>")
+	}
+	for _, c := range [][2]string{
+		{"minimum", "Minimum"}, {"maximum", "Maximum"},
+		{"minLength", "Minimum length"}, {"maxLength", "Maximum length"},
+		{"minItems", "Minimum items"}, {"maxItems", "Maximum items"},
+		{"pattern", "Pattern"},
+	} {
+		if v := s.get(c[0]); v != nil && v.kind != "object" && v.kind != "array" {
+			parts = append(parts, c[1]+": <code>"+gohtml.EscapeString(v.value)+"</code>")
+		}
+	}
+	if ex := s.get("example"); ex != nil && ex.kind != "object" && ex.kind != "array" {
+		parts = append(parts, "Example: <code>"+gohtml.EscapeString(exampleText(ex))+"</code>")
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(&w.buf, "<p class=\"cooked-openapi-constraints\">%s</p>\n", strings.Join(parts, " · "))
+	}
+}
+
+// schemaType describes a schema in a few words of HTML: its type and
+// format, "array of" its items, its alternatives, or a link to the
+// component schema it refers to.
+func (w *specWriter) schemaType(doc *specDoc, s *treeNode, depth int) string {
+	if s == nil {
+		return "any"
+	}
+	if depth > openAPIMaxDepth || w.spent() {
+		return "…"
+	}
+	d, t, ref := w.deref(doc, s)
+	if t == nil {
+		return unresolved(ref)
+	}
+	if a, ok := w.anchors[t]; ok {
+		return fmt.Sprintf("<a href=\"#%s\">%s</a>", gohtml.EscapeString(a.id), gohtml.EscapeString(a.name))
+	}
+	return w.typeLabel(d, t, depth)
+}
+
+// typeLabel describes a resolved schema as schemaType does, without linking
+// it to its own section.
+func (w *specWriter) typeLabel(d *specDoc, t *treeNode, depth int) string {
+	var label string
+	switch {
+	case t.get("oneOf") != nil || t.get("anyOf") != nil:
+		key, sep := "oneOf", " | "
+		if t.get("oneOf") == nil {
+			key, sep = "anyOf", " or "
+		}
+		var alts []string
+		for _, alt := range t.get(key).arrayItems() {
+			alts = append(alts, w.schemaType(d, alt, depth+1))
+		}
+		label = strings.Join(alts, sep)
+	case primaryType(t) == "array":
+		label = "array of " + w.schemaType(d, t.get("items"), depth+1)
+	case primaryType(t) != "":
+		label = gohtml.EscapeString(primaryType(t))
+		if f := scalar(t.get("format")); f != "" {
+			label += "(" + gohtml.EscapeString(f) + ")"
+		}
+	case t.get("allOf") != nil:
+		parts := t.get("allOf").arrayItems()
+		if len(parts) == 1 && t.get("properties") == nil {
+			label = w.schemaType(d, parts[0], depth+1)
+		} else {
+			label = "object"
+		}
+	case t.get("properties") != nil || t.get("additionalProperties") != nil:
+		label = "object"
+	default:
+		label = "any"
+	}
+	if nullable(t) {
+		label += " or null"
+	}
+	return label
+}
+
+// primaryType returns a schema's type, ignoring "null" in an OpenAPI 3.1
+// list of types.
+func primaryType(s *treeNode) string {
+	t := s.get("type")
+	if t == nil {
+		if s.get("properties") != nil {
+			return "object"
+		}
+		return ""
+	}
+	if t.kind != "array" {
+		return t.value
+	}
+	for _, item := range t.items {
+		if item.value != "null" {
+			return item.value
+		}
+	}
+	return ""
+}
+
+func nullable(s *treeNode) bool {
+	if scalar(s.get("nullable")) == "true" || scalar(s.get("x-nullable")) == "true" {
+		return true
+	}
+	for _, item := range s.get("type").arrayItems() {
+		if item.value == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// exampleFor builds an example value from a schema: its own example, its
+// default or first enum value, or one assembled from its properties and
+// items.
+func (w *specWriter) exampleFor(doc *specDoc, s *treeNode, depth int, stack map[*treeNode]bool) *treeNode {
+	doc, s, _ = w.deref(doc, s)
+	if s == nil || depth > openAPIMaxDepth || stack[s] || w.spent() {
+		return nil
+	}
+	if ex := s.get("example"); ex != nil {
+		return ex
+	}
+	if exs := s.get("examples").arrayItems(); len(exs) > 0 {
+		return exs[0]
+	}
+	if d := s.get("default"); d != nil {
+		return d
+	}
+	if enum := s.get("enum").arrayItems(); len(enum) > 0 {
+		return enum[0]
+	}
+	stack[s] = true
+	defer delete(stack, s)
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alts := s.get(key).arrayItems(); len(alts) > 0 {
+			return w.exampleFor(doc, alts[0], depth+1, stack)
+		}
+	}
+	switch typ := primaryType(s); typ {
+	case "array":
+		arr := &treeNode{kind: "array"}
+		if item := w.exampleFor(doc, s.get("items"), depth+1, stack); item != nil {
+			arr.items = append(arr.items, item)
+		}
+		return arr
+	case "string":
+		return &treeNode{kind: "string", value: exampleString(scalar(s.get("format")))}
+	case "integer", "number":
+		return &treeNode{kind: "number", value: "0"}
+	case "boolean":
+		return &treeNode{kind: "bool", value: "true"}
+	case "object", "":
+		props, _ := w.collectProps(doc, s, map[*treeNode]bool{})
+		if typ == "" && len(props) == 0 {
+			return nil
+		}
+		obj := &treeNode{kind: "object"}
+		for _, p := range props {
+			if v := w.exampleFor(p.doc, p.schema, depth+1, stack); v != nil {
+				obj.keys = append(obj.keys, p.name)
+				obj.items = append(obj.items, v)
+			}
+		}
+		return obj
+	}
+	return nil
+}
+
+// exampleString is an example value for a string of the given format.
+func exampleString(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "U3dhZ2dlcg=="
+	}
+	return "string"
+}
+
+// exampleText formats an example value: objects and arrays as indented
+// JSON, scalars as written.
+func exampleText(v *treeNode) string {
+	if v.kind != "object" && v.kind != "array" {
+		return v.value
+	}
+	var b strings.Builder
+	writeJSONValue(&b, v, "")
+	if b.Len() > openAPIMaxExample {
+		return cutAtLine(b.String(), openAPIMaxExample) + "…\n"
+	}
+	return b.String()
+}
+
+// writeJSONValue writes v as indented JSON, stopping once the output is
+// over openAPIMaxExample bytes: nodes shared through YAML aliases could
+// otherwise make it far larger than the document.
+func writeJSONValue(b *strings.Builder, v *treeNode, indent string) {
+	if b.Len() > openAPIMaxExample {
+		return
+	}
+	switch v.kind {
+	case "object", "array":
+		open, end := "{", "}"
+		if v.kind == "array" {
+			open, end = "[", "]"
+		}
+		if len(v.items) == 0 {
+			b.WriteString(open + end)
+			return
+		}
+		b.WriteString(open + "\n")
+		for i, item := range v.items {
+			b.WriteString(indent + "  ")
+			if v.kind == "object" {
+				k, _ := json.Marshal(v.keys[i])
+				b.Write(k)
+				b.WriteString(": ")
+			}
+			writeJSONValue(b, item, indent+"  ")
+			if i < len(v.items)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + end)
+	case "number":
+		if json.Valid([]byte(v.value)) {
+			b.WriteString(v.value)
+		} else if f, err := strconv.ParseFloat(strings.ReplaceAll(v.value, "_", ""), 64); err == nil {
+			b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			s, _ := json.Marshal(v.value)
+			b.Write(s)
+		}
+	case "bool":
+		b.WriteString(strings.ToLower(v.value))
+	case "null":
+		b.WriteString("null")
+	default:
+		s, _ := json.Marshal(v.value)
+		b.Write(s)
+	}
+}
+
+// deref follows a chain of $refs from n. It returns the target and the
+// document it is in; the target is nil if a ref cannot be resolved, and ref
+// is the last ref followed.
+func (w *specWriter) deref(doc *specDoc, n *treeNode) (*specDoc, *treeNode, string) {
+	ref := ""
+	for range openAPIMaxRefHops {
+		r := n.get("$ref")
+		if r == nil || r.kind != "string" {
+			return doc, n, ref
+		}
+		ref = r.value
+		var err error
+		doc, n, err = w.lookup(doc, ref)
+		if err != nil {
+			return doc, nil, ref
+		}
+	}
+	return doc, nil, ref
+}
+
+// lookup resolves a ref made in doc: an optional relative URL of another
+// document, and a JSON pointer within it.
+func (w *specWriter) lookup(doc *specDoc, ref string) (*specDoc, *treeNode, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	if file != "" {
+		target, err := w.document(doc, file)
+		if err != nil {
+			return doc, nil, err
+		}
+		doc = target
+	}
+	n := doc.root
+	if pointer == "" {
+		return doc, n, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return doc, nil, fmt.Errorf("unsupported ref %q", ref)
+	}
+	for _, seg := range strings.Split(pointer[1:], "/") {
+		if s, err := url.PathUnescape(seg); err == nil {
+			seg = s
+		}
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		switch n.kind {
+		case "object":
+			n = n.get(seg)
+		case "array":
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(n.items) {
+				return doc, nil, fmt.Errorf("ref %q not found", ref)
+			}
+			n = n.items[i]
+		default:
+			n = nil
+		}
+		if n == nil {
+			return doc, nil, fmt.Errorf("ref %q not found", ref)
+		}
+	}
+	return doc, n, nil
+}
+
+// document loads the document at a URL relative to doc.
+func (w *specWriter) document(doc *specDoc, file string) (*specDoc, error) {
+	if w.load == nil || doc.url == "" {
+		return nil, errors.New("refs to other files cannot be resolved here")
+	}
+	base, err := url.Parse(doc.url)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := url.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(rel)
+	u.Fragment = ""
+	key := u.String()
+	if d, ok := w.docs[key]; ok {
+		if d == nil {
+			return nil, fmt.Errorf("%s could not be loaded", key)
+		}
+		return d, nil
+	}
+	if len(w.docs) >= openAPIMaxRefDocs {
+		return nil, errors.New("too many referenced files")
+	}
+	w.docs[key] = nil
+	body, err := w.load(key)
+	if err != nil {
+		return nil, err
+	}
+	language := specLanguage(body)
+	if strings.EqualFold(path.Ext(u.Path), ".json") {
+		language = "json"
+	}
+	root, err := parseSpec(body, language)
+	if err != nil {
+		return nil, err
+	}
+	d := &specDoc{url: key, root: root}
+	w.docs[key] = d
+	return d, nil
+}
+
+// writeSecuritySchemes writes the authentication methods the API declares.
+func (w *specWriter) writeSecuritySchemes() {
+	schemes := w.main.root.get("components").get("securitySchemes")
+	if w.swagger {
+		schemes = w.main.root.get("securityDefinitions")
+	}
+	if schemes == nil || len(schemes.keys) == 0 {
+		return
+	}
+	w.heading(2, "Authentication", "", true)
+	w.buf.WriteString("<table class=\"cooked-openapi-params\">\n<thead>\n<tr><th>Name</th><th>Type</th><th>Details</th></tr>\n</thead>\n<tbody>\n")
+	for i, name := range schemes.keys {
+		_, s, ref := w.deref(w.main, schemes.items[i])
+		if s == nil {
+			fmt.Fprintf(&w.buf, "<tr><td><code>%s</code></td><td colspan=\"2\">%s</td></tr>\n", gohtml.EscapeString(name), unresolved(ref))
+			continue
+		}
+		typ := scalar(s.get("type"))
+		var details []string
+		switch typ {
+		case "apiKey":
+			details = append(details, fmt.Sprintf("<code>%s</code> in %s", gohtml.EscapeString(scalar(s.get("name"))), gohtml.EscapeString(scalar(s.get("in")))))
+		case "http":
+			d := "Scheme: <code>" + gohtml.EscapeString(scalar(s.get("scheme"))) + "</code>"
+			if f := scalar(s.get("bearerFormat")); f != "" {
+				d += " (" + gohtml.EscapeString(f) + ")"
+			}
+			details = append(details, d)
+		case "openIdConnect":
+			u := scalar(s.get("openIdConnectUrl"))
+			details = append(details, "Discovery: "+link(u, u))
+		case "oauth2":
+			flows := s.get("flows")
+			if w.swagger {
+				flows = &treeNode{kind: "object", keys: []string{scalar(s.get("flow"))}, items: []*treeNode{s}}
+			}
+			for j, flow := range flows.objectKeys() {
+				f := flows.items[j]
+				d := "Flow <code>" + gohtml.EscapeString(flow) + "</code>"
+				for _, u := range []string{"authorizationUrl", "tokenUrl", "refreshUrl"} {
+					if v := scalar(f.get(u)); v != "" {
+						d += "<br>" + u + ": " + link(v, v)
+					}
+				}
+				if scopes := f.get("scopes").objectKeys(); len(scopes) > 0 {
+					var names []string
+					for _, sc := range scopes {
+						names = append(names, "<code>"+gohtml.EscapeString(sc)+"</code>")
+					}
+					d += "<br>Scopes: " + strings.Join(names, ", ")
+				}
+				details = append(details, d)
+			}
+		}
+		fmt.Fprintf(&w.buf, "<tr><td><code>%s</code></td><td>%s</td><td>%s\n", gohtml.EscapeString(name), gohtml.EscapeString(typ), strings.Join(details, "<br>"))
+		w.markdown(s.get("description"))
+		w.buf.WriteString("</td></tr>\n")
+	}
+	w.buf.WriteString("</tbody>\n</table>\n")
+}
+
+// securityRequirements describes a security requirement list: any one of
+// its entries grants access, and an empty entry means none is needed.
+func securityRequirements(list *treeNode) string {
+	var alts []string
+	for _, req := range list.arrayItems() {
+		if req.kind != "object" || len(req.keys) == 0 {
+			alts = append(alts, "none")
+			continue
+		}
+		var all []string
+		for i, name := range req.keys {
+			s := "<code>" + gohtml.EscapeString(name) + "</code>"
+			var scopes []string
+			for _, sc := range req.items[i].arrayItems() {
+				scopes = append(scopes, gohtml.EscapeString(scalar(sc)))
+			}
+			if len(scopes) > 0 {
+				s += " (" + strings.Join(scopes, ", ") + ")"
+			}
+			all = append(all, s)
+		}
+		alts = append(alts, strings.Join(all, " and "))
+	}
+	if len(alts) == 0 {
+		return "none"
+	}
+	return strings.Join(alts, " or ")
+}
+
+// arrayItems returns the items of an array node, or nil for anything else.
+func (t *treeNode) arrayItems() []*treeNode {
+	if t == nil || t.kind != "array" {
+		return nil
+	}
+	return t.items
+}
+
+// objectKeys returns the keys of an object node, or nil for anything else.
+func (t *treeNode) objectKeys() []string {
+	if t == nil || t.kind != "object" {
+		return nil
+	}
+	return t.keys
+}
+
+// scalar returns the text of a scalar node, or "" for anything else.
+func scalar(n *treeNode) string {
+	if n == nil || n.kind == "object" || n.kind == "array" {
+		return ""
+	}
+	return n.value
+}
+
+func link(href, text string) string {
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(href), gohtml.EscapeString(text))
+}
+
+func externalDocs(d *treeNode) string {
+	u := scalar(d.get("url"))
+	text := scalar(d.get("description"))
+	if text == "" {
+		text = u
+	}
+	return link(u, text)
+}
+
+func unresolved(ref string) string {
+	return fmt.Sprintf("<code class=\"cooked-openapi-unresolved\" title=\"Unresolved reference\">%s</code>", gohtml.EscapeString(ref))
+}
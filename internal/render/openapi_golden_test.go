@@ -0,0 +1,83 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIGolden(t *testing.T) {
+	r := NewOpenAPIRenderer()
+
+	fixtures, err := filepath.Glob(filepath.Join(fixturesDir, "openapi", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no openapi fixtures found")
+	}
+
+	goldenSubdir := filepath.Join(goldenDir, "openapi")
+
+	for _, fixturePath := range fixtures {
+		filename := filepath.Base(fixturePath)
+		info := DetectFile(fixturePath)
+		if info.ContentType != TypeOpenAPI {
+			continue
+		}
+
+		t.Run(filename, func(t *testing.T) {
+			input, err := os.ReadFile(fixturePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, meta, err := r.Render(input, info.Language, "", nil)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+
+			goldenPath := filepath.Join(goldenSubdir, filename+".html")
+
+			if *update {
+				if err := os.MkdirAll(goldenSubdir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("updated %s", goldenPath)
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("golden file not found (run with -update to create): %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("output mismatch for %s (run with -update to regenerate)\n"+
+					"got %d bytes, want %d bytes\n"+
+					"first diff at byte %d",
+					filename, len(got), len(want), firstDiff(got, want))
+			}
+
+			// Every operation is listed in the TOC under its tag.
+			if meta == nil || meta.Title == "" {
+				t.Fatal("missing title")
+			}
+			ops := strings.Count(string(got), `<div class="cooked-openapi-op`)
+			listed := 0
+			for _, h := range meta.Headings {
+				if h.Level == 3 {
+					listed++
+				}
+			}
+			if ops == 0 || listed != ops {
+				t.Errorf("TOC lists %d operations, page has %d", listed, ops)
+			}
+		})
+	}
+}
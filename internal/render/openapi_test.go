@@ -0,0 +1,232 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetectOpenAPI(t *testing.T) {
+	yamlInfo := FileInfo{ContentType: TypeCode, Language: "yaml", Label: "YAML"}
+	jsonInfo := FileInfo{ContentType: TypeCode, Language: "json", Label: "JSON"}
+	tests := []struct {
+		name string
+		info FileInfo
+		body string
+		want ContentType
+	}{
+		{"openapi 3 yaml", yamlInfo, "openapi: 3.1.0\ninfo:\n  title: x\n", TypeOpenAPI},
+		{"quoted version", yamlInfo, "# API\nopenapi: \"3.0.3\" # comment\ninfo: {title: x}\n", TypeOpenAPI},
+		{"swagger yaml", yamlInfo, "swagger: '2.0'\ninfo:\n  title: x\n", TypeOpenAPI},
+		{"swagger json", jsonInfo, `{"swagger": "2.0", "info": {"title": "x"}}`, TypeOpenAPI},
+		{"openapi json with BOM", jsonInfo, "\ufeff{\"openapi\": \"3.0.0\", \"info\": {}}", TypeOpenAPI},
+		{"no info", yamlInfo, "openapi: 3.0.0\npaths: {}\n", TypeCode},
+		{"nested keys", yamlInfo, "spec:\n  openapi: 3.0.0\n  info: {}\n", TypeCode},
+		{"swagger 1.2", jsonInfo, `{"swaggerVersion": "1.2", "info": {}}`, TypeCode},
+		{"openapi 4", jsonInfo, `{"openapi": "4.0.0", "info": {}}`, TypeCode},
+		{"not code", FileInfo{ContentType: TypeMarkdown}, "openapi: 3.0.0\ninfo: {}\n", TypeMarkdown},
+		{"toml", FileInfo{ContentType: TypeCode, Language: "toml"}, "openapi = \"3.0.0\"\n[info]\n", TypeCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectOpenAPI(tt.info, []byte(tt.body))
+			if got.ContentType != tt.want {
+				t.Errorf("DetectOpenAPI() = %q, want %q", got.ContentType, tt.want)
+			}
+			if got.ContentType == TypeOpenAPI && got.Language != tt.info.Language {
+				t.Errorf("Language = %q, want %q", got.Language, tt.info.Language)
+			}
+		})
+	}
+}
+
+func TestOpenAPIRenderer_ExternalRefs(t *testing.T) {
+	spec := `openapi: 3.0.0
+info: {title: Refs, version: "1"}
+paths:
+  /orders:
+    $ref: 'paths/orders.yaml'
+  /missing:
+    get:
+      responses:
+        '200':
+          $ref: 'missing.yaml#/Response'
+`
+	files := map[string]string{
+		"https://example.com/api/paths/orders.yaml": `get:
+  tags: [orders]
+  responses:
+    '200':
+      description: The orders
+      content:
+        application/json:
+          schema:
+            $ref: '../schemas/order.json#/Order'
+`,
+		"https://example.com/api/schemas/order.json": `{"Order": {"type": "object", "properties": {"id~/x": {"type": "string", "format": "uuid"}, "next": {"$ref": "#/Order"}}}}`,
+	}
+	var loaded []string
+	load := func(url string) ([]byte, error) {
+		loaded = append(loaded, url)
+		if body, ok := files[url]; ok {
+			return []byte(body), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	html, meta, err := NewOpenAPIRenderer().Render([]byte(spec), "yaml", "https://example.com/api/openapi.yaml", load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<h2 id="orders">orders</h2>`,
+		`<code>/orders</code>`,
+		`<code class="cooked-openapi-prop">id~/x</code> <span class="cooked-openapi-type">string(uuid)</span>`,
+		`&#34;id~/x&#34;`,
+		`<code class="cooked-openapi-unresolved" title="Unresolved reference">missing.yaml#/Response</code>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+	if meta.Title != "Refs" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+
+	// Each file is fetched once, however often it is referenced.
+	want := []string{
+		"https://example.com/api/paths/orders.yaml",
+		"https://example.com/api/schemas/order.json",
+		"https://example.com/api/missing.yaml",
+	}
+	if strings.Join(loaded, " ") != strings.Join(want, " ") {
+		t.Errorf("loaded %v, want %v", loaded, want)
+	}
+
+	// Without a URL or loader, refs to other files are shown unresolved.
+	html, _, err = NewOpenAPIRenderer().Render([]byte(spec), "yaml", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), `<code class="cooked-openapi-unresolved" title="Unresolved reference">missing.yaml#/Response</code>`) {
+		t.Errorf("missing unresolved ref:\n%s", html)
+	}
+}
+
+func TestOpenAPIRenderer_YAMLFeatures(t *testing.T) {
+	// Aliases and merge keys are followed, and ref cycles end.
+	spec := `openapi: 3.1.0
+info: {title: Anchors, version: "1"}
+x-common: &common
+  description: Shared response
+paths:
+  /a:
+    get:
+      operationId: getA
+      responses:
+        '200':
+          <<: *common
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Loop'
+components:
+  schemas:
+    Loop:
+      $ref: '#/components/schemas/Loop'
+    Tree:
+      type: [object, "null"]
+      properties:
+        children:
+          type: array
+          items:
+            properties:
+              child:
+                $ref: '#/components/schemas/Tree'
+`
+	html, meta, err := NewOpenAPIRenderer().Render([]byte(spec), "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<p>Shared response</p>`,
+		`<h3 id="geta">`,
+		`<h3 id="schema-tree">Tree</h3>`,
+		`<p class="cooked-openapi-type">object or null</p>`,
+		`<span class="cooked-openapi-type">array of object</span>`,
+		`<a href="#schema-tree">Tree</a>`,
+		`cooked-openapi-unresolved`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+	var toc []string
+	for _, h := range meta.Headings {
+		toc = append(toc, h.Text)
+	}
+	if want := "Anchors|default|GET /a|Schemas"; strings.Join(toc, "|") != want {
+		t.Errorf("TOC = %q, want %q", strings.Join(toc, "|"), want)
+	}
+}
+
+func TestOpenAPIRenderer_AliasFanOut(t *testing.T) {
+	// Each level refers to the one below ten times over, so expanding it
+	// naively would visit 10^9 schemas.
+	var b strings.Builder
+	b.WriteString("openapi: 3.0.0\ninfo: {title: x}\nx:\n  l0: &l0 {type: string}\n")
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(&b, "  l%d: &l%d\n    oneOf: [%s]\n    properties:\n", i, i,
+			strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*l%d, ", i-1), 10), ", "))
+		for j := range 10 {
+			fmt.Fprintf(&b, "      p%d: *l%d\n", j, i-1)
+		}
+	}
+	b.WriteString("paths:\n  /a:\n    post:\n      requestBody:\n        content:\n          application/json:\n            schema: *l9\n      responses: {}\n")
+
+	html, _, err := NewOpenAPIRenderer().Render([]byte(b.String()), "yaml", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(html) > 32<<20 {
+		t.Errorf("rendered %d bytes", len(html))
+	}
+}
+
+func TestOpenAPIRenderer_NotASpec(t *testing.T) {
+	// A file named like a description that is not one is shown as data.
+	for _, tt := range []struct{ source, language string }{
+		{"name: not an API\n", "yaml"},
+		{`{"openapi": "3.0.0"`, "json"},
+	} {
+		html, meta, err := NewOpenAPIRenderer().Render([]byte(tt.source), tt.language, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(html)
+		if meta != nil {
+			t.Errorf("meta = %+v, want nil", meta)
+		}
+		if !strings.Contains(got, `<p class="cooked-openapi-notice">`) || !strings.Contains(got, `<div class="cooked-tree"`) {
+			t.Errorf("expected a notice and the tree view:\n%s", got)
+		}
+	}
+}
+
+func TestOpenAPIRenderer_Escaping(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "info": {"title": "<script>x</script>", "version": "1"},
+"paths": {"/a/{b}<i>": {"get": {"tags": ["<b>t</b>"], "summary": "<img>", "responses": {}}}}}`
+	html, _, err := NewOpenAPIRenderer().Render([]byte(spec), "json", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, bad := range []string{"<script>", "<i>", "<b>t", "<img>"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("unescaped %s in output:\n%s", bad, got)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	gohtml "html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+)
+
+// RSTRenderer renders reStructuredText content to HTML. It implements the
+// docutils block and inline syntax and the common Sphinx directives and roles
+// natively; directives that read other files or run code (include, toctree,
+// autodoc) are rendered as links or left out.
+type RSTRenderer struct {
+	formatter *chromahtml.Formatter
+}
+
+// NewRSTRenderer creates a new reStructuredText renderer.
+func NewRSTRenderer() *RSTRenderer {
+	return &RSTRenderer{formatter: chromahtml.New(chromahtml.WithClasses(true))}
+}
+
+// Render converts reStructuredText source to HTML and extracts metadata.
+func (r *RSTRenderer) Render(source []byte) ([]byte, *MarkdownMeta, error) {
+	d := newRSTDoc(r.formatter)
+	d.scanInlineTargets(string(source))
+	nodes := d.parse(rstLines(source))
+	d.numberFootnotes()
+
+	var buf bytes.Buffer
+	if err := d.write(&buf, nodes); err != nil {
+		return nil, nil, fmt.Errorf("render rst: %w", err)
+	}
+	return buf.Bytes(), d.meta, nil
+}
+
+// rstDoc is the state of one reStructuredText document: what the parser
+// collects for cross-references and what the writer tracks as it goes.
+type rstDoc struct {
+	formatter *chromahtml.Formatter
+	meta      *MarkdownMeta
+
+	styles    []string          // section adornment styles in order of first use
+	ids       map[string]bool   // element IDs in use
+	targets   map[string]string // normalized reference name → URL, or "=" + another name
+	titles    map[string]string // normalized label → title of the section it marks
+	anonymous []string          // anonymous target URLs in document order
+	subs      map[string]*rstNode
+	pending   []string // labels waiting for the next element
+	highlight string   // default literal block language
+
+	footnotes []*rstNode          // footnotes and citations in document order
+	labels    map[string]*rstNode // footnote labels and normalized citation labels
+	auto      []*rstNode          // auto-numbered footnotes without a name
+	symbols   []*rstNode          // auto-symbol footnotes
+	cited     map[*rstNode]bool   // footnotes with a rendered reference
+	anonRef   int                 // anonymous references rendered so far
+	autoRef   int                 // [#]_ references rendered so far
+	symbolRef int                 // [*]_ references rendered so far
+	depth     int                 // substitution nesting
+}
+
+func newRSTDoc(formatter *chromahtml.Formatter) *rstDoc {
+	return &rstDoc{
+		formatter: formatter,
+		meta:      &MarkdownMeta{},
+		ids:       map[string]bool{},
+		targets:   map[string]string{},
+		titles:    map[string]string{},
+		subs:      map[string]*rstNode{},
+		labels:    map[string]*rstNode{},
+		cited:     map[*rstNode]bool{},
+	}
+}
+
+// rstKind identifies a parsed reStructuredText element.
+type rstKind int
+
+const (
+	rstParagraph     rstKind = iota // text: inline source
+	rstSection                      // text: title, level, id
+	rstTransition                   //
+	rstLiteral                      // text: code, lang
+	rstParsedLiteral                // text: inline source
+	rstMath                         // text: TeX source
+	rstMermaid                      // text: diagram source
+	rstRaw                          // text: HTML
+	rstBulletList                   // children: items
+	rstEnumList                     // children: items, class: list type, level: start
+	rstListItem                     // children
+	rstDefList                      // children: items, class
+	rstDefItem                      // text: term, lines: classifiers, children
+	rstFieldList                    // children: fields
+	rstField                        // text: name, children
+	rstOptionList                   // children: options
+	rstOption                       // text: option synopsis, children
+	rstLineBlock                    // lines
+	rstBlockQuote                   // children, title: attribution, class
+	rstTable                        // table
+	rstAdmonition                   // class, title, children
+	rstTopic                        // class, title, children
+	rstRubric                       // text
+	rstContainer                    // class, children
+	rstFootnote                     // text: label, id, children
+	rstTarget                       // id
+	rstImage                        // text: URI, opts
+	rstFigure                       // text: URI, opts, children: caption and legend
+	rstTocTree                      // title: caption, entries
+	rstInclude                      // text: path
+	rstDesc                         // lines: signatures, class, children
+)
+
+// rstNode is a parsed reStructuredText element; which fields are used depends
+// on kind.
+type rstNode struct {
+	kind     rstKind
+	text     string
+	lang     string
+	level    int
+	id       string
+	class    string
+	title    string
+	lines    []string
+	opts     map[string]string
+	children []*rstNode
+	table    *rstTableData
+	entries  [][2]string // toctree title and document
+}
+
+// write renders nodes as HTML.
+func (d *rstDoc) write(w *bytes.Buffer, nodes []*rstNode) error {
+	for _, n := range nodes {
+		if err := d.writeNode(w, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *rstDoc) writeNode(w *bytes.Buffer, n *rstNode) error {
+	switch n.kind {
+	case rstParagraph:
+		fmt.Fprintf(w, "<p>%s</p>\n", d.inline(n.text))
+
+	case rstSection:
+		title := d.inline(n.text)
+		fmt.Fprintf(w, "<h%d id=\"%s\">%s</h%d>\n", n.level, n.id, title, n.level)
+		text := rstPlain(title)
+		d.meta.HeadingCount++
+		d.meta.Headings = append(d.meta.Headings, Heading{Level: n.level, Text: text, ID: n.id})
+		if d.meta.Title == "" {
+			d.meta.Title = text
+		}
+
+	case rstTransition:
+		w.WriteString("<hr>\n")
+
+	case rstLiteral:
+		d.meta.CodeBlockCount++
+		d.meta.Languages = append(d.meta.Languages, n.lang)
+		if err := writeCodeBlock(w, d.formatter, n.text, n.lang); err != nil {
+			return err
+		}
+		w.WriteByte('\n')
+
+	case rstParsedLiteral:
+		fmt.Fprintf(w, "<pre class=\"parsed-literal\">%s</pre>\n", d.inline(n.text))
+
+	case rstMath:
+		fmt.Fprintf(w, "<pre class=\"math\">%s</pre>\n", gohtml.EscapeString(n.text))
+
+	case rstMermaid:
+		d.meta.HasMermaid = true
+		fmt.Fprintf(w, "<pre class=\"mermaid\">%s</pre>\n", gohtml.EscapeString(n.text))
+
+	case rstRaw:
+		w.WriteString(n.text)
+		w.WriteByte('\n')
+
+	case rstBulletList:
+		w.WriteString("<ul>\n")
+		if err := d.writeItems(w, n.children, 0); err != nil {
+			return err
+		}
+		w.WriteString("</ul>\n")
+
+	case rstEnumList:
+		fmt.Fprintf(w, "<ol type=\"%s\">\n", n.class)
+		if err := d.writeItems(w, n.children, n.level); err != nil {
+			return err
+		}
+		w.WriteString("</ol>\n")
+
+	case rstDefList:
+		w.WriteString(rstOpenTag("dl", n.class))
+		for _, item := range n.children {
+			fmt.Fprintf(w, "<dt>%s", d.inline(item.text))
+			for _, c := range item.lines {
+				fmt.Fprintf(w, " <span class=\"classifier\">%s</span>", d.inline(c))
+			}
+			w.WriteString("</dt>\n<dd>\n")
+			if err := d.write(w, item.children); err != nil {
+				return err
+			}
+			w.WriteString("</dd>\n")
+		}
+		w.WriteString("</dl>\n")
+
+	case rstFieldList:
+		w.WriteString("<dl class=\"field-list\">\n")
+		for _, f := range n.children {
+			fmt.Fprintf(w, "<dt>%s</dt>\n<dd>", d.inline(f.text))
+			if err := d.writeCompact(w, f.children); err != nil {
+				return err
+			}
+			w.WriteString("</dd>\n")
+		}
+		w.WriteString("</dl>\n")
+
+	case rstOptionList:
+		w.WriteString("<dl class=\"option-list\">\n")
+		for _, o := range n.children {
+			fmt.Fprintf(w, "<dt><code>%s</code></dt>\n<dd>", gohtml.EscapeString(o.text))
+			if err := d.writeCompact(w, o.children); err != nil {
+				return err
+			}
+			w.WriteString("</dd>\n")
+		}
+		w.WriteString("</dl>\n")
+
+	case rstLineBlock:
+		w.WriteString("<div class=\"line-block\">\n")
+		for _, line := range n.lines {
+			trimmed := strings.TrimLeft(line, " ")
+			indent := strings.Repeat("\u00a0", len(line)-len(trimmed))
+			fmt.Fprintf(w, "<div class=\"line\">%s%s</div>\n", indent, d.inline(trimmed))
+		}
+		w.WriteString("</div>\n")
+
+	case rstBlockQuote:
+		w.WriteString(rstOpenTag("blockquote", n.class))
+		if err := d.write(w, n.children); err != nil {
+			return err
+		}
+		if n.title != "" {
+			fmt.Fprintf(w, "<p class=\"attribution\">— %s</p>\n", d.inline(n.title))
+		}
+		w.WriteString("</blockquote>\n")
+
+	case rstTable:
+		if err := d.writeTable(w, n.table); err != nil {
+			return err
+		}
+
+	case rstAdmonition:
+		fmt.Fprintf(w, "<div class=\"admonition %s\">\n", gohtml.EscapeString(n.class))
+		fmt.Fprintf(w, "<p class=\"admonition-title\">%s</p>\n", d.inline(n.title))
+		if err := d.write(w, n.children); err != nil {
+			return err
+		}
+		w.WriteString("</div>\n")
+
+	case rstTopic:
+		tag := "div"
+		if n.class == "sidebar" {
+			tag = "aside"
+		}
+		fmt.Fprintf(w, "<%s class=\"%s\">\n", tag, gohtml.EscapeString(n.class))
+		if n.title != "" {
+			fmt.Fprintf(w, "<p class=\"%s-title\">%s</p>\n", gohtml.EscapeString(n.class), d.inline(n.title))
+		}
+		if err := d.write(w, n.children); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "</%s>\n", tag)
+
+	case rstRubric:
+		fmt.Fprintf(w, "<p class=\"rubric\">%s</p>\n", d.inline(n.text))
+
+	case rstContainer:
+		w.WriteString(rstOpenTag("div", n.class))
+		if err := d.write(w, n.children); err != nil {
+			return err
+		}
+		w.WriteString("</div>\n")
+
+	case rstFootnote:
+		return d.writeFootnote(w, n)
+
+	case rstTarget:
+		fmt.Fprintf(w, "<span id=\"%s\"></span>\n", n.id)
+
+	case rstImage:
+		w.WriteString(d.image(n.text, n.opts))
+		w.WriteByte('\n')
+
+	case rstFigure:
+		w.WriteString(rstOpenTag("figure", n.opts["figclass"]))
+		w.WriteString(d.image(n.text, n.opts))
+		w.WriteByte('\n')
+		if len(n.children) > 0 {
+			w.WriteString("<figcaption>\n")
+			if caption := n.children[0]; caption.kind == rstParagraph {
+				fmt.Fprintf(w, "<p>%s</p>\n", d.inline(caption.text))
+			}
+			if err := d.write(w, n.children[1:]); err != nil {
+				return err
+			}
+			w.WriteString("</figcaption>\n")
+		}
+		w.WriteString("</figure>\n")
+
+	case rstTocTree:
+		w.WriteString("<div class=\"toctree-wrapper\">\n")
+		if n.title != "" {
+			fmt.Fprintf(w, "<p class=\"caption\">%s</p>\n", d.inline(n.title))
+		}
+		w.WriteString("<ul>\n")
+		for _, e := range n.entries {
+			fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", gohtml.EscapeString(e[1]), gohtml.EscapeString(e[0]))
+		}
+		w.WriteString("</ul>\n</div>\n")
+
+	case rstInclude:
+		fmt.Fprintf(w, "<p class=\"include\">Included from <a href=\"%s\"><code>%s</code></a></p>\n",
+			gohtml.EscapeString(n.text), gohtml.EscapeString(n.text))
+
+	case rstDesc:
+		fmt.Fprintf(w, "<dl class=\"%s\">\n", gohtml.EscapeString(n.class))
+		for _, sig := range n.lines {
+			fmt.Fprintf(w, "<dt><code>%s</code></dt>\n", gohtml.EscapeString(sig))
+		}
+		w.WriteString("<dd>\n")
+		if err := d.write(w, n.children); err != nil {
+			return err
+		}
+		w.WriteString("</dd>\n</dl>\n")
+	}
+	return nil
+}
+
+// writeItems writes list items, numbering the first from start when it is
+// greater than one.
+func (d *rstDoc) writeItems(w *bytes.Buffer, items []*rstNode, start int) error {
+	for i, item := range items {
+		if i == 0 && start > 1 {
+			fmt.Fprintf(w, "<li value=\"%d\">", start)
+		} else {
+			w.WriteString("<li>")
+		}
+		if err := d.writeCompact(w, item.children); err != nil {
+			return err
+		}
+		w.WriteString("</li>\n")
+	}
+	return nil
+}
+
+// writeCompact writes a body made of a single paragraph as bare inline
+// content, as docutils does for simple list items and fields.
+func (d *rstDoc) writeCompact(w *bytes.Buffer, nodes []*rstNode) error {
+	if len(nodes) == 1 && nodes[0].kind == rstParagraph {
+		w.WriteString(d.inline(nodes[0].text))
+		return nil
+	}
+	w.WriteByte('\n')
+	return d.write(w, nodes)
+}
+
+// writeFootnote writes a footnote or citation with a link back to its first
+// reference when one was rendered before it.
+func (d *rstDoc) writeFootnote(w *bytes.Buffer, n *rstNode) error {
+	class := "footnote"
+	if n.class == "citation" {
+		class = "citation"
+	}
+	fmt.Fprintf(w, "<div class=\"%s\" id=\"%s\">\n", class, n.id)
+	label := gohtml.EscapeString(n.title)
+	if d.cited[n] {
+		fmt.Fprintf(w, "<span class=\"label\"><a href=\"#ref-%s\">[%s]</a></span>\n", n.id, label)
+	} else {
+		fmt.Fprintf(w, "<span class=\"label\">[%s]</span>\n", label)
+	}
+	if err := d.write(w, n.children); err != nil {
+		return err
+	}
+	w.WriteString("</div>\n")
+	return nil
+}
+
+// image returns the HTML for an image or figure directive, linked when it has
+// a target option.
+func (d *rstDoc) image(uri string, opts map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<img src=\"%s\" alt=\"%s\"", gohtml.EscapeString(uri), gohtml.EscapeString(opts["alt"]))
+	for _, attr := range []string{"width", "height"} {
+		if v := strings.TrimSuffix(opts[attr], "px"); v != "" {
+			fmt.Fprintf(&b, " %s=\"%s\"", attr, gohtml.EscapeString(v))
+		}
+	}
+	class := opts["class"]
+	if align := opts["align"]; align != "" {
+		class = strings.TrimSpace(class + " align-" + align)
+	}
+	if class != "" {
+		fmt.Fprintf(&b, " class=\"%s\"", gohtml.EscapeString(class))
+	}
+	b.WriteString(">")
+	target := opts["target"]
+	if target == "" {
+		return b.String()
+	}
+	if strings.HasSuffix(target, "_") {
+		target = d.resolve(rstNormalize(strings.Trim(strings.TrimSuffix(target, "_"), "`")))
+	}
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(target), b.String())
+}
+
+// rstOpenTag returns an opening tag with an optional class and a newline.
+func rstOpenTag(tag, class string) string {
+	if class == "" {
+		return "<" + tag + ">\n"
+	}
+	return fmt.Sprintf("<%s class=\"%s\">\n", tag, gohtml.EscapeString(class))
+}
+
+var rstTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// rstPlain returns the text of inline HTML.
+func rstPlain(s string) string {
+	return strings.TrimSpace(gohtml.UnescapeString(rstTagRe.ReplaceAllString(s, "")))
+}
+
+// newID returns a unique element ID derived from name.
+func (d *rstDoc) newID(name string) string {
+	base := slugID(name)
+	if base == "" {
+		base = "section"
+	}
+	id := base
+	for i := 1; d.ids[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	d.ids[id] = true
+	return id
+}
+
+// rstNormalize returns the reference name form of name: lowercase with runs
+// of whitespace collapsed.
+func rstNormalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// resolve returns the URL of a named hyperlink target, following indirect
+// targets, or "" when the name is undefined.
+func (d *rstDoc) resolve(name string) string {
+	for range 10 {
+		url, ok := d.targets[name]
+		if !ok {
+			return ""
+		}
+		alias, indirect := strings.CutPrefix(url, "=")
+		if !indirect {
+			return url
+		}
+		name = alias
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"path"
+	"strings"
+)
+
+// rstAdmonitions maps admonition directives to their titles.
+var rstAdmonitions = map[string]string{
+	"attention": "Attention",
+	"caution":   "Caution",
+	"danger":    "Danger",
+	"error":     "Error",
+	"hint":      "Hint",
+	"important": "Important",
+	"note":      "Note",
+	"tip":       "Tip",
+	"warning":   "Warning",
+	"seealso":   "See also",
+	"todo":      "Todo",
+}
+
+// rstVersionNotes maps Sphinx version directives to their title prefixes.
+var rstVersionNotes = map[string]string{
+	"versionadded":   "New in version",
+	"versionchanged": "Changed in version",
+	"deprecated":     "Deprecated since version",
+	"versionremoved": "Removed in version",
+}
+
+// rstArgDirectives take a text argument that may continue on the lines after
+// the directive.
+var rstArgDirectives = map[string]bool{
+	"admonition": true,
+	"topic":      true,
+	"sidebar":    true,
+	"rubric":     true,
+	"table":      true,
+	"csv-table":  true,
+	"list-table": true,
+	"image":      true,
+	"figure":     true,
+	"math":       true,
+}
+
+// rstDescDirectives are Sphinx object descriptions outside a named domain.
+var rstDescDirectives = map[string]bool{
+	"function":  true,
+	"class":     true,
+	"method":    true,
+	"attribute": true,
+	"data":      true,
+	"exception": true,
+	"decorator": true,
+	"envvar":    true,
+	"option":    true,
+	"describe":  true,
+	"object":    true,
+	"confval":   true,
+}
+
+// directive renders a directive. Directives that would read other files or
+// run code are rendered as links or dropped, and unknown directives render
+// their content.
+func (d *rstDoc) directive(name, arg string, block []string) []*rstNode {
+	isDesc := strings.Contains(name, ":") || rstDescDirectives[name]
+	args, opts, content := rstDirectiveParts(arg, block, rstArgDirectives[name] || isDesc)
+	text := strings.Join(args, " ")
+
+	if title, ok := rstAdmonitions[name]; ok {
+		return []*rstNode{{kind: rstAdmonition, class: name, title: title, children: d.parse(append(args, content...))}}
+	}
+	if prefix, ok := rstVersionNotes[name]; ok {
+		version, rest, _ := strings.Cut(text, " ")
+		body := content
+		if rest != "" {
+			body = append([]string{rest}, content...)
+		}
+		return []*rstNode{{kind: rstAdmonition, class: name, title: prefix + " " + version, children: d.parse(body)}}
+	}
+
+	switch name {
+	case "code-block", "code", "sourcecode":
+		lang := text
+		if lang == "" {
+			lang = d.highlight
+		}
+		return []*rstNode{{kind: rstLiteral, text: rstCode(content), lang: lang}}
+
+	case "highlight":
+		d.highlight = text
+		return nil
+
+	case "parsed-literal":
+		return []*rstNode{{kind: rstParsedLiteral, text: strings.TrimRight(strings.Join(append(args, content...), "\n"), "\n")}}
+
+	case "math":
+		return []*rstNode{{kind: rstMath, text: strings.TrimSpace(strings.Join(append(args, content...), "\n"))}}
+
+	case "mermaid":
+		return []*rstNode{{kind: rstMermaid, text: rstCode(content)}}
+
+	case "raw":
+		if !strings.Contains(strings.ToLower(text), "html") || opts["file"] != "" || opts["url"] != "" {
+			return nil
+		}
+		return []*rstNode{{kind: rstRaw, text: strings.Join(content, "\n")}}
+
+	case "include", "literalinclude":
+		// Included files are never read; link to them instead.
+		return []*rstNode{{kind: rstInclude, text: strings.TrimPrefix(text, "/")}}
+
+	case "toctree":
+		return d.tocTree(opts, content)
+
+	case "image":
+		return []*rstNode{{kind: rstImage, text: rstJoinURL(args), opts: opts}}
+
+	case "figure":
+		return []*rstNode{{kind: rstFigure, text: rstJoinURL(args), opts: opts, children: d.parse(content)}}
+
+	case "admonition":
+		class := "admonition-" + slugID(text)
+		if c := opts["class"]; c != "" {
+			class = c
+		}
+		return []*rstNode{{kind: rstAdmonition, class: class, title: text, children: d.parse(content)}}
+
+	case "topic", "sidebar":
+		return []*rstNode{{kind: rstTopic, class: name, title: text, children: d.parse(content)}}
+
+	case "rubric":
+		return []*rstNode{{kind: rstRubric, text: text}}
+
+	case "epigraph", "highlights", "pull-quote":
+		n := d.blockQuote(content)
+		n.class = name
+		return []*rstNode{n}
+
+	case "line-block":
+		n, _ := rstLineBlockAt(rstPrefixLines(content), 0)
+		return []*rstNode{n}
+
+	case "table":
+		nodes := d.parse(content)
+		for _, n := range nodes {
+			if n.kind == rstTable && text != "" {
+				n.table.caption = text
+			}
+		}
+		return nodes
+
+	case "list-table":
+		return []*rstNode{d.listTable(text, opts, content)}
+
+	case "csv-table":
+		if opts["file"] != "" || opts["url"] != "" {
+			return nil
+		}
+		return []*rstNode{d.csvTable(text, opts, content)}
+
+	case "title":
+		d.meta.Title = text
+		return nil
+
+	case "module", "currentmodule", "program", "contents", "sectnum", "index",
+		"meta", "default-role", "role", "tabularcolumns", "highlightlang",
+		"header", "footer", "default-domain", "autosummary":
+		return nil
+
+	case "centered":
+		return []*rstNode{{kind: rstContainer, class: "centered", children: []*rstNode{{kind: rstParagraph, text: text}}}}
+
+	case "container", "compound", "only", "ifconfig", "hlist", "glossary", "tab", "tab-set", "tab-item":
+		class := name
+		if name == "container" && text != "" {
+			class = text
+		}
+		return []*rstNode{{kind: rstContainer, class: class, children: d.parse(append(args, content...))}}
+	}
+
+	if strings.HasPrefix(name, "auto") {
+		// autodoc directives import and run the documented code.
+		return nil
+	}
+	if isDesc {
+		var sigs []string
+		for _, a := range args {
+			if a = strings.TrimSpace(a); a != "" {
+				sigs = append(sigs, strings.TrimSuffix(a, "\\"))
+			}
+		}
+		class := strings.ReplaceAll(name, ":", " ")
+		return []*rstNode{{kind: rstDesc, class: class, lines: sigs, children: d.parse(content)}}
+	}
+	return d.parse(append(args, content...))
+}
+
+// rstCode joins the lines of a code directive without leading and trailing
+// blank lines.
+func rstCode(lines []string) string {
+	for len(lines) > 0 && rstBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && rstBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// rstPrefixLines turns the lines of a line-block directive into line block
+// syntax.
+func rstPrefixLines(lines []string) []string {
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, "| "+line)
+	}
+	return out
+}
+
+// tocTree renders a toctree as a list of links to its documents. Glob
+// patterns are left out, as the documents they match are not known.
+func (d *rstDoc) tocTree(opts map[string]string, content []string) []*rstNode {
+	if _, hidden := opts["hidden"]; hidden {
+		return nil
+	}
+	n := &rstNode{kind: rstTocTree, title: opts["caption"]}
+	_, glob := opts["glob"]
+	for _, line := range content {
+		entry := strings.TrimSpace(line)
+		if entry == "" || entry == "self" || glob && strings.ContainsAny(entry, "*?[") {
+			continue
+		}
+		title, doc := "", entry
+		if i := strings.LastIndex(entry, "<"); i >= 0 && strings.HasSuffix(entry, ">") {
+			title, doc = strings.TrimSpace(entry[:i]), entry[i+1:len(entry)-1]
+		}
+		if title == "" {
+			title = doc
+		}
+		if !strings.Contains(doc, "://") && path.Ext(doc) == "" {
+			doc += ".rst"
+		}
+		n.entries = append(n.entries, [2]string{title, strings.TrimPrefix(doc, "/")})
+	}
+	return []*rstNode{n}
+}
@@ -0,0 +1,511 @@
+package render
+
+import (
+	"fmt"
+	gohtml "html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rstEscaped is added to escaped ASCII characters so that they are not
+// taken as markup; rstUnescape maps them back.
+const rstEscaped = 0xE000
+
+// rstMaxInline bounds how far an end-string is looked for, so that unclosed
+// markup in a long paragraph does not make rendering quadratic.
+const rstMaxInline = 16 << 10
+
+var (
+	rstRoleRe      = regexp.MustCompile(`^:([\w.+-]+(?::[\w.+-]+)*):`)
+	rstFootRefRe   = regexp.MustCompile(`^\[(\d+|#[\w.-]*|\*|[\w.-]+)\]_`)
+	rstWordRefRe   = regexp.MustCompile(`^[\p{L}\p{N}]+(?:[-.:+][\p{L}\p{N}]+|_[\p{L}\p{N}]+)*__?`)
+	rstURIRe       = regexp.MustCompile(`^(?:https?|ftp|sftp|file|mailto|git|ssh):[^\s<>"` + "`" + `]*[^\s<>"` + "`" + `.,;:!?'()\[\]{}]`)
+	rstEmailRe     = regexp.MustCompile(`^[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`)
+	rstInlineTgtRe = regexp.MustCompile("(?:^|[\\s(\"'<\\[{-])_`([^`]+)`")
+	rstEmbeddedRe  = regexp.MustCompile(`(?s)^(.*?)\s*<([^<>]+)>$`)
+	rstAbbrRe      = regexp.MustCompile(`^(.*?)\s*\((.*)\)$`)
+)
+
+// scanInlineTargets registers the inline targets (_`name`) of the document
+// so that references before them resolve.
+func (d *rstDoc) scanInlineTargets(source string) {
+	for _, m := range rstInlineTgtRe.FindAllStringSubmatch(source, -1) {
+		name := rstNormalize(m[1])
+		if _, ok := d.targets[name]; !ok {
+			d.targets[name] = "#" + slugID(m[1])
+		}
+	}
+}
+
+// inline renders reStructuredText inline markup as HTML.
+func (d *rstDoc) inline(src string) string {
+	s := rstProtect(src)
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if rstStartOK(s, i) {
+			if html, n := d.markup(s, i); n > 0 {
+				b.WriteString(html)
+				i += n
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			// Skip the rest of the word: markup cannot start inside it.
+			j := i + size
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			b.WriteString(rstEscapeText(s[i:j]))
+			i = j
+			continue
+		}
+		b.WriteString(rstEscapeText(s[i : i+size]))
+		i += size
+	}
+	return b.String()
+}
+
+// markup renders the inline markup starting at s[i] and returns its HTML and
+// length, or a zero length when none starts there.
+func (d *rstDoc) markup(s string, i int) (string, int) {
+	rest := s[i:]
+	switch {
+	case strings.HasPrefix(rest, "``"):
+		if end := rstFindEnd(s, i+2, "``"); end > 0 {
+			return "<code>" + rstEscapeLiteral(s[i+2:end]) + "</code>", end + 2 - i
+		}
+	case strings.HasPrefix(rest, "**"):
+		if end := rstFindEnd(s, i+2, "**"); end > 0 {
+			return "<strong>" + d.inline(rstUnprotect(s[i+2:end])) + "</strong>", end + 2 - i
+		}
+	case rest[0] == '*':
+		if end := rstFindEnd(s, i+1, "*"); end > 0 {
+			return "<em>" + d.inline(rstUnprotect(s[i+1:end])) + "</em>", end + 1 - i
+		}
+	case strings.HasPrefix(rest, "_`"):
+		if end := rstFindEnd(s, i+2, "`"); end > 0 {
+			text := s[i+2 : end]
+			return fmt.Sprintf("<span id=\"%s\">%s</span>", slugID(rstUnescape(text)), rstEscapeText(text)), end + 1 - i
+		}
+	case rest[0] == '`':
+		return d.interpreted(s, i, "")
+	case rest[0] == ':':
+		if m := rstRoleRe.FindString(rest); m != "" && strings.HasPrefix(rest[len(m):], "`") {
+			if html, n := d.interpreted(s, i+len(m), strings.Trim(m, ":")); n > 0 {
+				return html, n + len(m)
+			}
+		}
+	case rest[0] == '|':
+		return d.substitutionRef(s, i)
+	case rest[0] == '[':
+		if m := rstFootRefRe.FindStringSubmatch(rest); m != nil && rstEndOK(s, i+len(m[0])) {
+			return d.footnoteRef(m[1]), len(m[0])
+		}
+	}
+
+	if m := rstURIRe.FindString(rest); m != "" {
+		text := rstUnescape(m)
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(text), gohtml.EscapeString(text)), len(m)
+	}
+	if m := rstEmailRe.FindString(rest); m != "" {
+		text := rstUnescape(m)
+		return fmt.Sprintf("<a href=\"mailto:%s\">%s</a>", gohtml.EscapeString(text), gohtml.EscapeString(text)), len(m)
+	}
+	if m := rstWordRefRe.FindString(rest); m != "" && rstEndOK(s, i+len(m)) {
+		anonymous := strings.HasSuffix(m, "__")
+		text := strings.TrimSuffix(m[:len(m)-1], "_")
+		return d.reference(text, "", anonymous), len(m)
+	}
+	return "", 0
+}
+
+// interpreted renders interpreted text starting with the backquote at s[i]:
+// a role, a hyperlink reference, or default-role text. role is the prefix
+// role, if any.
+func (d *rstDoc) interpreted(s string, i int, role string) (string, int) {
+	for k := i + 1; k < len(s) && k-i < rstMaxInline; k++ {
+		if s[k] != '`' || k == i+1 || unicode.IsSpace(rune(s[k-1])) {
+			continue
+		}
+		end := k + 1
+		suffix := ""
+		switch {
+		case strings.HasPrefix(s[end:], "__"):
+			suffix = "__"
+		case strings.HasPrefix(s[end:], "_"):
+			suffix = "_"
+		case role == "":
+			if m := rstRoleRe.FindString(s[end:]); m != "" {
+				suffix = m
+			}
+		}
+		if !rstEndOK(s, end+len(suffix)) {
+			continue
+		}
+		text := s[i+1 : k]
+		n := end + len(suffix) - i
+		switch {
+		case suffix == "_" || suffix == "__":
+			return d.phraseRef(text, suffix == "__"), n
+		case suffix != "":
+			role = strings.Trim(suffix, ":")
+		}
+		return d.role(role, text), n
+	}
+	return "", 0
+}
+
+// phraseRef renders `text`_ and `text <url>`_ references.
+func (d *rstDoc) phraseRef(text string, anonymous bool) string {
+	text = rstUnescape(text)
+	if m := rstEmbeddedRe.FindStringSubmatch(text); m != nil {
+		label, target := m[1], rstJoinURL([]string{m[2]})
+		if label == "" {
+			label = target
+		}
+		if strings.HasSuffix(target, "_") && !strings.Contains(target, ":") || strings.HasPrefix(target, "`") {
+			// An embedded alias names another target.
+			return d.reference(label, rstNormalize(strings.Trim(strings.TrimSuffix(target, "_"), "`")), false)
+		}
+		if !anonymous {
+			if name := rstNormalize(label); d.targets[name] == "" {
+				d.targets[name] = target
+			}
+		}
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(target), gohtml.EscapeString(label))
+	}
+	return d.reference(text, "", anonymous)
+}
+
+// reference renders a reference to a named or anonymous target. An
+// undefined target leaves the text unlinked.
+func (d *rstDoc) reference(text, name string, anonymous bool) string {
+	var url string
+	switch {
+	case anonymous:
+		if d.anonRef < len(d.anonymous) {
+			url = d.anonymous[d.anonRef]
+			if alias, ok := strings.CutPrefix(url, "="); ok {
+				url = d.resolve(alias)
+			}
+		}
+		d.anonRef++
+	case name != "":
+		url = d.resolve(name)
+	default:
+		url = d.resolve(rstNormalize(text))
+	}
+	label := gohtml.EscapeString(rstUnescape(text))
+	if url == "" {
+		return label
+	}
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(url), label)
+}
+
+// role renders interpreted text with a docutils or Sphinx role. Sphinx
+// cross-reference roles without a known target render as code.
+func (d *rstDoc) role(role, text string) string {
+	name := role
+	if i := strings.LastIndex(role, ":"); i >= 0 {
+		name = role[i+1:]
+	}
+	raw := rstUnescape(text)
+	esc := gohtml.EscapeString(raw)
+	switch name {
+	case "", "title-reference", "title", "t":
+		return "<cite>" + d.inline(rstUnprotect(text)) + "</cite>"
+	case "emphasis":
+		return "<em>" + esc + "</em>"
+	case "strong":
+		return "<strong>" + esc + "</strong>"
+	case "literal", "code":
+		return "<code>" + rstEscapeLiteral(text) + "</code>"
+	case "subscript", "sub":
+		return "<sub>" + esc + "</sub>"
+	case "superscript", "sup":
+		return "<sup>" + esc + "</sup>"
+	case "math":
+		return "<code class=\"math\">" + esc + "</code>"
+	case "kbd":
+		return "<code class=\"kbd\">" + esc + "</code>"
+	case "file", "samp", "makevar", "mimetype", "newsgroup", "regexp":
+		return "<code class=\"file\">" + esc + "</code>"
+	case "command", "program", "dfn":
+		return "<strong class=\"command\">" + esc + "</strong>"
+	case "guilabel", "menuselection":
+		return "<span class=\"guilabel\">" + gohtml.EscapeString(strings.ReplaceAll(strings.ReplaceAll(raw, "-->", "‣"), "&", "")) + "</span>"
+	case "abbr":
+		if m := rstAbbrRe.FindStringSubmatch(raw); m != nil {
+			return fmt.Sprintf("<abbr title=\"%s\">%s</abbr>", gohtml.EscapeString(m[2]), gohtml.EscapeString(m[1]))
+		}
+		return "<abbr>" + esc + "</abbr>"
+	case "pep", "PEP":
+		num, anchor, _ := strings.Cut(raw, "#")
+		num = strings.TrimSpace(num)
+		href := "https://peps.python.org/pep-" + strings.Repeat("0", max(0, 4-len(num))) + num + "/"
+		if anchor != "" {
+			href += "#" + anchor
+		}
+		return fmt.Sprintf("<a href=\"%s\">PEP %s</a>", gohtml.EscapeString(href), gohtml.EscapeString(num))
+	case "rfc", "RFC":
+		num, anchor, _ := strings.Cut(raw, "#")
+		href := "https://datatracker.ietf.org/doc/html/rfc" + strings.TrimSpace(num)
+		if anchor != "" {
+			href += "#" + anchor
+		}
+		return fmt.Sprintf("<a href=\"%s\">RFC %s</a>", gohtml.EscapeString(href), gohtml.EscapeString(strings.TrimSpace(num)))
+	case "ref":
+		label, target := rstRoleTarget(raw)
+		key := rstNormalize(target)
+		if label == "" {
+			label = d.titles[key]
+		}
+		if label == "" {
+			label = target
+		}
+		if url := d.resolve(key); url != "" {
+			return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(url), d.inline(label))
+		}
+		return "<em>" + d.inline(label) + "</em>"
+	case "doc", "download":
+		label, target := rstRoleTarget(raw)
+		if label == "" {
+			label = target
+		}
+		if name == "doc" && !strings.Contains(target, "://") && !strings.Contains(target[strings.LastIndex(target, "/")+1:], ".") {
+			target += ".rst"
+		}
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(strings.TrimPrefix(target, "/")), gohtml.EscapeString(label))
+	case "term":
+		label, _ := rstRoleTarget(raw)
+		if label == "" {
+			label = raw
+		}
+		return "<em>" + gohtml.EscapeString(label) + "</em>"
+	}
+
+	// A cross-reference to a code object: "~a.b" shows "b", "!a" is not
+	// linked and "title <target>" shows the title.
+	label, target := rstRoleTarget(raw)
+	if label == "" {
+		label = strings.TrimPrefix(target, "!")
+		if rest, ok := strings.CutPrefix(label, "~"); ok {
+			label = rest[strings.LastIndex(rest, ".")+1:]
+		}
+	}
+	return "<code class=\"xref\">" + gohtml.EscapeString(label) + "</code>"
+}
+
+// rstRoleTarget splits "title <target>" role content; plain content is the
+// target with no title.
+func rstRoleTarget(text string) (string, string) {
+	if m := rstEmbeddedRe.FindStringSubmatch(text); m != nil && m[1] != "" {
+		return m[1], m[2]
+	}
+	return "", strings.TrimSpace(text)
+}
+
+// substitutionRef renders |name|, |name|_ and |name|__ starting at s[i].
+func (d *rstDoc) substitutionRef(s string, i int) (string, int) {
+	k := strings.IndexByte(s[i+1:], '|')
+	if k <= 0 {
+		return "", 0
+	}
+	end := i + 1 + k
+	name := s[i+1 : end]
+	if unicode.IsSpace(rune(name[0])) || unicode.IsSpace(rune(name[len(name)-1])) {
+		return "", 0
+	}
+	suffix := ""
+	switch {
+	case strings.HasPrefix(s[end+1:], "__"):
+		suffix = "__"
+	case strings.HasPrefix(s[end+1:], "_"):
+		suffix = "_"
+	}
+	if !rstEndOK(s, end+1+len(suffix)) {
+		return "", 0
+	}
+	name = rstUnescape(name)
+	sub := d.subs[name]
+	if sub == nil {
+		for k, v := range d.subs {
+			if strings.EqualFold(k, name) {
+				sub = v
+			}
+		}
+	}
+
+	var html string
+	switch {
+	case sub == nil:
+		html = gohtml.EscapeString("|" + name + "|")
+	case sub.kind == rstImage:
+		opts := sub.opts
+		if opts["alt"] == "" {
+			opts = map[string]string{"alt": name}
+			for k, v := range sub.opts {
+				if k != "alt" {
+					opts[k] = v
+				}
+			}
+		}
+		html = d.image(sub.text, opts)
+	case sub.kind == rstRaw:
+		html = sub.text
+	case d.depth < 8:
+		d.depth++
+		html = d.inline(sub.text)
+		d.depth--
+	}
+	if suffix != "" {
+		if url := d.resolve(rstNormalize(name)); url != "" && suffix == "_" {
+			html = fmt.Sprintf("<a href=\"%s\">%s</a>", gohtml.EscapeString(url), html)
+		}
+	}
+	return html, end + 1 + len(suffix) - i
+}
+
+// footnoteRef renders a footnote or citation reference. The first reference
+// to each footnote gets the ID its backlink points to.
+func (d *rstDoc) footnoteRef(label string) string {
+	var n *rstNode
+	switch {
+	case label == "#":
+		if d.autoRef < len(d.auto) {
+			n = d.auto[d.autoRef]
+		}
+		d.autoRef++
+	case label == "*":
+		if d.symbolRef < len(d.symbols) {
+			n = d.symbols[d.symbolRef]
+		}
+		d.symbolRef++
+	case strings.HasPrefix(label, "#"), strings.Trim(label, "0123456789") == "":
+		n = d.labels[label]
+	default:
+		n = d.labels[rstNormalize(label)]
+	}
+	if n == nil {
+		return gohtml.EscapeString("[" + label + "]")
+	}
+	class := "footnote-reference"
+	if n.class == "citation" {
+		class = "citation-reference"
+	}
+	id := ""
+	if !d.cited[n] {
+		d.cited[n] = true
+		id = fmt.Sprintf(" id=\"ref-%s\"", n.id)
+	}
+	return fmt.Sprintf("<a class=\"%s\" href=\"#%s\"%s>[%s]</a>", class, n.id, id, gohtml.EscapeString(n.title))
+}
+
+// rstStartOK reports whether inline markup may start at s[i]: at the start
+// of the text or after whitespace or opening punctuation.
+func rstStartOK(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	if unicode.IsSpace(prev) || strings.ContainsRune(`-:/'"<([{`, prev) {
+		return true
+	}
+	return unicode.In(prev, unicode.Pd, unicode.Ps, unicode.Pi, unicode.Pf, unicode.Po) && prev != '*' && prev != '`' && prev != '|'
+}
+
+// rstEndOK reports whether inline markup may end before s[j]: at the end of
+// the text or before whitespace or closing punctuation.
+func rstEndOK(s string, j int) bool {
+	if j >= len(s) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(s[j:])
+	if unicode.IsSpace(next) || strings.ContainsRune(`-.,:;!?\/'")]}>`, next) {
+		return true
+	}
+	return unicode.In(next, unicode.Pd, unicode.Pe, unicode.Pi, unicode.Pf, unicode.Po)
+}
+
+// rstFindEnd returns the index of the end-string closing markup whose
+// content starts at s[from], or -1. The content must not start or end with
+// whitespace.
+func rstFindEnd(s string, from int, end string) int {
+	if from >= len(s) || unicode.IsSpace(rune(s[from])) {
+		return -1
+	}
+	for k := from + 1; k+len(end) <= len(s) && k-from < rstMaxInline; k++ {
+		if !strings.HasPrefix(s[k:], end) || unicode.IsSpace(rune(s[k-1])) {
+			continue
+		}
+		if rstEndOK(s, k+len(end)) {
+			return k
+		}
+	}
+	return -1
+}
+
+// rstProtect replaces backslash-escaped characters with private-use
+// characters and drops escaped whitespace.
+func rstProtect(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; {
+		case c == ' ' || c == '\n':
+		case c < utf8.RuneSelf:
+			b.WriteRune(rstEscaped + rune(c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// rstUnprotect turns escaped characters back into backslash escapes, for
+// content rendered again as inline markup.
+func rstUnprotect(s string) string {
+	return rstMapEscaped(s, func(b *strings.Builder, c rune) { b.WriteByte('\\'); b.WriteRune(c) })
+}
+
+// rstUnescape turns escaped characters back into the characters.
+func rstUnescape(s string) string {
+	return rstMapEscaped(s, func(b *strings.Builder, c rune) { b.WriteRune(c) })
+}
+
+func rstMapEscaped(s string, f func(*strings.Builder, rune)) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= rstEscaped && r < rstEscaped+utf8.RuneSelf {
+			f(&b, r-rstEscaped)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// rstEscapeText escapes text for HTML, restoring escaped characters.
+func rstEscapeText(s string) string {
+	return gohtml.EscapeString(rstUnescape(s))
+}
+
+// rstEscapeLiteral escapes inline literal text, in which backslashes are not
+// escapes.
+func rstEscapeLiteral(s string) string {
+	return gohtml.EscapeString(rstUnprotect(s))
+}
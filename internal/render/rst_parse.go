@@ -0,0 +1,666 @@
+package render
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	rstBulletRe    = regexp.MustCompile(`^([-*+•‣⁃])(?: +|$)`)
+	rstEnumRe      = regexp.MustCompile(`^(\(?)(\d+|#|[a-zA-Z]|[ivxlcdmIVXLCDM]+)([.)])(?: +|$)`)
+	rstFieldRe     = regexp.MustCompile(`^:((?:[^:\\\s]|\\.)(?:[^:\\]|\\.)*?):(?: +|$)`)
+	rstOptionRe    = regexp.MustCompile(`^((?:--?\w[\w-]*|/\w+)(?:[ =](?:<[^>]+>|[\w.-]+))?(?:, (?:--?\w[\w-]*|/\w+)(?:[ =](?:<[^>]+>|[\w.-]+))?)*)(?:  +(.*))?$`)
+	rstLineBlockRe = regexp.MustCompile(`^\|(?: +|$)`)
+	rstGridRe      = regexp.MustCompile(`^\+(?:[-=]+\+)+$`)
+	rstSimpleRe    = regexp.MustCompile(`^=+(?: +=+)+$`)
+	rstFootnoteRe  = regexp.MustCompile(`^\[([^\]\s]+)\](?: +(.*))?$`)
+	rstTargetRe    = regexp.MustCompile("^_(`[^`]+`|(?:[^:\\\\]|\\\\.)*):(?: +(.*))?$")
+	rstSubDefRe    = regexp.MustCompile(`^\|([^|\s](?:[^|]*[^|\s])?)\| +([\w:.+-]+)::(?: +(.*))?$`)
+	rstDirectiveRe = regexp.MustCompile(`^(\w[\w:.+-]*)::(?: +(.*))?$`)
+)
+
+// rstLines splits source into lines with tabs expanded to eight-column stops
+// and trailing whitespace removed.
+func rstLines(source []byte) []string {
+	text := strings.ReplaceAll(string(source), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "\t") {
+			var b strings.Builder
+			col := 0
+			for _, r := range line {
+				if r == '\t' {
+					n := 8 - col%8
+					b.WriteString(strings.Repeat(" ", n))
+					col += n
+					continue
+				}
+				b.WriteRune(r)
+				col++
+			}
+			line = b.String()
+		}
+		lines[i] = strings.TrimRight(line, " \r\f\v")
+	}
+	return lines
+}
+
+func rstBlank(line string) bool { return strings.TrimSpace(line) == "" }
+
+func rstIndent(line string) int { return len(line) - len(strings.TrimLeft(line, " ")) }
+
+// rstSkipBlank returns the index of the first non-blank line from i.
+func rstSkipBlank(lines []string, i int) int {
+	for i < len(lines) && rstBlank(lines[i]) {
+		i++
+	}
+	return i
+}
+
+// rstIndented returns the indented block starting at lines[start], up to its
+// last indented line, with the common indentation removed, and the index of
+// the line after it. Blank lines inside the block are kept.
+func rstIndented(lines []string, start int) ([]string, int) {
+	end := start
+	for j := start; j < len(lines); j++ {
+		if rstBlank(lines[j]) {
+			continue
+		}
+		if rstIndent(lines[j]) == 0 {
+			break
+		}
+		end = j + 1
+	}
+	return rstDedent(lines[start:end]), end
+}
+
+// rstDedent removes the common indentation of lines.
+func rstDedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if !rstBlank(line) && (indent < 0 || rstIndent(line) < indent) {
+			indent = rstIndent(line)
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if !rstBlank(line) {
+			out[i] = line[indent:]
+		}
+	}
+	return out
+}
+
+// rstAdornment reports whether line is a section adornment: one punctuation
+// character repeated.
+func rstAdornment(line string) bool {
+	if len(line) < 2 || !strings.ContainsRune(`!"#$%&'()*+,-./:;<=>?@[\]^_`+"`"+`{|}~`, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// parse parses lines, dedented to the left margin of a body, into elements.
+func (d *rstDoc) parse(lines []string) []*rstNode {
+	var nodes []*rstNode
+	add := func(n *rstNode) {
+		switch n.kind {
+		case rstSection:
+			for _, label := range d.pending {
+				d.titles[label] = n.text
+			}
+			d.pending = nil
+		case rstTarget:
+		default:
+			d.pending = nil
+		}
+		nodes = append(nodes, n)
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if rstBlank(line) {
+			i++
+			continue
+		}
+
+		if rstIndent(line) > 0 {
+			block, next := rstIndented(lines, i)
+			add(d.blockQuote(block))
+			i = next
+			continue
+		}
+
+		if n, next, ok := d.section(lines, i); ok {
+			add(n)
+			i = next
+			continue
+		}
+
+		var n *rstNode
+		next := i
+		switch {
+		case strings.HasPrefix(line, "..") && (len(line) == 2 || line[2] == ' '):
+			var out []*rstNode
+			out, next = d.explicit(lines, i)
+			for _, n := range out {
+				add(n)
+			}
+			i = next
+			continue
+		case strings.HasPrefix(line, "__ "):
+			block, end := rstIndented(lines, i+1)
+			d.anonymous = append(d.anonymous, rstJoinURL(append([]string{line[3:]}, block...)))
+			i = end
+			continue
+		case rstGridRe.MatchString(line):
+			n, next = d.gridTable(lines, i)
+		case rstSimpleRe.MatchString(line):
+			n, next = d.simpleTable(lines, i)
+		case rstLineBlockRe.MatchString(line):
+			n, next = rstLineBlockAt(lines, i)
+		case strings.HasPrefix(line, ">>> "):
+			next = i
+			for next < len(lines) && !rstBlank(lines[next]) {
+				next++
+			}
+			n = &rstNode{kind: rstLiteral, text: strings.Join(lines[i:next], "\n") + "\n", lang: "pycon"}
+		}
+		if n == nil {
+			var ok bool
+			if n, next, ok = d.list(lines, i); !ok {
+				n, next = nil, i
+			}
+		}
+		if n != nil {
+			add(n)
+			i = next
+			continue
+		}
+
+		var out []*rstNode
+		out, i = d.paragraph(lines, i)
+		for _, n := range out {
+			add(n)
+		}
+	}
+	return nodes
+}
+
+// section parses a section title or a transition at lines[i].
+func (d *rstDoc) section(lines []string, i int) (*rstNode, int, bool) {
+	line := lines[i]
+	var title, style string
+	next := i
+	switch {
+	case rstAdornment(line) && i+2 < len(lines) && !rstBlank(lines[i+1]) &&
+		rstAdornment(lines[i+2]) && lines[i+2][0] == line[0]:
+		title, style, next = strings.TrimSpace(lines[i+1]), "o"+line[:1], i+3
+	case !rstAdornment(line) && i+1 < len(lines) && rstAdornment(lines[i+1]) &&
+		(len(lines[i+1]) >= utf8.RuneCountInString(line) || len(lines[i+1]) >= 4):
+		title, style, next = line, "u"+lines[i+1][:1], i+2
+	case rstAdornment(line) && len(line) >= 4 && (i == 0 || rstBlank(lines[i-1])) &&
+		(i+1 == len(lines) || rstBlank(lines[i+1])):
+		return &rstNode{kind: rstTransition}, i + 1, true
+	default:
+		return nil, i, false
+	}
+
+	level := 0
+	for j, s := range d.styles {
+		if s == style {
+			level = j + 1
+		}
+	}
+	if level == 0 {
+		d.styles = append(d.styles, style)
+		level = len(d.styles)
+	}
+	id := d.newID(title)
+	if name := rstNormalize(title); d.targets[name] == "" {
+		d.targets[name] = "#" + id
+	}
+	return &rstNode{kind: rstSection, text: title, level: min(level, 6), id: id}, next, true
+}
+
+// paragraph parses the paragraph at lines[i] and the literal block its
+// trailing "::" introduces.
+func (d *rstDoc) paragraph(lines []string, i int) ([]*rstNode, int) {
+	j := i + 1
+	for j < len(lines) && !rstBlank(lines[j]) && rstIndent(lines[j]) == 0 {
+		j++
+	}
+	text := strings.Join(lines[i:j], "\n")
+	if !strings.HasSuffix(text, "::") {
+		return []*rstNode{{kind: rstParagraph, text: text}}, j
+	}
+
+	var nodes []*rstNode
+	switch {
+	case text == "::":
+	case strings.HasSuffix(text, " ::"), strings.HasSuffix(text, "\n::"):
+		nodes = append(nodes, &rstNode{kind: rstParagraph, text: strings.TrimSpace(text[:len(text)-2])})
+	default:
+		nodes = append(nodes, &rstNode{kind: rstParagraph, text: text[:len(text)-1]})
+	}
+
+	k := rstSkipBlank(lines, j)
+	if k == j || k >= len(lines) {
+		return nodes, j
+	}
+	var code []string
+	if rstIndent(lines[k]) > 0 {
+		code, j = rstIndented(lines, k)
+	} else if rstAdornment(lines[k][:1] + lines[k][:1]) {
+		// A quoted literal block: unindented lines starting with the same
+		// punctuation character.
+		for j = k; j < len(lines) && !rstBlank(lines[j]) && lines[j][0] == lines[k][0]; j++ {
+			code = append(code, lines[j])
+		}
+	} else {
+		return nodes, j
+	}
+	literal := &rstNode{kind: rstLiteral, text: strings.Join(code, "\n") + "\n", lang: d.highlight}
+	return append(nodes, literal), j
+}
+
+// blockQuote parses an indented block, taking a final paragraph that starts
+// with a dash as its attribution.
+func (d *rstDoc) blockQuote(block []string) *rstNode {
+	n := &rstNode{kind: rstBlockQuote, children: d.parse(block)}
+	if len(n.children) > 1 {
+		last := n.children[len(n.children)-1]
+		for _, dash := range []string{"--- ", "-- ", "— "} {
+			if rest, ok := strings.CutPrefix(last.text, dash); ok && last.kind == rstParagraph {
+				n.title = rest
+				n.children = n.children[:len(n.children)-1]
+				break
+			}
+		}
+	}
+	return n
+}
+
+// list parses a bullet, enumerated, field, option or definition list at
+// lines[i].
+func (d *rstDoc) list(lines []string, i int) (*rstNode, int, bool) {
+	line := lines[i]
+	if m := rstBulletRe.FindStringSubmatch(line); m != nil {
+		n := &rstNode{kind: rstBulletList}
+		for i < len(lines) {
+			m2 := rstBulletRe.FindStringSubmatch(lines[i])
+			if m2 == nil || m2[1] != m[1] {
+				break
+			}
+			var body []string
+			body, i = rstItemBody(lines, i, len(m2[0]))
+			n.children = append(n.children, &rstNode{kind: rstListItem, children: d.parse(body)})
+			i = rstSkipBlank(lines, i)
+		}
+		return n, i, true
+	}
+
+	if m := rstEnumRe.FindStringSubmatch(line); m != nil && rstEnumFormat(m) != "" && rstEnumStarts(lines, i) {
+		format := rstEnumFormat(m)
+		listType, start := rstEnumType(m[2])
+		n := &rstNode{kind: rstEnumList, class: listType, level: start}
+		for i < len(lines) {
+			m2 := rstEnumRe.FindStringSubmatch(lines[i])
+			if m2 == nil || rstEnumFormat(m2) != format {
+				break
+			}
+			if t, _ := rstEnumType(m2[2]); t != listType && m2[2] != "#" {
+				break
+			}
+			var body []string
+			body, i = rstItemBody(lines, i, len(m2[0]))
+			n.children = append(n.children, &rstNode{kind: rstListItem, children: d.parse(body)})
+			i = rstSkipBlank(lines, i)
+		}
+		return n, i, true
+	}
+
+	if rstFieldRe.MatchString(line) {
+		n := &rstNode{kind: rstFieldList}
+		for i < len(lines) {
+			m := rstFieldRe.FindStringSubmatch(lines[i])
+			if m == nil {
+				break
+			}
+			var body []string
+			body, i = rstItemBody(lines, i, len(m[0]))
+			n.children = append(n.children, &rstNode{kind: rstField, text: m[1], children: d.parse(body)})
+			i = rstSkipBlank(lines, i)
+		}
+		return n, i, true
+	}
+
+	if m := rstOptionRe.FindStringSubmatch(line); m != nil && (m[2] != "" || rstIndentedNext(lines, i)) {
+		n := &rstNode{kind: rstOptionList}
+		for i < len(lines) {
+			m := rstOptionRe.FindStringSubmatch(lines[i])
+			if m == nil || (m[2] == "" && !rstIndentedNext(lines, i)) {
+				break
+			}
+			block, next := rstIndented(lines, i+1)
+			body := block
+			if m[2] != "" {
+				body = append([]string{m[2]}, block...)
+			}
+			n.children = append(n.children, &rstNode{kind: rstOption, text: m[1], children: d.parse(body)})
+			i = rstSkipBlank(lines, next)
+		}
+		return n, i, true
+	}
+
+	if rstIndentedNext(lines, i) {
+		n := &rstNode{kind: rstDefList}
+		for i < len(lines) && rstIndent(lines[i]) == 0 && rstIndentedNext(lines, i) {
+			parts := strings.Split(lines[i], " : ")
+			block, next := rstIndented(lines, i+1)
+			n.children = append(n.children, &rstNode{kind: rstDefItem, text: parts[0], lines: parts[1:], children: d.parse(block)})
+			i = rstSkipBlank(lines, next)
+		}
+		return n, i, true
+	}
+	return nil, i, false
+}
+
+// rstIndentedNext reports whether the line after lines[i] is indented.
+func rstIndentedNext(lines []string, i int) bool {
+	return i+1 < len(lines) && !rstBlank(lines[i+1]) && rstIndent(lines[i+1]) > 0
+}
+
+// rstItemBody returns the body of the list item or field at lines[i] whose
+// marker is width bytes wide, and the index of the line after it.
+func rstItemBody(lines []string, i, width int) ([]string, int) {
+	first := strings.TrimSpace(lines[i][width:])
+	block, next := rstIndented(lines, i+1)
+	if first == "" {
+		return block, next
+	}
+	return append([]string{first}, block...), next
+}
+
+// rstEnumFormat returns the punctuation pattern of an enumerator, or "" for
+// an unbalanced parenthesis.
+func rstEnumFormat(m []string) string {
+	switch {
+	case m[1] == "(" && m[3] == ")":
+		return "()"
+	case m[1] == "" && m[3] == ")":
+		return ")"
+	case m[1] == "" && m[3] == ".":
+		return "."
+	}
+	return ""
+}
+
+// rstEnumStarts reports whether the enumerator at lines[i] starts a list:
+// its item must end there or continue with an indented block, so that
+// sentences like "A. Person said" stay paragraphs.
+func rstEnumStarts(lines []string, i int) bool {
+	return i+1 >= len(lines) || rstBlank(lines[i+1]) || rstIndent(lines[i+1]) > 0 ||
+		rstEnumRe.MatchString(lines[i+1])
+}
+
+// rstEnumType returns the HTML list type and ordinal of an enumerator.
+func rstEnumType(e string) (string, int) {
+	switch {
+	case e == "#":
+		return "1", 1
+	case e[0] >= '0' && e[0] <= '9':
+		n, _ := strconv.Atoi(e)
+		return "1", n
+	case (len(e) > 1 || e == "i" || e == "I") && strings.Trim(strings.ToLower(e), "ivxlcdm") == "":
+		t := "i"
+		if e[0] < 'a' {
+			t = "I"
+		}
+		return t, rstRoman(strings.ToLower(e))
+	case e[0] >= 'a':
+		return "a", int(e[0]-'a') + 1
+	default:
+		return "A", int(e[0]-'A') + 1
+	}
+}
+
+// rstRoman returns the value of a lowercase Roman numeral.
+func rstRoman(s string) int {
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		v := values[s[i]]
+		if i+1 < len(s) && values[s[i+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
+
+// rstLineBlockAt parses the line block at lines[i]. Indented lines continue
+// the line before them.
+func rstLineBlockAt(lines []string, i int) (*rstNode, int) {
+	n := &rstNode{kind: rstLineBlock}
+	for ; i < len(lines) && !rstBlank(lines[i]); i++ {
+		line := lines[i]
+		if rstLineBlockRe.MatchString(line) {
+			n.lines = append(n.lines, strings.TrimPrefix(strings.TrimPrefix(line, "|"), " "))
+		} else if len(n.lines) > 0 {
+			n.lines[len(n.lines)-1] += " " + strings.TrimSpace(line)
+		}
+	}
+	return n, i
+}
+
+// rstJoinURL joins the lines of a hyperlink target URL, dropping whitespace.
+func rstJoinURL(lines []string) string {
+	return strings.Join(strings.Fields(strings.Join(lines, " ")), "")
+}
+
+// explicit parses the explicit markup block at lines[i]: a footnote,
+// citation, hyperlink target, substitution definition, directive or comment.
+func (d *rstDoc) explicit(lines []string, i int) ([]*rstNode, int) {
+	rest := strings.TrimSpace(lines[i][2:])
+	block, next := rstIndented(lines, i+1)
+	if rest == "" {
+		// An empty comment ends at the first blank line.
+		if i+1 < len(lines) && rstBlank(lines[i+1]) {
+			return nil, i + 1
+		}
+		return nil, next
+	}
+
+	if m := rstFootnoteRe.FindStringSubmatch(rest); m != nil {
+		body := block
+		if m[2] != "" {
+			body = append([]string{m[2]}, block...)
+		}
+		return []*rstNode{d.footnote(m[1], d.parse(body))}, next
+	}
+
+	if m := rstTargetRe.FindStringSubmatch(rest); m != nil {
+		return d.target(m[1], append([]string{m[2]}, block...)), next
+	}
+
+	if m := rstSubDefRe.FindStringSubmatch(rest); m != nil {
+		d.substitution(m[1], m[2], m[3], block)
+		return nil, next
+	}
+
+	if m := rstDirectiveRe.FindStringSubmatch(rest); m != nil {
+		return d.directive(strings.ToLower(m[1]), m[2], block), next
+	}
+	return nil, next
+}
+
+// footnote registers a footnote or citation by its label.
+func (d *rstDoc) footnote(label string, body []*rstNode) *rstNode {
+	n := &rstNode{kind: rstFootnote, text: label, title: label, children: body}
+	switch {
+	case label == "#":
+		d.auto = append(d.auto, n)
+	case label == "*":
+		d.symbols = append(d.symbols, n)
+	case strings.HasPrefix(label, "#"):
+		d.labels[label] = n
+	case strings.Trim(label, "0123456789") == "":
+		n.id = d.newID("footnote-" + label)
+		d.labels[label] = n
+	default:
+		n.class = "citation"
+		n.id = d.newID("citation-" + label)
+		d.labels[rstNormalize(label)] = n
+	}
+	d.footnotes = append(d.footnotes, n)
+	return n
+}
+
+var rstSymbols = []string{"*", "†", "‡", "§", "¶", "#", "♠", "♥", "♦", "♣"}
+
+// numberFootnotes numbers auto-numbered footnotes with the numbers manual
+// footnotes leave free, in document order, and assigns auto-symbol labels.
+func (d *rstDoc) numberFootnotes() {
+	used := map[string]bool{}
+	for _, n := range d.footnotes {
+		if n.id != "" && n.class == "" {
+			used[n.text] = true
+		}
+	}
+	next := 1
+	symbol := 0
+	for _, n := range d.footnotes {
+		switch {
+		case n.text == "*":
+			n.title = strings.Repeat(rstSymbols[symbol%len(rstSymbols)], symbol/len(rstSymbols)+1)
+			n.id = d.newID("footnote-symbol-" + strconv.Itoa(symbol+1))
+			symbol++
+		case strings.HasPrefix(n.text, "#"):
+			for used[strconv.Itoa(next)] {
+				next++
+			}
+			n.title = strconv.Itoa(next)
+			used[n.title] = true
+			n.id = d.newID("footnote-" + n.title)
+		}
+	}
+}
+
+// target registers a hyperlink target. Targets without a URL mark the next
+// element and are rendered as anchors.
+func (d *rstDoc) target(name string, urlLines []string) []*rstNode {
+	if strings.HasPrefix(name, "`") {
+		name = strings.Trim(name, "`")
+	}
+	name = strings.ReplaceAll(name, `\:`, ":")
+	url := rstJoinURL(urlLines)
+	if strings.HasSuffix(url, "_") && !strings.Contains(url, ":") || strings.HasPrefix(url, "`") && strings.HasSuffix(url, "`_") {
+		// An indirect target names another target.
+		url = "=" + rstNormalize(strings.Trim(strings.TrimSuffix(url, "_"), "`"))
+	}
+	if name == "_" {
+		d.anonymous = append(d.anonymous, url)
+		return nil
+	}
+	label := rstNormalize(name)
+	if url != "" {
+		d.targets[label] = url
+		return nil
+	}
+	id := d.newID(name)
+	d.targets[label] = "#" + id
+	d.pending = append(d.pending, label)
+	return []*rstNode{{kind: rstTarget, id: id}}
+}
+
+// substitution registers a substitution definition. Only the replace, image
+// and unicode directives define substitutions.
+func (d *rstDoc) substitution(name, directive, arg string, block []string) {
+	args, opts, content := rstDirectiveParts(arg, block, true)
+	switch directive {
+	case "replace":
+		d.subs[name] = &rstNode{kind: rstParagraph, text: strings.Join(append(args, content...), "\n")}
+	case "image":
+		d.subs[name] = &rstNode{kind: rstImage, text: rstJoinURL(args), opts: opts}
+	case "unicode":
+		d.subs[name] = &rstNode{kind: rstRaw, text: rstUnicode(strings.Join(args, " "))}
+	}
+}
+
+// rstUnicode decodes the character codes and text of a unicode directive.
+func rstUnicode(arg string) string {
+	if i := strings.Index(arg, " .. "); i >= 0 {
+		arg = arg[:i]
+	}
+	var b strings.Builder
+	for _, tok := range strings.Fields(arg) {
+		code := tok
+		for _, prefix := range []string{"0x", "x", "\\x", "U+", "u", "\\u", "&#x"} {
+			if rest, ok := strings.CutPrefix(tok, prefix); ok {
+				code = strings.TrimSuffix(rest, ";")
+				if v, err := strconv.ParseUint(code, 16, 32); err == nil {
+					b.WriteRune(rune(v))
+					code = ""
+				}
+				break
+			}
+		}
+		if code == "" {
+			continue
+		}
+		if v, err := strconv.ParseUint(tok, 10, 32); err == nil {
+			b.WriteRune(rune(v))
+		} else {
+			b.WriteString(tok)
+		}
+	}
+	return rstEscapeText(b.String())
+}
+
+// rstDirectiveParts splits a directive's first line and indented block into
+// its argument lines, options and content. When hasArg is false the lines
+// before the first blank line that are not options are content.
+func rstDirectiveParts(arg string, block []string, hasArg bool) (args []string, opts map[string]string, content []string) {
+	opts = map[string]string{}
+	header := block
+	for i, line := range block {
+		if rstBlank(line) {
+			header, content = block[:i], block[i+1:]
+			break
+		}
+	}
+	if len(header) == len(block) {
+		content = nil
+	}
+	if arg != "" {
+		args = append(args, arg)
+	}
+
+	i := 0
+	if hasArg {
+		for ; i < len(header) && !rstFieldRe.MatchString(header[i]); i++ {
+			args = append(args, header[i])
+		}
+	}
+	last := ""
+	for ; i < len(header); i++ {
+		if m := rstFieldRe.FindStringSubmatch(header[i]); m != nil {
+			last = strings.ToLower(m[1])
+			opts[last] = strings.TrimSpace(header[i][len(m[0]):])
+		} else if last != "" && rstIndent(header[i]) > 0 {
+			opts[last] = strings.TrimSpace(opts[last] + " " + strings.TrimSpace(header[i]))
+		} else {
+			// Not an option block after all.
+			return args, map[string]string{}, append(header[i:], content...)
+		}
+	}
+	if !hasArg && len(opts) == 0 {
+		return args, opts, block[rstSkipBlank(block, 0):]
+	}
+	return args, opts, content
+}
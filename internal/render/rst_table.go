@@ -0,0 +1,435 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rstTableData is a parsed table: header and body rows of cells.
+type rstTableData struct {
+	caption string
+	head    [][]*rstCell
+	body    [][]*rstCell
+}
+
+type rstCell struct {
+	colspan  int
+	rowspan  int
+	children []*rstNode
+}
+
+// writeTable writes a table. Single-paragraph cells are written as bare
+// inline content.
+func (d *rstDoc) writeTable(w *bytes.Buffer, t *rstTableData) error {
+	w.WriteString("<table>\n")
+	if t.caption != "" {
+		fmt.Fprintf(w, "<caption>%s</caption>\n", d.inline(t.caption))
+	}
+	for _, part := range []struct {
+		tag, cell string
+		rows      [][]*rstCell
+	}{{"thead", "th", t.head}, {"tbody", "td", t.body}} {
+		if len(part.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "<%s>\n", part.tag)
+		for _, row := range part.rows {
+			w.WriteString("<tr>")
+			for _, c := range row {
+				w.WriteString("<" + part.cell)
+				if c.colspan > 1 {
+					fmt.Fprintf(w, " colspan=\"%d\"", c.colspan)
+				}
+				if c.rowspan > 1 {
+					fmt.Fprintf(w, " rowspan=\"%d\"", c.rowspan)
+				}
+				w.WriteString(">")
+				if err := d.writeCompact(w, c.children); err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "</%s>", part.cell)
+			}
+			w.WriteString("</tr>\n")
+		}
+		fmt.Fprintf(w, "</%s>\n", part.tag)
+	}
+	w.WriteString("</table>\n")
+	return nil
+}
+
+// cellBody parses the lines of a table cell.
+func (d *rstDoc) cellBody(lines []string) []*rstNode {
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return d.parse(rstDedent(lines))
+}
+
+// gridTable parses the grid table at lines[i]. A malformed table is
+// rendered as a literal block, as docutils does after reporting it.
+func (d *rstDoc) gridTable(lines []string, i int) (*rstNode, int) {
+	end := i
+	for end < len(lines) && !rstBlank(lines[end]) && (lines[end][0] == '+' || lines[end][0] == '|') {
+		end++
+	}
+	block := make([][]rune, 0, end-i)
+	for _, line := range lines[i:end] {
+		block = append(block, []rune(line))
+	}
+	if t := d.parseGrid(block); t != nil {
+		return &rstNode{kind: rstTable, table: t}, end
+	}
+	return &rstNode{kind: rstLiteral, text: strings.Join(lines[i:end], "\n") + "\n"}, end
+}
+
+// gridCell is a cell found in a grid table by the corners of its border.
+type gridCell struct{ top, left, bottom, right int }
+
+// parseGrid finds the cells of a grid table by following cell borders
+// clockwise from each top-left corner, as the docutils grid table parser
+// does.
+func (d *rstDoc) parseGrid(block [][]rune) *rstTableData {
+	if len(block) < 3 {
+		return nil
+	}
+	width := len(block[0])
+	for _, line := range block {
+		if len(line) != width {
+			return nil
+		}
+	}
+	headSep := -1
+	for r, line := range block[1:] {
+		if line[0] == '+' && strings.Contains(string(line), "=") {
+			headSep = r + 1
+			break
+		}
+	}
+
+	done := make([][]bool, len(block))
+	for r := range done {
+		done[r] = make([]bool, width)
+	}
+	var cells []gridCell
+	corners := [][2]int{{0, 0}}
+	for len(corners) > 0 {
+		top, left := corners[0][0], corners[0][1]
+		corners = corners[1:]
+		if top == len(block)-1 || left == width-1 || done[top][left] {
+			continue
+		}
+		c, ok := scanGridCell(block, top, left)
+		if !ok {
+			return nil
+		}
+		for r := c.top; r < c.bottom; r++ {
+			for col := c.left; col < c.right; col++ {
+				done[r][col] = true
+			}
+		}
+		cells = append(cells, c)
+		corners = append(corners, [2]int{c.top, c.right}, [2]int{c.bottom, c.left})
+		slices.SortFunc(corners, func(a, b [2]int) int {
+			if a[0] != b[0] {
+				return a[0] - b[0]
+			}
+			return a[1] - b[1]
+		})
+	}
+	for r := range len(block) - 1 {
+		for col := range width - 1 {
+			if !done[r][col] {
+				return nil
+			}
+		}
+	}
+
+	var rowSeps, colSeps []int
+	for _, c := range cells {
+		rowSeps = append(rowSeps, c.top, c.bottom)
+		colSeps = append(colSeps, c.left, c.right)
+	}
+	slices.Sort(rowSeps)
+	rowSeps = slices.Compact(rowSeps)
+	slices.Sort(colSeps)
+	colSeps = slices.Compact(colSeps)
+
+	t := &rstTableData{}
+	rows := make([][]*rstCell, len(rowSeps)-1)
+	for _, c := range cells {
+		row, _ := slices.BinarySearch(rowSeps, c.top)
+		bottom, _ := slices.BinarySearch(rowSeps, c.bottom)
+		left, _ := slices.BinarySearch(colSeps, c.left)
+		right, _ := slices.BinarySearch(colSeps, c.right)
+		var content []string
+		for r := c.top + 1; r < c.bottom; r++ {
+			content = append(content, string(block[r][c.left+1:c.right]))
+		}
+		rows[row] = append(rows[row], &rstCell{colspan: right - left, rowspan: bottom - row, children: d.cellBody(content)})
+	}
+	headRows := 0
+	if headSep > 0 {
+		headRows, _ = slices.BinarySearch(rowSeps, headSep)
+	}
+	t.head, t.body = rows[:headRows], rows[headRows:]
+	return t
+}
+
+// scanGridCell follows the border of the cell whose top-left corner is at
+// (top, left) right, down, left and up back to the corner.
+func scanGridCell(block [][]rune, top, left int) (gridCell, bool) {
+	width := len(block[0])
+	for right := left + 1; right < width; right++ {
+		switch block[top][right] {
+		case '+':
+			if bottom, ok := scanGridDown(block, top, left, right); ok {
+				return gridCell{top, left, bottom, right}, true
+			}
+		case '-', '=':
+		default:
+			return gridCell{}, false
+		}
+	}
+	return gridCell{}, false
+}
+
+func scanGridDown(block [][]rune, top, left, right int) (int, bool) {
+	for bottom := top + 1; bottom < len(block); bottom++ {
+		switch block[bottom][right] {
+		case '+':
+			if scanGridLeft(block, top, left, bottom, right) {
+				return bottom, true
+			}
+		case '|':
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func scanGridLeft(block [][]rune, top, left, bottom, right int) bool {
+	for col := right - 1; col > left; col-- {
+		if c := block[bottom][col]; c != '+' && c != '-' && c != '=' {
+			return false
+		}
+	}
+	if block[bottom][left] != '+' {
+		return false
+	}
+	for r := bottom - 1; r > top; r-- {
+		if c := block[r][left]; c != '+' && c != '|' {
+			return false
+		}
+	}
+	return true
+}
+
+// simpleTable parses the simple table at lines[i]. Its columns are the runs
+// of "=" in the top border; an optional second border separates the header,
+// and a line of "-" runs under a row joins the columns each run covers.
+func (d *rstDoc) simpleTable(lines []string, i int) (*rstNode, int) {
+	cols := rstColumns(lines[i], '=')
+	var borders []int
+	end := -1
+	for j := i + 1; j < len(lines); j++ {
+		if !rstSimpleBorder(lines[j], '=') {
+			continue
+		}
+		borders = append(borders, j)
+		if j+1 == len(lines) || rstBlank(lines[j+1]) || len(borders) == 2 {
+			end = j
+			break
+		}
+	}
+	if end < 0 {
+		return nil, i
+	}
+
+	t := &rstTableData{}
+	if len(borders) == 2 {
+		t.head = d.simpleRows(lines[i+1:borders[0]], cols)
+		t.body = d.simpleRows(lines[borders[0]+1:end], cols)
+	} else {
+		t.body = d.simpleRows(lines[i+1:end], cols)
+	}
+	return &rstNode{kind: rstTable, table: t}, end + 1
+}
+
+// rstSimpleBorder reports whether line is made of runs of c separated by
+// spaces.
+func rstSimpleBorder(line string, c byte) bool {
+	return line != "" && line[0] == c && strings.Trim(line, string(c)+" ") == ""
+}
+
+// rstColumns returns the rune offsets of the runs of c in a border line.
+func rstColumns(line string, c rune) [][2]int {
+	var cols [][2]int
+	start := -1
+	runes := []rune(line)
+	for k, r := range runes {
+		switch {
+		case r == c && start < 0:
+			start = k
+		case r != c && start >= 0:
+			cols = append(cols, [2]int{start, k})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		cols = append(cols, [2]int{start, len(runes)})
+	}
+	return cols
+}
+
+// simpleRows splits the lines of a simple table section into rows. A line
+// whose first column is blank continues the row before it.
+func (d *rstDoc) simpleRows(lines []string, cols [][2]int) [][]*rstCell {
+	type row struct {
+		lines []string
+		spans [][2]int // first and last column of each cell
+	}
+	var rows []*row
+	newRow := true
+	for _, line := range lines {
+		switch {
+		case rstBlank(line):
+			newRow = true
+		case rstSimpleBorder(line, '-') && len(rows) > 0:
+			var spans [][2]int
+			for _, run := range rstColumns(line, '-') {
+				first, last := -1, -1
+				for c, col := range cols {
+					if col[0] >= run[0] && col[0] < run[1] {
+						if first < 0 {
+							first = c
+						}
+						last = c
+					}
+				}
+				if first >= 0 {
+					spans = append(spans, [2]int{first, last})
+				}
+			}
+			rows[len(rows)-1].spans = spans
+			newRow = true
+		default:
+			runes := []rune(line)
+			firstBlank := strings.TrimSpace(string(runes[:min(len(runes), cols[0][1])])) == ""
+			if newRow || len(rows) == 0 || !firstBlank {
+				spans := make([][2]int, len(cols))
+				for c := range cols {
+					spans[c] = [2]int{c, c}
+				}
+				rows = append(rows, &row{spans: spans})
+			}
+			r := rows[len(rows)-1]
+			r.lines = append(r.lines, line)
+			newRow = false
+		}
+	}
+
+	out := make([][]*rstCell, 0, len(rows))
+	for _, r := range rows {
+		var cells []*rstCell
+		for _, span := range r.spans {
+			start := cols[span[0]][0]
+			end := -1
+			if span[1]+1 < len(cols) {
+				end = cols[span[1]+1][0]
+			}
+			var content []string
+			for _, line := range r.lines {
+				runes := []rune(line)
+				if start >= len(runes) {
+					content = append(content, "")
+					continue
+				}
+				stop := len(runes)
+				if end >= 0 && end < stop {
+					stop = end
+				}
+				content = append(content, string(runes[start:stop]))
+			}
+			cells = append(cells, &rstCell{colspan: span[1] - span[0] + 1, children: d.cellBody(content)})
+		}
+		out = append(out, cells)
+	}
+	return out
+}
+
+// listTable builds a table from a list-table directive's two-level bullet
+// list.
+func (d *rstDoc) listTable(caption string, opts map[string]string, content []string) *rstNode {
+	t := &rstTableData{caption: caption}
+	var rows [][]*rstCell
+	for _, n := range d.parse(content) {
+		if n.kind != rstBulletList {
+			continue
+		}
+		for _, item := range n.children {
+			var row []*rstCell
+			for _, c := range item.children {
+				if c.kind != rstBulletList {
+					continue
+				}
+				for _, cell := range c.children {
+					row = append(row, &rstCell{children: cell.children})
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	headRows, _ := strconv.Atoi(opts["header-rows"])
+	headRows = min(max(headRows, 0), len(rows))
+	t.head, t.body = rows[:headRows], rows[headRows:]
+	return &rstNode{kind: rstTable, table: t}
+}
+
+// csvTable builds a table from a csv-table directive's content. Tables read
+// from files or URLs are not supported.
+func (d *rstDoc) csvTable(caption string, opts map[string]string, content []string) *rstNode {
+	t := &rstTableData{caption: caption}
+	parse := func(text string) [][]string {
+		r := csv.NewReader(strings.NewReader(text))
+		r.LazyQuotes = true
+		r.TrimLeadingSpace = true
+		r.FieldsPerRecord = -1
+		switch delim := opts["delim"]; delim {
+		case "":
+		case "tab":
+			r.Comma = '\t'
+		case "space":
+			r.Comma = ' '
+		default:
+			r.Comma = []rune(delim)[0]
+		}
+		records, _ := r.ReadAll()
+		return records
+	}
+	toRows := func(records [][]string) [][]*rstCell {
+		var rows [][]*rstCell
+		for _, rec := range records {
+			var row []*rstCell
+			for _, field := range rec {
+				row = append(row, &rstCell{children: d.parse(strings.Split(field, "\n"))})
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	rows := toRows(parse(strings.Join(content, "\n")))
+	if header := opts["header"]; header != "" {
+		t.head = toRows(parse(header))
+	}
+	headRows, _ := strconv.Atoi(opts["header-rows"])
+	headRows = min(max(headRows, 0), len(rows))
+	t.head = append(t.head, rows[:headRows]...)
+	t.body = rows[headRows:]
+	return &rstNode{kind: rstTable, table: t}
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRSTRenderer_Sections(t *testing.T) {
+	r := NewRSTRenderer()
+	input := "=====\nTitle\n=====\n\nIntro.\n\nFirst\n=====\n\nSub\n---\n\nSecond\n======\n\nFirst\n=====\n"
+	html, meta, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Heading{
+		{Level: 1, Text: "Title", ID: "title"},
+		{Level: 2, Text: "First", ID: "first"},
+		{Level: 3, Text: "Sub", ID: "sub"},
+		{Level: 2, Text: "Second", ID: "second"},
+		{Level: 2, Text: "First", ID: "first-1"},
+	}
+	if len(meta.Headings) != len(want) {
+		t.Fatalf("got %d headings, want %d: %+v", len(meta.Headings), len(want), meta.Headings)
+	}
+	for i, h := range want {
+		if meta.Headings[i] != h {
+			t.Errorf("heading %d = %+v, want %+v", i, meta.Headings[i], h)
+		}
+	}
+	if meta.Title != "Title" || meta.HeadingCount != 5 {
+		t.Errorf("Title = %q, HeadingCount = %d", meta.Title, meta.HeadingCount)
+	}
+	if !strings.Contains(string(html), `<h3 id="sub">Sub</h3>`) {
+		t.Errorf("expected <h3> for the third adornment style:\n%s", html)
+	}
+}
+
+func TestRSTRenderer_CodeBlocks(t *testing.T) {
+	r := NewRSTRenderer()
+	input := ".. code-block:: go\n\n   package main\n\n.. highlight:: python\n\nLiteral::\n\n   print(1)\n"
+	html, meta, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CodeBlockCount != 2 {
+		t.Errorf("CodeBlockCount = %d, want 2", meta.CodeBlockCount)
+	}
+	if strings.Join(meta.Languages, ",") != "go,python" {
+		t.Errorf("Languages = %v, want [go python]", meta.Languages)
+	}
+	if !strings.Contains(string(html), `data-language="go"`) || !strings.Contains(string(html), `<span class="kn">package</span>`) {
+		t.Errorf("expected highlighted go block:\n%s", html)
+	}
+	if !strings.Contains(string(html), "<p>Literal:</p>") {
+		t.Errorf("expected the \"::\" to become \":\":\n%s", html)
+	}
+}
+
+func TestRSTRenderer_InlineMarkup(t *testing.T) {
+	r := NewRSTRenderer()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"*em* and **strong**", "<em>em</em> and <strong>strong</strong>"},
+		{"``a *b*``", "<code>a *b*</code>"},
+		{`\*not em\*`, "*not em*"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"snake_case_name", "snake_case_name"},
+		{"`Go <https://go.dev>`_", `<a href="https://go.dev">Go</a>`},
+		{"see https://example.com.", `see <a href="https://example.com">https://example.com</a>.`},
+		{":code:`x < y`", "<code>x &lt; y</code>"},
+		{":py:class:`~pkg.mod.Thing`", `<code class="xref">Thing</code>`},
+		{":pep:`8`", `<a href="https://peps.python.org/pep-0008/">PEP 8</a>`},
+		{"`cited`", "<cite>cited</cite>"},
+		{"undefined_ reference", "undefined reference"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			html, _, err := r.Render([]byte(tc.input + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(html); got != "<p>"+tc.want+"</p>\n" {
+				t.Errorf("got %q, want %q", got, "<p>"+tc.want+"</p>\n")
+			}
+		})
+	}
+}
+
+func TestRSTRenderer_References(t *testing.T) {
+	r := NewRSTRenderer()
+	input := "See Python_, `the docs`_, `Setup`_ and :ref:`setup-label`.\n\n" +
+		".. _Python: https://www.python.org/\n" +
+		".. _the docs: Python_\n\n" +
+		".. _setup-label:\n\nSetup\n=====\n"
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="https://www.python.org/">Python</a>`,
+		`<a href="https://www.python.org/">the docs</a>`,
+		`<a href="#setup">Setup</a>`,
+		`<a href="#setup-label">Setup</a>`,
+		`<span id="setup-label"></span>`,
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("expected %s in output:\n%s", want, html)
+		}
+	}
+}
+
+func TestRSTRenderer_Footnotes(t *testing.T) {
+	r := NewRSTRenderer()
+	input := "Auto [#]_, manual [1]_ and cited [Knuth]_.\n\n.. [1] Manual.\n.. [#] Auto.\n.. [Knuth] The book.\n"
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a class="footnote-reference" href="#footnote-2" id="ref-footnote-2">[2]</a>`,
+		`<a class="footnote-reference" href="#footnote-1" id="ref-footnote-1">[1]</a>`,
+		`<a class="citation-reference" href="#citation-knuth" id="ref-citation-knuth">[Knuth]</a>`,
+		`<div class="footnote" id="footnote-2">`,
+		`<a href="#ref-citation-knuth">[Knuth]</a>`,
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("expected %s in output:\n%s", want, html)
+		}
+	}
+}
+
+func TestRSTRenderer_Tables(t *testing.T) {
+	r := NewRSTRenderer()
+	grid := "+---+---+\n| A | B |\n+===+===+\n| wide  |\n+---+---+\n| 1 | 2 |\n+---+---+\n"
+	html, _, err := r.Render([]byte(grid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<th>A</th><th>B</th>", `<td colspan="2">wide</td>`, "<td>1</td><td>2</td>"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("grid table: expected %s in output:\n%s", want, html)
+		}
+	}
+
+	simple := "===  ===\nA    B\n===  ===\n1    2\n3    long last column\n===  ===\n"
+	html, _, err = r.Render([]byte(simple))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<th>A</th><th>B</th>", "<td>3</td><td>long last column</td>"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("simple table: expected %s in output:\n%s", want, html)
+		}
+	}
+
+	broken := "+---+---+\n| A | B\n+---+---+\n"
+	html, _, err = r.Render([]byte(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(html), "<table>") || !strings.Contains(string(html), "cooked-code-block") {
+		t.Errorf("malformed grid table should render as a literal block:\n%s", html)
+	}
+}
+
+func TestRSTRenderer_SafeDirectives(t *testing.T) {
+	r := NewRSTRenderer()
+	input := ".. include:: /etc/passwd\n\n" +
+		".. literalinclude:: ../secret.py\n\n" +
+		".. raw:: html\n   :file: /etc/hosts\n\n" +
+		".. raw:: latex\n\n   \\LaTeX\n\n" +
+		".. csv-table::\n   :url: http://169.254.169.254/\n\n" +
+		".. automodule:: project\n\n" +
+		".. toctree::\n   :glob:\n\n   intro\n   Guide <guide/index>\n   api/*\n\n" +
+		".. toctree::\n   :hidden:\n\n   hidden\n"
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<a href="etc/passwd"><code>etc/passwd</code></a>`,
+		`<a href="../secret.py">`,
+		`<li><a href="intro.rst">intro</a></li>`,
+		`<li><a href="guide/index.rst">Guide</a></li>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"hosts", "LaTeX", "169.254", "project", "api/", "hidden"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("unexpected %q in output:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestRSTRenderer_Admonitions(t *testing.T) {
+	r := NewRSTRenderer()
+	input := ".. warning:: Hot.\n\n.. admonition:: Custom\n\n   Body.\n\n.. versionchanged:: 1.1 Renamed.\n"
+	html, _, err := r.Render([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<div class=\"admonition warning\">\n<p class=\"admonition-title\">Warning</p>\n<p>Hot.</p>\n</div>\n" +
+		"<div class=\"admonition admonition-custom\">\n<p class=\"admonition-title\">Custom</p>\n<p>Body.</p>\n</div>\n" +
+		"<div class=\"admonition versionchanged\">\n<p class=\"admonition-title\">Changed in version 1.1</p>\n<p>Renamed.</p>\n</div>\n"
+	if string(html) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", html, want)
+	}
+}
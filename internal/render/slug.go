@@ -0,0 +1,26 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+)
+
+// slugID lowercases name and joins its runs of letters and digits with
+// hyphens, as docutils does for element IDs. It is used for IDs that readers
+// link to, such as reStructuredText sections and table columns.
+func slugID(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
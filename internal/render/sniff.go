@@ -0,0 +1,427 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TypeSource names the signal a document's content type was detected from.
+type TypeSource string
+
+const (
+	SourceOverride           TypeSource = "override"
+	SourcePath               TypeSource = "path"
+	SourceContentDisposition TypeSource = "content-disposition"
+	SourceContentType        TypeSource = "content-type"
+	SourceShebang            TypeSource = "shebang"
+	SourceModeline           TypeSource = "modeline"
+	SourceSniff              TypeSource = "sniff"
+)
+
+// Document is what is known about a fetched document when detecting its
+// content type.
+type Document struct {
+	Path               string // URL path
+	ContentType        string // Content-Type header
+	ContentDisposition string // Content-Disposition header
+	Body               []byte
+}
+
+// sniffLen is how much of the body is inspected for shebangs, modelines and
+// markup.
+const sniffLen = 8192
+
+// unsupported is the FileInfo of content cooked cannot render.
+var unsupported = FileInfo{ContentType: TypeUnsupported, Label: "Unknown"}
+
+// DetectDocument determines the content type of a fetched document, trying
+// in order: the URL path, the Content-Disposition filename, a specific
+// Content-Type, a shebang line, an Emacs or Vim modeline, and the content
+// itself. A generic text/plain Content-Type only applies when nothing more
+// specific is found and the path has no extension, as servers send it for
+// files they do not recognize. JSON and YAML that turn out to be OpenAPI
+// descriptions are detected as such. It returns the source of the decision;
+// the source is empty when the type is unsupported.
+func DetectDocument(doc Document) (FileInfo, TypeSource) {
+	info, source := detectDocument(doc)
+	return DetectOpenAPI(info, doc.Body), source
+}
+
+func detectDocument(doc Document) (FileInfo, TypeSource) {
+	if info := DetectFile(doc.Path); info.ContentType != TypeUnsupported {
+		return info, SourcePath
+	}
+	if doc.ContentDisposition != "" {
+		if _, params, err := mime.ParseMediaType(doc.ContentDisposition); err == nil && params["filename"] != "" {
+			if info := DetectFile(path.Base(params["filename"])); info.ContentType != TypeUnsupported {
+				return info, SourceContentDisposition
+			}
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(doc.ContentType)
+	if mediaType != "text/plain" {
+		if info := DetectMediaType(mediaType); info.ContentType != TypeUnsupported {
+			return info, SourceContentType
+		}
+	}
+
+	head := doc.Body
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	// Binary content is never rendered, whatever its first line says.
+	if bytes.IndexByte(head, 0) >= 0 {
+		return unsupported, ""
+	}
+	if info, ok := detectShebang(head); ok {
+		return info, SourceShebang
+	}
+	if info, ok := detectModeline(doc.Body); ok {
+		return info, SourceModeline
+	}
+	if info, ok := sniff(doc.Body, head); ok {
+		return info, SourceSniff
+	}
+	if mediaType == "text/plain" && path.Ext(doc.Path) == "" {
+		return FileInfo{ContentType: TypePlaintext, Label: "Plain Text"}, SourceContentType
+	}
+	return unsupported, ""
+}
+
+// languageNames maps language and format names, as written in modelines and
+// ?cooked_type=, to their content type.
+var languageNames = map[string]FileInfo{
+	"markdown":         {ContentType: TypeMarkdown, Label: "Markdown"},
+	"md":               {ContentType: TypeMarkdown, Label: "Markdown"},
+	"gfm":              {ContentType: TypeMarkdown, Label: "Markdown"},
+	"mdx":              {ContentType: TypeMDX, Label: "MDX"},
+	"asciidoc":         {ContentType: TypeAsciiDoc, Label: "AsciiDoc"},
+	"adoc":             {ContentType: TypeAsciiDoc, Label: "AsciiDoc"},
+	"org":              {ContentType: TypeOrg, Label: "Org"},
+	"rst":              {ContentType: TypeReST, Label: "reStructuredText"},
+	"rest":             {ContentType: TypeReST, Label: "reStructuredText"},
+	"restructuredtext": {ContentType: TypeReST, Label: "reStructuredText"},
+	"ipynb":            {ContentType: TypeNotebook, Label: "Jupyter Notebook"},
+	"notebook":         {ContentType: TypeNotebook, Label: "Jupyter Notebook"},
+	"jupyter":          {ContentType: TypeNotebook, Label: "Jupyter Notebook"},
+	"openapi":          {ContentType: TypeOpenAPI, Label: "OpenAPI"},
+	"swagger":          {ContentType: TypeOpenAPI, Label: "OpenAPI"},
+	"csv":              {ContentType: TypeTable, Language: "csv", Label: "CSV"},
+	"tsv":              {ContentType: TypeTable, Language: "tsv", Label: "TSV"},
+	"psv":              {ContentType: TypeTable, Language: "psv", Label: "PSV"},
+	"plaintext":        {ContentType: TypePlaintext, Label: "Plain Text"},
+	"text":             {ContentType: TypePlaintext, Label: "Plain Text"},
+	"txt":              {ContentType: TypePlaintext, Label: "Plain Text"},
+	"python":           {ContentType: TypeCode, Language: "python", Label: "Python"},
+	"go":               {ContentType: TypeCode, Language: "go", Label: "Go"},
+	"javascript":       {ContentType: TypeCode, Language: "javascript", Label: "JavaScript"},
+	"js":               {ContentType: TypeCode, Language: "javascript", Label: "JavaScript"},
+	"typescript":       {ContentType: TypeCode, Language: "typescript", Label: "TypeScript"},
+	"rust":             {ContentType: TypeCode, Language: "rust", Label: "Rust"},
+	"c":                {ContentType: TypeCode, Language: "c", Label: "C"},
+	"cpp":              {ContentType: TypeCode, Language: "cpp", Label: "C++"},
+	"c++":              {ContentType: TypeCode, Language: "cpp", Label: "C++"},
+	"java":             {ContentType: TypeCode, Language: "java", Label: "Java"},
+	"ruby":             {ContentType: TypeCode, Language: "ruby", Label: "Ruby"},
+	"lua":              {ContentType: TypeCode, Language: "lua", Label: "Lua"},
+	"perl":             {ContentType: TypeCode, Language: "perl", Label: "Perl"},
+	"php":              {ContentType: TypeCode, Language: "php", Label: "PHP"},
+	"bash":             {ContentType: TypeCode, Language: "bash", Label: "Shell"},
+	"sh":               {ContentType: TypeCode, Language: "bash", Label: "Shell"},
+	"shell":            {ContentType: TypeCode, Language: "bash", Label: "Shell"},
+	"zsh":              {ContentType: TypeCode, Language: "zsh", Label: "Zsh"},
+	"fish":             {ContentType: TypeCode, Language: "fish", Label: "Fish"},
+	"yaml":             {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"yml":              {ContentType: TypeCode, Language: "yaml", Label: "YAML"},
+	"json":             {ContentType: TypeCode, Language: "json", Label: "JSON"},
+	"toml":             {ContentType: TypeCode, Language: "toml", Label: "TOML"},
+	"xml":              {ContentType: TypeCode, Language: "xml", Label: "XML"},
+	"sql":              {ContentType: TypeCode, Language: "sql", Label: "SQL"},
+	"hcl":              {ContentType: TypeCode, Language: "hcl", Label: "HCL"},
+	"terraform":        {ContentType: TypeCode, Language: "hcl", Label: "Terraform"},
+	"diff":             {ContentType: TypeCode, Language: "diff", Label: "Diff"},
+	"dockerfile":       {ContentType: TypeCode, Language: "docker", Label: "Dockerfile"},
+	"make":             {ContentType: TypeCode, Language: "makefile", Label: "Makefile"},
+	"makefile":         {ContentType: TypeCode, Language: "makefile", Label: "Makefile"},
+	"groovy":           {ContentType: TypeCode, Language: "groovy", Label: "Groovy"},
+	"awk":              {ContentType: TypeCode, Language: "awk", Label: "AWK"},
+	"tcl":              {ContentType: TypeCode, Language: "tcl", Label: "Tcl"},
+}
+
+// ParseTypeOverride resolves an explicit type such as the ?cooked_type=
+// parameter: a format or language name ("markdown", "python"), a file
+// extension ("md", ".adoc"), or a media type ("text/markdown"). It reports
+// false for anything cooked cannot render.
+func ParseTypeOverride(v string) (FileInfo, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return unsupported, false
+	}
+	if info, ok := languageNames[v]; ok {
+		return info, true
+	}
+	var info FileInfo
+	if strings.Contains(v, "/") {
+		info = DetectMediaType(v)
+	} else {
+		info = DetectFile("document." + strings.TrimPrefix(v, "."))
+	}
+	return info, info.ContentType != TypeUnsupported
+}
+
+// interpreters maps shebang interpreters, without version suffixes, to
+// language names.
+var interpreters = map[string]string{
+	"python": "python",
+	"pypy":   "python",
+	"sh":     "sh",
+	"bash":   "bash",
+	"dash":   "sh",
+	"ksh":    "sh",
+	"ash":    "sh",
+	"zsh":    "zsh",
+	"fish":   "fish",
+	"node":   "javascript",
+	"nodejs": "javascript",
+	"deno":   "typescript",
+	"ruby":   "ruby",
+	"perl":   "perl",
+	"lua":    "lua",
+	"php":    "php",
+	"awk":    "awk",
+	"gawk":   "awk",
+	"groovy": "groovy",
+	"tclsh":  "tcl",
+	"make":   "make",
+}
+
+// detectShebang detects a script from its #! line, looking through env.
+func detectShebang(head []byte) (FileInfo, bool) {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return FileInfo{}, false
+	}
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return FileInfo{}, false
+	}
+	interp := path.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			// Skip env's options and variable assignments.
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			interp = path.Base(f)
+			break
+		}
+	}
+	interp = strings.TrimRight(interp, "0123456789.")
+	if name, ok := interpreters[interp]; ok {
+		return languageNames[name], true
+	}
+	return FileInfo{}, false
+}
+
+var (
+	// emacsModeline matches -*- mode: python -*- and -*- python -*-.
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	emacsMode     = regexp.MustCompile(`(?i)(?:^|;)\s*mode\s*:\s*([\w+-]+)`)
+	// vimModeline matches vim: set ft=python: and vi: syntax=markdown.
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):.*?\b(?:ft|filetype|syn|syntax)=([\w+-]+)`)
+)
+
+// modelineLines is how many lines at each end of a file Vim searches for
+// modelines.
+const modelineLines = 5
+
+// detectModeline detects the type named by an Emacs modeline on the first
+// line (or second, after a shebang) or a Vim modeline in the first or last
+// five lines.
+func detectModeline(body []byte) (FileInfo, bool) {
+	head := body
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	lines := strings.SplitN(string(head), "\n", modelineLines+1)
+	if len(lines) > modelineLines {
+		lines = lines[:modelineLines]
+	}
+
+	emacsLines := lines[:min(1, len(lines))]
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "#!") {
+		emacsLines = lines[:2]
+	}
+	for _, line := range emacsLines {
+		m := emacsModeline.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		mode := m[1]
+		if mm := emacsMode.FindStringSubmatch(mode); mm != nil {
+			mode = mm[1]
+		} else if strings.Contains(mode, ":") {
+			continue // only other variables
+		}
+		if info, ok := modeInfo(strings.TrimSuffix(strings.ToLower(mode), "-mode")); ok {
+			return info, true
+		}
+	}
+
+	tail := body
+	if len(tail) > sniffLen {
+		tail = tail[len(tail)-sniffLen:]
+	}
+	tailLines := strings.Split(strings.TrimRight(string(tail), "\n"), "\n")
+	tailLines = tailLines[max(0, len(tailLines)-modelineLines):]
+	for _, line := range append(lines, tailLines...) {
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			if info, ok := modeInfo(strings.ToLower(m[1])); ok {
+				return info, true
+			}
+		}
+	}
+	return FileInfo{}, false
+}
+
+// modeInfo resolves an editor mode or filetype name.
+func modeInfo(mode string) (FileInfo, bool) {
+	switch mode {
+	case "asciidoc", "adoc":
+		return languageNames["asciidoc"], true
+	case "python3":
+		mode = "python"
+	case "js2", "js":
+		mode = "javascript"
+	case "shell-script", "sh":
+		mode = "sh"
+	}
+	info, ok := languageNames[mode]
+	return info, ok
+}
+
+var (
+	orgKeyword     = regexp.MustCompile(`(?im)^#\+(title|author|options|startup|begin_src|begin_example|begin_quote|property|filetags):`)
+	orgBlock       = regexp.MustCompile(`(?im)^#\+begin_(src|example|quote)\b`)
+	adocTitle      = regexp.MustCompile(`^= \S`)
+	adocSection    = regexp.MustCompile(`(?m)^={2,5} \S`)
+	adocAttribute  = regexp.MustCompile(`(?m)^:[\w-]+:(\s|$)`)
+	adocBlock      = regexp.MustCompile(`(?m)^\[(source|NOTE|TIP|WARNING|IMPORTANT|CAUTION)[,\]]`)
+	rstExplicit    = regexp.MustCompile(`(?m)^\.\. ([\w-]+(:[\w-]+)?::|_[^:\n]+:)(\s|$)`)
+	yamlStart      = regexp.MustCompile(`^(---|%YAML)(\s|$)`)
+	yamlKey        = regexp.MustCompile(`^[\w"'.-]+:(\s|$)`)
+	yamlLine       = regexp.MustCompile(`^(\s*[\w"'./-]+\s*:(\s|$)|\s*- |\s+\S|---$|\.\.\.$)`)
+	mdHeading      = regexp.MustCompile(`(?m)^#{1,6} \S`)
+	mdFence        = regexp.MustCompile("(?m)^(```|~~~)")
+	mdLink         = regexp.MustCompile(`\[[^\]\n]+\]\([^)\s]+\)`)
+	mdList         = regexp.MustCompile(`(?m)^\s{0,3}([-*+]|\d+\.) \S`)
+	mdEmphasis     = regexp.MustCompile(`(\*\*|__)\S[^*\n]*\S(\*\*|__)|` + "`[^`\n]+`")
+	mdTableDivider = regexp.MustCompile(`(?m)^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)+\|?\s*$`)
+)
+
+// sniff recognizes markdown, AsciiDoc, Org, reStructuredText, Jupyter
+// notebooks, JSON and YAML from their content.
+func sniff(body, head []byte) (FileInfo, bool) {
+	text := strings.TrimLeft(string(head), " \t\r\n\ufeff")
+	if text == "" {
+		return FileInfo{}, false
+	}
+
+	if (text[0] == '{' || text[0] == '[') && json.Valid(body) {
+		if isNotebook(body) {
+			return languageNames["ipynb"], true
+		}
+		return languageNames["json"], true
+	}
+	if orgKeyword.MatchString(text) || orgBlock.MatchString(text) {
+		return languageNames["org"], true
+	}
+	if adocTitle.MatchString(text) &&
+		(adocSection.MatchString(text) || adocAttribute.MatchString(text) || adocBlock.MatchString(text)) {
+		return languageNames["asciidoc"], true
+	}
+	if rstExplicit.MatchString(text) {
+		return languageNames["rst"], true
+	}
+	if looksLikeYAML(text) {
+		return languageNames["yaml"], true
+	}
+
+	// Markdown needs more than headings: "# " also starts comments in
+	// shell, YAML and config files.
+	score := 0
+	if mdHeading.MatchString(text) {
+		score += 2
+	}
+	for _, re := range []*regexp.Regexp{mdFence, mdLink, mdTableDivider} {
+		if re.MatchString(text) {
+			score += 2
+		}
+	}
+	if len(mdList.FindAllStringIndex(text, 2)) == 2 {
+		score++
+	}
+	if mdEmphasis.MatchString(text) {
+		score++
+	}
+	if score >= 3 {
+		return languageNames["markdown"], true
+	}
+	return FileInfo{}, false
+}
+
+// looksLikeYAML reports whether text opens a YAML document and at least
+// nine in ten of its other lines look like YAML.
+func looksLikeYAML(text string) bool {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 1 {
+		lines = lines[:len(lines)-1] // may be cut off
+	}
+	var content []string
+	for _, l := range lines {
+		l = strings.TrimRight(l, "\r")
+		if t := strings.TrimSpace(l); t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		content = append(content, l)
+	}
+	if len(content) < 2 {
+		return false
+	}
+	first := content[0]
+	if !yamlStart.MatchString(first) && !yamlKey.MatchString(first) {
+		return false
+	}
+	matched := 0
+	for _, l := range content {
+		if yamlLine.MatchString(l) {
+			matched++
+		}
+	}
+	return matched*10 >= len(content)*9
+}
+
+// DetectOpenAPI returns the FileInfo of an OpenAPI description for JSON or
+// YAML whose top-level keys declare OpenAPI 3.x or Swagger 2.0, and info
+// unchanged for anything else.
+func DetectOpenAPI(info FileInfo, body []byte) FileInfo {
+	if info.ContentType != TypeCode || !isOpenAPI(body, info.Language) {
+		return info
+	}
+	return FileInfo{ContentType: TypeOpenAPI, Language: info.Language, Label: "OpenAPI"}
+}
+
+// isNotebook reports whether a JSON document is a Jupyter notebook: an
+// object with "nbformat" and "cells".
+func isNotebook(body []byte) bool {
+	var nb struct {
+		NBFormat *int            `json:"nbformat"`
+		Cells    json.RawMessage `json:"cells"`
+	}
+	return json.Unmarshal(body, &nb) == nil && nb.NBFormat != nil && nb.Cells != nil
+}
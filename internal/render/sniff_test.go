@@ -0,0 +1,204 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectDocument(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        Document
+		wantType   ContentType
+		wantLang   string
+		wantSource TypeSource
+	}{
+		{
+			name:       "path wins",
+			doc:        Document{Path: "/README.md", ContentType: "text/plain", Body: []byte("#!/bin/sh\n")},
+			wantType:   TypeMarkdown,
+			wantSource: SourcePath,
+		},
+		{
+			name:       "content-disposition filename",
+			doc:        Document{Path: "/download/123", ContentDisposition: `attachment; filename="notes.adoc"`},
+			wantType:   TypeAsciiDoc,
+			wantSource: SourceContentDisposition,
+		},
+		{
+			name:       "content-type",
+			doc:        Document{Path: "/raw", ContentType: "text/markdown; charset=utf-8"},
+			wantType:   TypeMarkdown,
+			wantSource: SourceContentType,
+		},
+		{
+			name:       "json content-type",
+			doc:        Document{Path: "/api/v1/spec", ContentType: "application/json"},
+			wantType:   TypeCode,
+			wantLang:   "json",
+			wantSource: SourceContentType,
+		},
+		{
+			name:       "shebang through env",
+			doc:        Document{Path: "/bin/deploy", ContentType: "text/plain", Body: []byte("#!/usr/bin/env -S python3.12 -u\nprint(1)\n")},
+			wantType:   TypeCode,
+			wantLang:   "python",
+			wantSource: SourceShebang,
+		},
+		{
+			name:       "shebang",
+			doc:        Document{Path: "/bin/run", Body: []byte("#!/bin/bash\necho hi\n")},
+			wantType:   TypeCode,
+			wantLang:   "bash",
+			wantSource: SourceShebang,
+		},
+		{
+			name:       "emacs modeline",
+			doc:        Document{Path: "/NOTES", Body: []byte("-*- mode: org; coding: utf-8 -*-\n* Heading\n")},
+			wantType:   TypeOrg,
+			wantSource: SourceModeline,
+		},
+		{
+			name:       "vim modeline at end",
+			doc:        Document{Path: "/README", Body: []byte("Title\n\nSome text.\n\n<!-- vim: set ft=markdown: -->\n")},
+			wantType:   TypeMarkdown,
+			wantSource: SourceModeline,
+		},
+		{
+			name:       "sniff markdown",
+			doc:        Document{Path: "/raw", ContentType: "text/plain", Body: []byte("# Project\n\nSee [the docs](docs/index.md).\n\n- one\n- two\n")},
+			wantType:   TypeMarkdown,
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff asciidoc",
+			doc:        Document{Path: "/raw", Body: []byte("= Guide\n:toc:\n\n== Install\n\nRun it.\n")},
+			wantType:   TypeAsciiDoc,
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff org",
+			doc:        Document{Path: "/raw", Body: []byte("#+TITLE: Notes\n\n* Heading\n")},
+			wantType:   TypeOrg,
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff rst",
+			doc:        Document{Path: "/raw", Body: []byte("Guide\n=====\n\n.. note::\n\n   Read this first.\n")},
+			wantType:   TypeReST,
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff notebook",
+			doc:        Document{Path: "/raw", Body: []byte(`{"cells": [], "metadata": {}, "nbformat": 4, "nbformat_minor": 5}`)},
+			wantType:   TypeNotebook,
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff json",
+			doc:        Document{Path: "/raw", ContentType: "application/octet-stream", Body: []byte(`{"name": "cooked", "tags": [1, 2]}`)},
+			wantType:   TypeCode,
+			wantLang:   "json",
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "sniff yaml",
+			doc:        Document{Path: "/raw", Body: []byte("# deployment\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n")},
+			wantType:   TypeCode,
+			wantLang:   "yaml",
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "openapi by path",
+			doc:        Document{Path: "/specs/swagger.json"},
+			wantType:   TypeOpenAPI,
+			wantLang:   "json",
+			wantSource: SourcePath,
+		},
+		{
+			name:       "openapi by top-level keys",
+			doc:        Document{Path: "/specs/billing.yaml", Body: []byte("openapi: 3.0.3\ninfo:\n  title: Billing\npaths: {}\n")},
+			wantType:   TypeOpenAPI,
+			wantLang:   "yaml",
+			wantSource: SourcePath,
+		},
+		{
+			name:       "sniff openapi",
+			doc:        Document{Path: "/raw", Body: []byte(`{"swagger": "2.0", "info": {"title": "x"}, "paths": {}}`)},
+			wantType:   TypeOpenAPI,
+			wantLang:   "json",
+			wantSource: SourceSniff,
+		},
+		{
+			name:       "shell comments are not markdown",
+			doc:        Document{Path: "/raw", Body: []byte("# set up\nexport A=1\n# run\nmake\n")},
+			wantType:   TypeUnsupported,
+			wantSource: "",
+		},
+		{
+			name:       "generic text/plain",
+			doc:        Document{Path: "/raw", ContentType: "text/plain", Body: []byte("just some words\n")},
+			wantType:   TypePlaintext,
+			wantSource: SourceContentType,
+		},
+		{
+			name:       "text/plain with an unsupported extension",
+			doc:        Document{Path: "/logo.png", ContentType: "text/plain", Body: []byte("x")},
+			wantType:   TypeUnsupported,
+			wantSource: "",
+		},
+		{
+			name:       "binary",
+			doc:        Document{Path: "/download/1", ContentType: "application/octet-stream", Body: []byte("#!/bin/sh\x00\x01\x02")},
+			wantType:   TypeUnsupported,
+			wantSource: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, source := DetectDocument(tt.doc)
+			if info.ContentType != tt.wantType || info.Language != tt.wantLang || source != tt.wantSource {
+				t.Errorf("DetectDocument() = %q/%q from %q, want %q/%q from %q",
+					info.ContentType, info.Language, source, tt.wantType, tt.wantLang, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestDetectDocument_LargeBody(t *testing.T) {
+	body := "#!/usr/bin/env ruby\n" + strings.Repeat("puts 1\n", 10000) + "# vim: ft=python\n"
+	info, source := DetectDocument(Document{Path: "/x", Body: []byte(body)})
+	if info.Language != "ruby" || source != SourceShebang {
+		t.Errorf("DetectDocument() = %q from %q, want ruby from shebang", info.Language, source)
+	}
+}
+
+func TestParseTypeOverride(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     ContentType
+		wantLang string
+		ok       bool
+	}{
+		{"markdown", TypeMarkdown, "", true},
+		{"MD", TypeMarkdown, "", true},
+		{".adoc", TypeAsciiDoc, "", true},
+		{"python", TypeCode, "python", true},
+		{"tf", TypeCode, "hcl", true},
+		{"text/x-org", TypeOrg, "", true},
+		{"application/yaml", TypeCode, "yaml", true},
+		{"tsv", TypeTable, "tsv", true},
+		{"text/csv", TypeTable, "csv", true},
+		{"openapi", TypeOpenAPI, "", true},
+		{"application/vnd.oai.openapi+json", TypeOpenAPI, "json", true},
+		{"exe", TypeUnsupported, "", false},
+		{"", TypeUnsupported, "", false},
+	}
+	for _, tt := range tests {
+		info, ok := ParseTypeOverride(tt.in)
+		if info.ContentType != tt.want || info.Language != tt.wantLang || ok != tt.ok {
+			t.Errorf("ParseTypeOverride(%q) = %q/%q, %v; want %q/%q, %v",
+				tt.in, info.ContentType, info.Language, ok, tt.want, tt.wantLang, tt.ok)
+		}
+	}
+}
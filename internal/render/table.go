@@ -0,0 +1,360 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Limits that keep the page's DOM small for long and wide tables. The page
+// is rendered with the first page of rows; the browser pages, sorts and
+// filters the rest from the embedded data.
+const (
+	tableMaxCells    = 25000     // cells per page
+	tableMaxColumns  = 2500      // columns; wider tables are shown as source
+	tableMaxData     = 200000    // cells read and embedded; later rows are dropped
+	tableMaxPageRows = 500       // rows per page
+	tableMinPageRows = 10        // rows per page, however wide the table
+	tableMaxSource   = 512 << 10 // bytes of highlighted source
+	tableSniffLen    = 8192      // bytes inspected for the delimiter
+)
+
+// tableDelimiters maps the table formats to their usual delimiter.
+var tableDelimiters = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+	"psv": '|',
+}
+
+// TableRenderer renders delimiter-separated data (CSV, TSV, PSV) as an
+// interactive table, with the highlighted source one toggle away.
+type TableRenderer struct {
+	code *CodeRenderer
+}
+
+// NewTableRenderer creates a new table renderer.
+func NewTableRenderer() *TableRenderer {
+	return &TableRenderer{code: NewCodeRenderer()}
+}
+
+// Render parses source in the given format ("csv", "tsv" or "psv") and
+// renders it as a table. The delimiter is detected from the content, with
+// the format's own delimiter preferred, and so is whether the first row is a
+// header. Content that cannot be parsed, or has more than tableMaxColumns
+// columns, is shown as source only. Only the rows that fit in tableMaxData
+// cells are shown as a table.
+func (r *TableRenderer) Render(source []byte, format string) ([]byte, error) {
+	text := strings.TrimPrefix(string(source), "\ufeff")
+	preferred, ok := tableDelimiters[format]
+	if !ok {
+		preferred = ','
+	}
+	delim := detectDelimiter(text, preferred)
+
+	records, truncated, parseErr := parseTable(text, delim)
+	var tooWide *tableTooWideError
+	showTable := parseErr == nil && len(records) > 0
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<div class="cooked-table" data-format="%s" data-delimiter="%s">`+"\n",
+		html.EscapeString(format), html.EscapeString(string(delim)))
+	if showTable {
+		writeTable(&buf, records, truncated)
+	}
+
+	buf.WriteString(`<div class="cooked-table-source"`)
+	if showTable {
+		buf.WriteString(" hidden")
+	}
+	buf.WriteString(">\n")
+	if errors.As(parseErr, &tooWide) {
+		fmt.Fprintf(&buf, "<p class=\"cooked-table-notice\">Too wide to show as a table: %s</p>\n",
+			html.EscapeString(tooWide.Error()))
+	} else if parseErr != nil {
+		fmt.Fprintf(&buf, "<p class=\"cooked-table-notice\">Could not parse as %s: %s</p>\n",
+			html.EscapeString(strings.ToUpper(format)), html.EscapeString(parseErr.Error()))
+	}
+	code := cutAtLine(string(source), tableMaxSource)
+	highlighted, err := r.code.Render([]byte(code), format)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(highlighted)
+	buf.WriteString("\n")
+	if len(code) < len(source) {
+		fmt.Fprintf(&buf, "<p class=\"cooked-table-notice\">Source truncated: showing the first %s of %s</p>\n",
+			formatBytes(len(code)), formatBytes(len(source)))
+	}
+	buf.WriteString("</div>\n</div>\n")
+	return buf.Bytes(), nil
+}
+
+// tableTooWideError reports a row with more than tableMaxColumns fields.
+type tableTooWideError struct {
+	columns int
+}
+
+func (e *tableTooWideError) Error() string {
+	return fmt.Sprintf("%d columns, above the limit of %d", e.columns, tableMaxColumns)
+}
+
+// parseTable reads the records of a table, padding short rows so every
+// record has as many fields as the widest. It fails at the first row wider
+// than tableMaxColumns, and stops reading before the padded records would
+// exceed tableMaxData cells, reporting that later rows were dropped. Both
+// limits are checked before any row is padded.
+func parseTable(text string, delim rune) (records [][]string, truncated bool, err error) {
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = delim
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	width := 0
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if len(rec) > tableMaxColumns {
+			return nil, false, &tableTooWideError{columns: len(rec)}
+		}
+		if max(width, len(rec))*(len(records)+1) > tableMaxData {
+			truncated = true
+			break
+		}
+		width = max(width, len(rec))
+		records = append(records, rec)
+	}
+	for i, rec := range records {
+		for len(rec) < width {
+			rec = append(rec, "")
+		}
+		records[i] = rec
+	}
+	return records, truncated, nil
+}
+
+// detectDelimiter picks the delimiter that splits the first lines of text
+// into the same number of fields, at least two. The preferred delimiter wins
+// when it qualifies; otherwise the one giving the most fields does.
+func detectDelimiter(text string, preferred rune) rune {
+	sample := text
+	if len(sample) > tableSniffLen {
+		sample = sample[:tableSniffLen]
+		if i := strings.LastIndexByte(sample, '\n'); i > 0 {
+			sample = sample[:i+1]
+		}
+	}
+
+	best, bestFields := preferred, 0
+	for _, d := range []rune{preferred, ',', '\t', ';', '|'} {
+		cr := csv.NewReader(strings.NewReader(sample))
+		cr.Comma = d
+		cr.LazyQuotes = true
+		cr.FieldsPerRecord = -1
+		var records [][]string
+		for len(records) < 20 {
+			rec, err := cr.Read()
+			if err != nil {
+				break
+			}
+			records = append(records, rec)
+		}
+		if len(records) == 0 || len(records[0]) < 2 {
+			continue
+		}
+		consistent := 0
+		for _, rec := range records {
+			if len(rec) == len(records[0]) {
+				consistent++
+			}
+		}
+		if consistent*10 < len(records)*9 {
+			continue
+		}
+		if d == preferred {
+			return d
+		}
+		if len(records[0]) > bestFields {
+			best, bestFields = d, len(records[0])
+		}
+	}
+	return best
+}
+
+// hasHeader guesses whether the first record names the columns: its fields
+// must be distinct and non-empty, and it must not look like the records
+// below it — text above numeric columns, and values not repeated further
+// down text columns.
+func hasHeader(records [][]string) bool {
+	if len(records) < 2 {
+		return false
+	}
+	first := records[0]
+	seen := map[string]bool{}
+	for _, f := range first {
+		f = strings.TrimSpace(f)
+		if f == "" || seen[f] {
+			return false
+		}
+		seen[f] = true
+	}
+
+	sample := records[1:min(len(records), 51)]
+	votes := 0
+	for c, head := range first {
+		values, numbers, repeated := 0, 0, false
+		for _, rec := range sample {
+			v := strings.TrimSpace(rec[c])
+			if v == "" {
+				continue
+			}
+			values++
+			if isNumeric(v) {
+				numbers++
+			}
+			if v == strings.TrimSpace(head) {
+				repeated = true
+			}
+		}
+		switch {
+		case values == 0:
+		case numbers*10 >= values*9:
+			if isNumeric(head) {
+				votes--
+			} else {
+				votes++
+			}
+		case repeated:
+			votes--
+		}
+	}
+	return votes >= 0
+}
+
+// numericColumns reports which columns hold numbers in nine rows in ten or
+// more of their non-empty cells.
+func numericColumns(rows [][]string, width int) []bool {
+	numeric := make([]bool, width)
+	for c := range numeric {
+		values, numbers := 0, 0
+		for _, rec := range rows {
+			v := strings.TrimSpace(rec[c])
+			if v == "" {
+				continue
+			}
+			values++
+			if isNumeric(v) {
+				numbers++
+			}
+		}
+		numeric[c] = values > 0 && numbers*10 >= values*9
+	}
+	return numeric
+}
+
+// thousandsRe matches numbers written with comma thousands separators.
+var thousandsRe = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// isNumeric reports whether s is a number, allowing a percent sign and comma
+// thousands separators.
+func isNumeric(s string) bool {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil || thousandsRe.MatchString(s)
+}
+
+// tablePageRows is how many rows of a table with the given number of
+// columns are shown at once.
+func tablePageRows(columns int) int {
+	return max(tableMinPageRows, min(tableMaxPageRows, tableMaxCells/max(columns, 1)))
+}
+
+func writeTable(w *bytes.Buffer, records [][]string, truncated bool) {
+	width := len(records[0])
+	var header []string
+	rows := records
+	if hasHeader(records) {
+		header, rows = records[0], records[1:]
+	} else {
+		for c := range width {
+			header = append(header, "Column "+strconv.Itoa(c+1))
+		}
+	}
+	numeric := numericColumns(rows, width)
+	pageRows := tablePageRows(width)
+
+	w.WriteString("<div class=\"cooked-table-toolbar\">\n")
+	w.WriteString("<input type=\"search\" class=\"cooked-table-filter\" placeholder=\"Filter rows\" aria-label=\"Filter rows\">\n")
+	fmt.Fprintf(w, "<span class=\"cooked-table-status\">%s × %s</span>\n",
+		plural(len(rows), "row"), plural(width, "column"))
+	w.WriteString("<button class=\"cooked-table-toggle\" title=\"Show the source\">Source</button>\n")
+	w.WriteString("</div>\n")
+
+	fmt.Fprintf(w, "<div class=\"cooked-table-view\" data-page-rows=\"%d\">\n", pageRows)
+	w.WriteString("<div class=\"cooked-table-scroll\">\n<table>\n<thead>\n<tr><th class=\"cooked-table-rownum\">#</th>")
+	ids := map[string]bool{}
+	for c, name := range header {
+		base := "col-" + slugID(name)
+		if base == "col-" {
+			base = "col-" + strconv.Itoa(c+1)
+		}
+		id := base
+		for i := 1; ids[id]; i++ {
+			id = base + "-" + strconv.Itoa(i)
+		}
+		ids[id] = true
+		dataType := "text"
+		if numeric[c] {
+			dataType = "number"
+		}
+		fmt.Fprintf(w, `<th id="%s" data-type="%s"><button class="cooked-table-sort">%s</button><a class="cooked-table-anchor" href="#%s" aria-label="Link to column">#</a></th>`,
+			id, dataType, html.EscapeString(name), id)
+	}
+	w.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for i, rec := range rows[:min(len(rows), pageRows)] {
+		writeTableRow(w, i+1, rec, numeric)
+	}
+	w.WriteString("</tbody>\n</table>\n</div>\n")
+	if len(rows) > pageRows {
+		fmt.Fprintf(w, "<p class=\"cooked-table-notice\">Showing rows 1–%d of %d.</p>\n", pageRows, len(rows))
+	}
+	w.WriteString("</div>\n")
+	if truncated {
+		fmt.Fprintf(w, "<p class=\"cooked-table-notice\">Table truncated: only the first %s fit in the table view; the source has the rest.</p>\n",
+			plural(len(rows), "row"))
+	}
+
+	// The rows as JSON, for paging, sorting and filtering in the browser.
+	// json.Marshal escapes <, > and &, so the data cannot end the element.
+	data, _ := json.Marshal(rows)
+	fmt.Fprintf(w, "<script type=\"application/json\" class=\"cooked-table-data\">%s</script>\n", data)
+}
+
+func writeTableRow(w io.Writer, n int, rec []string, numeric []bool) {
+	fmt.Fprintf(w, `<tr id="row-%d"><th class="cooked-table-rownum"><a href="#row-%d">%d</a></th>`, n, n, n)
+	for c, v := range rec {
+		if numeric[c] {
+			fmt.Fprintf(w, `<td class="num">%s</td>`, html.EscapeString(v))
+		} else {
+			fmt.Fprintf(w, `<td>%s</td>`, html.EscapeString(v))
+		}
+	}
+	io.WriteString(w, "</tr>\n")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTableGolden(t *testing.T) {
+	r := NewTableRenderer()
+
+	fixtures, err := filepath.Glob(filepath.Join(fixturesDir, "table", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no table fixtures found")
+	}
+
+	goldenSubdir := filepath.Join(goldenDir, "table")
+
+	for _, fixturePath := range fixtures {
+		filename := filepath.Base(fixturePath)
+		info := DetectFile(fixturePath)
+		if info.ContentType != TypeTable {
+			continue
+		}
+
+		t.Run(filename, func(t *testing.T) {
+			input, err := os.ReadFile(fixturePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.Render(input, info.Language)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+
+			goldenPath := filepath.Join(goldenSubdir, filename+".html")
+
+			if *update {
+				if err := os.MkdirAll(goldenSubdir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("updated %s", goldenPath)
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("golden file not found (run with -update to create): %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("output mismatch for %s (run with -update to regenerate)\n"+
+					"got %d bytes, want %d bytes\n"+
+					"first diff at byte %d",
+					filename, len(got), len(want), firstDiff(got, want))
+			}
+
+			// Every table keeps its source one toggle away.
+			s := string(got)
+			if !strings.Contains(s, `<div class="cooked-table-source" hidden>`) {
+				t.Error("missing hidden source view")
+			}
+			if !strings.Contains(s, `class="cooked-table-data"`) {
+				t.Error("missing embedded table data")
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		preferred rune
+		want      rune
+	}{
+		{"comma", "a,b,c\n1,2,3\n", ',', ','},
+		{"semicolon in csv", "a;b;c\n1,5;2;3\n4;5,5;6\n", ',', ';'},
+		{"tab in csv", "a\tb\n1\t2\n", ',', '\t'},
+		{"pipe", "a|b|c\n1|2|3\n", '|', '|'},
+		{"quoted delimiters", "name,note\n\"Smith; J\",\"a; b; c\"\n\"Doe; J\",x\n", ',', ','},
+		{"single column", "value\n1\n2\n", ',', ','},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectDelimiter(tc.input, tc.preferred); got != tc.want {
+				t.Errorf("detectDelimiter() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"text above numbers", "id,score\n1,90\n2,85\n", true},
+		{"numbers throughout", "1,90\n2,85\n3,70\n", false},
+		{"text columns", "name,city\nAda,London\nAlan,Wilmslow\n", true},
+		{"repeated first value", "red,small\nred,large\nblue,small\n", false},
+		{"empty field", "name,\nAda,1\n", false},
+		{"duplicate fields", "x,x\n1,2\n", false},
+		{"one row", "name,city\n", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			records, _, err := parseTable(tc.input, ',')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hasHeader(records); got != tc.want {
+				t.Errorf("hasHeader() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTableRenderer_Headerless(t *testing.T) {
+	r := NewTableRenderer()
+	html, err := r.Render([]byte("1,2\n3\n"), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<th id="col-column-1" data-type="number"><button class="cooked-table-sort">Column 1</button>`,
+		`<span class="cooked-table-status">2 rows × 2 columns</span>`,
+		`<tr id="row-2"><th class="cooked-table-rownum"><a href="#row-2">2</a></th><td class="num">3</td><td class="num"></td></tr>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+}
+
+func TestTableRenderer_Pagination(t *testing.T) {
+	r := NewTableRenderer()
+
+	var long strings.Builder
+	long.WriteString("id,value\n")
+	for i := range 2000 {
+		fmt.Fprintf(&long, "%d,v%d\n", i, i)
+	}
+	html, err := r.Render([]byte(long.String()), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	if n := strings.Count(got, "<tr id="); n != tableMaxPageRows {
+		t.Errorf("rendered %d rows, want %d", n, tableMaxPageRows)
+	}
+	if !strings.Contains(got, "Showing rows 1–500 of 2000.") {
+		t.Error("missing pagination notice")
+	}
+	if !strings.Contains(got, `["1999","v1999"]]</script>`) {
+		t.Error("embedded data should hold every row")
+	}
+
+	wide := func(columns int) []byte {
+		cells := make([]string, columns)
+		for i := range cells {
+			cells[i] = fmt.Sprintf("c%d", i)
+		}
+		return []byte(strings.Join(cells, ",") + "\n" + strings.Repeat(strings.Repeat("1,", columns-1)+"1\n", 50))
+	}
+	html, err = r.Render(wide(tableMaxColumns), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(html), "<tr id="); n != tableMinPageRows {
+		t.Errorf("wide table rendered %d rows, want %d", n, tableMinPageRows)
+	}
+
+	html, err = r.Render(wide(tableMaxColumns+1), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = string(html)
+	if strings.Contains(got, "<table>") || !strings.Contains(got, "Too wide to show as a table: 2501 columns") {
+		t.Error("table over the column limit should show only the source")
+	}
+}
+
+func TestTableRenderer_Limits(t *testing.T) {
+	r := NewTableRenderer()
+
+	// A wide first row would pad every short row below it out to its width.
+	src := strings.Repeat(",", tableMaxColumns-1) + "\n" + strings.Repeat("x\n", 1_000_000)
+	html, err := r.Render([]byte(src), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	rows := tableMaxData / tableMaxColumns
+	if !strings.Contains(got, fmt.Sprintf("Table truncated: only the first %d rows fit", rows)) {
+		t.Errorf("missing truncation notice for %d rows", rows)
+	}
+	if n := strings.Count(got, `[""`); n > rows {
+		t.Errorf("embedded %d rows, want at most %d", n, rows)
+	}
+
+	// A row over the column limit anywhere rejects the table.
+	src = "a,b\n" + strings.Repeat(",", tableMaxColumns+1) + "\n" + strings.Repeat("x\n", 1000)
+	html, err = r.Render([]byte(src), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = string(html)
+	if strings.Contains(got, "<table>") || !strings.Contains(got, "Too wide to show as a table: 2502 columns") {
+		t.Error("a row over the column limit should show only the source")
+	}
+}
+
+func TestTableRenderer_Empty(t *testing.T) {
+	r := NewTableRenderer()
+	html, err := r.Render(nil, "tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	if strings.Contains(got, "<table>") || !strings.Contains(got, `<div class="cooked-table-source">`) {
+		t.Errorf("empty input should show only the source:\n%s", got)
+	}
+}
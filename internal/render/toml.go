@@ -0,0 +1,123 @@
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML decodes a TOML document into a tree. Decoding yields maps, so
+// keys are put back in source order from the decoder's metadata.
+func parseTOML(src string) (*treeNode, error) {
+	var doc map[string]any
+	md, err := toml.Decode(src, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// A key's position is that of the first key path through it: a table
+	// opened by [a.b] or by a dotted key a.b.c = 1 sits where that line is.
+	// Paths carry no array indices, so tables in an array share an order.
+	first := make(map[string]int)
+	for i, key := range md.Keys() {
+		for j := 1; j <= len(key); j++ {
+			if _, ok := first[key[:j].String()]; !ok {
+				first[key[:j].String()] = i
+			}
+		}
+	}
+	return tomlTree(doc, nil, first, 0)
+}
+
+// tomlTree converts a decoded TOML value found at path.
+func tomlTree(v any, path toml.Key, first map[string]int, depth int) (*treeNode, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if depth >= treeMaxDepth {
+			return nil, fmt.Errorf("nested more than %d levels deep", treeMaxDepth)
+		}
+		n := &treeNode{kind: "object"}
+		for k := range v {
+			n.keys = append(n.keys, k)
+		}
+		position := func(k string) int {
+			if i, ok := first[append(path[:len(path):len(path)], k).String()]; ok {
+				return i
+			}
+			return len(first)
+		}
+		slices.SortFunc(n.keys, func(a, b string) int {
+			return cmp.Or(cmp.Compare(position(a), position(b)), cmp.Compare(a, b))
+		})
+		for _, k := range n.keys {
+			item, err := tomlTree(v[k], append(path[:len(path):len(path)], k), first, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		return n, nil
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, t := range v {
+			items[i] = t
+		}
+		return tomlTree(items, path, first, depth)
+	case []any:
+		if depth >= treeMaxDepth {
+			return nil, fmt.Errorf("nested more than %d levels deep", treeMaxDepth)
+		}
+		n := &treeNode{kind: "array"}
+		for _, e := range v {
+			item, err := tomlTree(e, path, first, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		return n, nil
+	case string:
+		return &treeNode{kind: "string", value: v}, nil
+	case int64:
+		return &treeNode{kind: "number", value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return &treeNode{kind: "number", value: tomlFloat(v)}, nil
+	case bool:
+		return &treeNode{kind: "bool", value: strconv.FormatBool(v)}, nil
+	case time.Time:
+		return &treeNode{kind: "datetime", value: tomlTime(v)}, nil
+	}
+	return nil, fmt.Errorf("unexpected TOML value of type %T", v)
+}
+
+// tomlFloat formats a float as TOML writes it, including inf and nan.
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// tomlTime formats a date or time as TOML writes it. The decoder marks
+// values without an offset with its own time zones.
+func tomlTime(t time.Time) string {
+	switch t.Location().String() {
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case "date-local":
+		return t.Format("2006-01-02")
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
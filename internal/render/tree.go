@@ -0,0 +1,303 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// treeMaxSource caps the size of data files given a tree view. Larger files
+// are shown as highlighted source only, since the tree's data is embedded in
+// the page alongside it.
+const treeMaxSource = 8 << 20
+
+// treeMaxDepth bounds the nesting of JSON and TOML documents, which are
+// parsed recursively.
+const treeMaxDepth = 1000
+
+// treeFormats maps the languages with a tree view to the path syntax their
+// users are most likely to paste into a tool.
+var treeFormats = map[string]string{
+	"json": "jsonpath",
+	"yaml": "yq",
+	"toml": "yq",
+}
+
+// HasTreeView reports whether code in the given language is rendered with
+// a tree view by TreeRenderer.
+func HasTreeView(language string) bool {
+	_, ok := treeFormats[language]
+	return ok
+}
+
+// treeNode is a parsed value of a JSON, YAML or TOML document. Object keys
+// keep their order in the source.
+type treeNode struct {
+	kind  string // object, array, string, number, bool, null, datetime, alias, binary or a YAML tag
+	keys  []string
+	items []*treeNode // object values, parallel to keys, or array items
+	value string
+}
+
+// get returns the value of key in object t, or nil.
+func (t *treeNode) get(key string) *treeNode {
+	if t == nil || t.kind != "object" {
+		return nil
+	}
+	for i, k := range t.keys {
+		if k == key {
+			return t.items[i]
+		}
+	}
+	return nil
+}
+
+// data returns the node in the compact form embedded in the page: an object
+// is ["object", keys, values], an array ["array", items] and a scalar
+// [kind, text].
+func (n *treeNode) data() any {
+	switch n.kind {
+	case "object":
+		values := make([]any, len(n.items))
+		for i, item := range n.items {
+			values[i] = item.data()
+		}
+		keys := n.keys
+		if keys == nil {
+			keys = []string{}
+		}
+		return []any{n.kind, keys, values}
+	case "array":
+		items := make([]any, len(n.items))
+		for i, item := range n.items {
+			items[i] = item.data()
+		}
+		return []any{n.kind, items}
+	default:
+		return []any{n.kind, n.value}
+	}
+}
+
+// TreeRenderer renders JSON, YAML and TOML as highlighted source with a
+// collapsible tree of the parsed document one toggle away.
+type TreeRenderer struct {
+	code *CodeRenderer
+}
+
+// NewTreeRenderer creates a new tree renderer.
+func NewTreeRenderer() *TreeRenderer {
+	return &TreeRenderer{code: NewCodeRenderer()}
+}
+
+// Render highlights source in the given language ("json", "yaml" or
+// "toml") and, when it parses, embeds the documents for the tree view.
+// Each document of a multi-document YAML stream gets its own tree. Content
+// that cannot be parsed is shown as source only, with the parse error.
+func (r *TreeRenderer) Render(source []byte, language string) ([]byte, error) {
+	highlighted, err := r.code.Render(source, language)
+	if err != nil {
+		return nil, err
+	}
+	pathStyle, ok := treeFormats[language]
+	if !ok || len(source) > treeMaxSource {
+		return highlighted, nil
+	}
+
+	docs, parseErr := parseTree(source, language)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<div class="cooked-tree" data-language="%s" data-path-style="%s">`+"\n",
+		html.EscapeString(language), pathStyle)
+	if parseErr != nil {
+		fmt.Fprintf(&buf, "<p class=\"cooked-tree-notice\">Could not parse as %s: %s</p>\n",
+			html.EscapeString(strings.ToUpper(language)), html.EscapeString(parseErr.Error()))
+	} else {
+		// The toolbar only works with scripts, so they reveal it.
+		buf.WriteString("<div class=\"cooked-tree-toolbar\" hidden>\n")
+		buf.WriteString("<button class=\"cooked-tree-toggle\" title=\"Show the tree\">Tree</button>\n")
+		buf.WriteString("<input type=\"search\" class=\"cooked-tree-search\" placeholder=\"Search keys and values\" aria-label=\"Search keys and values\" hidden>\n")
+		buf.WriteString("<span class=\"cooked-tree-status\"></span>\n")
+		buf.WriteString("<select class=\"cooked-tree-path-style\" aria-label=\"Path syntax\" hidden>")
+		for _, opt := range [][2]string{{"jsonpath", "JSONPath"}, {"yq", "yq"}} {
+			selected := ""
+			if opt[0] == pathStyle {
+				selected = " selected"
+			}
+			fmt.Fprintf(&buf, `<option value="%s"%s>%s</option>`, opt[0], selected, opt[1])
+		}
+		buf.WriteString("</select>\n</div>\n")
+		buf.WriteString("<div class=\"cooked-tree-view\" hidden></div>\n")
+	}
+	buf.WriteString("<div class=\"cooked-tree-source\">\n")
+	buf.Write(highlighted)
+	buf.WriteString("\n</div>\n")
+	if parseErr == nil {
+		data := make([]any, len(docs))
+		for i, doc := range docs {
+			data[i] = doc.data()
+		}
+		// json.Marshal escapes <, > and &, so the data cannot end the element.
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("encode tree: %w", err)
+		}
+		fmt.Fprintf(&buf, "<script type=\"application/json\" class=\"cooked-tree-data\">%s</script>\n", encoded)
+	}
+	buf.WriteString("</div>\n")
+	return buf.Bytes(), nil
+}
+
+// parseTree parses source into one tree per document.
+func parseTree(source []byte, language string) ([]*treeNode, error) {
+	source = bytes.TrimPrefix(source, []byte("\ufeff"))
+	switch language {
+	case "json":
+		doc, err := parseJSONTree(source)
+		if err != nil {
+			return nil, err
+		}
+		return []*treeNode{doc}, nil
+	case "yaml":
+		return parseYAMLTrees(source)
+	case "toml":
+		doc, err := parseTOML(string(source))
+		if err != nil {
+			return nil, err
+		}
+		return []*treeNode{doc}, nil
+	}
+	return nil, fmt.Errorf("no tree view for %s", language)
+}
+
+// parseJSONTree walks the document token by token, since decoding into a
+// map would lose the order of its keys.
+func parseJSONTree(source []byte) (*treeNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(source))
+	dec.UseNumber()
+	doc, err := decodeJSONValue(dec, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return doc, nil
+}
+
+func decodeJSONValue(dec *json.Decoder, depth int) (*treeNode, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, errors.New("empty document")
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if depth >= treeMaxDepth {
+			return nil, fmt.Errorf("nested more than %d levels deep", treeMaxDepth)
+		}
+		n := &treeNode{kind: "array"}
+		if t == '{' {
+			n.kind = "object"
+		}
+		for dec.More() {
+			if n.kind == "object" {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			item, err := decodeJSONValue(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &treeNode{kind: "string", value: t}, nil
+	case json.Number:
+		return &treeNode{kind: "number", value: t.String()}, nil
+	case bool:
+		return &treeNode{kind: "bool", value: fmt.Sprint(t)}, nil
+	default:
+		return &treeNode{kind: "null", value: "null"}, nil
+	}
+}
+
+// parseYAMLTrees parses every document in a YAML stream.
+func parseYAMLTrees(source []byte) ([]*treeNode, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(source))
+	var docs []*treeNode
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, yamlTree(&doc))
+	}
+	if len(docs) == 0 {
+		docs = append(docs, &treeNode{kind: "null", value: "null"})
+	}
+	return docs, nil
+}
+
+// yamlTree converts a YAML node. Aliases are shown by name rather than
+// expanded, so a document cannot grow without bound through them.
+func yamlTree(y *yaml.Node) *treeNode {
+	switch y.Kind {
+	case yaml.DocumentNode:
+		if len(y.Content) == 0 {
+			return &treeNode{kind: "null", value: "null"}
+		}
+		return yamlTree(y.Content[0])
+	case yaml.MappingNode:
+		n := &treeNode{kind: "object"}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			n.keys = append(n.keys, y.Content[i].Value)
+			n.items = append(n.items, yamlTree(y.Content[i+1]))
+		}
+		return n
+	case yaml.SequenceNode:
+		n := &treeNode{kind: "array"}
+		for _, c := range y.Content {
+			n.items = append(n.items, yamlTree(c))
+		}
+		return n
+	case yaml.AliasNode:
+		return &treeNode{kind: "alias", value: "*" + y.Value}
+	}
+	switch tag := y.ShortTag(); tag {
+	case "!!str":
+		return &treeNode{kind: "string", value: y.Value}
+	case "!!int", "!!float":
+		return &treeNode{kind: "number", value: y.Value}
+	case "!!bool":
+		return &treeNode{kind: "bool", value: y.Value}
+	case "!!null":
+		if y.Value == "" {
+			return &treeNode{kind: "null", value: "null"}
+		}
+		return &treeNode{kind: "null", value: y.Value}
+	case "!!timestamp":
+		return &treeNode{kind: "datetime", value: y.Value}
+	case "!!binary":
+		return &treeNode{kind: "binary", value: y.Value}
+	default:
+		return &treeNode{kind: tag, value: y.Value}
+	}
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTreeGolden(t *testing.T) {
+	r := NewTreeRenderer()
+
+	fixtures, err := filepath.Glob(filepath.Join(fixturesDir, "tree", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no tree fixtures found")
+	}
+
+	goldenSubdir := filepath.Join(goldenDir, "tree")
+
+	for _, fixturePath := range fixtures {
+		filename := filepath.Base(fixturePath)
+		info := DetectFile(fixturePath)
+		if !HasTreeView(info.Language) {
+			continue
+		}
+
+		t.Run(filename, func(t *testing.T) {
+			input, err := os.ReadFile(fixturePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.Render(input, info.Language)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+
+			goldenPath := filepath.Join(goldenSubdir, filename+".html")
+
+			if *update {
+				if err := os.MkdirAll(goldenSubdir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("updated %s", goldenPath)
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("golden file not found (run with -update to create): %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("output mismatch for %s (run with -update to regenerate)\n"+
+					"got %d bytes, want %d bytes\n"+
+					"first diff at byte %d",
+					filename, len(got), len(want), firstDiff(got, want))
+			}
+
+			// Every tree keeps the highlighted source in view.
+			s := string(got)
+			if !strings.Contains(s, `<div class="cooked-tree-source">`) {
+				t.Error("missing source view")
+			}
+			if !strings.Contains(s, `class="cooked-tree-data"`) {
+				t.Error("missing embedded tree data")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// treeJSON returns the embedded form of the parsed documents.
+func treeJSON(t *testing.T, source, language string) string {
+	t.Helper()
+	docs, err := parseTree([]byte(source), language)
+	if err != nil {
+		t.Fatalf("parseTree(%s): %v", language, err)
+	}
+	data := make([]any, len(docs))
+	for i, doc := range docs {
+		data[i] = doc.data()
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParseTree_JSON(t *testing.T) {
+	got := treeJSON(t, `{"zeta": 1.50, "alpha": [true, null, "x"], "empty": {}}`, "json")
+	want := `[["object",["zeta","alpha","empty"],[["number","1.50"],["array",[["bool","true"],["null","null"],["string","x"]]],["object",[],[]]]]]`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	for _, bad := range []string{"", `{"a": }`, `{"a": 1} {"b": 2}`, strings.Repeat("[", 5000)} {
+		if _, err := parseTree([]byte(bad), "json"); err == nil {
+			t.Errorf("parseTree(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseTree_YAML(t *testing.T) {
+	source := `base: &base
+  image: nginx
+  port: 8080
+web:
+  <<: *base
+  enabled: yes
+  when: 2024-01-02
+  ref: !Ref Bucket
+  none: ~
+---
+- one
+---
+`
+	got := treeJSON(t, source, "yaml")
+	want := `[["object",["base","web"],[["object",["image","port"],[["string","nginx"],["number","8080"]]],` +
+		`["object",["\u003c\u003c","enabled","when","ref","none"],[["alias","*base"],["string","yes"],["datetime","2024-01-02"],["!Ref","Bucket"],["null","~"]]]]],` +
+		`["array",[["string","one"]]],["null","null"]]`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	if _, err := parseTree([]byte("a: [1, 2\nb: 3\n"), "yaml"); err == nil {
+		t.Error("expected an error for an unclosed flow sequence")
+	}
+}
+
+func TestParseTree_TOML(t *testing.T) {
+	source := `# Cargo manifest
+title = "TOML \"example\"\u00e9"
+path = 'C:\Users'
+[package]
+name = "cooked"
+version.major = 1
+hex = 0xdead_beef
+when = 1979-05-27T07:32:00Z
+day = 1979-05-27
+float = -3.5e+2
+ports = [ 8000,
+  8001, # trailing comma
+]
+point = { x = 1, y = 2 }
+text = """
+Roses \
+  are red"""
+
+[[bin]]
+name = "a"
+
+[[bin]]
+name = "b"
+
+[dependencies.serde]
+features = ["derive"]
+`
+	got := treeJSON(t, source, "toml")
+	want := `[["object",["title","path","package","bin","dependencies"],[` +
+		`["string","TOML \"example\"é"],["string","C:\\Users"],` +
+		`["object",["name","version","hex","when","day","float","ports","point","text"],[` +
+		`["string","cooked"],["object",["major"],[["number","1"]]],["number","3735928559"],` +
+		`["datetime","1979-05-27T07:32:00Z"],["datetime","1979-05-27"],["number","-350"],` +
+		`["array",[["number","8000"],["number","8001"]]],` +
+		`["object",["x","y"],[["number","1"],["number","2"]]],["string","Roses are red"]]],` +
+		`["array",[["object",["name"],[["string","a"]]],["object",["name"],[["string","b"]]]]],` +
+		`["object",["serde"],[["object",["features"],[["array",[["string","derive"]]]]]]]]]]`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	for _, bad := range []string{
+		"a = 1\na = 2\n",
+		"[t]\n[t]\n",
+		"a = \"open\n",
+		"a = 1 b = 2\n",
+		"a =\n",
+		"a = [1 2]\n",
+		"a = " + strings.Repeat("[", 5000),
+	} {
+		if _, err := parseTOML(bad); err == nil {
+			t.Errorf("parseTOML(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestTreeRenderer_Render(t *testing.T) {
+	r := NewTreeRenderer()
+
+	html, err := r.Render([]byte("a: 1\n"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(html)
+	for _, want := range []string{
+		`<div class="cooked-tree" data-language="yaml" data-path-style="yq">`,
+		`<option value="yq" selected>yq</option>`,
+		`<div class="cooked-tree-source">`,
+		`class="cooked-code-block"`,
+		`<script type="application/json" class="cooked-tree-data">[["object",["a"],[["number","1"]]]]</script>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in output:\n%s", want, got)
+		}
+	}
+
+	// Content that does not parse is shown as source, with the error.
+	html, err = r.Render([]byte(`{"a": `), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = string(html)
+	if !strings.Contains(got, `<p class="cooked-tree-notice">Could not parse as JSON: `) {
+		t.Errorf("missing parse error notice:\n%s", got)
+	}
+	if strings.Contains(got, "cooked-tree-data") || strings.Contains(got, "cooked-tree-toolbar") {
+		t.Errorf("unparsable content should have no tree:\n%s", got)
+	}
+
+	// Data cannot close the script element it is embedded in.
+	html, err = r.Render([]byte(`{"x": "</script><b>"}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(html), "</script>") != 1 {
+		t.Errorf("embedded data ended the script element:\n%s", html)
+	}
+}
+
+func FuzzParseTOML(f *testing.F) {
+	seeds := []string{
+		"a = 1\n",
+		"[t]\nx = \"y\"\n[[arr]]\nk = 'v'\n",
+		"s = \"\"\"\nmulti \\\n  line\"\"\"\n",
+		"l = '''\nraw ''''\n",
+		"d = 1979-05-27T07:32:00-08:00\nt = 07:32\n",
+		"p = { x = 1, y.z = [1, [2, 3]] }\n",
+		"u = \"\\u00e9\\U0001F600\"\n",
+		"[a.b.c]\n[a]\n",
+		"a.b = 1\na.c = 2\n",
+		"[[a]]\n[a.b]\n[[a]]\n",
+		"= 1\n",
+		"a = [\n",
+		"\"unterminated",
+		"",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		_, _ = parseTOML(input)
+	})
+}
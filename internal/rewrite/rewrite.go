@@ -32,6 +32,32 @@ func RelativeURLs(html []byte, upstreamURL, baseURL, rawProxyPrefix string) []by
 	}
 	base := u.Scheme + "://" + u.Host + basePath
 
+	return Relative(html, func(ref string, isSrc bool) (string, bool) {
+		// Resolve relative path against base
+		resolved := resolveRelative(base, ref)
+
+		if render.IsRenderableLink(ref) {
+			// Markdown links go through cooked
+			cookedPrefix := "/"
+			if baseURL != "" {
+				cookedPrefix = strings.TrimRight(baseURL, "/") + "/"
+			}
+			return cookedPrefix + resolved, true
+		}
+
+		// Non-renderable: proxy src attributes through raw, leave href direct
+		if isSrc && rawProxyPrefix != "" {
+			return rawProxyPrefix + resolved, true
+		}
+		return resolved, true
+	})
+}
+
+// Relative calls fn for each relative href and src attribute in HTML content
+// and replaces the attribute's path with the result when ok is true. ref is
+// the path without its query and fragment, which are kept. Absolute URLs,
+// fragment-only links, and data: and mailto: URIs are left untouched.
+func Relative(html []byte, fn func(ref string, isSrc bool) (rewritten string, ok bool)) []byte {
 	rewriter := func(re *regexp.Regexp) func([]byte) []byte {
 		return func(match []byte) []byte {
 			parts := re.FindSubmatch(match)
@@ -73,24 +99,12 @@ func RelativeURLs(html []byte, upstreamURL, baseURL, rawProxyPrefix string) []by
 				return match
 			}
 
-			// Resolve relative path against base
-			resolved := resolveRelative(base, href)
-
-			if render.IsRenderableLink(href) {
-				// Markdown links go through cooked
-				cookedPrefix := "/"
-				if baseURL != "" {
-					cookedPrefix = strings.TrimRight(baseURL, "/") + "/"
-				}
-				return []byte(attrEq + quote + cookedPrefix + resolved + query + fragment + quote)
-			}
-
-			// Non-renderable: proxy src attributes through raw, leave href direct
 			isSrc := strings.HasPrefix(strings.ToLower(strings.TrimSpace(attrEq)), "src")
-			if isSrc && rawProxyPrefix != "" {
-				return []byte(attrEq + quote + rawProxyPrefix + resolved + query + fragment + quote)
+			rewritten, ok := fn(href, isSrc)
+			if !ok {
+				return match
 			}
-			return []byte(attrEq + quote + resolved + query + fragment + quote)
+			return []byte(attrEq + quote + rewritten + query + fragment + quote)
 		}
 	}
 
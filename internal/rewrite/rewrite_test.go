@@ -228,3 +228,26 @@ func TestRelativeURLs_QueryString(t *testing.T) {
 		t.Errorf("query string was lost: %s", got)
 	}
 }
+
+func TestRelative(t *testing.T) {
+	html := []byte(`<a href="guide.md?plain=1#setup">g</a> <img src='img/a.png'> <a href="https://x.com/b.md">x</a> <a href="#top">t</a>`)
+
+	var seen []string
+	got := string(Relative(html, func(ref string, isSrc bool) (string, bool) {
+		seen = append(seen, ref)
+		if isSrc {
+			return "", false
+		}
+		return "out/" + ref, true
+	}))
+
+	if strings.Join(seen, ",") != "guide.md,img/a.png" {
+		t.Errorf("fn called for %v, want only relative paths", seen)
+	}
+	if !strings.Contains(got, `href="out/guide.md?plain=1#setup"`) {
+		t.Errorf("rewritten href missing query or fragment: %s", got)
+	}
+	if !strings.Contains(got, `src='img/a.png'`) {
+		t.Errorf("declined attribute changed: %s", got)
+	}
+}
@@ -50,6 +50,11 @@ func init() {
 	// Allow details/summary.
 	p.AllowElements("details", "summary")
 
+	// Allow base64 data: URIs for raster and SVG images on img — Jupyter
+	// notebooks embed plot outputs and cell attachments this way. An SVG
+	// loaded through img cannot run scripts.
+	p.AllowDataURIImages()
+
 	policy = p
 }
 
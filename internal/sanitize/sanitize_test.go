@@ -142,6 +142,14 @@ func TestHTML_PreservesImages(t *testing.T) {
 	}
 }
 
+func TestHTML_PreservesDataURIImages(t *testing.T) {
+	input := `<img src="data:image/png;base64,iVBORw0KGgo=" alt="plot">`
+	got := string(HTML([]byte(input)))
+	if !strings.Contains(got, `src="data:image/png;base64,iVBORw0KGgo="`) {
+		t.Errorf("data: image src was stripped: %s", got)
+	}
+}
+
 func TestHTML_PreservesHeadingIDs(t *testing.T) {
 	input := `<h1 id="hello-world">Hello World</h1>`
 	got := string(HTML([]byte(input)))
@@ -228,6 +236,7 @@ func TestHTML_StripDangerousURIs(t *testing.T) {
 		{"data:text/html", `<a href="data:text/html,<script>alert(1)</script>">click</a>`, "data:text/html"},
 		{"mixed case", `<a HREF="JavaScript:alert(1)">click</a>`, "javascript:"},
 		{"src attribute", `<img src="javascript:alert(1)">`, "javascript:"},
+		{"data:text/html image", `<img src="data:text/html;base64,PHNjcmlwdD4=">`, "data:text/html"},
 	}
 
 	for _, tc := range tests {
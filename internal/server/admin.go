@@ -0,0 +1,262 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+)
+
+const defaultAdminTop = 20
+
+// adminRoutes registers the cache administration API on mux.
+func (s *Server) adminRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /_cooked/admin/cache", s.requireAdmin(http.HandlerFunc(s.handleAdminCache)))
+	mux.Handle("DELETE /_cooked/admin/cache", s.requireAdmin(http.HandlerFunc(s.handleAdminPurge)))
+}
+
+// AdminHandler returns the admin API as a standalone handler for serving on
+// a separate listener, so it need not be reachable through the public
+// ingress. When metrics are configured on the same address they are served
+// here too. It returns nil when no admin token is configured.
+func (s *Server) AdminHandler() http.Handler {
+	if s.cfg.AdminToken == "" {
+		return nil
+	}
+	mux := s.internalMux()
+	s.adminRoutes(mux)
+	if s.cfg.Metrics && s.cfg.MetricsListen == s.cfg.AdminListen {
+		mux.Handle("GET /metrics", s.metrics.Handler())
+	}
+	return s.loggingMiddleware(mux)
+}
+
+// MetricsHandler returns /metrics as a standalone handler for a separate
+// listener. It returns nil when metrics are disabled.
+func (s *Server) MetricsHandler() http.Handler {
+	if !s.cfg.Metrics {
+		return nil
+	}
+	mux := s.internalMux()
+	mux.Handle("GET /metrics", s.metrics.Handler())
+	return mux
+}
+
+// internalMux returns a mux for an operator-only listener with its own
+// health check.
+func (s *Server) internalMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// requireAdmin rejects requests without the configured bearer token.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	want := []byte(s.cfg.AdminToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cooked-admin"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminEntry is the JSON form of a cached entry.
+type adminEntry struct {
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Expired      bool      `json:"expired"`
+	Hits         int64     `json:"hits"`
+	Tier         string    `json:"tier"`
+}
+
+type adminDiskStats struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+type adminStats struct {
+	Entries     int             `json:"entries"`
+	Bytes       int64           `json:"bytes"`
+	MaxBytes    int64           `json:"max_bytes"`
+	Disk        *adminDiskStats `json:"disk,omitempty"`
+	Hits        int64           `json:"hits"`
+	Misses      int64           `json:"misses"`
+	Revalidated int64           `json:"revalidated"`
+	Expired     int64           `json:"expired"`
+	Stale       int64           `json:"stale"`
+	Evictions   int64           `json:"evictions"`
+	Top         []adminEntry    `json:"top"`
+}
+
+// handleAdminCache reports cache statistics, or with ?url= whether that URL
+// is cached (HEAD answers 200 or 404 without a body).
+func (s *Server) handleAdminCache(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if rawURL := q.Get("url"); rawURL != "" {
+		for _, key := range s.cacheKeys(rawURL) {
+			if info, ok := s.cache.Peek(key); ok {
+				w.Header().Set("X-Cooked-Cache-Tier", info.Tier)
+				writeJSON(w, http.StatusOK, toAdminEntry(info))
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not cached"})
+		return
+	}
+
+	top := defaultAdminTop
+	if v := q.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "top must be a non-negative integer"})
+			return
+		}
+		top = n
+	}
+
+	st := s.cache.Stats()
+	out := adminStats{
+		Entries:     st.Entries,
+		Bytes:       st.Bytes,
+		MaxBytes:    st.MaxBytes,
+		Hits:        st.Hits,
+		Misses:      st.Misses,
+		Revalidated: st.Revalidated,
+		Expired:     st.Expired,
+		Stale:       st.Stale,
+		Evictions:   st.Evictions,
+		Top:         []adminEntry{},
+	}
+	if st.DiskEnabled {
+		out.Disk = &adminDiskStats{Entries: st.DiskEntries, Bytes: st.DiskBytes}
+	}
+	for _, info := range s.cache.Top(top) {
+		out.Top = append(out.Top, toAdminEntry(info))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleAdminPurge removes cached pages selected by exactly one of url,
+// prefix, host, or all=true.
+func (s *Server) handleAdminPurge(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var selectors []string
+	for _, name := range []string{"url", "prefix", "host", "all"} {
+		if q.Has(name) {
+			selectors = append(selectors, name)
+		}
+	}
+	if len(selectors) != 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "specify exactly one of url, prefix, host, or all=true"})
+		return
+	}
+
+	selector, value := selectors[0], q.Get(selectors[0])
+	var match func(key string) bool
+	switch selector {
+	case "url":
+		match = matchKeys(s.cacheKeys(value))
+	case "prefix":
+		match = func(key string) bool { return strings.HasPrefix(key, value) }
+	case "host":
+		match = matchHost(value)
+	case "all":
+		if value != "true" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "all must be true"})
+			return
+		}
+		match = func(string) bool { return true }
+	}
+	if value == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": selector + " must not be empty"})
+		return
+	}
+
+	n := s.cache.Purge(match)
+	slog.Info("cache purged", "selector", selector, "value", redactUpstream(value), "purged", n)
+	writeJSON(w, http.StatusOK, map[string]int{"purged": n})
+}
+
+// cacheKeys returns the cache keys a user-facing URL may be stored under:
+// the URL itself, its forge-resolved raw file URL, the render API's variant,
+// and for forge directories the listing API URL. Other variants, such as type
+// overrides, are matched through their URL by matchKeys.
+func (s *Server) cacheKeys(rawURL string) []string {
+	keys := []string{rawURL}
+	resolved, _, _ := s.forges.Resolve(rawURL)
+	if resolved != rawURL {
+		keys = append(keys, resolved)
+	}
+	keys = append(keys, cache.VariantKey(resolved, apiVariant))
+	if dir, ok := s.forges.Directory(resolved); ok {
+		keys = append(keys, dir.APIURL)
+	}
+	return keys
+}
+
+func matchKeys(keys []string) func(string) bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return func(key string) bool { return set[key] || set[cache.KeyURL(key)] }
+}
+
+// matchHost matches keys whose URL host equals host. A host without a port
+// matches every port.
+func matchHost(host string) func(string) bool {
+	host = strings.ToLower(host)
+	_, _, err := net.SplitHostPort(host)
+	withPort := err == nil
+	return func(key string) bool {
+		u, err := url.Parse(key)
+		if err != nil {
+			return false
+		}
+		if withPort {
+			return strings.ToLower(u.Host) == host
+		}
+		return strings.ToLower(u.Hostname()) == strings.Trim(host, "[]")
+	}
+}
+
+func toAdminEntry(info cache.EntryInfo) adminEntry {
+	return adminEntry{
+		URL:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+		ExpiresAt:    info.ExpiresAt,
+		Expired:      time.Now().After(info.ExpiresAt),
+		Hits:         info.Hits,
+		Tier:         info.Tier,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+const testAdminToken = "test-admin-token"
+
+func adminConfig(adminListen string) *config.Config {
+	return &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      5 * 1024 * 1024,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+		FrameAncestors:   "none",
+		AdminToken:       testAdminToken,
+		AdminListen:      adminListen,
+	}
+}
+
+// adminDo sends an authenticated admin request and decodes the JSON body
+// into out (when non-nil).
+func adminDo(t *testing.T, method, target string, out any) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, target, err)
+		}
+	}
+	return resp
+}
+
+func warm(t *testing.T, target string) {
+	t.Helper()
+	resp, err := http.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("GET %s = %d", target, resp.StatusCode)
+	}
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	s := newTestServer(t, adminConfig(""))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for _, auth := range []string{"", "Bearer wrong", "Basic " + testAdminToken} {
+		req, _ := http.NewRequest("DELETE", srv.URL+"/_cooked/admin/cache?all=true", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", auth, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: missing WWW-Authenticate", auth)
+		}
+	}
+}
+
+func TestAdmin_DisabledWithoutToken(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/_cooked/admin/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 when the admin API is disabled", resp.StatusCode)
+	}
+	if s.AdminHandler() != nil {
+		t.Error("AdminHandler should be nil without a token")
+	}
+}
+
+func TestAdmin_StatsAndLookup(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Hello\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, adminConfig(""))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	page := upstream.URL + "/README.md"
+	warm(t, srv.URL+"/"+page) // miss
+	warm(t, srv.URL+"/"+page) // hit
+
+	var stats adminStats
+	adminDo(t, "GET", srv.URL+"/_cooked/admin/cache", &stats)
+	if stats.Entries != 1 || stats.Bytes == 0 {
+		t.Errorf("entries = %d, bytes = %d", stats.Entries, stats.Bytes)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("hits = %d, misses = %d, want 1 and 1", stats.Hits, stats.Misses)
+	}
+	if len(stats.Top) != 1 || stats.Top[0].URL != page || stats.Top[0].Hits != 1 {
+		t.Errorf("top = %+v", stats.Top)
+	}
+
+	var entry adminEntry
+	resp := adminDo(t, "GET", srv.URL+"/_cooked/admin/cache?url="+url.QueryEscape(page), &entry)
+	if resp.StatusCode != 200 || entry.URL != page || entry.ContentType != "markdown" || entry.Tier != "memory" {
+		t.Errorf("lookup = %d %+v", resp.StatusCode, entry)
+	}
+
+	resp = adminDo(t, "HEAD", srv.URL+"/_cooked/admin/cache?url="+url.QueryEscape(page), nil)
+	if resp.StatusCode != 200 {
+		t.Errorf("HEAD cached URL = %d, want 200", resp.StatusCode)
+	}
+	resp = adminDo(t, "HEAD", srv.URL+"/_cooked/admin/cache?url="+url.QueryEscape(upstream.URL+"/other.md"), nil)
+	if resp.StatusCode != 404 {
+		t.Errorf("HEAD uncached URL = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAdmin_Purge(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Doc\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, adminConfig(""))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	u, _ := url.Parse(upstream.URL)
+	fill := func() {
+		t.Helper()
+		s.cache.Purge(func(string) bool { return true })
+		for _, p := range []string{"/docs/a.md", "/docs/b.md", "/README.md"} {
+			warm(t, srv.URL+"/"+upstream.URL+p)
+		}
+		// A second host that host and prefix purges must leave alone.
+		warm(t, srv.URL+"/http://localhost:"+u.Port()+"/README.md")
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"url=" + url.QueryEscape(upstream.URL+"/README.md"), 1},
+		{"prefix=" + url.QueryEscape(upstream.URL+"/docs/"), 2},
+		{"host=" + u.Hostname(), 3},
+		{"host=" + u.Host, 3},
+		{"all=true", 4},
+	}
+	for _, tt := range tests {
+		fill()
+		var out map[string]int
+		resp := adminDo(t, "DELETE", srv.URL+"/_cooked/admin/cache?"+tt.query, &out)
+		if resp.StatusCode != 200 || out["purged"] != tt.want {
+			t.Errorf("DELETE ?%s = %d %v, want purged %d", tt.query, resp.StatusCode, out, tt.want)
+		}
+		if got := s.cache.Len(); got != 4-tt.want {
+			t.Errorf("DELETE ?%s: %d entries left, want %d", tt.query, got, 4-tt.want)
+		}
+	}
+
+	for _, query := range []string{"", "url=a&host=b", "all=yes", "prefix="} {
+		resp := adminDo(t, "DELETE", srv.URL+"/_cooked/admin/cache?"+query, nil)
+		if resp.StatusCode != 400 {
+			t.Errorf("DELETE ?%s = %d, want 400", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestAdmin_SeparateListener(t *testing.T) {
+	s := newTestServer(t, adminConfig("127.0.0.1:0"))
+	public := httptest.NewServer(s.Handler())
+	defer public.Close()
+	admin := httptest.NewServer(s.AdminHandler())
+	defer admin.Close()
+
+	resp := adminDo(t, "GET", public.URL+"/_cooked/admin/cache", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("public listener status = %d, want 404", resp.StatusCode)
+	}
+
+	var stats adminStats
+	resp = adminDo(t, "GET", admin.URL+"/_cooked/admin/cache", &stats)
+	if resp.StatusCode != 200 {
+		t.Errorf("admin listener status = %d, want 200", resp.StatusCode)
+	}
+}
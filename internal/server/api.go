@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/render"
+	"github.com/air-gapped/cooked/internal/tracing"
+)
+
+// apiVariant names the render API's entries in the cache; see
+// cache.VariantKey.
+const apiVariant = "api"
+
+// apiFile is the JSON form of render.FileInfo.
+type apiFile struct {
+	ContentType string `json:"content_type"`
+	Language    string `json:"language,omitempty"`
+	Label       string `json:"label"`
+	Source      string `json:"source,omitempty"` // what the type was detected from
+}
+
+type apiHeading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// apiMeta is the JSON form of render.MarkdownMeta.
+type apiMeta struct {
+	Title          string       `json:"title,omitempty"`
+	Headings       []apiHeading `json:"headings"`
+	Languages      []string     `json:"languages"`
+	HasMermaid     bool         `json:"has_mermaid"`
+	HeadingCount   int          `json:"heading_count"`
+	CodeBlockCount int          `json:"code_block_count"`
+}
+
+// apiRender is the render API response. It is cached without the per-request
+// fields (cache status and timings).
+type apiRender struct {
+	URL            string   `json:"url"`
+	Upstream       string   `json:"upstream"`
+	HTML           string   `json:"html"`
+	File           apiFile  `json:"file"`
+	Meta           *apiMeta `json:"meta,omitempty"`
+	UpstreamStatus int      `json:"upstream_status"`
+	ETag           string   `json:"etag,omitempty"`
+	LastModified   string   `json:"last_modified,omitempty"`
+	Size           int64    `json:"size"`
+	Cache          string   `json:"cache"`
+	RenderMs       int64    `json:"render_ms"`
+	UpstreamMs     int64    `json:"upstream_ms"`
+}
+
+// handleAPIRender renders ?url= and returns the HTML fragment and its
+// metadata as JSON, without cooked's page chrome. It shares handleRender's
+// validation, fetch, coalescing and cache paths.
+func (s *Server) handleAPIRender(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "handleAPIRender")
+	defer span.End()
+
+	sourceURL := r.URL.Query().Get("url")
+	if sourceURL == "" {
+		s.writeAPIError(w, "", &requestError{400, "bad-request", "The url parameter is required"})
+		return
+	}
+	rawUpstream, _, _ := s.forges.Resolve(sourceURL)
+	span.SetAttributes(tracing.URL(rawUpstream))
+
+	typeOverride := strings.ToLower(strings.TrimSpace(r.URL.Query().Get(typeOverrideParam)))
+	upstream, reqErr := s.checkUpstream(rawUpstream)
+	if reqErr == nil {
+		reqErr = checkTypeOverride(typeOverride)
+	}
+	if reqErr != nil {
+		s.writeAPIError(w, rawUpstream, reqErr)
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	req := renderRequest{upstream: upstream, rawUpstream: rawUpstream, sourceURL: sourceURL, api: true, typeOverride: typeOverride}
+	s.serveCoalesced(w, req.apiKey(), func(w http.ResponseWriter) {
+		s.renderAPI(ctx, w, req)
+	})
+}
+
+// apiKey is the cache key of req's render API response.
+func (req renderRequest) apiKey() string {
+	return req.variantKey(req.rawUpstream, apiVariant)
+}
+
+// renderAPI fetches a validated upstream URL and writes the render API
+// response, a cached copy, or a JSON error.
+func (s *Server) renderAPI(ctx context.Context, w http.ResponseWriter, req renderRequest) {
+	upstream, rawUpstream := req.upstream, req.rawUpstream
+
+	if _, ok := s.forges.Directory(rawUpstream); ok || upstream.Path == "" || strings.HasSuffix(upstream.Path, "/") {
+		s.writeAPIError(w, rawUpstream, &requestError{415, "unsupported",
+			"Directory listings are not available through the render API"})
+		return
+	}
+
+	key := req.apiKey()
+	result, cachedEntry, err := s.fetchCached(ctx, key, req)
+	if err != nil {
+		s.writeAPIError(w, rawUpstream, s.fetchFailure(rawUpstream, err))
+		return
+	}
+
+	if cachedEntry != nil && (result.CacheStatus == cache.StatusHit || result.CacheStatus == cache.StatusRevalidated || result.CacheStatus == cache.StatusStale) {
+		var out apiRender
+		if err := json.Unmarshal(cachedEntry.HTML, &out); err != nil {
+			slog.Error("decode cached render API response failed", "upstream", rawUpstream, "error", err)
+			s.writeAPIError(w, rawUpstream, &requestError{500, "render-error", "Failed to render document"})
+			return
+		}
+		out.Cache = string(result.CacheStatus)
+		out.UpstreamMs = result.FetchMs
+		s.writeAPI(w, out)
+		return
+	}
+
+	if result.StatusCode != 200 {
+		s.writeAPIError(w, rawUpstream, &requestError{result.StatusCode, "upstream-error",
+			fmt.Sprintf("Upstream returned %d", result.StatusCode)})
+		return
+	}
+
+	body := charset.ToUTF8(result.Body, result.ContentType)
+	fileInfo, typeSource := req.detectType(result, body)
+	if fileInfo.ContentType == render.TypeUnsupported {
+		s.writeAPIError(w, rawUpstream, &requestError{415, "unsupported",
+			"This file type is not supported for rendering"})
+		return
+	}
+
+	renderStart := time.Now()
+	htmlContent, meta, err := s.renderDocument(ctx, body, fileInfo, rawUpstream)
+	if err != nil {
+		slog.Error("render "+string(fileInfo.ContentType)+" failed", "error", err, "upstream", rawUpstream)
+		s.writeAPIError(w, rawUpstream, &requestError{500, "render-error", renderFailureMessage(fileInfo.ContentType)})
+		return
+	}
+
+	out := apiRender{
+		URL:            req.sourceURL,
+		Upstream:       rawUpstream,
+		HTML:           string(htmlContent),
+		File:           apiFile{ContentType: string(fileInfo.ContentType), Language: fileInfo.Language, Label: fileInfo.Label, Source: string(typeSource)},
+		Meta:           toAPIMeta(meta),
+		UpstreamStatus: result.StatusCode,
+		ETag:           result.ETag,
+		LastModified:   result.LastModified,
+		Size:           result.ContentLen,
+	}
+	encoded, err := json.Marshal(out)
+	if err == nil {
+		s.fetcher.Store(key, cache.Entry{
+			HTML:         encoded,
+			ETag:         result.ETag,
+			LastModified: result.LastModified,
+			Size:         int64(len(encoded)),
+			ContentType:  string(fileInfo.ContentType),
+			TypeSource:   string(typeSource),
+			Freshness:    result.Freshness,
+		})
+	}
+
+	out.Cache = string(result.CacheStatus)
+	out.RenderMs = time.Since(renderStart).Milliseconds()
+	out.UpstreamMs = result.FetchMs
+	s.writeAPI(w, out)
+}
+
+// writeAPI writes a render API response with the same X-Cooked-* headers as
+// a rendered page.
+func (s *Server) writeAPI(w http.ResponseWriter, out apiRender) {
+	s.setResponseHeaders(w, out.Upstream, out.UpstreamStatus, out.Cache,
+		out.File.ContentType, out.RenderMs, out.UpstreamMs, s.version)
+	setTypeSource(w, out.File.Source)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// writeAPIError writes a render API failure as {"error", "type"}.
+func (s *Server) writeAPIError(w http.ResponseWriter, rawUpstream string, e *requestError) {
+	s.setResponseHeaders(w, rawUpstream, e.status, "", "error", 0, 0, s.version)
+	writeJSON(w, e.status, map[string]string{"error": e.message, "type": e.errType})
+}
+
+func toAPIMeta(meta *render.MarkdownMeta) *apiMeta {
+	if meta == nil {
+		return nil
+	}
+	out := &apiMeta{
+		Title:          meta.Title,
+		Headings:       []apiHeading{},
+		Languages:      meta.Languages,
+		HasMermaid:     meta.HasMermaid,
+		HeadingCount:   meta.HeadingCount,
+		CodeBlockCount: meta.CodeBlockCount,
+	}
+	if out.Languages == nil {
+		out.Languages = []string{}
+	}
+	for _, h := range meta.Headings {
+		out.Headings = append(out.Headings, apiHeading{Level: h.Level, Text: h.Text, ID: h.ID})
+	}
+	return out
+}
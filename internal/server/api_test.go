@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+// apiGet calls the render API for upstreamURL and decodes the response.
+func apiGet(t *testing.T, srvURL, upstreamURL string, out any) *http.Response {
+	t.Helper()
+	resp, err := http.Get(srvURL + "/_cooked/api/render?url=" + url.QueryEscape(upstreamURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp
+}
+
+func TestAPIRender(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("# Guide\n\n## Setup\n\n![logo](img/logo.png)\n\n```go\nfunc main() {}\n```\n\n```mermaid\ngraph TD; A-->B\n```\n\n<script>alert(1)</script>\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	page := upstream.URL + "/docs/guide.md"
+	var out apiRender
+	resp := apiGet(t, srv.URL, page, &out)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	if out.Cache != "miss" || resp.Header.Get("X-Cooked-Cache") != "miss" {
+		t.Errorf("cache = %q, header %q, want miss", out.Cache, resp.Header.Get("X-Cooked-Cache"))
+	}
+	if out.URL != page || out.Upstream != page || out.UpstreamStatus != 200 || out.ETag != `"v1"` {
+		t.Errorf("url = %q, upstream = %q, status = %d, etag = %q", out.URL, out.Upstream, out.UpstreamStatus, out.ETag)
+	}
+	if out.File.ContentType != "markdown" || out.File.Label != "Markdown" || out.File.Source != "path" {
+		t.Errorf("file = %+v", out.File)
+	}
+	if out.Meta == nil {
+		t.Fatal("meta missing")
+	}
+	if out.Meta.Title != "Guide" || out.Meta.HeadingCount != 2 || !out.Meta.HasMermaid || out.Meta.CodeBlockCount != 1 {
+		t.Errorf("meta = %+v", out.Meta)
+	}
+	if len(out.Meta.Headings) != 2 || out.Meta.Headings[1].Text != "Setup" || out.Meta.Headings[1].Level != 2 {
+		t.Errorf("headings = %+v", out.Meta.Headings)
+	}
+	if len(out.Meta.Languages) == 0 || out.Meta.Languages[0] != "go" {
+		t.Errorf("languages = %v", out.Meta.Languages)
+	}
+
+	// The fragment is sanitized and URL-rewritten, without page chrome.
+	if strings.Contains(out.HTML, "<script>") {
+		t.Error("fragment contains unsanitized script")
+	}
+	if !strings.Contains(out.HTML, "/_cooked/raw/"+upstream.URL+"/docs/img/logo.png") {
+		t.Errorf("relative image not rewritten: %s", out.HTML)
+	}
+	if strings.Contains(out.HTML, "<html") || strings.Contains(out.HTML, "cooked-header") {
+		t.Error("fragment contains page chrome")
+	}
+
+	var again apiRender
+	apiGet(t, srv.URL, page, &again)
+	if again.Cache != "hit" || again.HTML != out.HTML || again.Meta.Title != "Guide" {
+		t.Errorf("second call: cache = %q, title = %q", again.Cache, again.Meta.Title)
+	}
+
+	// Page and API responses are cached separately.
+	resp, err := http.Get(srv.URL + "/" + page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Cooked-Cache"); got != "miss" {
+		t.Errorf("page after API call: cache = %q, want miss", got)
+	}
+}
+
+func TestAPIRender_Code(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("print('hi')\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var out apiRender
+	apiGet(t, srv.URL, upstream.URL+"/hello.py", &out)
+	if out.File.ContentType != "code" || out.File.Language != "python" {
+		t.Errorf("file = %+v", out.File)
+	}
+	if out.Meta != nil {
+		t.Errorf("meta = %+v, want none for code", out.Meta)
+	}
+}
+
+func TestAPIRender_Errors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.md":
+			http.NotFound(w, r)
+		case "/big.md":
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		default:
+			w.Write([]byte("x"))
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      1024,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+	}
+	s := newTestServer(t, cfg)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		upstream string
+		status   int
+		errType  string
+	}{
+		{"not allowlisted", "https://evil.com/README.md", 403, "blocked"},
+		{"invalid", "ftp://example.com/README.md", 400, "bad-request"},
+		{"too large", upstream.URL + "/big.md", 413, "too-large"},
+		{"upstream 404", upstream.URL + "/missing.md", 404, "upstream-error"},
+		{"unsupported", upstream.URL + "/image.png", 415, "unsupported"},
+		{"directory", upstream.URL + "/docs/", 415, "unsupported"},
+	}
+	for _, tt := range tests {
+		var out map[string]string
+		resp := apiGet(t, srv.URL, tt.upstream, &out)
+		if resp.StatusCode != tt.status || out["type"] != tt.errType || out["error"] == "" {
+			t.Errorf("%s: %d %v, want %d %s", tt.name, resp.StatusCode, out, tt.status, tt.errType)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/_cooked/api/render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("missing url: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAPIRender_SSRF(t *testing.T) {
+	s := newTestServer(t, &config.Config{
+		Listen:       ":8080",
+		CacheTTL:     5 * time.Minute,
+		CacheMaxSize: 100 * 1024 * 1024,
+		FetchTimeout: 10 * time.Second,
+		MaxFileSize:  5 * 1024 * 1024,
+		DefaultTheme: "auto",
+	})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var out map[string]string
+	resp := apiGet(t, srv.URL, "http://127.0.0.1:8080/secret.md", &out)
+	if resp.StatusCode != 403 || out["type"] != "blocked" {
+		t.Errorf("status = %d %v, want 403 blocked", resp.StatusCode, out)
+	}
+}
+
+func TestAPIRender_PurgedWithPage(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Doc\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, adminConfig(""))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	page := upstream.URL + "/README.md"
+	warm(t, srv.URL+"/"+page)
+	var out apiRender
+	apiGet(t, srv.URL, page, &out)
+
+	var purged map[string]int
+	adminDo(t, "DELETE", srv.URL+"/_cooked/admin/cache?url="+url.QueryEscape(page), &purged)
+	if purged["purged"] != 2 {
+		t.Errorf("purged = %d, want the page and its API entry", purged["purged"])
+	}
+	if s.cache.Len() != 0 {
+		t.Errorf("%d entries left after purge", s.cache.Len())
+	}
+}
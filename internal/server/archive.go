@@ -0,0 +1,263 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/archive"
+	"github.com/air-gapped/cooked/internal/fetch"
+	"github.com/air-gapped/cooked/internal/tracing"
+)
+
+// archiveSeparator separates an archive's URL from the path of a member, as
+// in https://host/app-1.2.tar.gz!/docs/install.md.
+const archiveSeparator = "!/"
+
+// archiveExpansion is how many times --max-archive-size a compressed tar
+// stream may decompress to, whatever its own size.
+const archiveExpansion = 4
+
+// splitArchivePath splits an upstream URL into the URL of an archive and the
+// raw path of a member below it. It reports false unless the URL has an
+// archive separator directly after a tar or zip file name.
+func splitArchivePath(rawUpstream string) (archiveURL, member string, ok bool) {
+	for i := 0; ; {
+		j := strings.Index(rawUpstream[i:], archiveSeparator)
+		if j < 0 {
+			return "", "", false
+		}
+		i += j
+		if _, ok := archive.Detect(rawUpstream[:i]); ok {
+			return rawUpstream[:i], rawUpstream[i+len(archiveSeparator):], true
+		}
+		i += len(archiveSeparator)
+	}
+}
+
+// handleArchive serves a member or directory of an upstream archive.
+// Members are rendered like upstream documents, with relative links
+// resolving inside the archive.
+func (s *Server) handleArchive(ctx context.Context, w http.ResponseWriter, r *http.Request, archiveURL, member string) {
+	ctx, span := tracing.Start(ctx, "handleArchive", tracing.URL(archiveURL))
+	defer span.End()
+
+	pageURL := archiveURL + archiveSeparator + member
+	if _, reqErr := s.checkUpstream(archiveURL); reqErr != nil {
+		s.renderError(w, pageURL, reqErr.status, reqErr.errType, reqErr.message)
+		return
+	}
+	rel, err := url.PathUnescape(member)
+	if err != nil {
+		s.renderError(w, pageURL, 400, "bad-request", "Invalid path")
+		return
+	}
+
+	a, status, err := s.archives.get(ctx, s.archiveClient, archiveURL, s.cfg.CacheTTL)
+	switch {
+	case err != nil && isTooLarge(err):
+		s.renderError(w, pageURL, 413, "too-large",
+			fmt.Sprintf("Archive too large (limit is %d bytes)", s.cfg.MaxArchiveSize))
+		return
+	case err != nil && status == 200:
+		slog.Warn("open archive failed", "upstream", redactUpstream(archiveURL), "error", err)
+		s.renderError(w, pageURL, 422, "bad-archive", "The archive could not be read")
+		return
+	case err != nil:
+		s.renderFetchError(w, archiveURL, err)
+		return
+	case status != 200:
+		s.renderError(w, pageURL, status, "upstream-error", fmt.Sprintf("Upstream returned %d", status))
+		return
+	}
+	s.serveTree(ctx, w, r, &archiveTree{archive: a, url: archiveURL, s: s}, rel)
+}
+
+// archiveTree is a fetched archive as a fileTree. Its pages are validated by
+// the archive's digest, so they are re-rendered only when the archive
+// changes upstream.
+type archiveTree struct {
+	archive *fetchedArchive
+	url     string
+	s       *Server
+}
+
+func (t *archiveTree) Stat(p string) (treeEntry, error) {
+	e, err := t.archive.Stat(p)
+	if err != nil {
+		return treeEntry{}, err
+	}
+	return t.entry(e), nil
+}
+
+func (t *archiveTree) ReadFile(p string, maxSize int64) ([]byte, error) {
+	return t.archive.ReadFile(p, maxSize)
+}
+
+func (t *archiveTree) ReadDir(p string) ([]treeEntry, error) {
+	entries, err := t.archive.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]treeEntry, len(entries))
+	for i, e := range entries {
+		out[i] = t.entry(e)
+	}
+	return out, nil
+}
+
+func (t *archiveTree) entry(e archive.Entry) treeEntry {
+	return treeEntry{
+		Name:    e.Name,
+		IsDir:   e.IsDir,
+		Regular: e.Regular,
+		Size:    e.Size,
+		ETag:    `"` + t.archive.digest + `"`,
+	}
+}
+
+func (t *archiveTree) URL(p string) string {
+	if p == "." {
+		p = ""
+	}
+	return t.s.cookedPrefix() + t.url + archiveSeparator + strings.TrimPrefix((&url.URL{Path: "/" + p}).EscapedPath(), "/")
+}
+
+func (t *archiveTree) Title() string { return path.Base(t.url) + archiveSeparator }
+
+func (t *archiveTree) CacheControl() string { return "no-cache" }
+
+// fetchedArchive is an indexed archive with the validators it was fetched
+// with.
+type fetchedArchive struct {
+	*archive.Archive
+	url          string
+	size         int64  // bytes held in memory, decompressed
+	digest       string // of the archive bytes
+	etag         string
+	lastModified string
+	checked      time.Time // last fetched or revalidated
+}
+
+// archiveStore keeps recently fetched archives in memory, so browsing an
+// archive does not download it again for every member. It is bounded by the
+// total size of the archives it holds, decompressed, evicting the least
+// recently used.
+type archiveStore struct {
+	mu        sync.Mutex
+	maxSize   int64
+	maxExpand int64 // bytes a compressed tar stream may decompress to
+	size      int64
+	lru       *list.List // of *fetchedArchive, most recent first
+	byURL     map[string]*list.Element
+	flights   *fetch.CachedClient
+}
+
+func newArchiveStore(maxSize, maxExpand int64, flights *fetch.CachedClient) *archiveStore {
+	return &archiveStore{
+		maxSize:   maxSize,
+		maxExpand: maxExpand,
+		lru:       list.New(),
+		byURL:     make(map[string]*list.Element),
+		flights:   flights,
+	}
+}
+
+// get returns the archive at rawURL, revalidating a held copy once it is
+// older than ttl. The status is the upstream's, or 200 for a held copy;
+// err is also set with status 200 when the archive is unreadable.
+// Concurrent requests for one archive share a fetch.
+func (st *archiveStore) get(ctx context.Context, client *fetch.Client, rawURL string, ttl time.Duration) (*fetchedArchive, int, error) {
+	st.mu.Lock()
+	held := st.lookup(rawURL)
+	fresh := held != nil && time.Since(held.checked) < ttl
+	st.mu.Unlock()
+	if fresh {
+		return held, 200, nil
+	}
+
+	type outcome struct {
+		archive *fetchedArchive
+		status  int
+	}
+	v, _, err := st.flights.Coalesce(rawURL+archiveSeparator, func() (any, error) {
+		etag, lastModified := "", ""
+		if held != nil {
+			etag, lastModified = held.etag, held.lastModified
+		}
+		// The fetch is shared, so one caller's cancellation must not abort it.
+		result, err := client.Fetch(context.WithoutCancel(ctx), rawURL, etag, lastModified)
+		if err != nil {
+			return outcome{}, err
+		}
+		if result.StatusCode == http.StatusNotModified && held != nil {
+			st.mu.Lock()
+			held.checked = time.Now()
+			st.mu.Unlock()
+			return outcome{held, 200}, nil
+		}
+		if result.StatusCode != 200 {
+			return outcome{status: result.StatusCode}, nil
+		}
+
+		format, _ := archive.Detect(strings.SplitN(rawURL, "?", 2)[0])
+		a, err := archive.Open(result.Body, format, st.maxExpand)
+		if err != nil {
+			return outcome{status: 200}, err
+		}
+		sum := sha256.Sum256(result.Body)
+		fetched := &fetchedArchive{
+			Archive:      a,
+			url:          rawURL,
+			size:         a.Size(),
+			digest:       hex.EncodeToString(sum[:16]),
+			etag:         result.ETag,
+			lastModified: result.LastModified,
+			checked:      time.Now(),
+		}
+		st.mu.Lock()
+		st.store(fetched)
+		st.mu.Unlock()
+		return outcome{fetched, 200}, nil
+	})
+	o, _ := v.(outcome)
+	return o.archive, o.status, err
+}
+
+// lookup returns the held archive for rawURL and marks it recently used. The
+// caller holds st.mu.
+func (st *archiveStore) lookup(rawURL string) *fetchedArchive {
+	el, ok := st.byURL[rawURL]
+	if !ok {
+		return nil
+	}
+	st.lru.MoveToFront(el)
+	return el.Value.(*fetchedArchive)
+}
+
+// store holds a, replacing any copy of the same URL and evicting the least
+// recently used archives to stay within maxSize. The caller holds st.mu.
+func (st *archiveStore) store(a *fetchedArchive) {
+	if el, ok := st.byURL[a.url]; ok {
+		st.size -= el.Value.(*fetchedArchive).size
+		st.lru.Remove(el)
+	}
+	st.byURL[a.url] = st.lru.PushFront(a)
+	st.size += a.size
+	for st.size > st.maxSize && st.lru.Len() > 1 {
+		oldest := st.lru.Back()
+		old := oldest.Value.(*fetchedArchive)
+		st.lru.Remove(oldest)
+		delete(st.byURL, old.url)
+		st.size -= old.size
+	}
+}
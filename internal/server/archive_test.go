@@ -0,0 +1,169 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+// newArchiveServer serves archives from an upstream test server, counting
+// the archive downloads, and returns the cooked server and upstream.
+func newArchiveServer(t *testing.T, archives map[string][]byte, maxArchiveSize int64) (*httptest.Server, *httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var fetches atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		w.Write(data)
+	}))
+	t.Cleanup(upstream.Close)
+
+	s := newTestServer(t, &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      1024,
+		MaxArchiveSize:   maxArchiveSize,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+		FrameAncestors:   "none",
+	})
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, upstream, &fetches
+}
+
+func testTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func testZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestArchive_Render(t *testing.T) {
+	files := map[string]string{
+		"app-1.2/README.md":         "# App\n\n[Install](docs/install.md)\n",
+		"app-1.2/docs/install.md":   "# Install\n\n[Back](../README.md) [Escape](../../../../etc/passwd)\n\n![arch](img/arch.png)\n",
+		"app-1.2/docs/img/arch.png": "PNG",
+	}
+	srv, upstream, fetches := newArchiveServer(t, map[string][]byte{
+		"/app-1.2.tar.gz": testTarGz(t, files),
+		"/app-1.2.zip":    testZip(t, files),
+	}, 1024*1024)
+
+	for _, name := range []string{"app-1.2.tar.gz", "app-1.2.zip"} {
+		archiveURL := upstream.URL + "/" + name
+		base := "/" + archiveURL + "!/"
+
+		resp, body := localGet(t, srv.URL+base+"app-1.2/docs/install.md")
+		if resp.StatusCode != 200 {
+			t.Fatalf("%s: status = %d: %s", name, resp.StatusCode, body)
+		}
+		if !strings.Contains(body, "<title>Install — cooked</title>") {
+			t.Errorf("%s: page not rendered", name)
+		}
+		for _, want := range []string{
+			`href="` + base + `app-1.2/README.md"`,
+			`href="` + base + `etc/passwd"`,
+			`src="` + base + `app-1.2/docs/img/arch.png"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: missing %s", name, want)
+			}
+		}
+
+		resp, body = localGet(t, srv.URL+base)
+		if resp.StatusCode != 200 || !strings.Contains(body, `href="`+base+`app-1.2/"`) {
+			t.Errorf("%s: top listing: status = %d", name, resp.StatusCode)
+		}
+		resp, body = localGet(t, srv.URL+base+"app-1.2/")
+		if resp.StatusCode != 200 || !strings.Contains(body, "cooked-dir-readme") {
+			t.Errorf("%s: directory with README: status = %d", name, resp.StatusCode)
+		}
+
+		resp, _ = localGet(t, srv.URL+base+"app-1.2/docs/img/arch.png")
+		if resp.StatusCode != 200 || resp.Header.Get("Content-Security-Policy") != "sandbox" {
+			t.Errorf("%s: raw member: status = %d", name, resp.StatusCode)
+		}
+		if resp, _ := localGet(t, srv.URL+base+"app-1.2/missing.md"); resp.StatusCode != 404 {
+			t.Errorf("%s: missing member: status = %d, want 404", name, resp.StatusCode)
+		}
+	}
+	// Each archive is downloaded once and browsed from memory after that.
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("archive fetches = %d, want 2", n)
+	}
+}
+
+func TestArchive_Redirect(t *testing.T) {
+	srv, upstream, _ := newArchiveServer(t, nil, 1024*1024)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	// ServeMux collapses the scheme's double slash; request the cleaned path.
+	resp, err := client.Get(srv.URL + "/http:/" + strings.TrimPrefix(upstream.URL, "http://") + "/app.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || !strings.HasSuffix(resp.Header.Get("Location"), "/app.tar.gz!/") {
+		t.Errorf("status = %d, location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestArchive_Limits(t *testing.T) {
+	big := testTarGz(t, map[string]string{"a.bin": strings.Repeat("x", 64*1024)})
+	srv, upstream, _ := newArchiveServer(t, map[string][]byte{
+		"/big.zip":    testZip(t, map[string]string{"a.txt": "x"}),
+		"/huge.tgz":   append(big, make([]byte, 4096)...),
+		"/member.zip": testZip(t, map[string]string{"big.md": strings.Repeat("# x\n", 512)}),
+		"/bad.zip":    []byte("not a zip"),
+	}, 2048)
+
+	for path, want := range map[string]int{
+		"/huge.tgz!/a.bin":    413, // archive over --max-archive-size
+		"/member.zip!/big.md": 413, // member over --max-file-size
+		"/bad.zip!/":          422,
+		"/missing.zip!/x.md":  404,
+	} {
+		if resp, body := localGet(t, srv.URL+"/"+upstream.URL+path); resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d: %s", path, resp.StatusCode, want, body)
+		}
+	}
+}
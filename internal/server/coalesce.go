@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// recordedResponse captures the response written by the request that leads
+// a coalesced fetch+render so it can be replayed to every request sharing it.
+type recordedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *recordedResponse) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *recordedResponse) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// serveCoalesced runs handle once for concurrent requests with the same key
+// and replays the recorded response to each of them. When requests were
+// collapsed, X-Cooked-Collapsed reports how many joined the shared one.
+func (s *Server) serveCoalesced(w http.ResponseWriter, key string, handle func(w http.ResponseWriter)) {
+	v, collapsed, err := s.fetcher.Coalesce(key, func() (any, error) {
+		rec := &recordedResponse{header: make(http.Header)}
+		handle(rec)
+		return rec, nil
+	})
+	if err != nil {
+		// Only reachable when the leading request panicked.
+		slog.Error("coalesced render failed", "upstream", redactUpstream(key), "error", err)
+		s.renderError(w, key, 500, "render-error", "Failed to render document")
+		return
+	}
+
+	rec := v.(*recordedResponse)
+	for k, vals := range rec.header {
+		w.Header()[k] = append([]string(nil), vals...)
+	}
+	if collapsed > 0 {
+		w.Header().Set("X-Cooked-Collapsed", strconv.Itoa(collapsed))
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(rec.body.Bytes())
+}
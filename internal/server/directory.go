@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/archive"
+	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/forge"
+	"github.com/air-gapped/cooked/internal/listing"
+	"github.com/air-gapped/cooked/internal/render"
+	cookedtemplate "github.com/air-gapped/cooked/internal/template"
+)
+
+// handleDirectory renders a directory URL as a file listing. Forge
+// directories are listed through the forge API (dir != nil); anything else is
+// fetched as an HTML autoindex page. A README found in the listing is
+// rendered beneath it.
+func (s *Server) handleDirectory(ctx context.Context, w http.ResponseWriter, req renderRequest, dir *forge.Directory, start time.Time) {
+	rawUpstream, sourceURL := req.rawUpstream, req.sourceURL
+
+	// The listing source is what gets fetched, cached and revalidated.
+	listURL := rawUpstream
+	if dir != nil {
+		listURL = dir.APIURL
+	}
+	listKey := req.variantKey(listURL)
+
+	result, cachedEntry, err := s.fetchCached(ctx, listKey, req)
+	if err != nil {
+		s.renderFetchError(w, rawUpstream, err)
+		return
+	}
+
+	if cachedEntry != nil && (result.CacheStatus == cache.StatusHit || result.CacheStatus == cache.StatusRevalidated || result.CacheStatus == cache.StatusStale) {
+		s.serveFromCache(w, rawUpstream, cachedEntry, result, start)
+		return
+	}
+
+	if result.StatusCode != 200 {
+		s.renderError(w, rawUpstream, result.StatusCode, "upstream-error",
+			fmt.Sprintf("Upstream returned %d", result.StatusCode))
+		return
+	}
+
+	renderStart := time.Now()
+
+	var entries []listing.Entry
+	truncated := false
+	if dir != nil {
+		entries, truncated, err = s.forgeEntries(ctx, dir, result.Body)
+	} else {
+		if !isHTMLContentType(result.ContentType) {
+			s.renderError(w, rawUpstream, 415, "unsupported",
+				"This directory has no browsable index")
+			return
+		}
+		entries, err = listing.ParseAutoindex(result.Body, rawUpstream)
+	}
+	if err != nil {
+		slog.Warn("parse directory listing failed", "upstream", rawUpstream, "error", err)
+		s.renderError(w, rawUpstream, 502, "upstream-error", "Could not read the directory listing")
+		return
+	}
+	listing.Sort(entries)
+
+	// Forge repository roots have no browsable parent.
+	parent := ""
+	if dir == nil || !dir.IsRoot() {
+		parent = s.parentLink(sourceURL)
+	}
+
+	var content bytes.Buffer
+	content.Write(listing.Render(entries, parent, s.listingLink))
+	if truncated {
+		fmt.Fprintf(&content, "\n<p class=\"cooked-dir-truncated\">Listing truncated: showing the first %d entries.</p>", len(entries))
+	}
+
+	pageData := cookedtemplate.PageData{
+		UpstreamURL:    rawUpstream,
+		SourceURL:      sourceURL,
+		ContentType:    render.TypeDirectory,
+		CacheStatus:    string(result.CacheStatus),
+		UpstreamStatus: result.StatusCode,
+		LastModified:   result.LastModified,
+		Title:          directoryTitle(sourceURL),
+	}
+
+	if readme := listing.SelectReadme(entries); readme != nil {
+		readmeHTML, meta, err := s.renderReadme(ctx, readme)
+		if err != nil {
+			slog.Warn("render directory readme failed", "upstream", readme.ContentURL(), "error", err)
+		} else {
+			fmt.Fprintf(&content, "\n<div class=\"cooked-dir-readme\" data-readme=\"%s\">\n",
+				template.HTMLEscapeString(readme.Name))
+			content.Write(readmeHTML)
+			content.WriteString("\n</div>")
+			applyMeta(&pageData, meta)
+			pageData.Title = directoryTitle(sourceURL)
+		}
+	}
+
+	pageData.Content = template.HTML(content.String())
+	renderMs := time.Since(renderStart).Milliseconds()
+
+	s.writePage(ctx, w, listKey, pageData, "", result, renderMs)
+}
+
+// forgeEntries parses a forge API listing from the body of its first page,
+// fetching the pages after it up to forge.MaxPages. It reports whether
+// entries were left out.
+func (s *Server) forgeEntries(ctx context.Context, dir *forge.Directory, body []byte) ([]listing.Entry, bool, error) {
+	entries, err := dir.Entries(body)
+	if err != nil {
+		return nil, false, err
+	}
+	for page := 1; ; page++ {
+		next, ok := dir.NextPage(page, body)
+		if !ok {
+			return entries, false, nil
+		}
+		if page == forge.MaxPages {
+			return entries, true, nil
+		}
+		result, err := s.fetcher.Client().Fetch(ctx, next, "", "")
+		if err == nil && result.StatusCode != 200 {
+			err = fmt.Errorf("upstream returned %d", result.StatusCode)
+		}
+		if err != nil {
+			slog.Warn("fetch directory listing page failed", "upstream", next, "error", err)
+			return entries, true, nil
+		}
+		more, err := dir.Entries(result.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		entries = append(entries, more...)
+		body = result.Body
+	}
+}
+
+// renderReadme fetches and renders a README from a directory listing.
+func (s *Server) renderReadme(ctx context.Context, readme *listing.Entry) ([]byte, *render.MarkdownMeta, error) {
+	contentURL := readme.ContentURL()
+	result, err := s.fetcher.Client().Fetch(ctx, contentURL, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.StatusCode != 200 {
+		return nil, nil, fmt.Errorf("upstream returned %d", result.StatusCode)
+	}
+
+	body := charset.ToUTF8(result.Body, result.ContentType)
+	fileInfo, _ := render.DetectDocument(render.Document{
+		Path:               readme.Name,
+		ContentType:        result.ContentType,
+		ContentDisposition: result.ContentDisposition,
+		Body:               body,
+	})
+	if fileInfo.ContentType == render.TypeUnsupported {
+		// Extensionless README is conventionally plain text.
+		fileInfo = render.FileInfo{ContentType: render.TypePlaintext, Label: "Plain Text"}
+	}
+	return s.renderDocument(ctx, body, fileInfo, contentURL)
+}
+
+// listingLink routes directories and renderable files back through cooked,
+// and opens archives for browsing; other files link straight to the
+// upstream.
+func (s *Server) listingLink(e listing.Entry) string {
+	if e.IsDir || render.DetectFile(e.Name).ContentType != render.TypeUnsupported {
+		return s.cookedPrefix() + e.URL
+	}
+	if _, ok := archive.Detect(e.Name); ok {
+		return s.cookedPrefix() + e.URL + archiveSeparator
+	}
+	return e.ContentURL()
+}
+
+// parentLink returns the cooked URL of the parent directory, or "" at the root.
+func (s *Server) parentLink(dirURL string) string {
+	u, err := url.Parse(dirURL)
+	if err != nil {
+		return ""
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	if p == "" {
+		return ""
+	}
+	u.Path = path.Dir(p)
+	if u.Path != "/" {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return s.cookedPrefix() + u.String()
+}
+
+// directoryTitle returns the last path segment of a directory URL.
+func directoryTitle(dirURL string) string {
+	u, err := url.Parse(dirURL)
+	if err != nil {
+		return dirURL
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	if p == "" {
+		return u.Host
+	}
+	return path.Base(p) + "/"
+}
+
+func isHTMLContentType(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/gitrepo"
+	"github.com/air-gapped/cooked/internal/tracing"
+)
+
+// gitPrefix is the path git repositories are served under.
+const gitPrefix = "/_cooked/git/"
+
+// openGitRepos opens the configured git repositories.
+func openGitRepos(dirs map[string]string) map[string]*gitrepo.Repo {
+	repos := make(map[string]*gitrepo.Repo, len(dirs))
+	for name, dir := range dirs {
+		repo, err := gitrepo.Open(dir)
+		if err != nil {
+			slog.Error("open git repository failed", "name", name, "dir", dir, "error", err)
+			continue
+		}
+		repos[name] = repo
+	}
+	return repos
+}
+
+// handleGit serves a file or directory of a git repository at a branch, tag
+// or commit, read straight from the object store.
+func (s *Server) handleGit(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "handleGit")
+	defer span.End()
+
+	name, refPath := r.PathValue("repo"), r.PathValue("refpath")
+	pageURL := strings.TrimSuffix(s.cookedPrefix(), "/") + r.URL.EscapedPath()
+
+	repo, ok := s.gitRepos[name]
+	if !ok {
+		s.renderError(w, pageURL, 404, "not-found", "No git repository is configured with this name")
+		return
+	}
+	commit, ref, rel, err := repo.ResolvePath(refPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.renderError(w, pageURL, 404, "not-found", "No branch, tag or commit matches this path")
+		return
+	}
+	if err != nil {
+		slog.Error("resolve git ref failed", "repo", name, "path", refPath, "error", err)
+		s.renderError(w, pageURL, 500, "git-error", "Failed to read the git repository")
+		return
+	}
+	s.serveTree(ctx, w, r, &gitTree{commit: commit, name: name, ref: ref, s: s}, rel)
+}
+
+// gitTree is a commit of a git repository as a fileTree, addressed by the
+// ref it was requested under so that links stay on that ref. Blob and tree
+// SHAs are the ETags. Pages under a commit SHA never change and may be cached
+// indefinitely; pages under a branch or tag are revalidated on every view.
+type gitTree struct {
+	commit *gitrepo.Commit
+	name   string
+	ref    string
+	s      *Server
+}
+
+func (t *gitTree) Stat(p string) (treeEntry, error) {
+	e, err := t.commit.Stat(p)
+	if err != nil {
+		return treeEntry{}, err
+	}
+	return gitEntry(e), nil
+}
+
+func (t *gitTree) ReadFile(p string, maxSize int64) ([]byte, error) {
+	return t.commit.ReadFile(p, maxSize)
+}
+
+func (t *gitTree) ReadDir(p string) ([]treeEntry, error) {
+	entries, err := t.commit.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]treeEntry, len(entries))
+	for i, e := range entries {
+		out[i] = gitEntry(e)
+	}
+	return out, nil
+}
+
+func (t *gitTree) URL(p string) string {
+	if p == "." {
+		p = ""
+	}
+	u := url.URL{Path: gitPrefix + t.name + "/" + t.ref + "/" + p}
+	return strings.TrimSuffix(t.s.cookedPrefix(), "/") + u.EscapedPath()
+}
+
+func (t *gitTree) Title() string { return t.name + "@" + t.ref + "/" }
+
+func (t *gitTree) CacheControl() string {
+	if t.commit.Immutable {
+		return "public, max-age=31536000, immutable"
+	}
+	return "no-cache"
+}
+
+func gitEntry(e gitrepo.Entry) treeEntry {
+	return treeEntry{
+		Name:    e.Name,
+		IsDir:   e.IsDir,
+		Regular: e.Regular,
+		Size:    e.Size,
+		ETag:    `"` + e.Hash + `"`,
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitcache "github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+// newGitServer serves a bare repository named "docs" holding files on its
+// master branch, and returns the test server, the repository and the commit.
+func newGitServer(t *testing.T, files map[string]string) (*httptest.Server, *git.Repository, plumbing.Hash) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.Init(filesystem.NewStorage(osfs.New(dir), gitcache.NewObjectLRUDefault()), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := gitCommit(t, repo, files)
+
+	s := newTestServer(t, &config.Config{
+		Listen:       ":8080",
+		CacheTTL:     5 * time.Minute,
+		CacheMaxSize: 100 * 1024 * 1024,
+		FetchTimeout: 10 * time.Second,
+		MaxFileSize:  1024,
+		DefaultTheme: "auto",
+		GitRepos:     map[string]string{"docs": dir},
+	})
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, repo, hash
+}
+
+func gitCommit(t *testing.T, repo *git.Repository, files map[string]string) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		f, err := wt.Filesystem.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestGit_Render(t *testing.T) {
+	srv, repo, hash := newGitServer(t, map[string]string{
+		"guide/intro.md":     "# Intro\n\n[Next](next.md) [Root](/README.md) [Up](../../x.md)\n\n![diagram](img/arch.png)\n",
+		"guide/img/arch.png": "PNG",
+	})
+	if _, err := repo.CreateTag("v1.0", hash, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"master", "v1.0", hash.String()} {
+		resp, body := localGet(t, srv.URL+"/_cooked/git/docs/"+ref+"/guide/intro.md")
+		if resp.StatusCode != 200 {
+			t.Fatalf("%s: status = %d: %s", ref, resp.StatusCode, body)
+		}
+		if !strings.Contains(body, "<title>Intro — cooked</title>") {
+			t.Errorf("%s: page not rendered", ref)
+		}
+		// Links stay on the ref the page was requested under.
+		for _, want := range []string{
+			`href="/_cooked/git/docs/` + ref + `/guide/next.md"`,
+			`href="/_cooked/git/docs/` + ref + `/README.md"`,
+			`href="/_cooked/git/docs/` + ref + `/x.md"`,
+			`src="/_cooked/git/docs/` + ref + `/guide/img/arch.png"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: missing %s", ref, want)
+			}
+		}
+	}
+
+	resp, _ := localGet(t, srv.URL+"/_cooked/git/docs/master/guide/img/arch.png")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "image/png" || resp.Header.Get("ETag") == "" {
+		t.Errorf("image: status = %d, type = %q, etag = %q", resp.StatusCode, resp.Header.Get("Content-Type"), resp.Header.Get("ETag"))
+	}
+}
+
+func TestGit_Caching(t *testing.T) {
+	srv, repo, first := newGitServer(t, map[string]string{"README.md": "# One\n"})
+	blob, err := repo.BlobObject(mustFile(t, repo, first, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch := srv.URL + "/_cooked/git/docs/master/README.md"
+
+	resp, _ := localGet(t, branch)
+	if resp.Header.Get("ETag") != `"`+blob.Hash.String()+`"` || resp.Header.Get("Cache-Control") != "no-cache" ||
+		resp.Header.Get("X-Cooked-Cache") != "miss" {
+		t.Errorf("branch: etag = %q, cache-control = %q, cache = %q",
+			resp.Header.Get("ETag"), resp.Header.Get("Cache-Control"), resp.Header.Get("X-Cooked-Cache"))
+	}
+	resp, _ = localGet(t, branch)
+	if resp.Header.Get("X-Cooked-Cache") != "hit" {
+		t.Errorf("second request: cache = %q, want hit", resp.Header.Get("X-Cooked-Cache"))
+	}
+	req, _ := http.NewRequest("GET", branch, nil)
+	req.Header.Set("If-None-Match", `"`+blob.Hash.String()+`"`)
+	if resp, _ := doBody(t, req); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional request: status = %d, want 304", resp.StatusCode)
+	}
+
+	// Commit SHAs never move: their pages may be cached indefinitely.
+	resp, _ = localGet(t, srv.URL+"/_cooked/git/docs/"+first.String()+"/README.md")
+	if cc := resp.Header.Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("commit SHA: Cache-Control = %q, want immutable", cc)
+	}
+	// An abbreviated SHA may become ambiguous, so it is revalidated.
+	resp, _ = localGet(t, srv.URL+"/_cooked/git/docs/"+first.String()[:12]+"/README.md")
+	if cc := resp.Header.Get("Cache-Control"); strings.Contains(cc, "immutable") {
+		t.Errorf("abbreviated SHA: Cache-Control = %q, want no immutable", cc)
+	}
+
+	// A new commit on the branch changes the blob and the page.
+	gitCommit(t, repo, map[string]string{"README.md": "# Two\n"})
+	resp, body := localGet(t, branch)
+	if resp.Header.Get("X-Cooked-Cache") != "miss" || !strings.Contains(body, "Two") {
+		t.Errorf("after commit: cache = %q", resp.Header.Get("X-Cooked-Cache"))
+	}
+	_, body = localGet(t, srv.URL+"/_cooked/git/docs/"+first.String()+"/README.md")
+	if !strings.Contains(body, "One") {
+		t.Error("old commit no longer serves its content")
+	}
+}
+
+func mustFile(t *testing.T, repo *git.Repository, commit plumbing.Hash, name string) plumbing.Hash {
+	t.Helper()
+	c, err := repo.CommitObject(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := c.File(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Hash
+}
+
+func TestGit_Directory(t *testing.T) {
+	srv, _, _ := newGitServer(t, map[string]string{
+		"README.md":  "# Docs home\n\n[Guide](guide/)\n",
+		"guide/a.md": "# A\n",
+		"guide/b.md": "# B\n",
+	})
+
+	resp, body := localGet(t, srv.URL+"/_cooked/git/docs/master/")
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	for _, want := range []string{
+		`href="/_cooked/git/docs/master/guide/"`,
+		`href="/_cooked/git/docs/master/README.md"`,
+		"Docs home",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing missing %s", want)
+		}
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Error("listing has no ETag")
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for target, want := range map[string]string{
+		"/_cooked/git/docs/master":       "/_cooked/git/docs/master/",
+		"/_cooked/git/docs/master/guide": "/_cooked/git/docs/master/guide/",
+	} {
+		resp, err := client.Get(srv.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+			t.Errorf("%s: status = %d, location = %q", target, resp.StatusCode, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestGit_Errors(t *testing.T) {
+	srv, _, _ := newGitServer(t, map[string]string{"README.md": "# Docs\n", "big.md": strings.Repeat("x", 2048)})
+
+	for target, want := range map[string]int{
+		"/_cooked/git/other/master/README.md": 404,
+		"/_cooked/git/docs/nope/README.md":    404,
+		"/_cooked/git/docs/master/missing.md": 404,
+		"/_cooked/git/docs/master/big.md":     413,
+	} {
+		if resp, _ := localGet(t, srv.URL+target); resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", target, resp.StatusCode, want)
+		}
+	}
+}
@@ -284,6 +284,205 @@ func TestIntegration_OrgBasic(t *testing.T) {
 	}
 }
 
+// --- Full pipeline: reStructuredText rendering ---
+
+func TestIntegration_ReSTFeatures(t *testing.T) {
+	upstream := serveFixture(t, filepath.Join(fixtureDir(), "rst", "features.rst"))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, headers, body := getBody(t, srv.URL+"/"+upstream.URL+"/docs/features.rst")
+
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got := headers.Get("X-Cooked-Content-Type"); got != "rst" {
+		t.Errorf("X-Cooked-Content-Type = %q, want rst", got)
+	}
+
+	if !strings.Contains(body, `<h1 id="feature-tour">Feature Tour</h1>`) {
+		t.Error("missing <h1> for the document title")
+	}
+	if !strings.Contains(body, `id="cooked-toc"`) {
+		t.Error("missing table of contents")
+	}
+	if !strings.Contains(body, `<div class="admonition tip">`) {
+		t.Error("missing admonition")
+	}
+	if !strings.Contains(body, `<td rowspan="2">`) {
+		t.Error("missing grid table row span")
+	}
+	// toctree entries link to the rendered documents next to the page.
+	if !strings.Contains(body, upstream.URL+"/docs/install.rst") {
+		t.Error("toctree link not rewritten through cooked")
+	}
+}
+
+// --- Full pipeline: Jupyter notebook rendering ---
+
+func TestIntegration_Notebook(t *testing.T) {
+	upstream := serveFixture(t, filepath.Join(fixtureDir(), "notebook", "outputs.ipynb"))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, headers, body := getBody(t, srv.URL+"/"+upstream.URL+"/outputs.ipynb")
+
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got := headers.Get("X-Cooked-Content-Type"); got != "notebook" {
+		t.Errorf("X-Cooked-Content-Type = %q, want notebook", got)
+	}
+
+	if !strings.Contains(body, `<h1 id="outputs">Outputs</h1>`) {
+		t.Error("missing <h1> from the markdown cell")
+	}
+	if !strings.Contains(body, `<span class="ansi-red-fg">ZeroDivisionError</span>`) {
+		t.Error("missing ANSI-colored traceback")
+	}
+	if !strings.Contains(body, `src="data:image/svg+xml;base64,`) {
+		t.Error("embedded image stripped by the sanitizer")
+	}
+	// HTML outputs are sanitized like any upstream HTML.
+	if strings.Contains(body, "alert(1)</script>") {
+		t.Error("script in an HTML output was not sanitized")
+	}
+}
+
+// --- Full pipeline: CSV table rendering ---
+
+func TestIntegration_Table(t *testing.T) {
+	upstream := serveFixture(t, filepath.Join(fixtureDir(), "table", "people.csv"))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, headers, body := getBody(t, srv.URL+"/"+upstream.URL+"/people.csv")
+
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got := headers.Get("X-Cooked-Content-Type"); got != "table" {
+		t.Errorf("X-Cooked-Content-Type = %q, want table", got)
+	}
+
+	if !strings.Contains(body, `<th id="col-age" data-type="number">`) {
+		t.Error("missing numeric column header")
+	}
+	if !strings.Contains(body, `<script type="application/json" class="cooked-table-data">`) {
+		t.Error("missing embedded table data")
+	}
+	if !strings.Contains(body, `<div class="cooked-table-source" hidden>`) {
+		t.Error("missing source view")
+	}
+	if strings.Contains(body, "<script>alert(1)") {
+		t.Error("cell content was not escaped")
+	}
+}
+
+// --- Full pipeline: JSON, YAML and TOML tree view ---
+
+func TestIntegration_Tree(t *testing.T) {
+	upstream := serveFixture(t, filepath.Join(fixtureDir(), "tree", "values.yaml"))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, headers, body := getBody(t, srv.URL+"/"+upstream.URL+"/values.yaml")
+
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got := headers.Get("X-Cooked-Content-Type"); got != "code" {
+		t.Errorf("X-Cooked-Content-Type = %q, want code", got)
+	}
+
+	if !strings.Contains(body, `<div class="cooked-tree" data-language="yaml" data-path-style="yq">`) {
+		t.Error("missing tree wrapper")
+	}
+	if !strings.Contains(body, `class="cooked-code-block"`) {
+		t.Error("missing highlighted source")
+	}
+	// Both documents of the stream are embedded.
+	if !strings.Contains(body, `["string","ConfigMap"]`) {
+		t.Error("missing second YAML document")
+	}
+	if strings.Contains(body, `"</script>"`) {
+		t.Error("embedded data was not escaped")
+	}
+}
+
+func TestIntegration_OpenAPI(t *testing.T) {
+	spec, err := os.ReadFile(filepath.Join(fixtureDir(), "openapi", "openapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/openapi.yaml":
+			w.Write(spec)
+		case "/api/schemas/order.yaml":
+			w.Write([]byte("Order:\n  type: object\n  properties:\n    quantity:\n      type: integer\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, headers, body := getBody(t, srv.URL+"/"+upstream.URL+"/api/openapi.yaml")
+
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got := headers.Get("X-Cooked-Content-Type"); got != "openapi" {
+		t.Errorf("X-Cooked-Content-Type = %q, want openapi", got)
+	}
+
+	for _, want := range []string{
+		`<h3 id="listpets"><span class="cooked-openapi-method cooked-openapi-method-get">GET</span> <code>/pets</code></h3>`,
+		`<a href="#listpets">GET /pets</a>`,
+		`<a href="#store">store</a>`,
+		`<a href="#schema-pet" rel="nofollow">Pet</a>`,
+		// The schema in schemas/order.yaml is fetched through the allowlist.
+		`<code class="cooked-openapi-prop">quantity</code>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(body, `<code class="cooked-openapi-unresolved"`) {
+		t.Error("relative file ref was not resolved")
+	}
+}
+
+func TestIntegration_OpenAPIRefsBlocked(t *testing.T) {
+	// Refs to hosts outside the allowlist are not fetched.
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("openapi: 3.0.0\ninfo: {title: API}\npaths:\n  /a:\n    get:\n      responses:\n        '200':\n          $ref: 'http://example.com/responses.yaml#/OK'\n"))
+	}))
+	defer upstream.Close()
+
+	srv, cleanup := newIntegrationServer(t)
+	defer cleanup()
+
+	status, _, body := getBody(t, srv.URL+"/"+upstream.URL+"/openapi.yaml")
+	if status != 200 {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if !strings.Contains(body, `<code class="cooked-openapi-unresolved" title="Unresolved reference">http://example.com/responses.yaml#/OK</code>`) {
+		t.Error("blocked ref should be shown unresolved")
+	}
+}
+
 // --- Full pipeline: plaintext rendering ---
 
 func TestIntegration_Plaintext(t *testing.T) {
@@ -641,6 +840,16 @@ func TestIntegration_MultipleFileTypes(t *testing.T) {
 			w.Write([]byte("== Guide\n\nAsciiDoc content.\n"))
 		case "/readme.org":
 			w.Write([]byte("* Org\n\nOrg content.\n"))
+		case "/index.rst":
+			w.Write([]byte("ReST\n====\n\nReST content.\n"))
+		case "/data.csv":
+			w.Write([]byte("name,count\nfoo,1\n"))
+		case "/values.yaml":
+			w.Write([]byte("replicas: 2\n"))
+		case "/api.yaml":
+			w.Write([]byte("openapi: 3.0.0\ninfo:\n  title: API\npaths:\n  /ping:\n    get:\n      responses:\n        '200':\n          description: pong\n"))
+		case "/demo.ipynb":
+			w.Write([]byte(`{"cells": [{"cell_type": "markdown", "metadata": {}, "source": "# Notebook"}], "metadata": {}, "nbformat": 4, "nbformat_minor": 5}`))
 		default:
 			http.NotFound(w, r)
 		}
@@ -660,6 +869,11 @@ func TestIntegration_MultipleFileTypes(t *testing.T) {
 		{"/notes.txt", "plaintext", "<pre"},
 		{"/guide.adoc", "asciidoc", "<h2"},
 		{"/readme.org", "org", "<h1"},
+		{"/index.rst", "rst", "<h1"},
+		{"/demo.ipynb", "notebook", "<h1"},
+		{"/data.csv", "table", "<table>"},
+		{"/values.yaml", "code", "cooked-tree-data"},
+		{"/api.yaml", "openapi", "cooked-openapi-method-get"},
 	}
 
 	for _, tc := range tests {
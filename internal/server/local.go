@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/tracing"
+)
+
+// localPrefix is the path local roots are served under.
+const localPrefix = "/_cooked/local/"
+
+// openLocalRoots opens the configured local roots. Every access goes
+// through os.Root, which rejects paths and symlinks leading outside it.
+func openLocalRoots(dirs map[string]string) map[string]*os.Root {
+	roots := make(map[string]*os.Root, len(dirs))
+	for name, dir := range dirs {
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			slog.Error("open local root failed", "name", name, "dir", dir, "error", err)
+			continue
+		}
+		roots[name] = root
+	}
+	return roots
+}
+
+// handleLocal serves a file or directory below a local root.
+func (s *Server) handleLocal(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "handleLocal")
+	defer span.End()
+
+	name := r.PathValue("root")
+	root, ok := s.localRoots[name]
+	if !ok {
+		s.renderError(w, s.localURL(name, r.PathValue("path")), 404, "not-found", "No local root is configured with this name")
+		return
+	}
+	s.serveTree(ctx, w, r, &localTree{root: root, name: name, s: s}, r.PathValue("path"))
+}
+
+// localURL returns the cooked URL of path p (slash-separated, relative) in
+// the named local root.
+func (s *Server) localURL(name, p string) string {
+	if p == "." {
+		p = ""
+	}
+	u := url.URL{Path: localPrefix + name + "/" + p}
+	return strings.TrimSuffix(s.cookedPrefix(), "/") + u.EscapedPath()
+}
+
+// localTree is a local root as a fileTree. Browsers revalidate its pages on
+// every view, against an ETag derived from the file's mtime and size.
+// Directory listings are rendered on every request: a directory's mtime does
+// not change when its README is edited.
+type localTree struct {
+	root *os.Root
+	name string
+	s    *Server
+}
+
+func (t *localTree) Stat(p string) (treeEntry, error) {
+	info, err := t.root.Stat(p)
+	if err != nil {
+		return treeEntry{}, err
+	}
+	e := treeEntry{
+		Name:    info.Name(),
+		IsDir:   info.IsDir(),
+		Regular: info.Mode().IsRegular(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if e.Regular {
+		e.ETag = localETag(info)
+	}
+	return e, nil
+}
+
+// ReadFile reads a file below the root, failing if it grew past maxSize
+// since it was checked.
+func (t *localTree) ReadFile(p string, maxSize int64) ([]byte, error) {
+	f, err := t.root.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	body, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("file too large: exceeds %d bytes", maxSize)
+	}
+	return body, nil
+}
+
+func (t *localTree) ReadDir(p string) ([]treeEntry, error) {
+	f, err := t.root.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := f.ReadDir(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	var entries []treeEntry
+	for _, de := range dirEntries {
+		// Stat through the root: symlinks leading outside it are left out.
+		e, err := t.Stat(path.Join(p, de.Name()))
+		if err != nil {
+			continue
+		}
+		e.Name = de.Name()
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (t *localTree) URL(p string) string { return t.s.localURL(t.name, p) }
+
+func (t *localTree) Title() string { return t.name + "/" }
+
+func (t *localTree) CacheControl() string { return "no-cache" }
+
+// localETag derives a validator from a file's modification time and size.
+func localETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
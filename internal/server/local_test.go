@@ -0,0 +1,216 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+// newLocalServer serves a temporary local root named "docs" and returns the
+// test server and the root's directory.
+func newLocalServer(t *testing.T, files map[string]string) (*httptest.Server, string) {
+	t.Helper()
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "docs")
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.md"), []byte("# Secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, &config.Config{
+		Listen:       ":8080",
+		CacheTTL:     5 * time.Minute,
+		CacheMaxSize: 100 * 1024 * 1024,
+		FetchTimeout: 10 * time.Second,
+		MaxFileSize:  1024,
+		DefaultTheme: "auto",
+		LocalRoots:   map[string]string{"docs": dir},
+	})
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, dir
+}
+
+func doBody(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func localGet(t *testing.T, target string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doBody(t, req)
+}
+
+func TestLocal_Render(t *testing.T) {
+	srv, _ := newLocalServer(t, map[string]string{
+		"guide/intro.md":     "# Intro\n\n[Next](next.md) [Up](../../../README.md) [Root](/README.md) [Site](https://example.com/x.md)\n\n![diagram](img/arch.png)\n",
+		"guide/img/arch.png": "PNG",
+		"bin/deploy":         "#!/bin/sh\necho deploying\n",
+	})
+
+	resp, body := localGet(t, srv.URL+"/_cooked/local/docs/guide/intro.md")
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, "<title>Intro — cooked</title>") {
+		t.Error("page not rendered")
+	}
+	for _, want := range []string{
+		`href="/_cooked/local/docs/guide/next.md"`,
+		`href="/_cooked/local/docs/README.md"`,
+		`href="https://example.com/x.md"`,
+		`src="/_cooked/local/docs/guide/img/arch.png"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if resp.Header.Get("ETag") == "" || resp.Header.Get("X-Cooked-Cache") != "miss" {
+		t.Errorf("ETag = %q, cache = %q", resp.Header.Get("ETag"), resp.Header.Get("X-Cooked-Cache"))
+	}
+
+	resp, _ = localGet(t, srv.URL+"/_cooked/local/docs/guide/img/arch.png")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("image: status = %d, type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// Files without an extension are detected from their content.
+	resp, _ = localGet(t, srv.URL+"/_cooked/local/docs/bin/deploy")
+	if resp.StatusCode != 200 || resp.Header.Get("X-Cooked-Content-Type") != "code" {
+		t.Errorf("script: status = %d, type = %q", resp.StatusCode, resp.Header.Get("X-Cooked-Content-Type"))
+	}
+}
+
+func TestLocal_ETag(t *testing.T) {
+	srv, dir := newLocalServer(t, map[string]string{"README.md": "# One\n"})
+	target := srv.URL + "/_cooked/local/docs/README.md"
+
+	resp, _ := localGet(t, target)
+	etag := resp.Header.Get("ETag")
+
+	resp, _ = localGet(t, target)
+	if resp.Header.Get("X-Cooked-Cache") != "hit" || resp.Header.Get("ETag") != etag {
+		t.Errorf("second request: cache = %q, etag = %q", resp.Header.Get("X-Cooked-Cache"), resp.Header.Get("ETag"))
+	}
+
+	req, _ := http.NewRequest("GET", target, nil)
+	req.Header.Set("If-None-Match", etag)
+	if resp, _ := doBody(t, req); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional request: status = %d, want 304", resp.StatusCode)
+	}
+
+	// A changed file gets a new ETag and is re-rendered.
+	file := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(file, []byte("# Two, longer\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	resp, body := localGet(t, target)
+	if resp.Header.Get("ETag") == etag || resp.Header.Get("X-Cooked-Cache") != "miss" || !strings.Contains(body, "Two, longer") {
+		t.Errorf("after edit: etag = %q, cache = %q", resp.Header.Get("ETag"), resp.Header.Get("X-Cooked-Cache"))
+	}
+}
+
+func TestLocal_Directory(t *testing.T) {
+	srv, _ := newLocalServer(t, map[string]string{
+		"README.md":      "# Docs home\n\n[Guide](guide/)\n",
+		"guide/intro.md": "# Intro\n",
+		"logo.png":       "PNG",
+	})
+
+	resp, _ := localGet(t, srv.URL+"/_cooked/local/docs")
+	if resp.Request.URL.Path != "/_cooked/local/docs/" {
+		t.Errorf("root not redirected to trailing slash: %s", resp.Request.URL.Path)
+	}
+
+	resp, body := localGet(t, srv.URL+"/_cooked/local/docs/")
+	if resp.StatusCode != 200 || resp.Header.Get("X-Cooked-Content-Type") != "directory" {
+		t.Fatalf("status = %d, type = %q", resp.StatusCode, resp.Header.Get("X-Cooked-Content-Type"))
+	}
+	for _, want := range []string{
+		`href="/_cooked/local/docs/guide/"`,
+		`href="/_cooked/local/docs/README.md"`,
+		`href="/_cooked/local/docs/logo.png"`,
+		"Docs home",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing missing %s", want)
+		}
+	}
+	if strings.Contains(body, `data-type="parent"`) {
+		t.Error("root listing has a parent link")
+	}
+
+	resp, body = localGet(t, srv.URL+"/_cooked/local/docs/guide")
+	if resp.Request.URL.Path != "/_cooked/local/docs/guide/" || !strings.Contains(body, `href="/_cooked/local/docs/"`) {
+		t.Errorf("subdirectory: path = %s, parent link missing", resp.Request.URL.Path)
+	}
+}
+
+func TestLocal_Containment(t *testing.T) {
+	srv, dir := newLocalServer(t, map[string]string{"README.md": "# Docs\n", "big.md": strings.Repeat("x", 2048)})
+	if err := os.Symlink(filepath.Join(dir, "..", "secret.md"), filepath.Join(dir, "escape.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("README.md", filepath.Join(dir, "inside.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/_cooked/local/docs/escape.md", 403},
+		{"/_cooked/local/docs/%2e%2e/secret.md", 400},
+		{"/_cooked/local/docs/a%2F..%2F..%2Fsecret.md", 400},
+		{"/_cooked/local/docs/missing.md", 404},
+		{"/_cooked/local/docs/big.md", 413},
+		{"/_cooked/local/other/README.md", 404},
+		{"/_cooked/local/docs/inside.md", 200},
+	}
+	for _, tt := range tests {
+		resp, body := localGet(t, srv.URL+tt.path)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if strings.Contains(body, "Secret") {
+			t.Errorf("%s: served a file outside the root", tt.path)
+		}
+	}
+
+	_, body := localGet(t, srv.URL+"/_cooked/local/docs/")
+	if strings.Contains(body, "escape.md") {
+		t.Error("listing shows a symlink leading outside the root")
+	}
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
@@ -8,19 +10,31 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/air-gapped/cooked/internal/archive"
 	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/charset"
 	"github.com/air-gapped/cooked/internal/config"
 	"github.com/air-gapped/cooked/internal/fetch"
+	"github.com/air-gapped/cooked/internal/forge"
+	"github.com/air-gapped/cooked/internal/gitrepo"
 	"github.com/air-gapped/cooked/internal/logging"
+	"github.com/air-gapped/cooked/internal/metrics"
 	"github.com/air-gapped/cooked/internal/render"
 	"github.com/air-gapped/cooked/internal/rewrite"
 	"github.com/air-gapped/cooked/internal/sanitize"
 	cookedtemplate "github.com/air-gapped/cooked/internal/template"
+	"github.com/air-gapped/cooked/internal/tracing"
 )
 
 // Server is the main cooked HTTP server.
@@ -28,22 +42,28 @@ type Server struct {
 	cfg            *config.Config
 	version        string
 	fetcher        *fetch.CachedClient
-	mdRender       *render.MarkdownRenderer
-	codeRender     *render.CodeRenderer
-	asciidocRender *render.AsciiDocRenderer
-	orgRender      *render.OrgRenderer
+	archiveClient  *fetch.Client
+	archives       *archiveStore
+	cache          *cache.Cache
+	metrics        *metrics.Metrics
+	docRender      *render.DocumentRenderer
 	tmpl           *cookedtemplate.Renderer
 	assets         fs.FS
 	docsAssets     fs.FS
 	allowlist      *Allowlist
+	forges         *forge.Resolver
+	localRoots     map[string]*os.Root
+	gitRepos       map[string]*gitrepo.Repo
 	healthzCount   atomic.Int64
 	trustedProxies []*net.IPNet
 	mux            *http.ServeMux
 	readmePage     []byte // pre-rendered docs page (nil if README not embedded)
+	prewarms       sync.WaitGroup
 }
 
 // New creates a new cooked server with all dependencies.
-// Extra fetch options can be passed for testing (e.g. disabling SSRF protection).
+// Extra fetch options configure the upstream client (e.g. server-side
+// credentials, or disabling SSRF protection in tests).
 func New(cfg *config.Config, version string, assets fs.FS, docsAssets fs.FS, extraFetchOpts ...fetch.Option) *Server {
 	allowlist := ParseAllowlist(cfg.AllowedUpstreams)
 
@@ -68,26 +88,53 @@ func New(cfg *config.Config, version string, assets fs.FS, docsAssets fs.FS, ext
 		}))
 	}
 
-	client := fetch.NewClient(cfg.FetchTimeout, cfg.MaxFileSize, cfg.TLSSkipVerify, fetchOpts...)
 	memCache := cache.New(cfg.CacheTTL, cfg.CacheMaxSize)
-	cachedClient := fetch.NewCachedClient(client, memCache)
+	memCache.SetTTLBounds(cfg.CacheMinTTL, cfg.CacheMaxTTL)
+	m := metrics.New(memCache, version)
+	fetchOpts = append(fetchOpts, fetch.WithObserver(m.ObserveFetch))
+
+	client := fetch.NewClient(cfg.FetchTimeout, cfg.MaxFileSize, cfg.TLSSkipVerify, fetchOpts...)
+	if cfg.CacheDir != "" {
+		disk, err := cache.OpenDisk(cfg.CacheDir, cfg.CacheDiskMaxSize)
+		if err != nil {
+			slog.Error("open disk cache failed, using memory cache only", "dir", cfg.CacheDir, "error", err)
+		} else {
+			memCache.SetDisk(disk)
+			slog.Info("disk cache opened", "dir", cfg.CacheDir, "entries", disk.Len(), "bytes", disk.Size())
+		}
+	}
+	cachedClient := fetch.NewCachedClient(client, memCache,
+		fetch.WithStaleWhileRevalidate(cfg.CacheStaleWhileRevalidate, cfg.CacheRefreshWorkers),
+		fetch.WithStaleIfError(cfg.CacheStaleIfError))
 
 	if cfg.FrameAncestors == "" {
 		cfg.FrameAncestors = "none"
 	}
 
+	// Forge configuration is validated by config.Parse; a failure here means
+	// the profiles file changed since startup, so fall back to built-ins.
+	forges, err := forge.Load(cfg.ForgeHosts, cfg.ForgeProfilesFile, cfg.ForgeAutodetect)
+	if err != nil {
+		slog.Error("load forge profiles failed, using built-in profiles", "error", err)
+		forges, _ = forge.New("", nil, cfg.ForgeAutodetect)
+	}
+
 	s := &Server{
 		cfg:            cfg,
 		version:        version,
 		fetcher:        cachedClient,
-		mdRender:       render.NewMarkdownRenderer(),
-		codeRender:     render.NewCodeRenderer(),
-		asciidocRender: render.NewAsciiDocRenderer(),
-		orgRender:      render.NewOrgRenderer(),
+		archiveClient:  fetch.NewClient(cfg.FetchTimeout, cfg.MaxArchiveSize, cfg.TLSSkipVerify, fetchOpts...),
+		archives:       newArchiveStore(cfg.ArchiveCacheSize, archiveExpansion*cfg.MaxArchiveSize, cachedClient),
+		cache:          memCache,
+		metrics:        m,
+		docRender:      render.NewDocumentRenderer(),
 		tmpl:           cookedtemplate.NewRenderer(),
 		assets:         assets,
 		docsAssets:     docsAssets,
 		allowlist:      allowlist,
+		forges:         forges,
+		localRoots:     openLocalRoots(cfg.LocalRoots),
+		gitRepos:       openGitRepos(cfg.GitRepos),
 		trustedProxies: parseTrustedProxies(cfg.TrustedProxies),
 		mux:            http.NewServeMux(),
 	}
@@ -103,6 +150,20 @@ func (s *Server) routes() {
 	s.mux.HandleFunc("GET /_cooked/docs", s.handleDocs)
 	s.mux.HandleFunc("GET /_cooked/docs/{path...}", s.handleDocsAsset)
 	s.mux.HandleFunc("GET /_cooked/raw/{upstream...}", s.handleRaw)
+	s.mux.HandleFunc("GET /_cooked/api/render", s.handleAPIRender)
+	s.mux.HandleFunc("POST /_cooked/render", s.handleSubmitRender)
+	s.mux.HandleFunc("GET /_cooked/local/{root}", s.handleLocal)
+	s.mux.HandleFunc("GET /_cooked/local/{root}/{path...}", s.handleLocal)
+	s.mux.HandleFunc("GET /_cooked/git/{repo}/{refpath...}", s.handleGit)
+	if s.cfg.Metrics && s.cfg.MetricsListen == "" {
+		s.mux.Handle("GET /metrics", s.metrics.Handler())
+	}
+	if s.cfg.WebhookSecret != "" {
+		s.mux.HandleFunc("POST /_cooked/webhook/{forge}", s.handleWebhook)
+	}
+	if s.cfg.AdminToken != "" && s.cfg.AdminListen == "" {
+		s.adminRoutes(s.mux)
+	}
 	s.mux.HandleFunc("GET /_cooked/{path...}", s.handleAsset)
 	s.mux.HandleFunc("GET /.well-known/{path...}", s.handleWellKnown)
 	s.mux.HandleFunc("GET /{$}", s.handleLanding)
@@ -113,6 +174,12 @@ func (s *Server) routes() {
 func (s *Server) Handler() http.Handler {
 	var h http.Handler = s.mux
 	h = s.loggingMiddleware(h)
+	// Outermost, so the server span (continuing any incoming traceparent)
+	// covers the whole request and the logger can read its trace ID.
+	h = otelhttp.NewHandler(h, "request",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz" && r.URL.Path != "/metrics"
+		}))
 	return h
 }
 
@@ -147,7 +214,7 @@ func (s *Server) preRenderDocs() {
 		return // file not embedded — docs page will 404
 	}
 
-	htmlContent, meta, err := s.mdRender.Render(readmeBytes)
+	htmlContent, meta, err := s.docRender.Render(readmeBytes, render.FileInfo{ContentType: render.TypeMarkdown}, "", nil)
 	if err != nil {
 		slog.Error("pre-render docs failed", "error", err)
 		return
@@ -228,6 +295,7 @@ func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
 		strings.TrimPrefix(r.URL.Path, "/_cooked/raw"),
 		r.URL.RawQuery,
 	)
+	rawUpstream, _, _ = s.forges.Resolve(rawUpstream)
 
 	upstream, err := ParseUpstreamURL(rawUpstream)
 	if err != nil {
@@ -248,7 +316,7 @@ func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := s.fetcher.Client().Fetch(rawUpstream, "", "")
+	result, err := s.fetcher.Client().Fetch(r.Context(), rawUpstream, "", "")
 	if err != nil {
 		http.Error(w, "upstream fetch failed", http.StatusBadGateway)
 		return
@@ -266,6 +334,7 @@ func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", ct)
 	w.Header().Set("Cache-Control", "public, max-age=300")
 	w.Write(result.Body)
+	s.metrics.AddRawBytes(len(result.Body))
 }
 
 func (s *Server) handleWellKnown(w http.ResponseWriter, _ *http.Request) {
@@ -274,22 +343,67 @@ func (s *Server) handleWellKnown(w http.ResponseWriter, _ *http.Request) {
 
 func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
+	ctx, span := tracing.Start(r.Context(), "handleRender")
+	defer span.End()
+
+	// Extract upstream URL from path. Forge page URLs (Gitea /src/branch/,
+	// GitLab /-/blob/, ...) are translated to the raw file; the header still
+	// links to the URL the user pasted.
+	rawQuery, typeOverride := splitTypeOverride(r.URL.RawQuery)
+	rawUpstream := ExtractUpstreamFromPath(r.URL.Path, rawQuery)
+	sourceURL := rawUpstream
+	rawUpstream, _, _ = s.forges.Resolve(rawUpstream)
+	span.SetAttributes(tracing.URL(rawUpstream))
+
+	// Paths inside tar and zip archives are served from the fetched archive;
+	// the archive itself opens at its top directory.
+	if archiveURL, member, ok := splitArchivePath(rawUpstream); ok {
+		s.handleArchive(ctx, w, r, archiveURL, member)
+		return
+	}
+	if _, ok := archive.Detect(r.URL.Path); ok && rawQuery == "" {
+		http.Redirect(w, r, r.URL.EscapedPath()+archiveSeparator, http.StatusMovedPermanently)
+		return
+	}
 
-	// Extract upstream URL from path
-	rawUpstream := ExtractUpstreamFromPath(r.URL.Path, r.URL.RawQuery)
+	upstream, reqErr := s.checkUpstream(rawUpstream)
+	if reqErr == nil {
+		reqErr = checkTypeOverride(typeOverride)
+	}
+	if reqErr != nil {
+		s.renderError(w, rawUpstream, reqErr.status, reqErr.errType, reqErr.message)
+		return
+	}
 
-	// Parse and validate
+	// Concurrent requests for the same upstream share one fetch and render,
+	// so the leader's cancellation must not abort it for the others.
+	ctx = context.WithoutCancel(ctx)
+	req := renderRequest{upstream: upstream, rawUpstream: rawUpstream, sourceURL: sourceURL, typeOverride: typeOverride}
+	s.serveCoalesced(w, req.pageKey(), func(w http.ResponseWriter) {
+		s.renderUpstream(ctx, w, req, start)
+	})
+}
+
+// requestError is a failure reported to the client as an error page or
+// JSON error.
+type requestError struct {
+	status  int
+	errType string
+	message string
+}
+
+// checkUpstream parses rawUpstream and applies the allowlist and SSRF
+// checks every fetch of a user-supplied URL must pass.
+func (s *Server) checkUpstream(rawUpstream string) (*url.URL, *requestError) {
 	upstream, err := ParseUpstreamURL(rawUpstream)
 	if err != nil {
 		slog.Warn("invalid upstream URL", "upstream", rawUpstream, "error", err)
-		s.renderError(w, rawUpstream, 400, "bad-request", "The provided URL is not valid")
-		return
+		return nil, &requestError{400, "bad-request", "The provided URL is not valid"}
 	}
 
 	// Check allowed upstreams (nil allowlist = allow all, falls through to SSRF)
 	if !s.allowlist.Allows(upstream.Host) {
-		s.renderError(w, rawUpstream, 403, "blocked", "This upstream is not in the allowed list")
-		return
+		return nil, &requestError{403, "blocked", "This upstream is not in the allowed list"}
 	}
 
 	// SSRF protection: when no allowlist is set, block private/loopback IPs.
@@ -299,30 +413,91 @@ func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
 		private, err := IsPrivateAddress(upstream.Host)
 		if err != nil {
 			slog.Warn("DNS resolution failed", "host", upstream.Host, "error", err)
-			s.renderError(w, rawUpstream, 502, "unreachable", "Could not reach the upstream server")
-			return
+			return nil, &requestError{502, "unreachable", "Could not reach the upstream server"}
 		}
 		if private {
-			s.renderError(w, rawUpstream, 403, "blocked", "Fetching from private/loopback addresses is not allowed")
-			return
+			return nil, &requestError{403, "blocked", "Fetching from private/loopback addresses is not allowed"}
 		}
 	}
+	return upstream, nil
+}
+
+// renderRequest identifies a validated upstream to render. Background
+// refreshes replay it with background set.
+type renderRequest struct {
+	upstream    *url.URL
+	rawUpstream string
+	sourceURL   string
+	background  bool
+	api         bool // render API JSON rather than a page
+	// typeOverride is the ?cooked_type= value, which replaces detection.
+	typeOverride string
+}
+
+// pageKey is the cache key of req's rendered page. Pages rendered with a
+// type override are cached apart from the detected rendering, and pages
+// requested through a forge page URL apart from those for the raw file, as
+// each links back to the URL it was requested by.
+func (req renderRequest) pageKey() string {
+	return req.variantKey(req.rawUpstream)
+}
+
+// variantKey returns the cache key of a rendering of rawURL for req: the
+// given variants, then req's type override and source URL variants.
+func (req renderRequest) variantKey(rawURL string, variants ...string) string {
+	if req.typeOverride != "" {
+		variants = append(variants, typeVariant(req.typeOverride))
+	}
+	if req.sourceURL != req.rawUpstream {
+		variants = append(variants, sourceVariant(req.sourceURL))
+	}
+	if len(variants) == 0 {
+		return rawURL
+	}
+	return cache.VariantKey(rawURL, strings.Join(variants, "-"))
+}
+
+// sourceVariant names the entries of pages requested through a forge page
+// URL; see cache.VariantKey.
+func sourceVariant(sourceURL string) string {
+	return "source-" + url.QueryEscape(sourceURL)
+}
+
+// detectType determines the content type of a fetched upstream document, body
+// being its content transcoded to UTF-8, and what it was detected from.
+func (req renderRequest) detectType(result *fetch.CachedResult, body []byte) (render.FileInfo, render.TypeSource) {
+	if req.typeOverride != "" {
+		info, _ := render.ParseTypeOverride(req.typeOverride)
+		return info, render.SourceOverride
+	}
+	return render.DetectDocument(render.Document{
+		Path:               req.upstream.Path,
+		ContentType:        result.ContentType,
+		ContentDisposition: result.ContentDisposition,
+		Body:               body,
+	})
+}
+
+// renderUpstream fetches a validated upstream URL and writes the rendered
+// page, a cached copy, or an error page.
+func (s *Server) renderUpstream(ctx context.Context, w http.ResponseWriter, req renderRequest, start time.Time) {
+	upstream, rawUpstream, sourceURL := req.upstream, req.rawUpstream, req.sourceURL
+
+	// Directory URLs render as a file listing with the README beneath it.
+	if dir, ok := s.forges.Directory(rawUpstream); ok {
+		s.handleDirectory(ctx, w, req, dir, start)
+		return
+	}
+	if upstream.Path == "" || strings.HasSuffix(upstream.Path, "/") {
+		s.handleDirectory(ctx, w, req, nil, start)
+		return
+	}
 
 	// Fetch from upstream (with caching)
-	result, cachedEntry, err := s.fetcher.Fetch(rawUpstream)
+	key := req.pageKey()
+	result, cachedEntry, err := s.fetchCached(ctx, key, req)
 	if err != nil {
-		if isTimeout(err) {
-			s.renderError(w, rawUpstream, 504, "timeout",
-				fmt.Sprintf("Upstream request timed out after %s", s.cfg.FetchTimeout))
-			return
-		}
-		if isTooLarge(err) {
-			s.renderError(w, rawUpstream, 413, "too-large",
-				fmt.Sprintf("File too large (limit is %d bytes)", s.cfg.MaxFileSize))
-			return
-		}
-		slog.Warn("upstream fetch failed", "upstream", rawUpstream, "error", err)
-		s.renderError(w, rawUpstream, 502, "unreachable", "Could not reach the upstream server")
+		s.renderFetchError(w, rawUpstream, err)
 		return
 	}
 
@@ -339,134 +514,254 @@ func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Legacy encodings are transcoded before detection, which reads the
+	// content, and rendering.
+	body := charset.ToUTF8(result.Body, result.ContentType)
+
 	// Detect file type
-	fileInfo := render.DetectFile(upstream.Path)
+	fileInfo, typeSource := req.detectType(result, body)
+	if fileInfo.ContentType == render.TypeUnsupported {
+		s.renderError(w, rawUpstream, 415, "unsupported",
+			"This file type is not supported for rendering")
+		return
+	}
 
-	// Render based on content type
-	var htmlContent []byte
-	var meta *render.MarkdownMeta
+	// Render, sanitize and rewrite based on content type
 	renderStart := time.Now()
+	htmlContent, meta, err := s.renderDocument(ctx, body, fileInfo, rawUpstream)
+	if err != nil {
+		slog.Error("render "+string(fileInfo.ContentType)+" failed", "error", err, "upstream", rawUpstream)
+		s.renderError(w, rawUpstream, 500, "render-error", renderFailureMessage(fileInfo.ContentType))
+		return
+	}
+	renderMs := time.Since(renderStart).Milliseconds()
 
-	switch fileInfo.ContentType {
-	case render.TypeMarkdown:
-		htmlContent, meta, err = s.mdRender.Render(result.Body)
-		if err != nil {
-			slog.Error("render markdown failed", "error", err, "upstream", rawUpstream)
-			s.renderError(w, rawUpstream, 500, "render-error", "Failed to render markdown")
-			return
-		}
+	// Build page data
+	pageData := cookedtemplate.PageData{
+		UpstreamURL:    rawUpstream,
+		SourceURL:      sourceURL,
+		ContentType:    fileInfo.ContentType,
+		CacheStatus:    string(result.CacheStatus),
+		UpstreamStatus: result.StatusCode,
+		FileSize:       result.ContentLen,
+		LastModified:   result.LastModified,
+		Content:        template.HTML(htmlContent),
+	}
+	applyMeta(&pageData, meta)
 
-	case render.TypeMDX:
-		preprocessed := render.PreprocessMDX(result.Body)
-		htmlContent, meta, err = s.mdRender.Render(preprocessed)
-		if err != nil {
-			slog.Error("render mdx failed", "error", err, "upstream", rawUpstream)
-			s.renderError(w, rawUpstream, 500, "render-error", "Failed to render MDX")
-			return
-		}
+	s.writePage(ctx, w, key, pageData, typeSource, result, renderMs)
+}
 
-	case render.TypeAsciiDoc:
-		htmlContent, meta, err = s.asciidocRender.Render(result.Body)
-		if err != nil {
-			slog.Error("render asciidoc failed", "error", err, "upstream", rawUpstream)
-			s.renderError(w, rawUpstream, 500, "render-error", "Failed to render AsciiDoc")
-			return
-		}
+// fetchCached fetches key through the cache. Foreground requests may be
+// served a stale entry, in which case a background refresh of req is
+// scheduled; background refreshes always revalidate.
+func (s *Server) fetchCached(ctx context.Context, key string, req renderRequest) (*fetch.CachedResult, *cache.Entry, error) {
+	if req.background {
+		return s.fetcher.Revalidate(ctx, key)
+	}
+
+	result, entry, err := s.fetcher.Fetch(ctx, key)
+	if err == nil && result.Refresh {
+		req.background = true
+		s.fetcher.Refresh(key, func() {
+			// The refreshed page is stored in the cache by the normal
+			// render path; the response itself is discarded.
+			rec := &recordedResponse{header: make(http.Header)}
+			if req.api {
+				s.renderAPI(ctx, rec, req)
+				return
+			}
+			s.renderUpstream(ctx, rec, req, time.Now())
+		})
+	}
+	return result, entry, err
+}
 
-	case render.TypeOrg:
-		htmlContent, meta, err = s.orgRender.Render(result.Body)
-		if err != nil {
-			slog.Error("render org failed", "error", err, "upstream", rawUpstream)
-			s.renderError(w, rawUpstream, 500, "render-error", "Failed to render Org")
-			return
-		}
+// renderDocument runs the renderer for the detected content type, then
+// sanitizes and rewrites relative URLs for markup formats. upstreamURL is the
+// URL the source was fetched from and anchors relative link resolution; when
+// it is empty relative URLs are left as written.
+func (s *Server) renderDocument(ctx context.Context, body []byte, fileInfo render.FileInfo, upstreamURL string) ([]byte, *render.MarkdownMeta, error) {
+	start := time.Now()
 
-	case render.TypeCode:
-		htmlContent, err = s.codeRender.Render(result.Body, fileInfo.Language)
-		if err != nil {
-			slog.Error("render code failed", "error", err, "upstream", rawUpstream)
-			s.renderError(w, rawUpstream, 500, "render-error", "Failed to render code")
-			return
-		}
+	_, span := tracing.Start(ctx, "render."+string(fileInfo.ContentType),
+		attribute.Int("cooked.source_bytes", len(body)))
+	htmlContent, meta, err := s.docRender.Render(body, fileInfo, upstreamURL, s.refLoader(ctx, upstreamURL))
+	if err != nil {
+		tracing.Fail(span, err)
+		span.End()
+		return nil, nil, err
+	}
+	span.End()
 
-	case render.TypePlaintext:
-		htmlContent = render.RenderPlaintext(result.Body)
+	// Sanitize HTML and rewrite relative URLs (for formats that may contain
+	// upstream HTML)
+	if render.IsMarkup(fileInfo.ContentType) {
+		_, span := tracing.Start(ctx, "sanitize.HTML")
+		htmlContent = sanitize.HTML(htmlContent)
+		span.End()
 
-	default:
-		s.renderError(w, rawUpstream, 415, "unsupported",
-			"This file type is not supported for rendering")
-		return
+		if upstreamURL != "" {
+			_, span := tracing.Start(ctx, "rewrite.RelativeURLs")
+			htmlContent = rewrite.RelativeURLs(htmlContent, upstreamURL, s.cfg.BaseURL, s.rawPrefix())
+			span.End()
+		}
 	}
 
-	renderMs := time.Since(renderStart).Milliseconds()
+	s.metrics.ObserveRender(string(fileInfo.ContentType), time.Since(start))
+	return htmlContent, meta, nil
+}
 
-	// Sanitize HTML (for formats that may contain upstream HTML)
-	switch fileInfo.ContentType {
-	case render.TypeMarkdown, render.TypeMDX, render.TypeAsciiDoc, render.TypeOrg:
-		htmlContent = sanitize.HTML(htmlContent)
+// refLoader returns the loader an OpenAPI description rendered from
+// upstreamURL fetches the files it references with. The files are subject
+// to the same allowlist and address checks as upstreams; there is no loader
+// when the description has no URL.
+func (s *Server) refLoader(ctx context.Context, upstreamURL string) render.RefLoader {
+	if upstreamURL == "" {
+		return nil
 	}
-
-	// Rewrite relative URLs
-	switch fileInfo.ContentType {
-	case render.TypeMarkdown, render.TypeMDX, render.TypeAsciiDoc, render.TypeOrg:
-		rawPrefix := "/_cooked/raw/"
-		if s.cfg.BaseURL != "" {
-			rawPrefix = strings.TrimRight(s.cfg.BaseURL, "/") + rawPrefix
+	return func(rawURL string) ([]byte, error) {
+		if _, reqErr := s.checkUpstream(rawURL); reqErr != nil {
+			return nil, errors.New(reqErr.message)
+		}
+		result, err := s.fetcher.Client().Fetch(ctx, rawURL, "", "")
+		if err != nil {
+			return nil, err
 		}
-		htmlContent = rewrite.RelativeURLs(htmlContent, rawUpstream, s.cfg.BaseURL, rawPrefix)
+		if result.StatusCode != 200 {
+			return nil, fmt.Errorf("upstream returned %d", result.StatusCode)
+		}
+		return charset.ToUTF8(result.Body, result.ContentType), nil
 	}
+}
 
-	// Load embedded CSS
-	lightCSS := readAssetString(s.assets, "github-markdown-light.css")
-	darkCSS := readAssetString(s.assets, "github-markdown-dark.css")
+// renderFailureMessage returns the user-facing message for a render error.
+func renderFailureMessage(ct render.ContentType) string {
+	switch ct {
+	case render.TypeMarkdown:
+		return "Failed to render markdown"
+	case render.TypeMDX:
+		return "Failed to render MDX"
+	case render.TypeAsciiDoc:
+		return "Failed to render AsciiDoc"
+	case render.TypeOrg:
+		return "Failed to render Org"
+	case render.TypeReST:
+		return "Failed to render reStructuredText"
+	case render.TypeNotebook:
+		return "Failed to render notebook"
+	case render.TypeOpenAPI:
+		return "Failed to render OpenAPI description"
+	case render.TypeTable:
+		return "Failed to render table"
+	case render.TypeCode:
+		return "Failed to render code"
+	default:
+		return "Failed to render document"
+	}
+}
 
-	// Build page data
-	pageData := cookedtemplate.PageData{
-		Version:        s.version,
-		UpstreamURL:    rawUpstream,
-		ContentType:    fileInfo.ContentType,
-		CacheStatus:    string(result.CacheStatus),
-		UpstreamStatus: result.StatusCode,
-		FileSize:       result.ContentLen,
-		LastModified:   result.LastModified,
-		DefaultTheme:   s.cfg.DefaultTheme,
-		Content:        template.HTML(htmlContent),
-		MermaidPath:    "/_cooked/mermaid.min.js",
+// rawPrefix returns the raw asset proxy prefix, absolute when a base URL is set.
+func (s *Server) rawPrefix() string {
+	rawPrefix := "/_cooked/raw/"
+	if s.cfg.BaseURL != "" {
+		rawPrefix = strings.TrimRight(s.cfg.BaseURL, "/") + rawPrefix
 	}
+	return rawPrefix
+}
 
-	if meta != nil {
-		pageData.Title = meta.Title
-		pageData.HasMermaid = meta.HasMermaid
-		pageData.HeadingCount = meta.HeadingCount
-		pageData.CodeBlockCount = meta.CodeBlockCount
-		pageData.Headings = meta.Headings
+// cookedPrefix returns the prefix that routes an upstream URL through cooked.
+func (s *Server) cookedPrefix() string {
+	if s.cfg.BaseURL != "" {
+		return strings.TrimRight(s.cfg.BaseURL, "/") + "/"
+	}
+	return "/"
+}
+
+// applyMeta copies render metadata into the page data.
+func applyMeta(pageData *cookedtemplate.PageData, meta *render.MarkdownMeta) {
+	if meta == nil {
+		return
 	}
+	pageData.Title = meta.Title
+	pageData.HasMermaid = meta.HasMermaid
+	pageData.HeadingCount = meta.HeadingCount
+	pageData.CodeBlockCount = meta.CodeBlockCount
+	pageData.Headings = meta.Headings
+}
 
-	// Render full page
-	page := s.tmpl.RenderPage(pageData, lightCSS, darkCSS)
+// writePage renders the full page, stores it in the cache under cacheKey
+// (unless the upstream said no-store), and writes it to the client.
+// typeSource is what the content type was detected from, if anything.
+func (s *Server) writePage(ctx context.Context, w http.ResponseWriter, cacheKey string, pageData cookedtemplate.PageData, typeSource render.TypeSource, result *fetch.CachedResult, renderMs int64) {
+	page := s.renderPage(ctx, pageData)
 
 	// Store in cache
-	s.fetcher.Store(rawUpstream, cache.Entry{
+	s.fetcher.Store(cacheKey, cache.Entry{
 		HTML:         page,
 		ETag:         result.ETag,
 		LastModified: result.LastModified,
 		Size:         int64(len(page)),
-		ContentType:  string(fileInfo.ContentType),
+		ContentType:  string(pageData.ContentType),
+		TypeSource:   string(typeSource),
+		Freshness:    result.Freshness,
 	})
 
 	// Set response headers
-	s.setResponseHeaders(w, rawUpstream, result.StatusCode, string(result.CacheStatus),
-		string(fileInfo.ContentType), renderMs, result.FetchMs, s.version)
+	s.setResponseHeaders(w, pageData.UpstreamURL, result.StatusCode, string(result.CacheStatus),
+		string(pageData.ContentType), renderMs, result.FetchMs, s.version)
+	setTypeSource(w, string(typeSource))
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("Cache-Control", "public, max-age=300")
+	if result.Freshness.NoStore {
+		// Stored nowhere, including the browser and intermediaries.
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
 	w.WriteHeader(200)
 	w.Write(page)
 }
 
+// renderPage wraps rendered content in the full cooked page.
+func (s *Server) renderPage(ctx context.Context, pageData cookedtemplate.PageData) []byte {
+	pageData.Version = s.version
+	pageData.DefaultTheme = s.cfg.DefaultTheme
+	pageData.MermaidPath = "/_cooked/mermaid.min.js"
+
+	// Load embedded CSS
+	lightCSS := readAssetString(s.assets, "github-markdown-light.css")
+	darkCSS := readAssetString(s.assets, "github-markdown-dark.css")
+
+	_, span := tracing.Start(ctx, "RenderPage")
+	defer span.End()
+	return s.tmpl.RenderPage(pageData, lightCSS, darkCSS)
+}
+
+// renderFetchError maps an upstream fetch error onto an error page.
+func (s *Server) renderFetchError(w http.ResponseWriter, rawUpstream string, err error) {
+	f := s.fetchFailure(rawUpstream, err)
+	s.renderError(w, rawUpstream, f.status, f.errType, f.message)
+}
+
+// fetchFailure classifies an upstream fetch error for the client.
+func (s *Server) fetchFailure(rawUpstream string, err error) *requestError {
+	if isTimeout(err) {
+		return &requestError{504, "timeout",
+			fmt.Sprintf("Upstream request timed out after %s", s.cfg.FetchTimeout)}
+	}
+	if isTooLarge(err) {
+		return &requestError{413, "too-large",
+			fmt.Sprintf("File too large (limit is %d bytes)", s.cfg.MaxFileSize)}
+	}
+	slog.Warn("upstream fetch failed", "upstream", rawUpstream, "error", err)
+	return &requestError{502, "unreachable", "Could not reach the upstream server"}
+}
+
 func (s *Server) serveFromCache(w http.ResponseWriter, rawUpstream string, entry *cache.Entry, result *fetch.CachedResult, start time.Time) {
 	s.setResponseHeaders(w, rawUpstream, 200, string(result.CacheStatus),
 		entry.ContentType, 0, result.FetchMs, s.version)
+	setTypeSource(w, entry.TypeSource)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "public, max-age=300")
@@ -539,13 +834,28 @@ const healthzLogLimit = 3
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		done := s.metrics.RequestStarted()
 		wrapped := &logging.ByteCountingWriter{ResponseWriter: w}
 		next.ServeHTTP(wrapped, r)
+		done()
 
 		if wrapped.StatusCode == 0 {
 			wrapped.StatusCode = 200
 		}
 
+		// The mux records the matched pattern on r, which keeps the route
+		// label (and span name) bounded regardless of the upstream URL.
+		if r.Pattern != "" {
+			trace.SpanFromContext(r.Context()).SetName(r.Pattern)
+		}
+		s.metrics.ObserveRequest(r.Pattern, wrapped.Header().Get("X-Cooked-Content-Type"),
+			wrapped.StatusCode, time.Since(start))
+
+		// Prometheus scrapes are as regular as probes and never logged.
+		if r.Pattern == "GET /metrics" {
+			return
+		}
+
 		// Suppress healthz probe logging after the first few requests.
 		if r.URL.Path == "/healthz" {
 			n := s.healthzCount.Add(1)
@@ -570,6 +880,8 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 			ContentType: wrapped.Header().Get("X-Cooked-Content-Type"),
 			Bytes:       wrapped.Bytes,
 			ClientIP:    s.clientIP(r),
+			Collapsed:   int(parseHeaderInt64(wrapped.Header().Get("X-Cooked-Collapsed"))),
+			TraceID:     tracing.TraceID(r.Context()),
 		})
 	})
 }
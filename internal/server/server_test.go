@@ -1,15 +1,23 @@
 package server
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 	"time"
 
 	"github.com/air-gapped/cooked/internal/config"
+	"github.com/air-gapped/cooked/internal/credentials"
+	"github.com/air-gapped/cooked/internal/fetch"
 )
 
 func newTestServer(t *testing.T, cfg *config.Config) *Server {
@@ -24,6 +32,7 @@ func newTestServer(t *testing.T, cfg *config.Config) *Server {
 			DefaultTheme:     "auto",
 			AllowedUpstreams: "127.0.0.0/8", // Allow loopback CIDR for tests
 			FrameAncestors:   "none",
+			ForgeAutodetect:  true,
 		}
 	}
 
@@ -161,6 +170,136 @@ func TestRenderCode(t *testing.T) {
 	}
 }
 
+func TestRenderTypeDetection(t *testing.T) {
+	var queries sync.Map
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Store(r.URL.Path, r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/raw":
+			w.Header().Set("Content-Type", "text/markdown")
+			w.Write([]byte("# Hello\n"))
+		case "/download/123":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="guide.adoc"`)
+			w.Write([]byte("= Guide\n"))
+		case "/bin/deploy":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("#!/usr/bin/env python3\nprint('hi')\n"))
+		default:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("\x00\x01binary"))
+		}
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		path, wantType, wantSource string
+		wantStatus                 int
+	}{
+		{"/raw?file=README", "markdown", "content-type", 200},
+		{"/download/123", "asciidoc", "content-disposition", 200},
+		{"/bin/deploy", "code", "shebang", 200},
+		{"/blob", "error", "", 415},
+		{"/blob?cooked_type=plaintext", "plaintext", "override", 200},
+		{"/raw?file=README&cooked_type=python", "code", "override", 200},
+		{"/raw?cooked_type=exe", "error", "", 400},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+		if got := resp.Header.Get("X-Cooked-Content-Type"); got != tt.wantType {
+			t.Errorf("%s: X-Cooked-Content-Type = %q, want %q", tt.path, got, tt.wantType)
+		}
+		if got := resp.Header.Get("X-Cooked-Type-Source"); got != tt.wantSource {
+			t.Errorf("%s: X-Cooked-Type-Source = %q, want %q", tt.path, got, tt.wantSource)
+		}
+	}
+
+	// The override is cooked's own and never reaches the upstream.
+	if q, _ := queries.Load("/raw"); q != "file=README" {
+		t.Errorf("upstream query = %q, want file=README", q)
+	}
+
+	// Cached pages keep their detection source, and overrides are cached
+	// apart from the detected rendering.
+	for path, want := range map[string]string{
+		"/raw?file=README":                    "content-type",
+		"/raw?file=README&cooked_type=python": "override",
+	} {
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Header.Get("X-Cooked-Cache") != "hit" || resp.Header.Get("X-Cooked-Type-Source") != want {
+			t.Errorf("%s: cache = %q, source = %q, want hit from %q", path,
+				resp.Header.Get("X-Cooked-Cache"), resp.Header.Get("X-Cooked-Type-Source"), want)
+		}
+	}
+}
+
+func TestRenderLegacyCharsets(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1.md":
+			w.Header().Set("Content-Type", "text/markdown; charset=ISO-8859-1")
+			w.Write([]byte("# Caf\xE9 cr\xE8me\n"))
+		case "/cp1252.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("He said \x93hello\x94 \x96 twice.\n"))
+		case "/sjis.md":
+			// "# 日本語" in Shift-JIS, undeclared
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("# \x93\xfa\x96\x7b\x8c\xea\n\n\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\n"))
+		case "/raw":
+			// "# Title\n\n[a](b.md)\n- x\n- y\n" in UTF-16LE with a BOM
+			w.Header().Set("Content-Type", "application/octet-stream")
+			body := []byte{0xFF, 0xFE}
+			for _, c := range "# Title\n\n[a](b.md)\n- x\n- y\n" {
+				body = append(body, byte(c), 0)
+			}
+			w.Write(body)
+		}
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := map[string]string{
+		"/latin1.md":  "Café crème",
+		"/cp1252.txt": "He said “hello” – twice.",
+		"/sjis.md":    "日本語",
+		"/raw":        "<h1",
+	}
+	for path, want := range tests {
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Errorf("%s: status = %d, want 200", path, resp.StatusCode)
+			continue
+		}
+		if !strings.Contains(string(body), want) {
+			t.Errorf("%s: page does not contain %q", path, want)
+		}
+	}
+}
+
 func TestCacheHitMiss(t *testing.T) {
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("# Hello\n"))
@@ -194,6 +333,233 @@ func TestCacheHitMiss(t *testing.T) {
 	}
 }
 
+func TestConcurrentRequestsCoalesced(t *testing.T) {
+	var fetchCount atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount.Add(1)
+		<-release
+		w.Write([]byte("# Incident\n\nRestart the thing.\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	rawUpstream := upstream.URL + "/incident.md"
+
+	const n = 6
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	collapsed := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.Get(srv.URL + "/" + rawUpstream)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Errorf("request %d status = %d, want 200", i, resp.StatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			bodies[i] = string(body)
+			collapsed[i] = resp.Header.Get("X-Cooked-Collapsed")
+		}(i)
+	}
+
+	// Release the upstream once every request has joined the shared render.
+	deadline := time.Now().Add(5 * time.Second)
+	for fetchCount.Load() == 0 || s.fetcher.Waiting(rawUpstream) < n-1 {
+		if time.Now().After(deadline) {
+			t.Fatal("requests did not coalesce")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := fetchCount.Load(); got != 1 {
+		t.Errorf("upstream fetched %d times, want 1", got)
+	}
+	for i := range n {
+		if bodies[i] != bodies[0] || !strings.Contains(bodies[i], "Restart the thing.") {
+			t.Errorf("request %d did not receive the shared render", i)
+		}
+		if collapsed[i] != strconv.Itoa(n-1) {
+			t.Errorf("request %d X-Cooked-Collapsed = %q, want %d", i, collapsed[i], n-1)
+		}
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "# Version %d\n", version.Load())
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Listen:                    ":8080",
+		CacheTTL:                  time.Millisecond,
+		CacheMaxSize:              100 * 1024 * 1024,
+		CacheStaleWhileRevalidate: time.Hour,
+		CacheRefreshWorkers:       2,
+		FetchTimeout:              10 * time.Second,
+		MaxFileSize:               5 * 1024 * 1024,
+		DefaultTheme:              "auto",
+		AllowedUpstreams:          "127.0.0.0/8",
+		FrameAncestors:            "none",
+	}
+	s := newTestServer(t, cfg)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func() (string, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + "/runbook.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.Header.Get("X-Cooked-Cache"), string(body)
+	}
+
+	if status, _ := get(); status != "miss" {
+		t.Fatalf("first request cache = %q, want miss", status)
+	}
+
+	// The upstream changes and the entry expires: the old page is served
+	// immediately and refreshed in the background.
+	version.Store(2)
+	time.Sleep(5 * time.Millisecond)
+	status, body := get()
+	if status != "stale" {
+		t.Errorf("expired request cache = %q, want stale", status)
+	}
+	if !strings.Contains(body, "Version 1") {
+		t.Error("stale response should carry the previously rendered page")
+	}
+
+	s.fetcher.WaitRefreshes()
+	_, body = get()
+	if !strings.Contains(body, "Version 2") {
+		t.Error("background refresh should have stored the re-rendered page")
+	}
+	s.fetcher.WaitRefreshes()
+}
+
+func TestNoStoreUpstreamNotCached(t *testing.T) {
+	var fetchCount atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("# Secret rotation\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for i := range 2 {
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + "/rotation.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != 200 || !strings.Contains(string(body), "Secret rotation") {
+			t.Fatalf("request %d: status %d, want rendered page", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Cooked-Cache"); got != "miss" {
+			t.Errorf("request %d: X-Cooked-Cache = %q, want miss", i, got)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+			t.Errorf("request %d: Cache-Control = %q, want no-store", i, got)
+		}
+	}
+	if got := fetchCount.Load(); got != 2 {
+		t.Errorf("upstream fetched %d times, want 2", got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Hello\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      5 * 1024 * 1024,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+		FrameAncestors:   "none",
+		Metrics:          true,
+	})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for range 2 {
+		resp, err := http.Get(srv.URL + "/" + upstream.URL + "/secret-name.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	out := string(body)
+
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	for _, want := range []string{
+		`cooked_http_requests_total{content_type="markdown",route="GET /{upstream...}",status="200"} 2`,
+		`cooked_upstream_fetch_duration_seconds_count{host="` + host + `"} 1`,
+		`cooked_render_duration_seconds_count{renderer="markdown"} 1`,
+		`cooked_cache_lookups_total{result="hit"} 1`,
+		`cooked_cache_lookups_total{result="miss"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics missing %q", want)
+		}
+	}
+	if strings.Contains(out, "secret-name") {
+		t.Error("/metrics must not expose upstream paths")
+	}
+
+	// Disabled or moved to a separate listener: not on the main handler.
+	s = newTestServer(t, nil)
+	srv2 := httptest.NewServer(s.Handler())
+	defer srv2.Close()
+	resp2, err := http.Get(srv2.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode == 200 {
+		t.Error("/metrics should not be served when metrics are disabled")
+	}
+	if s.MetricsHandler() != nil {
+		t.Error("MetricsHandler should be nil when metrics are disabled")
+	}
+}
+
 func TestBlockedUpstream(t *testing.T) {
 	s := newTestServer(t, &config.Config{
 		Listen:           ":8080",
@@ -600,6 +966,222 @@ func TestErrorMessages_NoInternalDetails(t *testing.T) {
 	}
 }
 
+func TestRenderForgePageURL(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte("# Runbook\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	humanURL := upstream.URL + "/ops/runbooks/src/branch/main/README.md"
+	resp, err := http.Get(srv.URL + "/" + humanURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if gotPath != "/ops/runbooks/raw/branch/main/README.md" {
+		t.Errorf("upstream path = %q, want raw file path", gotPath)
+	}
+	if got := resp.Header.Get("X-Cooked-Upstream"); got != upstream.URL+"/ops/runbooks/raw/branch/main/README.md" {
+		t.Errorf("X-Cooked-Upstream = %q, want raw URL", got)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `id="cooked-source-link" href="`+humanURL+`"`) {
+		t.Error("source link should show the original forge page URL")
+	}
+
+	// The raw URL's page is cached apart, linking to the raw URL.
+	rawURL := upstream.URL + "/ops/runbooks/raw/branch/main/README.md"
+	resp2, err := http.Get(srv.URL + "/" + rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	body, _ = io.ReadAll(resp2.Body)
+	if resp2.Header.Get("X-Cooked-Cache") == "hit" || !strings.Contains(string(body), `id="cooked-source-link" href="`+rawURL+`"`) {
+		t.Errorf("raw URL page: cache = %q, should link to the raw URL", resp2.Header.Get("X-Cooked-Cache"))
+	}
+
+	resp3, err := http.Get(srv.URL + "/" + humanURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp3.Body.Close()
+	body, _ = io.ReadAll(resp3.Body)
+	if resp3.Header.Get("X-Cooked-Cache") != "hit" || !strings.Contains(string(body), `id="cooked-source-link" href="`+humanURL+`"`) {
+		t.Errorf("forge page URL again: cache = %q, should link to the forge page URL", resp3.Header.Get("X-Cooked-Cache"))
+	}
+}
+
+func TestRenderDirectory_Autoindex(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<pre><a href="../">../</a>
+<a href="guides/">guides/</a>      01-Jan-2026 10:00    -
+<a href="README.md">README.md</a>  01-Jan-2026 10:00    42
+<a href="logo.bin">logo.bin</a>    01-Jan-2026 10:00    9
+</pre>`))
+		case "/docs/README.md":
+			w.Write([]byte("# Docs Home\n\nWelcome.\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/" + upstream.URL + "/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Cooked-Content-Type"); got != "directory" {
+		t.Errorf("X-Cooked-Content-Type = %q, want directory", got)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	html := string(body)
+	for _, want := range []string{
+		`class="cooked-dir-listing" data-entry-count="3"`,
+		`href="/` + upstream.URL + `/docs/guides/"`,
+		`href="/` + upstream.URL + `/docs/README.md"`,
+		`href="` + upstream.URL + `/docs/logo.bin"`, // unrenderable files link upstream
+		`href="/` + upstream.URL + `/">..</a>`,
+		`data-readme="README.md"`,
+		`Docs Home`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("response missing %q", want)
+		}
+	}
+}
+
+func TestRenderDirectory_GiteaAPI(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/ops/runbooks/contents":
+			if r.URL.Query().Get("ref") != "main" {
+				http.Error(w, "bad ref", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"name":"README.md","path":"README.md","type":"file","size":12},
+				{"name":"docs","path":"docs","type":"dir","size":0}]`))
+		case "/ops/runbooks/raw/branch/main/README.md":
+			w.Write([]byte("# Runbooks\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/" + upstream.URL + "/ops/runbooks/src/branch/main/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	html := string(body)
+	for _, want := range []string{
+		`href="/` + upstream.URL + `/ops/runbooks/src/branch/main/docs/"`,
+		`href="/` + upstream.URL + `/ops/runbooks/src/branch/main/README.md"`,
+		`Runbooks`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("response missing %q", want)
+		}
+	}
+	if strings.Contains(html, `data-type="parent"`) {
+		t.Error("repository root should not link to a parent directory")
+	}
+}
+
+func TestRenderDirectory_GitLabPages(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		pages         int // pages the project's tree fills; the last is short
+		wantEntries   int
+		wantTruncated bool
+	}{
+		{"two pages", 2, 105, false},
+		{"over the page cap", 20, 1000, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v4/projects/group/repo/repository/tree" {
+					http.NotFound(w, r)
+					return
+				}
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				page = max(page, 1)
+				n := 100
+				if page == tc.pages {
+					n = 5
+				} else if page > tc.pages {
+					n = 0
+				}
+				items := make([]string, n)
+				for i := range items {
+					items[i] = fmt.Sprintf(`{"name":"f%d-%d.txt","path":"f%d-%d.txt","type":"blob"}`, page, i, page, i)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+			}))
+			defer upstream.Close()
+
+			s := newTestServer(t, nil)
+			srv := httptest.NewServer(s.Handler())
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/" + upstream.URL + "/group/repo/-/tree/main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			html := string(body)
+			if want := fmt.Sprintf(`data-entry-count="%d"`, tc.wantEntries); !strings.Contains(html, want) {
+				t.Errorf("response missing %q", want)
+			}
+			if got := strings.Contains(html, `<p class="cooked-dir-truncated">`); got != tc.wantTruncated {
+				t.Errorf("truncated notice = %v, want %v", got, tc.wantTruncated)
+			}
+		})
+	}
+}
+
 func TestRawEndpoint(t *testing.T) {
 	const rawContent = "# Hello World\n\nRaw markdown content.\n"
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -633,6 +1215,52 @@ func TestRawEndpoint(t *testing.T) {
 	}
 }
 
+func TestRawEndpoint_AppliesCredentials(t *testing.T) {
+	var gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte("private image"))
+	}))
+	defer upstream.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("tok123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := credentials.Parse([]byte(`{"credentials": [{"match": "` +
+		upstream.Listener.Addr().String() + `", "type": "bearer", "secret_file": "` + tokenFile + `"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      5 * 1024 * 1024,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+		FrameAncestors:   "none",
+	}
+	s := New(cfg, "v0.1.0-test", fstest.MapFS{}, nil, fetch.WithCredentials(creds))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/_cooked/raw/" + upstream.URL + "/img.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if gotAuth != "Bearer tok123" {
+		t.Errorf("Authorization = %q, want Bearer tok123", gotAuth)
+	}
+}
+
 func TestRawEndpoint_BlockedUpstream(t *testing.T) {
 	s := newTestServer(t, &config.Config{
 		Listen:           ":8080",
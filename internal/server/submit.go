@@ -0,0 +1,121 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/render"
+	cookedtemplate "github.com/air-gapped/cooked/internal/template"
+	"github.com/air-gapped/cooked/internal/tracing"
+)
+
+// handleSubmitRender renders a document posted in the request body, for
+// previewing content that is not published anywhere cooked can fetch it from.
+//
+// The content type comes from ?type= (a format name, a media type such as
+// text/markdown or a file extension such as md or go); without it, ?filename=,
+// the request's Content-Type and the body are detected as for a fetched
+// document. ?output=fragment returns the bare rendered HTML instead of the
+// full page. Relative links are rewritten against ?base=, the URL the
+// document will be published at, which must pass the same upstream checks as
+// a rendered URL; without it they are left as written. Results are never
+// cached.
+func (s *Server) handleSubmitRender(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "handleSubmitRender")
+	defer span.End()
+
+	q := r.URL.Query()
+	output := q.Get("output")
+	if output == "" {
+		output = "page"
+	}
+	if output != "page" && output != "fragment" {
+		s.writeAPIError(w, "", &requestError{400, "bad-request", `The output parameter must be "page" or "fragment"`})
+		return
+	}
+
+	var rawBase, sourceURL string
+	if base := q.Get("base"); base != "" {
+		rawBase, _, _ = s.forges.Resolve(base)
+		span.SetAttributes(tracing.URL(rawBase))
+		if _, reqErr := s.checkUpstream(rawBase); reqErr != nil {
+			s.writeAPIError(w, rawBase, reqErr)
+			return
+		}
+		sourceURL = base
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxFileSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeAPIError(w, rawBase, &requestError{413, "too-large",
+				fmt.Sprintf("File too large (limit is %d bytes)", s.cfg.MaxFileSize)})
+			return
+		}
+		s.writeAPIError(w, rawBase, &requestError{400, "bad-request", "Failed to read the request body"})
+		return
+	}
+
+	body = charset.ToUTF8(body, r.Header.Get("Content-Type"))
+	fileInfo, typeSource := submittedFileInfo(q.Get("type"), q.Get("filename"), r.Header.Get("Content-Type"), body)
+	if fileInfo.ContentType == render.TypeUnsupported {
+		s.writeAPIError(w, rawBase, &requestError{415, "unsupported",
+			"Set a supported type or filename parameter, or a document Content-Type"})
+		return
+	}
+
+	renderStart := time.Now()
+	htmlContent, meta, err := s.renderDocument(ctx, body, fileInfo, rawBase)
+	if err != nil {
+		slog.Error("render "+string(fileInfo.ContentType)+" failed", "error", err, "base", rawBase)
+		s.writeAPIError(w, rawBase, &requestError{500, "render-error", renderFailureMessage(fileInfo.ContentType)})
+		return
+	}
+
+	out := htmlContent
+	if output == "page" {
+		pageData := cookedtemplate.PageData{
+			UpstreamURL:    rawBase,
+			SourceURL:      sourceURL,
+			ContentType:    fileInfo.ContentType,
+			UpstreamStatus: 200,
+			FileSize:       int64(len(body)),
+			Content:        template.HTML(htmlContent),
+		}
+		if pageData.UpstreamURL == "" {
+			pageData.UpstreamURL = q.Get("filename")
+		}
+		applyMeta(&pageData, meta)
+		out = s.renderPage(ctx, pageData)
+	}
+	renderMs := time.Since(renderStart).Milliseconds()
+
+	s.setResponseHeaders(w, rawBase, 200, "", string(fileInfo.ContentType), renderMs, 0, s.version)
+	setTypeSource(w, string(typeSource))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(200)
+	w.Write(out)
+}
+
+// submittedFileInfo determines the content type of a posted document. The
+// type parameter decides it when set; otherwise the filename parameter, the
+// request Content-Type and the body are detected as for a fetched document.
+func submittedFileInfo(typ, filename, contentType string, body []byte) (render.FileInfo, render.TypeSource) {
+	if typ != "" {
+		info, _ := render.ParseTypeOverride(typ)
+		return info, render.SourceOverride
+	}
+	return render.DetectDocument(render.Document{
+		Path:        filename,
+		ContentType: contentType,
+		Body:        body,
+	})
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/config"
+)
+
+// submit posts body to the submit endpoint with the given query and
+// Content-Type, returning the response and its body.
+func submit(t *testing.T, srvURL, query, contentType, body string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Post(srvURL+"/_cooked/render?"+query, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestSubmitRender_Page(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, body := submit(t, srv.URL, "filename=docs/guide.md", "application/octet-stream",
+		"# Draft\n\n[setup](setup.md)\n\n<script>alert(1)</script>\n")
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, "<html") || !strings.Contains(body, "<title>Draft — cooked</title>") {
+		t.Errorf("not a full page: %.300s", body)
+	}
+	if strings.Contains(body, "<script>alert") {
+		t.Error("page contains unsanitized script")
+	}
+	if !strings.Contains(body, `href="setup.md"`) {
+		t.Error("relative link rewritten without a base")
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	if got := resp.Header.Get("X-Cooked-Content-Type"); got != "markdown" {
+		t.Errorf("X-Cooked-Content-Type = %q, want markdown", got)
+	}
+	if s.cache.Len() != 0 {
+		t.Errorf("%d cache entries after submit, want none", s.cache.Len())
+	}
+}
+
+func TestSubmitRender_Fragment(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, body := submit(t, srv.URL, "output=fragment", "text/markdown; charset=utf-8", "# Hello\n")
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, "Hello</h1>") || strings.Contains(body, "<html") || strings.Contains(body, "cooked-header") {
+		t.Errorf("fragment = %s", body)
+	}
+}
+
+func TestSubmitRender_Types(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		want        string
+	}{
+		{"filename code", "filename=main.go", "", "code"},
+		{"type extension", "type=adoc", "", "asciidoc"},
+		{"type media type", "type=" + url.QueryEscape("text/org"), "", "org"},
+		{"type overrides filename", "type=txt&filename=README.md", "", "plaintext"},
+		{"content type", "", "text/x-markdown", "markdown"},
+	}
+	for _, tt := range tests {
+		resp, body := submit(t, srv.URL, tt.query+"&output=fragment", tt.contentType, "package main\n")
+		if resp.StatusCode != 200 {
+			t.Errorf("%s: status = %d: %s", tt.name, resp.StatusCode, body)
+			continue
+		}
+		if got := resp.Header.Get("X-Cooked-Content-Type"); got != tt.want {
+			t.Errorf("%s: content type = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubmitRender_Detection(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	spec := "openapi: 3.0.0\ninfo:\n  title: Pets\n  version: '1'\npaths: {}\n"
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		want        string
+		wantSource  string
+	}{
+		{"openapi by filename", "filename=api.yaml", "", spec, "openapi", "path"},
+		{"openapi by content type", "", "application/yaml", spec, "openapi", "content-type"},
+		{"openapi sniffed", "", "", spec, "openapi", "sniff"},
+		{"type keeps yaml", "type=yaml", "", spec, "code", "override"},
+		{"shebang", "filename=deploy", "", "#!/bin/sh\necho hi\n", "code", "shebang"},
+		{"modeline", "", "text/plain", "-*- mode: org -*-\n* Heading\n", "org", "modeline"},
+	}
+	for _, tt := range tests {
+		resp, body := submit(t, srv.URL, tt.query+"&output=fragment", tt.contentType, tt.body)
+		if resp.StatusCode != 200 {
+			t.Errorf("%s: status = %d: %s", tt.name, resp.StatusCode, body)
+			continue
+		}
+		if got := resp.Header.Get("X-Cooked-Content-Type"); got != tt.want {
+			t.Errorf("%s: content type = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := resp.Header.Get("X-Cooked-Type-Source"); got != tt.wantSource {
+			t.Errorf("%s: type source = %q, want %q", tt.name, got, tt.wantSource)
+		}
+	}
+}
+
+func TestSubmitRender_Base(t *testing.T) {
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	base := "http://127.0.0.1:9/docs/guide.md"
+	resp, body := submit(t, srv.URL, "output=fragment&base="+url.QueryEscape(base), "text/markdown",
+		"[setup](setup.md)\n\n![logo](img/logo.png)\n")
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, `href="/http://127.0.0.1:9/docs/setup.md"`) {
+		t.Errorf("link not resolved against base: %s", body)
+	}
+	if !strings.Contains(body, "/_cooked/raw/http://127.0.0.1:9/docs/img/logo.png") {
+		t.Errorf("image not resolved against base: %s", body)
+	}
+
+	resp, body = submit(t, srv.URL, "base="+url.QueryEscape(base), "text/markdown", "# Guide\n")
+	if resp.StatusCode != 200 || !strings.Contains(body, `href="`+base+`"`) {
+		t.Errorf("page source link is not the base: %d %.200s", resp.StatusCode, body)
+	}
+}
+
+func TestSubmitRender_Errors(t *testing.T) {
+	s := newTestServer(t, &config.Config{
+		Listen:           ":8080",
+		CacheTTL:         5 * time.Minute,
+		CacheMaxSize:     100 * 1024 * 1024,
+		FetchTimeout:     10 * time.Second,
+		MaxFileSize:      1024,
+		DefaultTheme:     "auto",
+		AllowedUpstreams: "127.0.0.0/8",
+	})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		query   string
+		body    string
+		status  int
+		errType string
+	}{
+		{"too large", "filename=README.md", strings.Repeat("x", 2048), 413, "too-large"},
+		{"no type", "", "# Doc\n", 415, "unsupported"},
+		{"unsupported filename", "filename=logo.png", "x", 415, "unsupported"},
+		{"base not allowlisted", "filename=README.md&base=" + url.QueryEscape("https://evil.com/README.md"), "x", 403, "blocked"},
+		{"bad output", "filename=README.md&output=pdf", "x", 400, "bad-request"},
+	}
+	for _, tt := range tests {
+		resp, body := submit(t, srv.URL, tt.query, "application/octet-stream", tt.body)
+		var out map[string]string
+		if err := json.Unmarshal([]byte(body), &out); err != nil {
+			t.Errorf("%s: decode %q: %v", tt.name, body, err)
+			continue
+		}
+		if resp.StatusCode != tt.status || out["type"] != tt.errType || out["error"] == "" {
+			t.Errorf("%s: %d %v, want %d %s", tt.name, resp.StatusCode, out, tt.status, tt.errType)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_RenderSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+		_ = tp.Shutdown(context.Background())
+	})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old.md" {
+			http.Redirect(w, r, "/README.md", http.StatusFound)
+			return
+		}
+		w.Write([]byte("# Hello\n\n[link](other.md)\n"))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest("GET", srv.URL+"/"+upstream.URL+"/old.md", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	counts := map[string]int{}
+	for _, span := range recorder.Ended() {
+		counts[span.Name()]++
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("span %q trace ID = %s, want the incoming %s", span.Name(), got, traceID)
+		}
+	}
+	// The mux redirects the "//" in the upstream URL once, and the client
+	// carries traceparent across the redirect.
+	if counts["GET /{upstream...}"] == 0 {
+		t.Errorf("no server span named after the route (spans: %v)", counts)
+	}
+	for _, name := range []string{
+		"handleRender", "CachedClient.Fetch", "Client.Fetch",
+		"render.markdown", "sanitize.HTML", "rewrite.RelativeURLs", "RenderPage",
+	} {
+		if counts[name] != 1 {
+			t.Errorf("%d %q spans, want 1 (spans: %v)", counts[name], name, counts)
+		}
+	}
+	// One client span per hop: the redirect and its target.
+	if hops := counts["HTTP GET"]; hops != 2 {
+		t.Errorf("%d upstream hop spans, want 2 (spans: %v)", hops, counts)
+	}
+}
@@ -0,0 +1,338 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/air-gapped/cooked/internal/cache"
+	"github.com/air-gapped/cooked/internal/charset"
+	"github.com/air-gapped/cooked/internal/listing"
+	"github.com/air-gapped/cooked/internal/render"
+	"github.com/air-gapped/cooked/internal/rewrite"
+	cookedtemplate "github.com/air-gapped/cooked/internal/template"
+)
+
+// fileTree is a tree of files that cooked reads itself rather than fetching
+// from an upstream: a local root or a git commit. Paths are slash-separated
+// and valid per fs.ValidPath, with "." for the top of the tree.
+type fileTree interface {
+	// Stat describes the file or directory at p.
+	Stat(p string) (treeEntry, error)
+	// ReadFile returns the contents of the file at p, failing if it is
+	// larger than maxSize.
+	ReadFile(p string, maxSize int64) ([]byte, error)
+	// ReadDir lists the directory at p, leaving out entries that cannot be
+	// accessed.
+	ReadDir(p string) ([]treeEntry, error)
+	// URL returns the cooked URL of p.
+	URL(p string) string
+	// Title names the top of the tree in directory listings.
+	Title() string
+	// CacheControl is the Cache-Control header of responses from the tree.
+	CacheControl() string
+}
+
+// treeEntry describes a file or directory in a fileTree.
+type treeEntry struct {
+	Name    string
+	IsDir   bool
+	Regular bool // false for symlinks and other special files
+	Size    int64
+	ModTime time.Time // zero when the tree does not record it
+	// ETag validates the entry's content; directories without one are
+	// rendered on every request.
+	ETag string
+}
+
+// serveTree serves the file or directory at rel, the raw path below the
+// tree's URL. Renderable files are rendered like upstream documents; other
+// files, such as images, are served as they are.
+func (s *Server) serveTree(ctx context.Context, w http.ResponseWriter, r *http.Request, t fileTree, rel string) {
+	pageURL := t.URL(rel)
+
+	// The top of the tree is a directory and needs its trailing slash for
+	// relative links to resolve.
+	if !strings.HasSuffix(r.URL.Path, "/") && rel == "" {
+		http.Redirect(w, r, pageURL, http.StatusMovedPermanently)
+		return
+	}
+
+	clean := strings.TrimSuffix(rel, "/")
+	if clean == "" {
+		clean = "."
+	}
+	if !fs.ValidPath(clean) {
+		s.renderError(w, pageURL, 400, "bad-request", "Invalid path")
+		return
+	}
+
+	info, err := t.Stat(clean)
+	if err != nil {
+		s.treeError(w, pageURL, err)
+		return
+	}
+
+	if info.IsDir {
+		if !strings.HasSuffix(rel, "/") && clean != "." {
+			http.Redirect(w, r, pageURL+"/", http.StatusMovedPermanently)
+			return
+		}
+		s.treeDirectory(ctx, w, r, t, clean, pageURL, info)
+		return
+	}
+	if !info.Regular {
+		s.renderError(w, pageURL, 403, "blocked", "This path is not a regular file")
+		return
+	}
+	if info.Size > s.cfg.MaxFileSize {
+		s.renderError(w, pageURL, 413, "too-large",
+			fmt.Sprintf("File too large (limit is %d bytes)", s.cfg.MaxFileSize))
+		return
+	}
+
+	lastModified := ""
+	if !info.ModTime.IsZero() {
+		lastModified = info.ModTime.UTC().Format(http.TimeFormat)
+	}
+	if s.treeCached(w, r, t, clean, pageURL, info.ETag, lastModified) {
+		return
+	}
+
+	raw, err := t.ReadFile(clean, s.cfg.MaxFileSize)
+	if err != nil {
+		s.treeError(w, pageURL, err)
+		return
+	}
+
+	body := charset.ToUTF8(raw, "")
+	fileInfo, _ := render.DetectDocument(render.Document{Path: clean, Body: body})
+	if fileInfo.ContentType == render.TypeUnsupported {
+		s.serveTreeRaw(w, r, t, info, raw)
+		return
+	}
+
+	renderStart := time.Now()
+	htmlContent, meta, err := s.renderDocument(ctx, body, fileInfo, "")
+	if err != nil {
+		slog.Error("render "+string(fileInfo.ContentType)+" failed", "error", err, "page", pageURL)
+		s.renderError(w, pageURL, 500, "render-error", renderFailureMessage(fileInfo.ContentType))
+		return
+	}
+	htmlContent = rewriteTree(htmlContent, t, path.Dir(clean))
+
+	pageData := cookedtemplate.PageData{
+		UpstreamURL:    pageURL,
+		ContentType:    fileInfo.ContentType,
+		CacheStatus:    string(cache.StatusMiss),
+		UpstreamStatus: 200,
+		FileSize:       info.Size,
+		LastModified:   lastModified,
+		Content:        template.HTML(htmlContent),
+	}
+	applyMeta(&pageData, meta)
+	page := s.renderPage(ctx, pageData)
+	s.storeTreePage(w, t, clean, pageURL, page, info.ETag, lastModified, string(fileInfo.ContentType), time.Since(renderStart).Milliseconds())
+}
+
+// treeDirectory renders a directory as a file listing, with its README
+// beneath.
+func (s *Server) treeDirectory(ctx context.Context, w http.ResponseWriter, r *http.Request, t fileTree, dir, pageURL string, info treeEntry) {
+	if s.treeCached(w, r, t, dir, pageURL, info.ETag, "") {
+		return
+	}
+	children, err := t.ReadDir(dir)
+	if err != nil {
+		s.treeError(w, pageURL, err)
+		return
+	}
+
+	renderStart := time.Now()
+	var entries []listing.Entry
+	for _, c := range children {
+		e := listing.Entry{Name: c.Name, IsDir: c.IsDir, Size: c.Size, URL: t.URL(path.Join(dir, c.Name))}
+		if e.IsDir {
+			e.Size = -1
+			e.URL += "/"
+		}
+		entries = append(entries, e)
+	}
+	listing.Sort(entries)
+
+	parent := ""
+	if dir != "." {
+		parent = t.URL(path.Dir(dir))
+		if path.Dir(dir) != "." {
+			parent += "/"
+		}
+	}
+
+	var content bytes.Buffer
+	content.Write(listing.Render(entries, parent, func(e listing.Entry) string { return e.URL }))
+
+	title := t.Title()
+	if dir != "." {
+		title = path.Base(dir) + "/"
+	}
+	pageData := cookedtemplate.PageData{
+		UpstreamURL:    pageURL,
+		ContentType:    render.TypeDirectory,
+		UpstreamStatus: 200,
+	}
+
+	if readme := listing.SelectReadme(entries); readme != nil {
+		readmeHTML, meta, err := s.renderTreeReadme(ctx, t, path.Join(dir, readme.Name))
+		if err != nil {
+			slog.Warn("render directory readme failed", "page", readme.URL, "error", err)
+		} else {
+			fmt.Fprintf(&content, "\n<div class=\"cooked-dir-readme\" data-readme=\"%s\">\n",
+				template.HTMLEscapeString(readme.Name))
+			content.Write(readmeHTML)
+			content.WriteString("\n</div>")
+			applyMeta(&pageData, meta)
+		}
+	}
+	pageData.Title = title
+	pageData.Content = template.HTML(content.String())
+
+	page := s.renderPage(ctx, pageData)
+	s.storeTreePage(w, t, dir, pageURL, page, info.ETag, "", string(render.TypeDirectory), time.Since(renderStart).Milliseconds())
+}
+
+// renderTreeReadme renders a README from a directory listing.
+func (s *Server) renderTreeReadme(ctx context.Context, t fileTree, p string) ([]byte, *render.MarkdownMeta, error) {
+	body, err := t.ReadFile(p, s.cfg.MaxFileSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	body = charset.ToUTF8(body, "")
+	fileInfo, _ := render.DetectDocument(render.Document{Path: p, Body: body})
+	if fileInfo.ContentType == render.TypeUnsupported {
+		// Extensionless README is conventionally plain text.
+		fileInfo = render.FileInfo{ContentType: render.TypePlaintext, Label: "Plain Text"}
+	}
+	htmlContent, meta, err := s.renderDocument(ctx, body, fileInfo, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewriteTree(htmlContent, t, path.Dir(p)), meta, nil
+}
+
+// treeCached answers a request from the browser's or cooked's cache when the
+// page for p is still valid for etag, and reports whether it did. Pages are
+// cached under their cooked URL.
+func (s *Server) treeCached(w http.ResponseWriter, r *http.Request, t fileTree, p, pageURL, etag, lastModified string) bool {
+	if etag == "" {
+		return false
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", t.CacheControl())
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	key := t.URL(p)
+	entry, status := s.cache.Get(key)
+	if entry == nil || entry.ETag != etag {
+		return false
+	}
+	if status == cache.StatusExpired {
+		s.cache.RefreshTTL(key)
+	}
+	s.writeTreePage(w, t, pageURL, entry.HTML, etag, lastModified, string(cache.StatusHit), entry.ContentType, 0)
+	return true
+}
+
+// storeTreePage caches a freshly rendered page when it has a validator, and
+// writes it.
+func (s *Server) storeTreePage(w http.ResponseWriter, t fileTree, p, pageURL string, page []byte, etag, lastModified, contentType string, renderMs int64) {
+	cacheStatus := ""
+	if etag != "" {
+		s.cache.Put(t.URL(p), cache.Entry{
+			HTML:         page,
+			ETag:         etag,
+			LastModified: lastModified,
+			Size:         int64(len(page)),
+			ContentType:  contentType,
+		})
+		cacheStatus = string(cache.StatusMiss)
+	}
+	s.writeTreePage(w, t, pageURL, page, etag, lastModified, cacheStatus, contentType, renderMs)
+}
+
+// writeTreePage writes a rendered page. Browsers revalidate it against its
+// ETag as the tree's Cache-Control allows.
+func (s *Server) writeTreePage(w http.ResponseWriter, t fileTree, pageURL string, page []byte, etag, lastModified, cacheStatus, contentType string, renderMs int64) {
+	s.setResponseHeaders(w, pageURL, 200, cacheStatus, contentType, renderMs, 0, s.version)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", t.CacheControl())
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if lastModified != "" {
+		w.Header().Set("Last-Modified", lastModified)
+	}
+	w.WriteHeader(200)
+	w.Write(page)
+}
+
+// serveTreeRaw serves a file that cooked does not render, such as an image
+// embedded in a document.
+func (s *Server) serveTreeRaw(w http.ResponseWriter, r *http.Request, t fileTree, info treeEntry, body []byte) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// HTML or SVG from the tree must not run script on cooked's origin.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Cache-Control", t.CacheControl())
+	if info.ETag != "" {
+		w.Header().Set("ETag", info.ETag)
+	}
+	http.ServeContent(w, r, info.Name, info.ModTime, bytes.NewReader(body))
+}
+
+// treeError maps a tree access error onto an error page. Paths escaping a
+// local root fail like any other inaccessible path.
+func (s *Server) treeError(w http.ResponseWriter, pageURL string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		s.renderError(w, pageURL, 404, "not-found", "File not found")
+	case isTooLarge(err):
+		s.renderError(w, pageURL, 413, "too-large",
+			fmt.Sprintf("File too large (limit is %d bytes)", s.cfg.MaxFileSize))
+	default:
+		slog.Warn("file tree access failed", "page", pageURL, "error", err)
+		s.renderError(w, pageURL, 403, "blocked", "This path is not accessible")
+	}
+}
+
+// rewriteTree points relative links and images in a document from dir of a
+// tree at the tree's cooked URLs. Paths are resolved within the tree, so ../
+// cannot climb out of it; a leading / refers to the top of the tree.
+func rewriteTree(htmlContent []byte, t fileTree, dir string) []byte {
+	return rewrite.Relative(htmlContent, func(ref string, _ bool) (string, bool) {
+		if i := strings.IndexAny(ref, ":/"); i >= 0 && ref[i] == ':' {
+			return "", false // another scheme
+		}
+		unescaped, err := url.PathUnescape(ref)
+		if err != nil {
+			return "", false
+		}
+		target := path.Join("/", dir, unescaped)
+		if strings.HasPrefix(unescaped, "/") {
+			target = path.Clean(unescaped)
+		}
+		rewritten := t.URL(strings.TrimPrefix(target, "/"))
+		if strings.HasSuffix(unescaped, "/") && target != "/" {
+			rewritten += "/"
+		}
+		return rewritten, true
+	})
+}
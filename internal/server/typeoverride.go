@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/render"
+)
+
+// typeOverrideParam is the query parameter that sets a document's type,
+// for URLs whose name, headers and content say nothing useful about it. It
+// is removed from the upstream URL.
+const typeOverrideParam = "cooked_type"
+
+// splitTypeOverride removes the type override parameter from a raw query,
+// leaving the upstream's own parameters in their original form and order.
+func splitTypeOverride(rawQuery string) (rest, override string) {
+	if !strings.Contains(rawQuery, typeOverrideParam) {
+		return rawQuery, ""
+	}
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		name, value, _ := strings.Cut(param, "=")
+		if name != typeOverrideParam {
+			kept = append(kept, param)
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			override = v
+		}
+	}
+	return strings.Join(kept, "&"), strings.ToLower(strings.TrimSpace(override))
+}
+
+// checkTypeOverride rejects a type override cooked cannot render.
+func checkTypeOverride(override string) *requestError {
+	if override == "" {
+		return nil
+	}
+	if _, ok := render.ParseTypeOverride(override); !ok {
+		return &requestError{400, "bad-request", "Unknown " + typeOverrideParam + " value"}
+	}
+	return nil
+}
+
+// typeVariant names a type override's entries in the cache; see
+// cache.VariantKey.
+func typeVariant(override string) string {
+	return "type-" + url.QueryEscape(override)
+}
+
+// setTypeSource reports what a document's content type was detected from.
+func setTypeSource(w http.ResponseWriter, source string) {
+	if source != "" {
+		w.Header().Set("X-Cooked-Type-Source", source)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/webhook"
+)
+
+// maxWebhookBody bounds push payloads. GitLab includes at most 20 commits,
+// so real payloads are far smaller.
+const maxWebhookBody = 5 << 20
+
+// handleWebhook receives a forge push notification, purges the pages it
+// touches, and optionally re-renders them in the background.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	kind, ok := webhook.Kind(r.PathValue("forge"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown webhook kind"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "payload too large"})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read the request body"})
+		return
+	}
+	if err := webhook.Verify(kind, r.Header, body, s.cfg.WebhookSecret); err != nil {
+		slog.Warn("webhook rejected", "kind", kind, "error", err)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid signature or token"})
+		return
+	}
+	if !webhook.IsPush(kind, r.Header) {
+		writeJSON(w, http.StatusOK, map[string]any{"purged": 0, "ignored": "not a push event"})
+		return
+	}
+
+	push, err := webhook.Parse(kind, body)
+	if err != nil {
+		slog.Warn("webhook payload invalid", "kind", kind, "error", err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	purged, warm := s.purgePush(push)
+	if s.cfg.WebhookPrewarm && len(warm) > 0 {
+		s.prewarm(warm)
+	} else {
+		warm = nil
+	}
+
+	slog.Info("webhook purged cache", "kind", kind, "repository", redactUpstream(push.RepoURL),
+		"ref", push.Ref, "paths", len(push.Paths), "truncated", push.Truncated,
+		"purged", purged, "prewarming", len(warm))
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged, "prewarming": len(warm)})
+}
+
+// purgePush removes the cached pages affected by a push. It returns the
+// number of entries removed and the page URLs that were cached, which are
+// the ones worth re-rendering.
+func (s *Server) purgePush(push *webhook.Push) (int, []string) {
+	purged := 0
+	if push.Truncated && push.RepoURL != "" {
+		purged += s.cache.Purge(s.matchRepo(push))
+	}
+
+	var warm []string
+	for _, pageURL := range append(push.PageURLs(), push.URLs...) {
+		if n := s.cache.Purge(matchKeys(s.cacheKeys(pageURL))); n > 0 {
+			purged += n
+			warm = append(warm, pageURL)
+		}
+	}
+	return purged, warm
+}
+
+// matchRepo matches every cache key belonging to the pushed repository: its
+// file URLs and its forge directory listing API URLs.
+func (s *Server) matchRepo(push *webhook.Push) func(string) bool {
+	prefixes := []string{push.RepoURL + "/"}
+	if dir, ok := s.forges.Directory(push.DirURL("")); ok {
+		api, _, _ := strings.Cut(dir.APIURL, "?")
+		prefixes = append(prefixes, api)
+	}
+	return func(key string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(key, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// prewarm re-renders pages one at a time in the background, through the
+// same validation, fetch and cache path as a reader's request.
+func (s *Server) prewarm(pageURLs []string) {
+	s.prewarms.Add(1)
+	go func() {
+		defer s.prewarms.Done()
+		for _, pageURL := range pageURLs {
+			req, err := http.NewRequest(http.MethodGet, "/"+pageURL, nil)
+			if err != nil {
+				slog.Warn("prewarm skipped", "upstream", redactUpstream(pageURL), "error", err)
+				continue
+			}
+			rec := &recordedResponse{header: make(http.Header)}
+			s.handleRender(rec, req)
+			if rec.status != http.StatusOK {
+				slog.Warn("prewarm failed", "upstream", redactUpstream(pageURL), "status", rec.status)
+			}
+		}
+	}()
+}
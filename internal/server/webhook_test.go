@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// newWebhookTestServer returns a cooked server with the push webhook
+// enabled in front of a fake forge whose files carry the current version.
+func newWebhookTestServer(t *testing.T, prewarm bool) (*Server, *httptest.Server, *httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var version atomic.Int32
+	version.Store(1)
+	forge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "# %s version %d\n", r.URL.Path, version.Load())
+	}))
+	t.Cleanup(forge.Close)
+
+	cfg := adminConfig("")
+	cfg.WebhookSecret = testWebhookSecret
+	cfg.WebhookPrewarm = prewarm
+	s := newTestServer(t, cfg)
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		s.prewarms.Wait()
+	})
+	return s, srv, forge, &version
+}
+
+func postWebhook(t *testing.T, target string, header http.Header, payload any) (int, map[string]any) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", target, bytes.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("X-Gitea-Signature") == "sign" {
+		mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Gitea-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func getPage(t *testing.T, target string) (string, string) {
+	t.Helper()
+	resp, err := http.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.Header.Get("X-Cooked-Cache"), string(body)
+}
+
+func TestWebhook_GiteaPushPurgesAndPrewarms(t *testing.T) {
+	s, srv, forge, version := newWebhookTestServer(t, true)
+
+	readme := srv.URL + "/" + forge.URL + "/org/repo/src/branch/main/README.md"
+	other := srv.URL + "/" + forge.URL + "/org/repo/src/branch/main/docs/other.md"
+	warm(t, readme)
+	warm(t, other)
+
+	version.Store(2)
+	payload := map[string]any{
+		"ref":           "refs/heads/main",
+		"total_commits": 1,
+		"commits":       []map[string]any{{"modified": []string{"README.md"}}},
+		"repository":    map[string]any{"html_url": forge.URL + "/org/repo"},
+	}
+	status, out := postWebhook(t, srv.URL+"/_cooked/webhook/gitea",
+		http.Header{"X-Gitea-Event": {"push"}, "X-Gitea-Signature": {"sign"}}, payload)
+	if status != 200 || out["purged"] != float64(1) || out["prewarming"] != float64(1) {
+		t.Fatalf("webhook = %d %v, want purged 1, prewarming 1", status, out)
+	}
+
+	s.prewarms.Wait()
+	cacheStatus, body := getPage(t, readme)
+	if cacheStatus != "hit" || !strings.Contains(body, "version 2") {
+		t.Errorf("pushed page after prewarm: cache = %q, fresh = %v", cacheStatus, strings.Contains(body, "version 2"))
+	}
+	cacheStatus, body = getPage(t, other)
+	if cacheStatus != "hit" || !strings.Contains(body, "version 1") {
+		t.Errorf("untouched page should still be cached: cache = %q", cacheStatus)
+	}
+}
+
+func TestWebhook_GitLabTruncatedPurgesRepository(t *testing.T) {
+	s, srv, forge, _ := newWebhookTestServer(t, false)
+
+	warm(t, srv.URL+"/"+forge.URL+"/group/repo/-/blob/main/README.md")
+	warm(t, srv.URL+"/"+forge.URL+"/group/repo/-/blob/main/docs/a.md")
+	warm(t, srv.URL+"/"+forge.URL+"/group/other/-/blob/main/README.md")
+
+	payload := map[string]any{
+		"ref":                 "refs/heads/main",
+		"total_commits_count": 30,
+		"commits":             []map[string]any{{"modified": []string{"CHANGELOG.md"}}},
+		"project":             map[string]any{"web_url": forge.URL + "/group/repo"},
+	}
+	status, out := postWebhook(t, srv.URL+"/_cooked/webhook/gitlab",
+		http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {testWebhookSecret}}, payload)
+	if status != 200 || out["purged"] != float64(2) || out["prewarming"] != float64(0) {
+		t.Errorf("webhook = %d %v, want purged 2, prewarming 0", status, out)
+	}
+	if s.cache.Len() != 1 {
+		t.Errorf("cache entries = %d, want only the other repository's page", s.cache.Len())
+	}
+}
+
+func TestWebhook_Rejected(t *testing.T) {
+	_, srv, forge, _ := newWebhookTestServer(t, false)
+	payload := map[string]any{"urls": []string{forge.URL + "/a.md"}}
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"unsigned", "/_cooked/webhook/gitea", http.Header{}, 401},
+		{"wrong token", "/_cooked/webhook/gitlab", http.Header{"X-Gitlab-Token": {"nope"}}, 401},
+		{"unknown kind", "/_cooked/webhook/svn", http.Header{}, 404},
+		{"bad payload", "/_cooked/webhook/gitea", http.Header{"X-Gitea-Signature": {"sign"}}, 400},
+	}
+	for _, tt := range tests {
+		if status, _ := postWebhook(t, srv.URL+tt.path, tt.header, payload); status != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.want)
+		}
+	}
+
+	status, out := postWebhook(t, srv.URL+"/_cooked/webhook/gitea",
+		http.Header{"X-Gitea-Event": {"issues"}, "X-Gitea-Signature": {"sign"}}, payload)
+	if status != 200 || out["ignored"] == nil {
+		t.Errorf("non-push event = %d %v, want ignored", status, out)
+	}
+}
+
+func TestWebhook_BodyErrors(t *testing.T) {
+	s, _, _, _ := newWebhookTestServer(t, false)
+
+	tests := []struct {
+		name string
+		body io.Reader
+		want int
+	}{
+		{"too large", bytes.NewReader(make([]byte, maxWebhookBody+1)), 413},
+		{"read error", iotest.ErrReader(errors.New("connection reset")), 400},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/_cooked/webhook/gitea", tt.body)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestWebhook_GenericURLs(t *testing.T) {
+	s, srv, forge, _ := newWebhookTestServer(t, false)
+	warm(t, srv.URL+"/"+forge.URL+"/runbooks/deploy.md")
+
+	status, out := postWebhook(t, srv.URL+"/_cooked/webhook/generic",
+		http.Header{"Authorization": {"Bearer " + testWebhookSecret}},
+		map[string]any{"urls": []string{forge.URL + "/runbooks/deploy.md", forge.URL + "/runbooks/never-viewed.md"}})
+	if status != 200 || out["purged"] != float64(1) {
+		t.Errorf("webhook = %d %v, want purged 1", status, out)
+	}
+	if s.cache.Len() != 0 {
+		t.Errorf("cache entries = %d, want 0", s.cache.Len())
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"github.com/air-gapped/cooked/internal/render"
 )
 
 // faviconSVG is an inline SVG favicon — a three-layer flame icon.
@@ -168,6 +170,19 @@ func writeLayoutCSS(buf *bytes.Buffer) {
 	buf.WriteString(layoutCSS)
 }
 
+// writeContentCSS writes the styles of the views that only pages of content
+// type ct contain.
+func writeContentCSS(buf *bytes.Buffer, ct render.ContentType) {
+	switch ct {
+	case render.TypeTable:
+		buf.WriteString(tableCSS)
+	case render.TypeCode:
+		buf.WriteString(treeCSS)
+	case render.TypeOpenAPI:
+		buf.WriteString(openapiCSS)
+	}
+}
+
 // prefixThemeCSS prepends a theme selector before each .markdown-body selector
 // in the embedded github-markdown CSS. The CSS files use .markdown-body as the
 // root selector for every rule, so we prefix each occurrence to scope rules to
@@ -284,6 +299,56 @@ const layoutCSS = `
       [data-theme="auto"] .cooked-code-header { background: #161b22; border-color: #30363d; color: #8b949e; }
     }
 
+    .cooked-dir-listing table { display: table; width: 100%; margin: 0 0 24px; }
+    .cooked-dir-listing th, .cooked-dir-listing td { border: none; border-bottom: 1px solid rgba(128,128,128,0.2); padding: 6px 12px; }
+    .cooked-dir-listing th { text-align: left; }
+    .cooked-dir-listing tr { background: transparent !important; }
+    .cooked-dir-listing .cooked-dir-size { text-align: right; white-space: nowrap; color: #656d76; width: 1%; }
+    .cooked-dir-icon { display: inline-block; width: 1.6em; }
+    .cooked-dir-readme { border-top: 1px solid rgba(128,128,128,0.3); padding-top: 16px; }
+    .cooked-dir-truncated { font-size: 12px; font-style: italic; color: #656d76; }
+
+    .admonition {
+      margin: 0 0 16px; padding: 8px 16px; border-left: 4px solid #0969da;
+      background: rgba(128,128,128,0.06); border-radius: 0 6px 6px 0;
+    }
+    .admonition > :last-child { margin-bottom: 0; }
+    .admonition-title { font-weight: 600; margin: 0 0 4px; }
+    .admonition.tip, .admonition.hint, .admonition.versionadded { border-left-color: #1a7f37; }
+    .admonition.important, .admonition.seealso, .admonition.versionchanged { border-left-color: #8250df; }
+    .admonition.warning, .admonition.caution, .admonition.attention, .admonition.deprecated { border-left-color: #9a6700; }
+    .admonition.danger, .admonition.error { border-left-color: #cf222e; }
+    .sidebar { float: right; width: 35%; margin: 0 0 16px 16px; padding: 8px 16px; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px; }
+    .topic-title, .sidebar-title, .rubric { font-weight: 600; }
+    .field-list, .option-list { display: grid; grid-template-columns: max-content auto; gap: 4px 16px; }
+    .field-list dt, .option-list dt { font-style: normal; margin: 0; }
+    .field-list dd, .option-list dd { margin: 0; }
+    .line-block .line { min-height: 1.5em; }
+    .footnote, .citation { display: flex; gap: 8px; font-size: 85%; }
+    .attribution { text-align: right; font-style: italic; }
+    .toctree-wrapper .caption { font-weight: 600; margin-bottom: 4px; }
+
+    .notebook-cell { margin: 0 0 16px; }
+    .notebook-input, .notebook-output { display: grid; grid-template-columns: 72px minmax(0, 1fr); gap: 0 8px; }
+    .notebook-input > .cooked-code-block { margin: 0; }
+    .notebook-prompt { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 12px; color: #656d76; text-align: right; padding-top: 8px; }
+    .notebook-output > :not(.notebook-prompt) { grid-column: -2 / -1; margin: 8px 0 0; overflow-x: auto; }
+    .notebook-output pre { background: transparent !important; padding: 0 !important; margin: 8px 0 0; white-space: pre-wrap; word-break: break-word; }
+    .notebook-output pre.notebook-stderr { background: rgba(207,34,46,0.08) !important; }
+    .notebook-output pre.notebook-error { background: rgba(207,34,46,0.08) !important; padding: 8px !important; }
+    .notebook-output img { max-width: 100%; background: #fff; }
+    .notebook-output .notebook-html table { display: table; width: auto; font-size: 13px; }
+    .notebook-truncated { font-size: 12px; font-style: italic; color: #656d76; }
+    .ansi-black-fg { color: #3e424d; } .ansi-red-fg { color: #e75c58; } .ansi-green-fg { color: #00a250; } .ansi-yellow-fg { color: #ddb62b; }
+    .ansi-blue-fg { color: #208ffb; } .ansi-magenta-fg { color: #d160c4; } .ansi-cyan-fg { color: #60c6c8; } .ansi-white-fg { color: #c5c1b4; }
+    .ansi-black-intense-fg { color: #282c36; } .ansi-red-intense-fg { color: #b22b31; } .ansi-green-intense-fg { color: #007427; } .ansi-yellow-intense-fg { color: #b27d12; }
+    .ansi-blue-intense-fg { color: #0065ca; } .ansi-magenta-intense-fg { color: #a03196; } .ansi-cyan-intense-fg { color: #258f8f; } .ansi-white-intense-fg { color: #a1a6b2; }
+    .ansi-black-bg { background: #3e424d; } .ansi-red-bg { background: #e75c58; } .ansi-green-bg { background: #00a250; } .ansi-yellow-bg { background: #ddb62b; }
+    .ansi-blue-bg { background: #208ffb; } .ansi-magenta-bg { background: #d160c4; } .ansi-cyan-bg { background: #60c6c8; } .ansi-white-bg { background: #c5c1b4; }
+    .ansi-black-intense-bg { background: #282c36; } .ansi-red-intense-bg { background: #b22b31; } .ansi-green-intense-bg { background: #007427; } .ansi-yellow-intense-bg { background: #b27d12; }
+    .ansi-blue-intense-bg { background: #0065ca; } .ansi-magenta-intense-bg { background: #a03196; } .ansi-cyan-intense-bg { background: #258f8f; } .ansi-white-intense-bg { background: #a1a6b2; }
+    .ansi-bold { font-weight: 700; } .ansi-italic { font-style: italic; } .ansi-underline { text-decoration: underline; }
+
     #cooked-error {
       text-align: center; padding: 80px 16px;
     }
@@ -348,5 +413,122 @@ const layoutCSS = `
       #cooked-toc { width: 100%; }
       main { padding: 16px 8px; }
       .markdown-body { padding: 16px; border-radius: 0; border-left: 0; border-right: 0; }
+      .notebook-input, .notebook-output { grid-template-columns: minmax(0, 1fr); }
+      .notebook-prompt { text-align: left; }
+    }
+`
+
+// tableCSS styles the tables of CSV, TSV and PSV files.
+const tableCSS = `
+    .cooked-table-toolbar { display: flex; align-items: center; gap: 12px; margin: 0 0 12px; font-size: 13px; }
+    .cooked-table-filter { flex: 0 1 280px; padding: 4px 8px; font: inherit; color: inherit; background: transparent; border: 1px solid rgba(128,128,128,0.4); border-radius: 6px; }
+    .cooked-table-status { color: #656d76; }
+    .cooked-table-toggle, .cooked-table-pager button {
+      margin-left: auto; background: none; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px;
+      cursor: pointer; padding: 3px 10px; font-size: 12px; color: inherit;
+    }
+    .cooked-table-scroll { max-height: 80vh; overflow: auto; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px; }
+    .markdown-body .cooked-table table { display: table; width: max-content; min-width: 100%; margin: 0; font-size: 13px; }
+    .markdown-body .cooked-table th, .markdown-body .cooked-table td { padding: 4px 10px; white-space: pre; }
+    .markdown-body .cooked-table td.num { text-align: right; font-variant-numeric: tabular-nums; }
+    .markdown-body .cooked-table thead th { position: sticky; top: 0; z-index: 2; background: #f6f8fa; text-align: left; }
+    .markdown-body .cooked-table .cooked-table-rownum { position: sticky; left: 0; z-index: 1; background: #f6f8fa; text-align: right; font-weight: 400; color: #656d76; }
+    .markdown-body .cooked-table thead .cooked-table-rownum { z-index: 3; }
+    .cooked-table-rownum a { color: inherit; }
+    [data-theme="dark"] .markdown-body .cooked-table thead th, [data-theme="dark"] .markdown-body .cooked-table .cooked-table-rownum { background: #161b22; }
+    @media (prefers-color-scheme: dark) {
+      [data-theme="auto"] .markdown-body .cooked-table thead th, [data-theme="auto"] .markdown-body .cooked-table .cooked-table-rownum { background: #161b22; }
+    }
+    .cooked-table-sort { background: none; border: none; padding: 0; font: inherit; font-weight: 600; color: inherit; cursor: pointer; }
+    th[aria-sort="ascending"] .cooked-table-sort::after { content: " \25B2"; font-size: 10px; }
+    th[aria-sort="descending"] .cooked-table-sort::after { content: " \25BC"; font-size: 10px; }
+    .cooked-table-anchor { margin-left: 6px; font-weight: 400; opacity: 0; }
+    th:hover .cooked-table-anchor, th:target .cooked-table-anchor { opacity: 0.6; }
+    .markdown-body .cooked-table th:target, .markdown-body .cooked-table tr:target > *, .markdown-body .cooked-table tr.cooked-table-target > * { background: rgba(212,167,44,0.25); }
+    .cooked-table-pager { display: flex; justify-content: center; align-items: center; gap: 12px; margin: 12px 0 0; font-size: 13px; }
+    .cooked-table-pager button { margin: 0; }
+    .cooked-table-pager button:disabled { opacity: 0.4; cursor: default; }
+    .cooked-table-notice { font-size: 12px; font-style: italic; color: #656d76; }
+`
+
+// treeCSS styles the tree view of JSON, YAML and TOML files.
+const treeCSS = `
+    .cooked-tree-toolbar { display: flex; align-items: center; gap: 12px; margin: 0 0 12px; font-size: 13px; }
+    .cooked-tree-toolbar[hidden], .cooked-tree-toolbar [hidden] { display: none; }
+    .cooked-tree-search { flex: 0 1 280px; padding: 4px 8px; font: inherit; color: inherit; background: transparent; border: 1px solid rgba(128,128,128,0.4); border-radius: 6px; }
+    .cooked-tree-status { color: #656d76; }
+    .cooked-tree-path-style { margin-left: auto; font: inherit; font-size: 12px; color: inherit; background: transparent; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px; padding: 2px 4px; }
+    .cooked-tree-toggle {
+      background: none; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px;
+      cursor: pointer; padding: 3px 10px; font-size: 12px; color: inherit;
+    }
+    .cooked-tree-view { max-height: 80vh; overflow: auto; padding: 8px 0; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px; font-family: ui-monospace, SFMono-Regular, "SF Mono", Menlo, Consolas, monospace; font-size: 13px; }
+    .markdown-body .cooked-tree-view ul { list-style: none; margin: 0; padding: 0 0 0 18px; }
+    .markdown-body .cooked-tree-view ul.cooked-tree-root { padding-left: 4px; }
+    .markdown-body .cooked-tree-view li { margin: 0; }
+    .cooked-tree-doc { padding: 8px 12px 4px; font-weight: 600; color: #656d76; border-top: 1px solid rgba(128,128,128,0.2); }
+    .cooked-tree-doc:first-child { border-top: none; padding-top: 0; }
+    .cooked-tree-row { display: flex; align-items: baseline; gap: 6px; padding: 1px 8px 1px 0; white-space: pre; }
+    .cooked-tree-row:hover { background: rgba(128,128,128,0.1); }
+    .cooked-tree-caret, .cooked-tree-pad { flex: none; width: 16px; }
+    .cooked-tree-caret { background: none; border: none; padding: 0; font: inherit; color: #656d76; cursor: pointer; }
+    .cooked-tree-key { color: #0550ae; }
+    .cooked-tree-key::after { content: ":"; color: #656d76; }
+    .cooked-tree-index { color: #656d76; }
+    .cooked-tree-value { overflow: hidden; text-overflow: ellipsis; }
+    .cooked-tree-object, .cooked-tree-array { color: #656d76; }
+    .cooked-tree-string { color: #0a3069; }
+    .cooked-tree-number, .cooked-tree-bool, .cooked-tree-null { color: #cf222e; }
+    .cooked-tree-datetime, .cooked-tree-binary, .cooked-tree-alias, .cooked-tree-tag { color: #8250df; }
+    .cooked-tree-badge { flex: none; padding: 0 6px; font-size: 11px; border-radius: 10px; color: #656d76; background: rgba(128,128,128,0.15); }
+    .cooked-tree-copy { flex: none; background: none; border: none; padding: 0 4px; font: inherit; color: #656d76; cursor: pointer; opacity: 0; }
+    .cooked-tree-row:hover .cooked-tree-copy, .cooked-tree-copy:focus { opacity: 1; }
+    .cooked-tree-row.cooked-tree-match { background: rgba(212,167,44,0.2); }
+    .cooked-tree-row.cooked-tree-current { background: rgba(212,167,44,0.45); }
+    [data-theme="dark"] .cooked-tree-key { color: #79c0ff; }
+    [data-theme="dark"] .cooked-tree-string { color: #a5d6ff; }
+    [data-theme="dark"] .cooked-tree-number, [data-theme="dark"] .cooked-tree-bool, [data-theme="dark"] .cooked-tree-null { color: #ff7b72; }
+    [data-theme="dark"] .cooked-tree-datetime, [data-theme="dark"] .cooked-tree-binary, [data-theme="dark"] .cooked-tree-alias, [data-theme="dark"] .cooked-tree-tag { color: #d2a8ff; }
+    @media (prefers-color-scheme: dark) {
+      [data-theme="auto"] .cooked-tree-key { color: #79c0ff; }
+      [data-theme="auto"] .cooked-tree-string { color: #a5d6ff; }
+      [data-theme="auto"] .cooked-tree-number, [data-theme="auto"] .cooked-tree-bool, [data-theme="auto"] .cooked-tree-null { color: #ff7b72; }
+      [data-theme="auto"] .cooked-tree-datetime, [data-theme="auto"] .cooked-tree-binary, [data-theme="auto"] .cooked-tree-alias, [data-theme="auto"] .cooked-tree-tag { color: #d2a8ff; }
     }
+    .cooked-tree-notice { font-size: 12px; font-style: italic; color: #656d76; }
+`
+
+// openapiCSS styles OpenAPI and Swagger reference pages.
+const openapiCSS = `
+    .cooked-openapi-badges { display: flex; flex-wrap: wrap; gap: 6px; }
+    .cooked-openapi-badge, .cooked-openapi-flag, .cooked-openapi-required { display: inline-block; padding: 0 8px; font-size: 12px; border-radius: 10px; color: #656d76; background: rgba(128,128,128,0.15); }
+    .cooked-openapi-required { color: #cf222e; background: rgba(207,34,46,0.1); }
+    .cooked-openapi-flag { color: #9a6700; background: rgba(212,167,44,0.2); }
+    .markdown-body .cooked-openapi-info, .markdown-body .cooked-openapi-facts { display: grid; grid-template-columns: max-content 1fr; gap: 4px 16px; }
+    .markdown-body .cooked-openapi-info dt, .markdown-body .cooked-openapi-facts dt { margin: 0; padding: 0; font-style: normal; }
+    .markdown-body .cooked-openapi-info dd, .markdown-body .cooked-openapi-facts dd { margin: 0; padding: 0; }
+    .cooked-openapi-op { margin: 16px 0; padding: 0 16px 8px; border: 1px solid rgba(128,128,128,0.3); border-radius: 6px; }
+    .cooked-openapi-deprecated h3 code { text-decoration: line-through; }
+    .cooked-openapi-method { display: inline-block; min-width: 56px; padding: 2px 6px; font-size: 12px; font-weight: 700; text-align: center; border-radius: 4px; color: #fff; background: #6e7781; vertical-align: middle; }
+    .cooked-openapi-method-get { background: #0969da; }
+    .cooked-openapi-method-post { background: #1a7f37; }
+    .cooked-openapi-method-put, .cooked-openapi-method-patch { background: #9a6700; }
+    .cooked-openapi-method-delete { background: #cf222e; }
+    .cooked-openapi-status { display: inline-block; padding: 0 8px; font-weight: 600; border-radius: 4px; background: rgba(128,128,128,0.15); }
+    .cooked-openapi-status-2xx { color: #1a7f37; background: rgba(26,127,55,0.12); }
+    .cooked-openapi-status-3xx { color: #0969da; background: rgba(9,105,218,0.12); }
+    .cooked-openapi-status-4xx { color: #9a6700; background: rgba(212,167,44,0.2); }
+    .cooked-openapi-status-5xx { color: #cf222e; background: rgba(207,34,46,0.12); }
+    .cooked-openapi-response, .cooked-openapi-media { margin: 8px 0; }
+    .cooked-openapi-media { padding-left: 12px; border-left: 2px solid rgba(128,128,128,0.3); }
+    .cooked-openapi-type { color: #656d76; }
+    .cooked-openapi-props { list-style: none; }
+    .markdown-body .cooked-openapi-props { padding-left: 0; }
+    .cooked-openapi-props > li { padding: 4px 0; border-top: 1px solid rgba(128,128,128,0.2); }
+    .cooked-openapi-props > li > p, .cooked-openapi-props .cooked-openapi-description p { margin: 2px 0; }
+    .cooked-openapi-nested { margin: 4px 0 0 16px; }
+    .cooked-openapi-constraints { font-size: 13px; color: #656d76; }
+    .cooked-openapi-example summary { cursor: pointer; font-size: 13px; color: #656d76; }
+    .cooked-openapi-unresolved { color: #cf222e; }
+    .cooked-openapi-notice { font-size: 12px; font-style: italic; color: #656d76; }
 `
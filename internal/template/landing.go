@@ -77,7 +77,7 @@ func (r *Renderer) RenderLanding(version, defaultTheme string) []byte {
   <!-- cooked: scripts -->
 `)
 
-	writeScripts(&buf)
+	writeScripts(&buf, "")
 
 	fmt.Fprintf(&buf, "</body>\n</html>\n")
 
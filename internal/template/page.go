@@ -15,6 +15,7 @@ import (
 type PageData struct {
 	Version        string
 	UpstreamURL    string
+	SourceURL      string // human-facing URL for the header link; defaults to UpstreamURL
 	ContentType    render.ContentType
 	CacheStatus    string
 	UpstreamStatus int
@@ -29,6 +30,7 @@ type PageData struct {
 	CodeBlockCount int
 	Headings       []render.Heading
 	MermaidPath    string // path to embedded mermaid.js
+	MermaidScript  string // inline mermaid.js source for standalone pages; overrides MermaidPath
 }
 
 // ErrorData holds data for error pages.
@@ -72,7 +74,13 @@ func (r *Renderer) RenderPage(data PageData, lightCSS, darkCSS string) []byte {
 	}
 
 	escapedURL := html.EscapeString(data.UpstreamURL)
-	truncatedURL := truncateURL(data.UpstreamURL, 80)
+
+	sourceURL := data.SourceURL
+	if sourceURL == "" {
+		sourceURL = data.UpstreamURL
+	}
+	escapedSourceURL := html.EscapeString(sourceURL)
+	truncatedURL := truncateURL(sourceURL, 80)
 
 	// HTML head
 	fmt.Fprintf(&buf, `<!DOCTYPE html>
@@ -101,6 +109,7 @@ func (r *Renderer) RenderPage(data PageData, lightCSS, darkCSS string) []byte {
 	// Embedded CSS
 	writeThemeCSS(&buf, lightCSS, darkCSS, r.chromaLightCSS, r.chromaDarkCSS)
 	writeLayoutCSS(&buf)
+	writeContentCSS(&buf, data.ContentType)
 
 	fmt.Fprintf(&buf, `
   </style>
@@ -110,7 +119,7 @@ func (r *Renderer) RenderPage(data PageData, lightCSS, darkCSS string) []byte {
 
 	// Header
 	fmt.Fprintf(&buf, "  <!-- cooked: header -->\n")
-	writeHeader(&buf, data, escapedURL, truncatedURL)
+	writeHeader(&buf, data, escapedSourceURL, truncatedURL)
 
 	// TOC
 	hasTOC := len(data.Headings) >= 3
@@ -141,10 +150,14 @@ func (r *Renderer) RenderPage(data PageData, lightCSS, darkCSS string) []byte {
 
 	// Scripts
 	fmt.Fprintf(&buf, "  <!-- cooked: scripts -->\n")
-	writeScripts(&buf)
+	writeScripts(&buf, data.ContentType)
 
 	// Mermaid
-	if data.HasMermaid && data.MermaidPath != "" {
+	if data.HasMermaid && data.MermaidScript != "" {
+		// A literal </script would end the element early.
+		fmt.Fprintf(&buf, "  <script>%s</script>\n", strings.ReplaceAll(data.MermaidScript, "</script", `<\/script`))
+		fmt.Fprintf(&buf, "  <script>mermaid.initialize({startOnLoad: true, theme: 'default'});</script>\n")
+	} else if data.HasMermaid && data.MermaidPath != "" {
 		fmt.Fprintf(&buf, "  <script src=\"%s\"></script>\n", html.EscapeString(data.MermaidPath))
 		fmt.Fprintf(&buf, "  <script>mermaid.initialize({startOnLoad: true, theme: 'default'});</script>\n")
 	}
@@ -215,7 +228,7 @@ func (r *Renderer) RenderError(data ErrorData) []byte {
 		escapedURL,
 	)
 
-	writeScripts(&buf)
+	writeScripts(&buf, "")
 
 	fmt.Fprintf(&buf, "</body>\n</html>\n")
 
@@ -372,10 +385,16 @@ func contentTypeLabel(ct render.ContentType) string {
 		return "Markdown"
 	case render.TypeMDX:
 		return "MDX"
+	case render.TypeOpenAPI:
+		return "OpenAPI"
 	case render.TypeCode:
 		return "Code"
+	case render.TypeTable:
+		return "Table"
 	case render.TypePlaintext:
 		return "Plain Text"
+	case render.TypeDirectory:
+		return "Directory"
 	default:
 		return "Unknown"
 	}
@@ -84,6 +84,25 @@ func TestRenderPage_DataAttributes(t *testing.T) {
 	}
 }
 
+func TestRenderPage_SourceURL(t *testing.T) {
+	r := NewRenderer()
+	html := string(r.RenderPage(PageData{
+		Version:      "v0.1.0",
+		UpstreamURL:  "https://gitea.internal/o/r/raw/branch/main/README.md",
+		SourceURL:    "https://gitea.internal/o/r/src/branch/main/README.md",
+		ContentType:  render.TypeMarkdown,
+		DefaultTheme: "auto",
+		Content:      template.HTML("<p>hi</p>"),
+	}, "", ""))
+
+	if !strings.Contains(html, `<a id="cooked-source-link" href="https://gitea.internal/o/r/src/branch/main/README.md"`) {
+		t.Error("source link should point at the human-facing SourceURL")
+	}
+	if !strings.Contains(html, `data-upstream-url="https://gitea.internal/o/r/raw/branch/main/README.md"`) {
+		t.Error("data-upstream-url should remain the raw upstream URL")
+	}
+}
+
 func TestRenderPage_NoTOCWithFewHeadings(t *testing.T) {
 	r := NewRenderer()
 	html := string(r.RenderPage(PageData{
@@ -149,6 +168,25 @@ func TestRenderPage_MermaidScript(t *testing.T) {
 	}
 }
 
+func TestRenderPage_MermaidInline(t *testing.T) {
+	r := NewRenderer()
+
+	html := string(r.RenderPage(PageData{
+		DefaultTheme:  "auto",
+		HasMermaid:    true,
+		MermaidPath:   "/_cooked/mermaid.min.js",
+		MermaidScript: `var mermaid = {s: "</script>"};`,
+		Content:       template.HTML("<p>Hello</p>"),
+	}, "", ""))
+
+	if strings.Contains(html, "mermaid.min.js") {
+		t.Error("inline mermaid should replace the script src")
+	}
+	if !strings.Contains(html, `<script>var mermaid = {s: "<\/script>"};</script>`) {
+		t.Error("inline mermaid missing or not escaped")
+	}
+}
+
 func TestRenderPage_PrintCSS(t *testing.T) {
 	r := NewRenderer()
 	html := string(r.RenderPage(PageData{
@@ -161,6 +199,33 @@ func TestRenderPage_PrintCSS(t *testing.T) {
 	}
 }
 
+func TestRenderPage_ContentCSS(t *testing.T) {
+	r := NewRenderer()
+	for _, tc := range []struct {
+		ct   render.ContentType
+		want string // the only view's styles expected, or ""
+	}{
+		{render.TypeMarkdown, ""},
+		{render.TypeTable, ".cooked-table-toolbar"},
+		{render.TypeCode, ".cooked-tree-toolbar"},
+		{render.TypeOpenAPI, ".cooked-openapi-op"},
+	} {
+		html := string(r.RenderPage(PageData{ContentType: tc.ct, DefaultTheme: "auto"}, "", ""))
+		for _, rule := range []string{".cooked-table-toolbar", ".cooked-tree-toolbar", ".cooked-openapi-op"} {
+			if got := strings.Contains(html, rule+" {"); got != (rule == tc.want) {
+				t.Errorf("%s page: has %s styles = %v", tc.ct, rule, got)
+			}
+		}
+	}
+
+	html := string(r.RenderError(ErrorData{StatusCode: 404, DefaultTheme: "auto"}))
+	for _, rule := range []string{".cooked-table-toolbar", ".cooked-tree-toolbar", ".cooked-openapi-op", ".cooked-table-data"} {
+		if strings.Contains(html, rule) {
+			t.Errorf("error page carries %s", rule)
+		}
+	}
+}
+
 func TestRenderError_RequiredElements(t *testing.T) {
 	r := NewRenderer()
 	html := string(r.RenderError(ErrorData{
@@ -2,11 +2,27 @@ package template
 
 import (
 	"bytes"
+
+	"github.com/air-gapped/cooked/internal/render"
 )
 
-func writeScripts(buf *bytes.Buffer) {
-	buf.WriteString(`  <script>
-    // Theme toggle: auto → light → dark → auto
+// writeScripts writes the page's script element: the scripts every page
+// needs, then those of the interactive views that pages of content type ct
+// contain, so other pages do not carry them.
+func writeScripts(buf *bytes.Buffer, ct render.ContentType) {
+	buf.WriteString("  <script>\n")
+	buf.WriteString(pageScript)
+	switch ct {
+	case render.TypeTable:
+		buf.WriteString(tableScript)
+	case render.TypeCode:
+		buf.WriteString(treeScript)
+	}
+	buf.WriteString("  </script>\n")
+}
+
+// pageScript drives the header, table of contents and copy buttons.
+const pageScript = `    // Theme toggle: auto → light → dark → auto
     (function() {
       var toggle = document.getElementById('cooked-theme-toggle');
       if (!toggle) return;
@@ -171,6 +187,360 @@ func writeScripts(buf *bytes.Buffer) {
         });
       });
     })();
-  </script>
-`)
-}
+`
+
+// tableScript drives the tables of CSV, TSV and PSV files.
+const tableScript = `
+    // Data tables: source toggle, paging, sorting, filtering and row links.
+    // Rows come from the embedded JSON, so only one page is ever in the DOM.
+    (function() {
+      document.querySelectorAll('.cooked-table').forEach(function(root) {
+        var view = root.querySelector('.cooked-table-view');
+        var source = root.querySelector('.cooked-table-source');
+        var toggle = root.querySelector('.cooked-table-toggle');
+        var filter = root.querySelector('.cooked-table-filter');
+        var status = root.querySelector('.cooked-table-status');
+        var dataEl = root.querySelector('.cooked-table-data');
+        if (!view || !source || !toggle || !filter || !status || !dataEl) return;
+
+        toggle.addEventListener('click', function() {
+          var showSource = source.hidden;
+          source.hidden = !showSource;
+          view.hidden = showSource;
+          filter.disabled = showSource;
+          toggle.textContent = showSource ? 'Table' : 'Source';
+          toggle.title = showSource ? 'Show the table' : 'Show the source';
+        });
+
+        var rows = JSON.parse(dataEl.textContent);
+        var heads = view.querySelectorAll('thead th[data-type]');
+        var numeric = [];
+        heads.forEach(function(th) { numeric.push(th.getAttribute('data-type') === 'number'); });
+        var tbody = view.querySelector('tbody');
+        var pageRows = parseInt(view.getAttribute('data-page-rows'), 10) || 500;
+        var notice = view.querySelector('.cooked-table-notice');
+        if (notice) notice.remove();
+
+        var order = rows.map(function(_, i) { return i; });
+        var shown = order;
+        var page = 0;
+
+        var pager = document.createElement('div');
+        pager.className = 'cooked-table-pager';
+        var prev = document.createElement('button');
+        prev.textContent = '\u2039 Prev';
+        var info = document.createElement('span');
+        var next = document.createElement('button');
+        next.textContent = 'Next \u203A';
+        pager.append(prev, info, next);
+        view.appendChild(pager);
+        prev.addEventListener('click', function() { page--; renderPage(); });
+        next.addEventListener('click', function() { page++; renderPage(); });
+
+        function renderPage() {
+          var pages = Math.max(1, Math.ceil(shown.length / pageRows));
+          page = Math.max(0, Math.min(page, pages - 1));
+          var frag = document.createDocumentFragment();
+          shown.slice(page * pageRows, (page + 1) * pageRows).forEach(function(i) {
+            var tr = document.createElement('tr');
+            tr.id = 'row-' + (i + 1);
+            var th = document.createElement('th');
+            th.className = 'cooked-table-rownum';
+            var a = document.createElement('a');
+            a.href = '#row-' + (i + 1);
+            a.textContent = i + 1;
+            th.appendChild(a);
+            tr.appendChild(th);
+            rows[i].forEach(function(v, c) {
+              var td = document.createElement('td');
+              if (numeric[c]) td.className = 'num';
+              td.textContent = v;
+              tr.appendChild(td);
+            });
+            frag.appendChild(tr);
+          });
+          tbody.replaceChildren(frag);
+          var count = shown.length === rows.length ? String(rows.length) : shown.length + ' of ' + rows.length;
+          status.textContent = count + ' rows \u00D7 ' + numeric.length + ' columns';
+          pager.hidden = pages <= 1;
+          info.textContent = 'Page ' + (page + 1) + ' of ' + pages;
+          prev.disabled = page === 0;
+          next.disabled = page >= pages - 1;
+        }
+
+        function applyFilter() {
+          var q = filter.value.trim().toLowerCase();
+          shown = q ? order.filter(function(i) {
+            return rows[i].some(function(v) { return v.toLowerCase().indexOf(q) !== -1; });
+          }) : order;
+          page = 0;
+          renderPage();
+        }
+        var filterTimer;
+        filter.addEventListener('input', function() {
+          clearTimeout(filterTimer);
+          filterTimer = setTimeout(applyFilter, 150);
+        });
+
+        // Sort on header click: ascending, then descending. Empty cells
+        // always sort last, and ties keep file order.
+        var collator = new Intl.Collator(undefined, { numeric: true, sensitivity: 'base' });
+        var sortCol = -1, sortDir = 1;
+        heads.forEach(function(th, c) {
+          var btn = th.querySelector('.cooked-table-sort');
+          if (!btn) return;
+          btn.addEventListener('click', function() {
+            sortDir = sortCol === c ? -sortDir : 1;
+            sortCol = c;
+            heads.forEach(function(h) { h.removeAttribute('aria-sort'); });
+            th.setAttribute('aria-sort', sortDir === 1 ? 'ascending' : 'descending');
+            order = order.slice().sort(function(x, y) {
+              var a = rows[x][c].trim(), b = rows[y][c].trim();
+              if (a === '' || b === '') return (a === '') - (b === '') || x - y;
+              var d = numeric[c] ? parseFloat(a.replace(/,/g, '')) - parseFloat(b.replace(/,/g, '')) : NaN;
+              if (isNaN(d)) d = collator.compare(a, b);
+              return sortDir * d || x - y;
+            });
+            applyFilter();
+          });
+        });
+
+        // #row-N shows the page holding row N, clearing a filter that hides it.
+        function showRow() {
+          var m = /^#row-(\d+)$/.exec(location.hash);
+          if (!m) return;
+          var i = parseInt(m[1], 10) - 1;
+          if (i < 0 || i >= rows.length) return;
+          var k = shown.indexOf(i);
+          if (k === -1) {
+            filter.value = '';
+            shown = order;
+            k = shown.indexOf(i);
+          }
+          page = Math.floor(k / pageRows);
+          renderPage();
+          var tr = document.getElementById('row-' + (i + 1));
+          if (tr) {
+            tr.classList.add('cooked-table-target');
+            tr.scrollIntoView({ block: 'center' });
+          }
+        }
+        window.addEventListener('hashchange', showRow);
+
+        renderPage();
+        showRow();
+      });
+    })();
+`
+
+// treeScript drives the tree view of JSON, YAML and TOML files.
+const treeScript = `
+    // Data trees: a collapsible view of parsed JSON, YAML and TOML with
+    // per-node path copy and search. Children are built on first expand.
+    (function() {
+      var simpleKey = /^[A-Za-z_][A-Za-z0-9_]*$/;
+
+      function convert(d, parent, key) {
+        var node = { kind: d[0], parent: parent, key: key };
+        if (d[0] === 'object') {
+          node.children = d[2].map(function(v, i) { return convert(v, node, d[1][i]); });
+        } else if (d[0] === 'array') {
+          node.children = d[1].map(function(v, i) { return convert(v, node, i); });
+        } else {
+          node.value = d[1];
+        }
+        return node;
+      }
+
+      // pathOf returns the JSONPath ($.a['b c'][0]) or yq path (.a."b c"[0])
+      // of a node. yq paths into a multi-document stream select the document.
+      function pathOf(node, style, multi) {
+        var parts = [];
+        for (var n = node; n.parent; n = n.parent) parts.unshift(n.key);
+        var out = style === 'yq' ? '' : '$';
+        parts.forEach(function(k) {
+          if (typeof k === 'number') out += (out ? '' : '.') + '[' + k + ']';
+          else if (simpleKey.test(k)) out += '.' + k;
+          else if (style === 'yq') out += '.' + JSON.stringify(k);
+          else out += "['" + k.replace(/\\/g, '\\\\').replace(/'/g, "\\'") + "']";
+        });
+        if (style === 'yq' && multi) return 'select(documentIndex == ' + n.doc + ') | ' + (out || '.');
+        return out || '.';
+      }
+
+      document.querySelectorAll('.cooked-tree').forEach(function(root) {
+        var toolbar = root.querySelector('.cooked-tree-toolbar');
+        var view = root.querySelector('.cooked-tree-view');
+        var source = root.querySelector('.cooked-tree-source');
+        var toggle = root.querySelector('.cooked-tree-toggle');
+        var search = root.querySelector('.cooked-tree-search');
+        var status = root.querySelector('.cooked-tree-status');
+        var styleSel = root.querySelector('.cooked-tree-path-style');
+        var dataEl = root.querySelector('.cooked-tree-data');
+        if (!toolbar || !view || !source || !toggle || !search || !status || !styleSel || !dataEl) return;
+        toolbar.hidden = false;
+
+        var docs = JSON.parse(dataEl.textContent).map(function(d, i) {
+          var doc = convert(d, null, null);
+          doc.doc = i;
+          return doc;
+        });
+        var built = false;
+
+        function setView(tree) {
+          if (tree && !built) build();
+          view.hidden = !tree;
+          source.hidden = tree;
+          search.hidden = !tree;
+          styleSel.hidden = !tree;
+          status.textContent = '';
+          toggle.textContent = tree ? 'Source' : 'Tree';
+          toggle.title = tree ? 'Show the source' : 'Show the tree';
+          try { localStorage.setItem('cooked-tree-view', tree ? 'tree' : 'source'); } catch (e) {}
+        }
+        toggle.addEventListener('click', function() { setView(view.hidden); });
+
+        function summary(node) {
+          var n = node.children.length;
+          return node.kind === 'object' ? '{' + n + (n === 1 ? ' key}' : ' keys}') : '[' + n + (n === 1 ? ' item]' : ' items]');
+        }
+
+        function renderNode(node, depth) {
+          var li = document.createElement('li');
+          li.className = 'cooked-tree-node';
+          node.el = li;
+          var row = document.createElement('div');
+          row.className = 'cooked-tree-row';
+          li.appendChild(row);
+          var container = !!node.children;
+          if (container && node.children.length) {
+            var caret = document.createElement('button');
+            caret.className = 'cooked-tree-caret';
+            caret.setAttribute('aria-expanded', 'false');
+            caret.setAttribute('aria-label', 'Expand');
+            caret.textContent = '\u25B8';
+            caret.addEventListener('click', function() { setOpen(node, caret.getAttribute('aria-expanded') !== 'true'); });
+            row.appendChild(caret);
+            node.caret = caret;
+          } else {
+            var pad = document.createElement('span');
+            pad.className = 'cooked-tree-pad';
+            row.appendChild(pad);
+          }
+          if (node.parent) {
+            var key = document.createElement('span');
+            key.className = typeof node.key === 'number' ? 'cooked-tree-key cooked-tree-index' : 'cooked-tree-key';
+            key.textContent = node.key;
+            row.appendChild(key);
+          }
+          var value = document.createElement('span');
+          value.className = 'cooked-tree-value cooked-tree-' + (container ? node.kind : /^[a-z]+$/.test(node.kind) ? node.kind : 'tag');
+          value.textContent = container ? summary(node) : node.kind === 'string' ? JSON.stringify(node.value) : node.value;
+          row.appendChild(value);
+          var badge = document.createElement('span');
+          badge.className = 'cooked-tree-badge';
+          badge.textContent = node.kind;
+          row.appendChild(badge);
+          var copy = document.createElement('button');
+          copy.className = 'cooked-tree-copy';
+          copy.title = 'Copy path';
+          copy.textContent = '\u2398';
+          copy.addEventListener('click', function() {
+            var text = pathOf(node, styleSel.value, docs.length > 1);
+            navigator.clipboard.writeText(text).then(function() {
+              copy.textContent = '\u2713';
+              copy.title = 'Copied ' + text;
+              setTimeout(function() { copy.textContent = '\u2398'; copy.title = 'Copy path'; }, 1500);
+            });
+          });
+          row.appendChild(copy);
+          // Open the first two levels of small containers.
+          if (container && depth < 2 && node.children.length && node.children.length <= 50) setOpen(node, true);
+          return li;
+        }
+
+        function setOpen(node, open) {
+          if (!node.caret) return;
+          if (open && !node.list) {
+            node.list = document.createElement('ul');
+            var depth = 0;
+            for (var n = node; n.parent; n = n.parent) depth++;
+            node.children.forEach(function(c) { node.list.appendChild(renderNode(c, depth + 1)); });
+            node.el.appendChild(node.list);
+          }
+          if (node.list) node.list.hidden = !open;
+          node.caret.setAttribute('aria-expanded', String(open));
+          node.caret.setAttribute('aria-label', open ? 'Collapse' : 'Expand');
+          node.caret.textContent = open ? '\u25BE' : '\u25B8';
+        }
+
+        function build() {
+          built = true;
+          docs.forEach(function(doc, i) {
+            if (docs.length > 1) {
+              var h = document.createElement('div');
+              h.className = 'cooked-tree-doc';
+              h.textContent = 'Document ' + (i + 1);
+              view.appendChild(h);
+            }
+            var ul = document.createElement('ul');
+            ul.className = 'cooked-tree-root';
+            ul.appendChild(renderNode(doc, 0));
+            view.appendChild(ul);
+          });
+        }
+
+        // Search matches keys and scalar values. Enter steps through the
+        // matches, opening the nodes above each one.
+        var matches = [], current = -1;
+        function collect(node, q) {
+          if (matches.length >= 1000) return;
+          var hit = node.parent && typeof node.key === 'string' && node.key.toLowerCase().indexOf(q) !== -1;
+          if (!hit && !node.children) hit = String(node.value).toLowerCase().indexOf(q) !== -1;
+          if (hit) matches.push(node);
+          if (node.children) node.children.forEach(function(c) { collect(c, q); });
+        }
+        function reveal(node) {
+          var chain = [];
+          for (var n = node.parent; n; n = n.parent) chain.unshift(n);
+          chain.forEach(function(n) { setOpen(n, true); });
+          var prev = view.querySelector('.cooked-tree-current');
+          if (prev) prev.classList.remove('cooked-tree-current');
+          var row = node.el.firstChild;
+          row.classList.add('cooked-tree-match', 'cooked-tree-current');
+          row.scrollIntoView({ block: 'center' });
+        }
+        function step() {
+          if (!matches.length) return;
+          current = (current + 1) % matches.length;
+          reveal(matches[current]);
+          status.textContent = (current + 1) + ' of ' + matches.length + (matches.length >= 1000 ? '+' : '') + ' matches';
+        }
+        function runSearch() {
+          view.querySelectorAll('.cooked-tree-match').forEach(function(r) { r.classList.remove('cooked-tree-match'); });
+          matches = [];
+          current = -1;
+          var q = search.value.trim().toLowerCase();
+          if (!q) { status.textContent = ''; return; }
+          docs.forEach(function(d) { collect(d, q); });
+          if (!matches.length) { status.textContent = 'No matches'; return; }
+          step();
+          matches.forEach(function(m) { if (m.el) m.el.firstChild.classList.add('cooked-tree-match'); });
+        }
+        var searchTimer;
+        search.addEventListener('input', function() {
+          clearTimeout(searchTimer);
+          searchTimer = setTimeout(runSearch, 150);
+        });
+        search.addEventListener('keydown', function(e) {
+          if (e.key !== 'Enter') return;
+          e.preventDefault();
+          step();
+        });
+
+        var saved = null;
+        try { saved = localStorage.getItem('cooked-tree-view'); } catch (e) {}
+        if (saved === 'tree') setView(true);
+      });
+    })();
+`
@@ -10,7 +10,7 @@ import (
 
 func TestWriteScripts_ThemeCycling(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	// The theme cycles auto → light → dark → auto
@@ -28,7 +28,7 @@ func TestWriteScripts_ThemeCycling(t *testing.T) {
 
 func TestWriteScripts_CookieSetting(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.Contains(script, `_cooked_theme=`) {
@@ -47,7 +47,7 @@ func TestWriteScripts_CookieSetting(t *testing.T) {
 
 func TestWriteScripts_URLParamOverride(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.Contains(script, `URLSearchParams`) {
@@ -64,7 +64,7 @@ func TestWriteScripts_URLParamOverride(t *testing.T) {
 
 func TestWriteScripts_ThemeToggleButton(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.Contains(script, `getElementById('cooked-theme-toggle')`) {
@@ -85,7 +85,7 @@ func TestWriteScripts_ThemeToggleButton(t *testing.T) {
 
 func TestWriteScripts_TOCToggle(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.Contains(script, `getElementById('cooked-toc-toggle')`) {
@@ -101,7 +101,7 @@ func TestWriteScripts_TOCToggle(t *testing.T) {
 
 func TestWriteScripts_TOCMobileClose(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	// On mobile (<=768px), clicking a TOC link should close the TOC
@@ -115,7 +115,7 @@ func TestWriteScripts_TOCMobileClose(t *testing.T) {
 
 func TestWriteScripts_CopyButton(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.Contains(script, `.cooked-copy-btn`) {
@@ -139,9 +139,77 @@ func TestWriteScripts_CopyButton(t *testing.T) {
 	}
 }
 
+func TestWriteScripts_DataTable(t *testing.T) {
+	var buf bytes.Buffer
+	writeScripts(&buf, render.TypeTable)
+	script := buf.String()
+
+	// Rows come from the embedded JSON, one page at a time.
+	if !strings.Contains(script, `JSON.parse(dataEl.textContent)`) {
+		t.Error("missing table data parsing")
+	}
+	if !strings.Contains(script, `data-page-rows`) {
+		t.Error("missing page size lookup")
+	}
+	if !strings.Contains(script, `aria-sort`) {
+		t.Error("missing sort state on headers")
+	}
+	if !strings.Contains(script, `'hashchange', showRow`) {
+		t.Error("missing row anchor handling")
+	}
+	if !strings.Contains(script, `source.hidden = !showSource`) {
+		t.Error("missing source view toggle")
+	}
+}
+
+func TestWriteScripts_DataTree(t *testing.T) {
+	var buf bytes.Buffer
+	writeScripts(&buf, render.TypeCode)
+	script := buf.String()
+
+	// The tree is built from the embedded JSON, children on first expand.
+	if !strings.Contains(script, `root.querySelector('.cooked-tree-data')`) {
+		t.Error("missing tree data lookup")
+	}
+	if !strings.Contains(script, `if (open && !node.list)`) {
+		t.Error("missing lazy child rendering")
+	}
+	if !strings.Contains(script, `pathOf(node, styleSel.value, docs.length > 1)`) {
+		t.Error("missing path copy")
+	}
+	if !strings.Contains(script, `select(documentIndex == `) {
+		t.Error("missing document selection in yq paths")
+	}
+	if !strings.Contains(script, `search.addEventListener('keydown'`) {
+		t.Error("missing search stepping")
+	}
+}
+
+func TestWriteScripts_OnlyForContentType(t *testing.T) {
+	for _, tc := range []struct {
+		ct                  render.ContentType
+		wantTable, wantTree bool
+	}{
+		{render.TypeMarkdown, false, false},
+		{render.TypeTable, true, false},
+		{render.TypeCode, false, true},
+		{"", false, false},
+	} {
+		var buf bytes.Buffer
+		writeScripts(&buf, tc.ct)
+		script := buf.String()
+		if got := strings.Contains(script, ".cooked-table-data"); got != tc.wantTable {
+			t.Errorf("%q: table script = %v, want %v", tc.ct, got, tc.wantTable)
+		}
+		if got := strings.Contains(script, ".cooked-tree-data"); got != tc.wantTree {
+			t.Errorf("%q: tree script = %v, want %v", tc.ct, got, tc.wantTree)
+		}
+	}
+}
+
 func TestWriteScripts_WrappedInScriptTag(t *testing.T) {
 	var buf bytes.Buffer
-	writeScripts(&buf)
+	writeScripts(&buf, render.TypeMarkdown)
 	script := buf.String()
 
 	if !strings.HasPrefix(strings.TrimSpace(script), "<script>") {
@@ -0,0 +1,103 @@
+// Package tracing configures OpenTelemetry trace export and provides the
+// span helpers used across cooked.
+//
+// Until Setup installs an exporter the global tracer provider is a no-op, so
+// instrumented code costs next to nothing when tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the service.name resource attribute of exported spans.
+const ServiceName = "cooked"
+
+const tracerName = "github.com/air-gapped/cooked"
+
+// Setup installs a global tracer provider exporting to the OTLP collector at
+// endpoint over protocol ("http" or "grpc"), sampling sampleRatio of new
+// traces. Traces started upstream of cooked keep their sampling decision.
+// The returned function flushes and stops the exporter. With an empty
+// endpoint Setup does nothing.
+func Setup(ctx context.Context, endpoint, protocol string, sampleRatio float64, version string) (func(context.Context) error, error) {
+	// Propagate incoming traceparent headers even when not exporting, so
+	// trace IDs still reach the request log.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch protocol {
+	case "grpc":
+		exporter, err = otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(endpoint))
+	case "http":
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q", protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", ServiceName),
+		attribute.String("service.version", version),
+	)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span named name as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Fail records err on span and marks it failed.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// URL returns the url.full attribute for rawURL without userinfo, query, or
+// fragment, which may carry credentials.
+func URL(rawURL string) attribute.KeyValue {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return attribute.String("url.full", "")
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return attribute.String("url.full", u.String())
+}
+
+// TraceID returns the hex trace ID of the span in ctx, or "" when there is
+// none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(t.Context(), "", "http", 1, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(t.Context()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+
+	// Incoming trace context is still honoured without an exporter.
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	if got := TraceID(ctx); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q", got)
+	}
+}
+
+func TestSetup_UnknownProtocol(t *testing.T) {
+	if _, err := Setup(t.Context(), "http://localhost:4318", "thrift", 1, "test"); err == nil {
+		t.Error("expected error for unknown protocol, got nil")
+	}
+}
+
+func TestStart(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = tp.Shutdown(context.Background())
+	})
+
+	ctx, parent := Start(t.Context(), "parent")
+	_, child := Start(ctx, "child", URL("https://user:pw@example.com/a.md?token=x#top"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("child span is not parented to the enclosing span")
+	}
+	if TraceID(ctx) != spans[1].SpanContext().TraceID().String() {
+		t.Errorf("TraceID = %q, want %q", TraceID(ctx), spans[1].SpanContext().TraceID())
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "url.full" && attr.Value.AsString() != "https://example.com/a.md" {
+			t.Errorf("url.full = %q, want credentials, query and fragment removed", attr.Value.AsString())
+		}
+	}
+}
+
+func TestTraceID_None(t *testing.T) {
+	if got := TraceID(context.Background()); got != "" {
+		t.Errorf("TraceID = %q, want empty", got)
+	}
+}
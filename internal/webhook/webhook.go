@@ -0,0 +1,330 @@
+// Package webhook parses and verifies forge push notifications and maps the
+// files they touch onto the forge page URLs cooked may have rendered.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/air-gapped/cooked/internal/forge"
+)
+
+// Payload kinds accepted by Parse.
+const (
+	KindGitea   = "gitea" // also Forgejo and Gogs
+	KindGitLab  = "gitlab"
+	KindGeneric = "generic"
+)
+
+// ErrUnauthorized is returned by Verify when the request does not carry a
+// valid signature or token.
+var ErrUnauthorized = errors.New("webhook signature or token invalid")
+
+// Push describes the files changed by a push.
+type Push struct {
+	Forge   string // KindGitea or KindGitLab; "" when only URLs are known
+	RepoURL string // human URL of the repository, no trailing slash
+	RefKind string // gitea only: branch or tag
+	Ref     string // branch or tag name
+	Paths   []string
+	URLs    []string // upstream URLs named directly (generic payloads)
+
+	// Truncated is set when the payload does not list every changed file
+	// (too many commits, a new tag, a force push), so callers should
+	// invalidate the whole repository instead of individual paths.
+	Truncated bool
+}
+
+// Kind normalizes a webhook kind from the request path, reporting false for
+// unknown kinds.
+func Kind(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "gitea", "forgejo", "gogs":
+		return KindGitea, true
+	case "gitlab":
+		return KindGitLab, true
+	case "generic":
+		return KindGeneric, true
+	}
+	return "", false
+}
+
+// Verify checks the request's signature or token against secret.
+//
+// Gitea/Forgejo sign the body with HMAC-SHA256 (X-Gitea-Signature,
+// X-Forgejo-Signature, X-Gogs-Signature, or X-Hub-Signature-256). GitLab
+// sends the secret itself in X-Gitlab-Token. Generic payloads may use
+// either X-Hub-Signature-256 or an Authorization: Bearer token.
+func Verify(kind string, h http.Header, body []byte, secret string) error {
+	switch kind {
+	case KindGitea:
+		for _, name := range []string{"X-Forgejo-Signature", "X-Gitea-Signature", "X-Gogs-Signature"} {
+			if sig := h.Get(name); sig != "" {
+				return verifyHMAC(sig, body, secret)
+			}
+		}
+		if sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256="); ok {
+			return verifyHMAC(sig, body, secret)
+		}
+	case KindGitLab:
+		if token := h.Get("X-Gitlab-Token"); token != "" {
+			return verifyToken(token, secret)
+		}
+	case KindGeneric:
+		if sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256="); ok {
+			return verifyHMAC(sig, body, secret)
+		}
+		if token, ok := strings.CutPrefix(h.Get("Authorization"), "Bearer "); ok {
+			return verifyToken(strings.TrimSpace(token), secret)
+		}
+	}
+	return ErrUnauthorized
+}
+
+func verifyHMAC(sigHex string, body []byte, secret string) error {
+	sig, err := hex.DecodeString(strings.TrimSpace(sigHex))
+	if err != nil {
+		return ErrUnauthorized
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func verifyToken(token, secret string) error {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// IsPush reports whether the request headers announce a push event. Requests
+// without an event header are assumed to be pushes.
+func IsPush(kind string, h http.Header) bool {
+	switch kind {
+	case KindGitea:
+		for _, name := range []string{"X-Forgejo-Event", "X-Gitea-Event", "X-Gogs-Event"} {
+			if ev := h.Get(name); ev != "" {
+				return ev == "push"
+			}
+		}
+	case KindGitLab:
+		if ev := h.Get("X-Gitlab-Event"); ev != "" {
+			return ev == "Push Hook" || ev == "Tag Push Hook"
+		}
+	}
+	return true
+}
+
+// commit is the part of a Gitea or GitLab commit the push handler uses.
+type commit struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// giteaPush is the subset of the Gitea/Forgejo push payload cooked reads.
+type giteaPush struct {
+	Ref          string   `json:"ref"`
+	Commits      []commit `json:"commits"`
+	TotalCommits int      `json:"total_commits"`
+	Repository   struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+}
+
+// gitlabPush is the subset of the GitLab push payload cooked reads.
+type gitlabPush struct {
+	Ref               string   `json:"ref"`
+	Commits           []commit `json:"commits"`
+	TotalCommitsCount int      `json:"total_commits_count"`
+	Project           struct {
+		WebURL string `json:"web_url"`
+	} `json:"project"`
+}
+
+// genericPush is cooked's own payload format for CI jobs and scripts.
+//
+//	{
+//	  "forge": "gitea",
+//	  "repository": "https://git.internal/org/docs",
+//	  "ref": "main",
+//	  "paths": ["README.md", "docs/setup.md"],
+//	  "urls": ["https://files.internal/runbooks/deploy.md"]
+//	}
+type genericPush struct {
+	Forge      string   `json:"forge"`
+	Repository string   `json:"repository"`
+	Ref        string   `json:"ref"`
+	Paths      []string `json:"paths"`
+	URLs       []string `json:"urls"`
+}
+
+// Parse decodes a push payload of the given kind.
+func Parse(kind string, body []byte) (*Push, error) {
+	switch kind {
+	case KindGitea:
+		var p giteaPush
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, fmt.Errorf("parse gitea push: %w", err)
+		}
+		push := &Push{Forge: KindGitea, RepoURL: p.Repository.HTMLURL}
+		push.setRef(p.Ref)
+		push.addCommits(p.Commits, p.TotalCommits)
+		return push, push.validate()
+
+	case KindGitLab:
+		var p gitlabPush
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, fmt.Errorf("parse gitlab push: %w", err)
+		}
+		push := &Push{Forge: KindGitLab, RepoURL: p.Project.WebURL}
+		push.setRef(p.Ref)
+		push.addCommits(p.Commits, p.TotalCommitsCount)
+		return push, push.validate()
+
+	case KindGeneric:
+		var p genericPush
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, fmt.Errorf("parse generic push: %w", err)
+		}
+		push := &Push{URLs: p.URLs, Paths: cleanPaths(p.Paths)}
+		if p.Repository == "" {
+			if len(push.Paths) > 0 {
+				return nil, errors.New("paths require a repository")
+			}
+			if len(push.URLs) == 0 {
+				return nil, errors.New("payload names no repository or urls")
+			}
+			return push, nil
+		}
+		forge, ok := Kind(p.Forge)
+		if !ok || forge == KindGeneric {
+			return nil, fmt.Errorf("unknown forge %q: must be gitea, forgejo, or gitlab", p.Forge)
+		}
+		push.Forge, push.RepoURL = forge, p.Repository
+		push.setRef(p.Ref)
+		// Without paths the whole repository is invalidated.
+		push.Truncated = len(push.Paths) == 0
+		return push, push.validate()
+	}
+	return nil, fmt.Errorf("unknown webhook kind %q", kind)
+}
+
+// setRef splits a full ref name into its kind and short name.
+func (p *Push) setRef(ref string) {
+	p.RefKind = "branch"
+	if name, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		p.RefKind, p.Ref = "tag", name
+		return
+	}
+	p.Ref = strings.TrimPrefix(ref, "refs/heads/")
+}
+
+func (p *Push) addCommits(commits []commit, total int) {
+	var paths []string
+	for _, c := range commits {
+		paths = append(paths, c.Added...)
+		paths = append(paths, c.Modified...)
+		paths = append(paths, c.Removed...)
+	}
+	p.Paths = cleanPaths(paths)
+	// No commits means a new tag, a branch deletion, or a force push to an
+	// existing commit: the changed files are unknown.
+	p.Truncated = len(commits) == 0 || total > len(commits)
+}
+
+func (p *Push) validate() error {
+	u, err := url.Parse(p.RepoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid repository URL %q", p.RepoURL)
+	}
+	p.RepoURL = strings.TrimSuffix(p.RepoURL, "/")
+	if p.Ref == "" {
+		return errors.New("push has no ref")
+	}
+	return nil
+}
+
+// cleanPaths normalizes and deduplicates repository paths, dropping any
+// that would escape the repository root.
+func cleanPaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	var out []string
+	for _, p := range paths {
+		p = path.Clean("/" + p)
+		if p == "/" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, strings.TrimPrefix(p, "/"))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// PageURLs returns the forge page URLs whose rendering may have changed: each
+// changed file, and every directory containing one (its listing and inline
+// README), up to the repository root.
+func (p *Push) PageURLs() []string {
+	if p.Forge == "" {
+		return nil
+	}
+	var urls []string
+	dirs := map[string]bool{"": true}
+	for _, file := range p.Paths {
+		urls = append(urls, p.fileURL(file))
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	for _, dir := range sorted {
+		urls = append(urls, p.DirURL(dir))
+	}
+	return urls
+}
+
+// fileURL returns the forge page URL of a file at the pushed ref.
+func (p *Push) fileURL(file string) string {
+	if p.Forge == KindGitLab {
+		return p.RepoURL + "/-/blob/" + forge.EscapePath(p.Ref) + "/" + forge.EscapePath(file)
+	}
+	return p.RepoURL + "/src/" + p.RefKind + "/" + forge.EscapePath(p.Ref) + "/" + forge.EscapePath(file)
+}
+
+// DirURL returns the forge page URL of a directory at the pushed ref; ""
+// is the repository root.
+func (p *Push) DirURL(dir string) string {
+	var base string
+	if p.Forge == KindGitLab {
+		base = p.RepoURL + "/-/tree/" + forge.EscapePath(p.Ref)
+		if dir == "" {
+			return base
+		}
+		return base + "/" + forge.EscapePath(dir)
+	}
+	base = p.RepoURL + "/src/" + p.RefKind + "/" + forge.EscapePath(p.Ref) + "/"
+	if dir == "" {
+		return base
+	}
+	return base + forge.EscapePath(dir) + "/"
+}
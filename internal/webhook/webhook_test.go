@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestKind(t *testing.T) {
+	for name, want := range map[string]string{
+		"gitea": KindGitea, "Forgejo": KindGitea, "gogs": KindGitea,
+		"gitlab": KindGitLab, "generic": KindGeneric,
+	} {
+		if got, ok := Kind(name); !ok || got != want {
+			t.Errorf("Kind(%q) = %q, %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := Kind("github"); ok {
+		t.Error("Kind(github) should be unknown")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	const secret = "s3cret"
+
+	tests := []struct {
+		name   string
+		kind   string
+		header http.Header
+		ok     bool
+	}{
+		{"gitea signature", KindGitea, http.Header{"X-Gitea-Signature": {sign(body, secret)}}, true},
+		{"forgejo signature", KindGitea, http.Header{"X-Forgejo-Signature": {sign(body, secret)}}, true},
+		{"hub signature", KindGitea, http.Header{"X-Hub-Signature-256": {"sha256=" + sign(body, secret)}}, true},
+		{"wrong secret", KindGitea, http.Header{"X-Gitea-Signature": {sign(body, "other")}}, false},
+		{"not hex", KindGitea, http.Header{"X-Gitea-Signature": {"zz"}}, false},
+		{"gitea unsigned", KindGitea, http.Header{}, false},
+		{"gitlab token", KindGitLab, http.Header{"X-Gitlab-Token": {secret}}, true},
+		{"gitlab wrong token", KindGitLab, http.Header{"X-Gitlab-Token": {"nope"}}, false},
+		{"gitlab signature only", KindGitLab, http.Header{"X-Hub-Signature-256": {"sha256=" + sign(body, secret)}}, false},
+		{"generic signature", KindGeneric, http.Header{"X-Hub-Signature-256": {"sha256=" + sign(body, secret)}}, true},
+		{"generic bearer", KindGeneric, http.Header{"Authorization": {"Bearer " + secret}}, true},
+		{"generic wrong bearer", KindGeneric, http.Header{"Authorization": {"Bearer x"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.kind, tt.header, body, secret)
+			if tt.ok && err != nil {
+				t.Errorf("Verify = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("Verify = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestIsPush(t *testing.T) {
+	tests := []struct {
+		kind   string
+		header http.Header
+		want   bool
+	}{
+		{KindGitea, http.Header{"X-Gitea-Event": {"push"}}, true},
+		{KindGitea, http.Header{"X-Forgejo-Event": {"issues"}}, false},
+		{KindGitLab, http.Header{"X-Gitlab-Event": {"Push Hook"}}, true},
+		{KindGitLab, http.Header{"X-Gitlab-Event": {"Merge Request Hook"}}, false},
+		{KindGeneric, http.Header{}, true},
+	}
+	for _, tt := range tests {
+		if got := IsPush(tt.kind, tt.header); got != tt.want {
+			t.Errorf("IsPush(%s, %v) = %v, want %v", tt.kind, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Gitea(t *testing.T) {
+	body := []byte(`{
+		"ref": "refs/heads/main",
+		"total_commits": 2,
+		"commits": [
+			{"added": ["docs/new.md"], "modified": ["README.md"], "removed": []},
+			{"added": [], "modified": ["docs/new.md"], "removed": ["docs/old/gone.md"]}
+		],
+		"repository": {"html_url": "https://git.internal/org/repo"}
+	}`)
+	push, err := Parse(KindGitea, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if push.RepoURL != "https://git.internal/org/repo" || push.Ref != "main" || push.RefKind != "branch" {
+		t.Errorf("push = %+v", push)
+	}
+	if push.Truncated {
+		t.Error("push listing every commit should not be truncated")
+	}
+	want := []string{"README.md", "docs/new.md", "docs/old/gone.md"}
+	if !reflect.DeepEqual(push.Paths, want) {
+		t.Errorf("Paths = %q, want %q", push.Paths, want)
+	}
+
+	wantURLs := []string{
+		"https://git.internal/org/repo/src/branch/main/README.md",
+		"https://git.internal/org/repo/src/branch/main/docs/new.md",
+		"https://git.internal/org/repo/src/branch/main/docs/old/gone.md",
+		"https://git.internal/org/repo/src/branch/main/",
+		"https://git.internal/org/repo/src/branch/main/docs/",
+		"https://git.internal/org/repo/src/branch/main/docs/old/",
+	}
+	if got := push.PageURLs(); !reflect.DeepEqual(got, wantURLs) {
+		t.Errorf("PageURLs =\n%q\nwant\n%q", got, wantURLs)
+	}
+}
+
+func TestParse_GitLab(t *testing.T) {
+	body := []byte(`{
+		"object_kind": "push",
+		"ref": "refs/heads/release/1.x",
+		"total_commits_count": 25,
+		"commits": [{"added": [], "modified": ["guide/a b.md"], "removed": []}],
+		"project": {"web_url": "https://gitlab.internal/group/sub/repo"}
+	}`)
+	push, err := Parse(KindGitLab, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !push.Truncated {
+		t.Error("push with more commits than listed should be truncated")
+	}
+	wantURLs := []string{
+		"https://gitlab.internal/group/sub/repo/-/blob/release/1.x/guide/a%20b.md",
+		"https://gitlab.internal/group/sub/repo/-/tree/release/1.x",
+		"https://gitlab.internal/group/sub/repo/-/tree/release/1.x/guide",
+	}
+	if got := push.PageURLs(); !reflect.DeepEqual(got, wantURLs) {
+		t.Errorf("PageURLs =\n%q\nwant\n%q", got, wantURLs)
+	}
+}
+
+func TestParse_TagPushIsTruncated(t *testing.T) {
+	body := []byte(`{"ref": "refs/tags/v1.0", "commits": [], "repository": {"html_url": "https://git.internal/org/repo/"}}`)
+	push, err := Parse(KindGitea, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if push.RefKind != "tag" || push.Ref != "v1.0" || !push.Truncated {
+		t.Errorf("push = %+v, want truncated tag v1.0", push)
+	}
+	if push.RepoURL != "https://git.internal/org/repo" {
+		t.Errorf("RepoURL = %q, want trailing slash trimmed", push.RepoURL)
+	}
+	if got := push.DirURL(""); got != "https://git.internal/org/repo/src/tag/v1.0/" {
+		t.Errorf("DirURL = %q", got)
+	}
+}
+
+func TestParse_Generic(t *testing.T) {
+	push, err := Parse(KindGeneric, []byte(`{"urls": ["https://files.internal/a.md"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if push.Forge != "" || len(push.PageURLs()) != 0 || push.URLs[0] != "https://files.internal/a.md" {
+		t.Errorf("push = %+v", push)
+	}
+
+	push, err = Parse(KindGeneric, []byte(`{"forge": "forgejo", "repository": "https://git.internal/org/repo", "ref": "main", "paths": ["../../etc/passwd", "/README.md"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if push.Forge != KindGitea || push.Truncated {
+		t.Errorf("push = %+v", push)
+	}
+	if want := []string{"README.md", "etc/passwd"}; !reflect.DeepEqual(push.Paths, want) {
+		t.Errorf("Paths = %q, want %q (kept inside the repository)", push.Paths, want)
+	}
+
+	push, err = Parse(KindGeneric, []byte(`{"forge": "gitlab", "repository": "https://gitlab.internal/g/r", "ref": "main"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !push.Truncated {
+		t.Error("generic push without paths should invalidate the whole repository")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		kind string
+		body string
+	}{
+		{KindGitea, `not json`},
+		{KindGitea, `{"ref": "refs/heads/main", "repository": {"html_url": "file:///etc"}}`},
+		{KindGitLab, `{"project": {"web_url": "https://gitlab.internal/g/r"}}`},
+		{KindGeneric, `{}`},
+		{KindGeneric, `{"paths": ["README.md"]}`},
+		{KindGeneric, `{"forge": "svn", "repository": "https://x.internal/r", "ref": "main"}`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.kind, []byte(tt.body)); err == nil {
+			t.Errorf("Parse(%s, %s): expected error, got nil", tt.kind, tt.body)
+		}
+	}
+}